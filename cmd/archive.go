@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	store "github.com/kkrt-labs/go-utils/store"
+	"github.com/kkrt-labs/zk-pig/src/generator"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/spf13/cobra"
+)
+
+// NewArchiveFsckCommand creates and returns the archive-fsck command
+func NewArchiveFsckCommand(_ *RootContext) *cobra.Command {
+	var (
+		dir      string
+		codec    string
+		encoding string
+		rewrite  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "archive-fsck",
+		Short: "Verify and re-canonicalize an archive of stored prover inputs",
+		Long:  "Walk the prover inputs stored in --dir, verify each of them, and optionally rewrite the valid ones in canonical form. It runs off-line and fails if any input does not pass verification",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := input.GetCodec(codec)
+			if err != nil {
+				return err
+			}
+
+			contentEncoding, err := store.ParseContentEncoding(encoding)
+			if err != nil {
+				return err
+			}
+
+			results, err := generator.FsckArchive(cmd.Context(), dir, c, contentEncoding, rewrite)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, res := range results {
+				switch {
+				case res.Err != nil:
+					failed++
+					fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s: %v\n", res.Path, res.Err)
+				case res.Rewritten:
+					fmt.Fprintf(cmd.OutOrStdout(), "FIXED %s\n", res.Path)
+				default:
+					fmt.Fprintf(cmd.OutOrStdout(), "OK %s\n", res.Path)
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d prover inputs failed verification", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory of the archive of prover inputs")
+	cmd.Flags().StringVar(&codec, "codec", "json", fmt.Sprintf("Codec of the stored prover inputs (one of %q)", input.Codecs()))
+	cmd.Flags().StringVar(&encoding, "content-encoding", "", "Content encoding the prover inputs are stored with (one of \"gzip\", \"zlib\", \"flate\", empty for plain)")
+	cmd.Flags().BoolVar(&rewrite, "rewrite", false, "Rewrite valid prover inputs in canonical form")
+	_ = cmd.MarkFlagRequired("dir")
+
+	return cmd
+}
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/kkrt-labs/go-utils/ethereum/rpc/jsonrpc"
 	"github.com/kkrt-labs/zk-pig/src"
+	"github.com/kkrt-labs/zk-pig/src/generator"
 	"github.com/spf13/cobra"
 )
 
@@ -36,7 +36,7 @@ func NewGenerateCommand(rootCtx *RootContext) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number")
+	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number, or block tag (latest, finalized or safe)")
 
 	return cmd
 }
@@ -60,15 +60,16 @@ func NewPreflightCommand(rootCtx *RootContext) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number")
+	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number, or block tag (latest, finalized or safe)")
 
 	return cmd
 }
 
 func NewPrepareCommand(rootCtx *RootContext) *cobra.Command {
 	var (
-		ctx         = &ProverInputContext{RootContext: *rootCtx}
-		blockNumber string
+		ctx           = &ProverInputContext{RootContext: *rootCtx}
+		blockNumber   string
+		preflightFile string
 	)
 
 	cmd := &cobra.Command{
@@ -77,6 +78,9 @@ func NewPrepareCommand(rootCtx *RootContext) *cobra.Command {
 		Long:    "Prepare prover inputs by basing on data previously collected during preflight. It can be ran off-line in which case it needs --chain-id to be provided",
 		PreRunE: preRun(ctx, &blockNumber),
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			if preflightFile != "" {
+				return ctx.svc.PrepareFromFile(cmd.Context(), preflightFile)
+			}
 			return ctx.svc.Prepare(cmd.Context(), ctx.blockNumber)
 		},
 		PostRunE: func(cmd *cobra.Command, _ []string) error {
@@ -84,7 +88,8 @@ func NewPrepareCommand(rootCtx *RootContext) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number")
+	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number, or block tag (latest, finalized or safe) resolved with --chain-rpc-url")
+	cmd.Flags().StringVar(&preflightFile, "preflight-file", "", "Optional preflight data file to prepare from, instead of the preflight data store (overrides --block-number)")
 
 	return cmd
 }
@@ -108,7 +113,7 @@ func NewExecuteCommand(rootCtx *RootContext) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number")
+	cmd.Flags().StringVarP(&blockNumber, "block-number", "b", "latest", "Block number, or block tag (latest, finalized or safe) resolved with --chain-rpc-url")
 
 	return cmd
 }
@@ -161,7 +166,7 @@ func preRun(ctx *ProverInputContext, blockNumber *string) func(cmd *cobra.Comman
 			return fmt.Errorf("failed to start prover inputs service: %v", err)
 		}
 
-		ctx.blockNumber, err = jsonrpc.FromBlockNumArg(*blockNumber)
+		ctx.blockNumber, err = generator.ParseBlockNumber(*blockNumber)
 		if err != nil {
 			return fmt.Errorf("invalid block number: %v", err)
 		}
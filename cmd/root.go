@@ -71,6 +71,7 @@ func NewZkPigCommand() *cobra.Command {
 	rootCmd.AddCommand(NewPrepareCommand(ctx))
 	rootCmd.AddCommand(NewExecuteCommand(ctx))
 	rootCmd.AddCommand(NewConfigCommand(ctx))
+	rootCmd.AddCommand(NewArchiveFsckCommand(ctx))
 
 	return rootCmd
 }
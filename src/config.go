@@ -4,22 +4,39 @@ import (
 	"fmt"
 	"math/big"
 	"path/filepath"
+	"time"
 
 	aws "github.com/kkrt-labs/go-utils/aws"
+	jsonrpchttp "github.com/kkrt-labs/go-utils/jsonrpc/http"
 	jsonrpcmrgd "github.com/kkrt-labs/go-utils/jsonrpc/merged"
+	comhttp "github.com/kkrt-labs/go-utils/net/http"
 	store "github.com/kkrt-labs/go-utils/store"
 	filestore "github.com/kkrt-labs/go-utils/store/file"
 	multistore "github.com/kkrt-labs/go-utils/store/multi"
 	s3store "github.com/kkrt-labs/go-utils/store/s3"
+	comtime "github.com/kkrt-labs/go-utils/time"
 	"github.com/kkrt-labs/zk-pig/src/config"
+	"github.com/kkrt-labs/zk-pig/src/generator"
 	inputstore "github.com/kkrt-labs/zk-pig/src/store"
 )
 
 type ChainConfig struct {
-	ID  *big.Int
-	RPC *jsonrpcmrgd.Config
+	ID      *big.Int
+	Genesis string // Optional path to a genesis.json file
+	RPC     *jsonrpcmrgd.Config
+
+	CallTimeout time.Duration          // Timeout of each RPC call made during preflight, none if zero or negative
+	RetryPolicy *generator.RetryPolicy // Optional, retries of the failed RPC calls made during preflight
 }
 
+const (
+	defaultCallTimeout      = 5 * time.Second
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 5 * time.Second
+	defaultRetryJitter      = 0.2
+)
+
 type StoreConfig struct {
 	Format      store.ContentType
 	Compression store.ContentEncoding
@@ -48,9 +65,16 @@ func (cfg *Config) SetDefault() *Config {
 func FromGlobalConfig(gcfg *config.Config) (*Config, error) {
 	// Initialize configuration with default values
 	cfg := &Config{
-		Chain:   ChainConfig{},
+		Chain: ChainConfig{
+			Genesis:     gcfg.Chain.Genesis,
+			CallTimeout: gcfg.Chain.RPC.CallTimeout,
+			RetryPolicy: retryPolicy(gcfg),
+		},
 		DataDir: gcfg.DataDir,
 	}
+	if cfg.Chain.CallTimeout == 0 {
+		cfg.Chain.CallTimeout = defaultCallTimeout
+	}
 
 	// Set Chain ID if provided
 	var err error
@@ -62,7 +86,14 @@ func FromGlobalConfig(gcfg *config.Config) (*Config, error) {
 
 	// --- Set RPC configuration if URL is provided ---
 	if gcfg.Chain.RPC.URL != "" {
-		cfg.Chain.RPC = &jsonrpcmrgd.Config{Addr: gcfg.Chain.RPC.URL}
+		cfg.Chain.RPC = &jsonrpcmrgd.Config{
+			Addr: gcfg.Chain.RPC.URL,
+			HTTP: &jsonrpchttp.Config{
+				HTTP: &comhttp.ClientConfig{
+					Transport: poolTransportConfig(gcfg),
+				},
+			},
+		}
 	}
 
 	// --- Set Preflight Data Store configuration ---
@@ -109,11 +140,44 @@ func FromGlobalConfig(gcfg *config.Config) (*Config, error) {
 		StoreConfig:     proverInputStoreCfg,
 		ContentEncoding: contentEncoding,
 		ContentType:     contentType,
+		Codec:           gcfg.ProverInputStore.Codec,
 	}
 
 	return cfg, err
 }
 
+// poolTransportConfig returns the HTTP transport configuration bounding the pool of connections to the RPC endpoint
+func poolTransportConfig(gcfg *config.Config) *comhttp.TransportConfig {
+	pool := gcfg.Chain.RPC.Pool
+	transportCfg := &comhttp.TransportConfig{
+		MaxIdleConnsPerHost: pool.MaxIdleConns,
+		MaxConnsPerHost:     pool.MaxOpenConns,
+	}
+	if pool.IdleTimeout > 0 {
+		transportCfg.IdleConnTimeout = &comtime.Duration{Duration: pool.IdleTimeout}
+	}
+	return transportCfg
+}
+
+// retryPolicy returns the retry policy of the RPC calls made during preflight, the default one if the max attempts are not set
+func retryPolicy(gcfg *config.Config) *generator.RetryPolicy {
+	retry := gcfg.Chain.RPC.Retry
+	if retry.MaxAttempts == 0 {
+		return &generator.RetryPolicy{
+			MaxAttempts: defaultRetryMaxAttempts,
+			BaseDelay:   defaultRetryBaseDelay,
+			MaxDelay:    defaultRetryMaxDelay,
+			Jitter:      defaultRetryJitter,
+		}
+	}
+	return &generator.RetryPolicy{
+		MaxAttempts: retry.MaxAttempts,
+		BaseDelay:   retry.BaseDelay,
+		MaxDelay:    retry.MaxDelay,
+		Jitter:      retry.Jitter,
+	}
+}
+
 // Helper function to parse chain ID
 func parseChainID(chainID string) (*big.Int, error) {
 	id := new(big.Int)
@@ -2,15 +2,29 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	Chain struct {
-		ID  string `mapstructure:"id,omitempty"`
-		RPC struct {
-			URL string `mapstructure:"url"`
+		ID      string `mapstructure:"id,omitempty"`
+		Genesis string `mapstructure:"genesis,omitempty"`
+		RPC     struct {
+			URL  string `mapstructure:"url"`
+			Pool struct {
+				MaxIdleConns int           `mapstructure:"max-idle-conns"`
+				MaxOpenConns int           `mapstructure:"max-open-conns"`
+				IdleTimeout  time.Duration `mapstructure:"idle-timeout"`
+			} `mapstructure:"pool"`
+			CallTimeout time.Duration `mapstructure:"call-timeout"`
+			Retry       struct {
+				MaxAttempts int           `mapstructure:"max-attempts"`
+				BaseDelay   time.Duration `mapstructure:"base-delay"`
+				MaxDelay    time.Duration `mapstructure:"max-delay"`
+				Jitter      float64       `mapstructure:"jitter"`
+			} `mapstructure:"retry"`
 		} `mapstructure:"rpc,omitempty"`
 	} `mapstructure:"chain"`
 	Log struct {
@@ -27,6 +41,7 @@ type Config struct {
 	ProverInputStore struct {
 		ContentType     string `mapstructure:"content-type"`
 		ContentEncoding string `mapstructure:"content-encoding"`
+		Codec           string `mapstructure:"codec"`
 		File            struct {
 			Dir string `mapstructure:"dir"`
 		} `mapstructure:"file"`
@@ -30,12 +30,74 @@ var (
 		Env:         "CHAIN_ID",
 		Description: "Chain ID (decimal)",
 	}
+	chainGenesisFlag = &spf13.StringFlag{
+		ViperKey:    "chain.genesis",
+		Name:        "chain-genesis",
+		Env:         "CHAIN_GENESIS",
+		Description: "Path to a genesis.json file of the chain, required for chains other than mainnet, Sepolia and Holesky",
+	}
 	chainRPCURLFlag = &spf13.StringFlag{
 		ViperKey:    "chain.rpc.url",
 		Name:        "chain-rpc-url",
 		Env:         "CHAIN_RPC_URL",
 		Description: "Chain JSON-RPC URL",
 	}
+	chainRPCMaxIdleConnsFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.pool.max-idle-conns",
+		Name:         "chain-rpc-max-idle-conns",
+		Env:          "CHAIN_RPC_MAX_IDLE_CONNS",
+		Description:  "Maximum number of idle connections kept open to the chain JSON-RPC endpoint",
+		DefaultValue: common.Ptr("10"),
+	}
+	chainRPCMaxOpenConnsFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.pool.max-open-conns",
+		Name:         "chain-rpc-max-open-conns",
+		Env:          "CHAIN_RPC_MAX_OPEN_CONNS",
+		Description:  "Maximum number of connections open to the chain JSON-RPC endpoint (0 means no limit)",
+		DefaultValue: common.Ptr("0"),
+	}
+	chainRPCIdleTimeoutFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.pool.idle-timeout",
+		Name:         "chain-rpc-idle-timeout",
+		Env:          "CHAIN_RPC_IDLE_TIMEOUT",
+		Description:  "Maximum amount of time an idle connection to the chain JSON-RPC endpoint remains open",
+		DefaultValue: common.Ptr("90s"),
+	}
+	chainRPCCallTimeoutFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.call-timeout",
+		Name:         "chain-rpc-call-timeout",
+		Env:          "CHAIN_RPC_CALL_TIMEOUT",
+		Description:  "Timeout of each RPC call made during preflight, a call timing out is retried (negative means no timeout)",
+		DefaultValue: common.Ptr("5s"),
+	}
+	chainRPCRetryMaxAttemptsFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.retry.max-attempts",
+		Name:         "chain-rpc-retry-max-attempts",
+		Env:          "CHAIN_RPC_RETRY_MAX_ATTEMPTS",
+		Description:  "Maximum number of attempts of a failed RPC call made during preflight, including the first one (1 means no retry)",
+		DefaultValue: common.Ptr("3"),
+	}
+	chainRPCRetryBaseDelayFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.retry.base-delay",
+		Name:         "chain-rpc-retry-base-delay",
+		Env:          "CHAIN_RPC_RETRY_BASE_DELAY",
+		Description:  "Delay before the first retry of a failed RPC call made during preflight, doubled at each retry",
+		DefaultValue: common.Ptr("500ms"),
+	}
+	chainRPCRetryMaxDelayFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.retry.max-delay",
+		Name:         "chain-rpc-retry-max-delay",
+		Env:          "CHAIN_RPC_RETRY_MAX_DELAY",
+		Description:  "Maximum delay between two attempts of a failed RPC call made during preflight (0 means no limit)",
+		DefaultValue: common.Ptr("5s"),
+	}
+	chainRPCRetryJitterFlag = &spf13.StringFlag{
+		ViperKey:     "chain.rpc.retry.jitter",
+		Name:         "chain-rpc-retry-jitter",
+		Env:          "CHAIN_RPC_RETRY_JITTER",
+		Description:  "Fraction of the retry delay that is randomized, between 0 and 1",
+		DefaultValue: common.Ptr("0.2"),
+	}
 	dataDirFlag = &spf13.StringFlag{
 		ViperKey:     "data-dir",
 		Name:         "data-dir",
@@ -71,11 +133,27 @@ var (
 		Description:  fmt.Sprintf("Optional content encoding to apply to prover inputs before storing (one of %q)", []string{"gzip", "flate"}),
 		DefaultValue: common.Ptr(""),
 	}
+	codecFlag = &spf13.StringFlag{
+		ViperKey:     "prover-input-store.codec",
+		Name:         "inputs-codec",
+		Env:          "INPUTS_CODEC",
+		Description:  fmt.Sprintf("Optional codec for serializing prover inputs, overrides --inputs-content-type (one of %q)", []string{"json", "rlp", "gzip"}),
+		DefaultValue: common.Ptr(""),
+	}
 )
 
 func AddChainFlags(v *viper.Viper, f *pflag.FlagSet) {
 	chainIDFlag.Add(v, f)
+	chainGenesisFlag.Add(v, f)
 	chainRPCURLFlag.Add(v, f)
+	chainRPCMaxIdleConnsFlag.Add(v, f)
+	chainRPCMaxOpenConnsFlag.Add(v, f)
+	chainRPCIdleTimeoutFlag.Add(v, f)
+	chainRPCCallTimeoutFlag.Add(v, f)
+	chainRPCRetryMaxAttemptsFlag.Add(v, f)
+	chainRPCRetryBaseDelayFlag.Add(v, f)
+	chainRPCRetryMaxDelayFlag.Add(v, f)
+	chainRPCRetryJitterFlag.Add(v, f)
 }
 
 var (
@@ -125,4 +203,5 @@ func AddStoreFlags(v *viper.Viper, f *pflag.FlagSet) {
 	inputsDirFlag.Add(v, f)
 	contentTypeFlag.Add(v, f)
 	contentEncodingFlag.Add(v, f)
+	codecFlag.Add(v, f)
 }
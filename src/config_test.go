@@ -0,0 +1,88 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kkrt-labs/zk-pig/src/config"
+	"github.com/kkrt-labs/zk-pig/src/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newChainIDServer starts a JSON-RPC server answering eth_chainId and counting opened connections
+func newChainIDServer(t *testing.T, conns *atomic.Int32) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0x1"})
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRPCConnectionPooling(t *testing.T) {
+	var conns atomic.Int32
+	srv := newChainIDServer(t, &conns)
+
+	gcfg := &config.Config{DataDir: t.TempDir()}
+	gcfg.PreflightDataStore.File.Dir = "preflight"
+	gcfg.Chain.RPC.URL = srv.URL
+	gcfg.Chain.RPC.Pool.MaxIdleConns = 2
+	gcfg.Chain.RPC.Pool.MaxOpenConns = 2
+	gcfg.Chain.RPC.Pool.IdleTimeout = time.Minute
+	gcfg.ProverInputStore.ContentType = "json"
+
+	cfg, err := FromGlobalConfig(gcfg)
+	require.NoError(t, err)
+	s, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, s.Start(context.Background()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ethrpc.ChainID(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Positive(t, conns.Load())
+	assert.LessOrEqual(t, conns.Load(), int32(2), "connections should be reused across calls")
+}
+
+func TestFromGlobalConfigPreflightDefaults(t *testing.T) {
+	gcfg := &config.Config{}
+	gcfg.ProverInputStore.ContentType = "json"
+	cfg, err := FromGlobalConfig(gcfg)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, cfg.Chain.CallTimeout)
+	assert.Equal(t, &generator.RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second, Jitter: 0.2}, cfg.Chain.RetryPolicy)
+
+	gcfg.Chain.RPC.CallTimeout = -1
+	gcfg.Chain.RPC.Retry.MaxAttempts = 1
+	cfg, err = FromGlobalConfig(gcfg)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), cfg.Chain.CallTimeout)
+	assert.Equal(t, &generator.RetryPolicy{MaxAttempts: 1}, cfg.Chain.RetryPolicy)
+}
@@ -9,14 +9,24 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
-// NewChain creates a new core.HeaderChain instance
+// NewChain creates a new core.HeaderChain instance on the mainnet genesis
 func NewChain(cfg *params.ChainConfig, stateDB gethstate.Database) (*core.HeaderChain, error) {
+	return NewChainFromGenesis(cfg, core.DefaultGenesisBlock(), stateDB)
+}
+
+// NewChainFromGenesis creates a new core.HeaderChain instance on the given genesis
+func NewChainFromGenesis(cfg *params.ChainConfig, genesis *core.Genesis, stateDB gethstate.Database) (*core.HeaderChain, error) {
 	// Setup the genesis block, to avoid error on core.NewHeaderChain
-	_, err := core.DefaultGenesisBlock().Commit(stateDB.TrieDB().Disk(), stateDB.TrieDB())
+	_, err := genesis.Commit(stateDB.TrieDB().Disk(), stateDB.TrieDB())
 	if err != nil {
 		return nil, fmt.Errorf("failed to apply genesis block: %v", err)
 	}
 
+	return NewChainOnCommittedGenesis(cfg, stateDB)
+}
+
+// NewChainOnCommittedGenesis creates a new core.HeaderChain instance on the genesis already committed to the database of stateDB
+func NewChainOnCommittedGenesis(cfg *params.ChainConfig, stateDB gethstate.Database) (*core.HeaderChain, error) {
 	// Create consensus engine
 	engine, err := ethconfig.CreateConsensusEngine(cfg, stateDB.TrieDB().Disk())
 	if err != nil {
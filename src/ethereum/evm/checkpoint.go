@@ -0,0 +1,86 @@
+package evm
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// IntermediateRootMismatchError is returned when the state root after a transaction differs from the expected one
+type IntermediateRootMismatchError struct {
+	TxIndex  int             // Index of the first transaction after which the state diverged
+	Expected gethcommon.Hash // Expected state root after the transaction
+	Actual   gethcommon.Hash // State root computed after the transaction
+}
+
+func (e *IntermediateRootMismatchError) Error() string {
+	return fmt.Sprintf("state diverged at transaction %d: expected intermediate root %v, got %v", e.TxIndex, e.Expected, e.Actual)
+}
+
+// CheckpointTracer records the state root after each transaction of a block
+// and compares it with the expected intermediate roots.
+type CheckpointTracer struct {
+	config   *params.ChainConfig
+	state    *gethstate.StateDB
+	expected []gethcommon.Hash
+
+	roots    []gethcommon.Hash
+	mismatch *IntermediateRootMismatchError
+}
+
+// NewCheckpointTracer creates a new CheckpointTracer computing intermediate roots on state.
+//
+// expected[i] is the expected state root after transaction i, a zero hash or a missing entry skips the check.
+func NewCheckpointTracer(config *params.ChainConfig, state *gethstate.StateDB, expected []gethcommon.Hash) *CheckpointTracer {
+	return &CheckpointTracer{
+		config:   config,
+		state:    state,
+		expected: expected,
+	}
+}
+
+// Hooks returns the tracing hooks to set on the VM configuration.
+func (t *CheckpointTracer) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnTxEnd: t.onTxEnd,
+	}
+}
+
+// Roots returns the intermediate roots recorded so far.
+func (t *CheckpointTracer) Roots() []gethcommon.Hash {
+	return t.roots
+}
+
+// Mismatch returns the first intermediate root mismatch, or nil if none has been found.
+func (t *CheckpointTracer) Mismatch() *IntermediateRootMismatchError {
+	return t.mismatch
+}
+
+func (t *CheckpointTracer) onTxEnd(receipt *gethtypes.Receipt, err error) {
+	if err != nil || receipt == nil {
+		return
+	}
+
+	// Pre-Byzantium receipts already hold the intermediate root
+	var root gethcommon.Hash
+	if len(receipt.PostState) > 0 {
+		root = gethcommon.BytesToHash(receipt.PostState)
+	} else {
+		root = t.state.IntermediateRoot(t.config.IsEIP158(receipt.BlockNumber))
+	}
+
+	i := len(t.roots)
+	t.roots = append(t.roots, root)
+
+	if t.mismatch == nil && i < len(t.expected) && t.expected[i] != (gethcommon.Hash{}) && t.expected[i] != root {
+		t.mismatch = &IntermediateRootMismatchError{
+			TxIndex:  i,
+			Expected: t.expected[i],
+			Actual:   root,
+		}
+	}
+}
@@ -50,6 +50,7 @@ func NewExecutor() Executor {
 
 // Execute executes an EVM block.
 // It processes the block on the given state and chain then validates the block if requested.
+// The processing result is returned whenever the block has been processed, even if its validation failed.
 func (e *executor) Execute(ctx context.Context, params *ExecParams) (res *core.ProcessResult, execErr error) {
 	if vmCfg := params.VMConfig; vmCfg != nil && vmCfg.Tracer != nil {
 		if vmCfg.Tracer.OnBlockStart != nil {
@@ -64,20 +65,24 @@ func (e *executor) Execute(ctx context.Context, params *ExecParams) (res *core.P
 		}
 	}
 
-	if params.Chain.Config().IsByzantium(params.Block.Number()) {
-		if params.VMConfig.StatelessSelfValidation {
-			// Create witness for tracking state accesses
-			witness, err := stateless.NewWitness(params.Block.Header(), params.Chain)
-			if err != nil {
-				execErr = fmt.Errorf("failed to create witness: %v", err)
-				return
-			}
-
-			params.State.StartPrefetcher("chain", witness)
+	if params.VMConfig.StatelessSelfValidation {
+		// Create witness for tracking state accesses
+		witness, err := stateless.NewWitness(params.Block.Header(), params.Chain)
+		if err != nil {
+			execErr = fmt.Errorf("failed to create witness: %v", err)
+			return
+		}
 
+		params.State.StartPrefetcher("chain", witness)
+		if params.Chain.Config().IsByzantium(params.Block.Number()) {
 			defer func() {
 				params.State.StopPrefetcher()
 			}()
+		} else {
+			// Before Byzantium, the intermediate root computed after each transaction drops the prefetcher,
+			// so the witness collects the state without it. It then misses the state that is only read,
+			// which the caller must add to it, for instance from proofs of the accessed state.
+			params.State.StopPrefetcher()
 		}
 	}
 
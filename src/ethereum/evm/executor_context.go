@@ -0,0 +1,77 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// cancelled is the value panicked with to interrupt the execution once the context is done
+type cancelled struct {
+	err error
+}
+
+// ExecutorWithContext is an executor decorator aborting the execution once the context is done,
+// checking the context before every transaction and system call.
+// If opcodeInterval is not zero, the context is also checked every opcodeInterval opcodes, so a long transaction is aborted while it executes,
+// at the cost of a tracing hook called on every opcode.
+// The returned error wraps the context error, and the result of the aborted execution is discarded.
+func ExecutorWithContext(opcodeInterval uint64) ExecutorDecorator {
+	return func(executor Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, params *ExecParams) (res *core.ProcessResult, err error) {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("execution aborted: %w", err)
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					c, ok := r.(cancelled)
+					if !ok {
+						panic(r)
+					}
+					res, err = nil, fmt.Errorf("execution aborted: %w", c.err)
+				}
+			}()
+
+			return executor.Execute(ctx, withCancelHooks(ctx, params, opcodeInterval))
+		})
+	}
+}
+
+func withCancelHooks(ctx context.Context, params *ExecParams, opcodeInterval uint64) *ExecParams {
+	check := func() {
+		if err := ctx.Err(); err != nil {
+			panic(cancelled{err: err})
+		}
+	}
+
+	hooks := &tracing.Hooks{
+		OnTxStart: func(_ *tracing.VMContext, _ *gethtypes.Transaction, _ gethcommon.Address) {
+			check()
+		},
+		OnSystemCallStart: check,
+	}
+	if opcodeInterval > 0 {
+		var steps uint64
+		hooks.OnOpcode = func(_ uint64, _ byte, _, _ uint64, _ tracing.OpContext, _ []byte, _ int, _ error) {
+			if steps++; steps%opcodeInterval == 0 {
+				check()
+			}
+		}
+	}
+
+	// Copy the parameters so the caller's VM config is left untouched
+	p := *params
+	var vmConfig vm.Config
+	if params.VMConfig != nil {
+		vmConfig = *params.VMConfig
+	}
+	vmConfig.Tracer = MultiHooks(vmConfig.Tracer, hooks)
+	p.VMConfig = &vmConfig
+	return &p
+}
@@ -0,0 +1,42 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorWithContext(t *testing.T) {
+	// Without an opcode interval only the transaction and system call boundaries are checked
+	var executed bool
+	res, err := ExecutorWithContext(0)(ExecutorFunc(func(_ context.Context, params *ExecParams) (*core.ProcessResult, error) {
+		executed = true
+		require.NotNil(t, params.VMConfig.Tracer)
+		assert.NotNil(t, params.VMConfig.Tracer.OnTxStart)
+		assert.NotNil(t, params.VMConfig.Tracer.OnSystemCallStart)
+		assert.Nil(t, params.VMConfig.Tracer.OnOpcode)
+		return &core.ProcessResult{}, nil
+	})).Execute(context.Background(), &ExecParams{})
+	require.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.True(t, executed)
+
+	// With an opcode interval a transaction is aborted while it executes
+	ctx, cancel := context.WithCancel(context.Background())
+	var opcodes int
+	res, err = ExecutorWithContext(2)(ExecutorFunc(func(_ context.Context, params *ExecParams) (*core.ProcessResult, error) {
+		cancel()
+		for ; opcodes < 10; opcodes++ {
+			params.VMConfig.Tracer.OnOpcode(0, 0, 0, 0, nil, nil, 0, nil)
+		}
+		return &core.ProcessResult{}, nil
+	})).Execute(ctx, &ExecParams{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Nil(t, res)
+	assert.Equal(t, 1, opcodes)
+}
@@ -53,8 +53,8 @@ func ExecutorWithLog(namespaces ...string) ExecutorDecorator {
 		return ExecutorFunc(func(ctx context.Context, params *ExecParams) (*core.ProcessResult, error) {
 			logger := log.LoggerWithFieldsFromNamespaceContext(ctx, namespaces...)
 
-			// Set tracing logger
-			params.VMConfig.Tracer = NewLoggerTracer(logger).Hooks()
+			// Set tracing logger, preserving any tracer already configured
+			params.VMConfig.Tracer = MultiHooks(NewLoggerTracer(logger).Hooks(), params.VMConfig.Tracer)
 
 			logger.Info("Start block execution...")
 			res, err := executor.Execute(log.WithLogger(ctx, logger), params)
@@ -120,7 +120,7 @@ func (t *LoggerTracer) OnTxEnd(receipt *gethtypes.Receipt, err error) {
 		t.txLogger.Error("failed to execute transaction",
 			zap.Error(err),
 		)
-	} else {
+	} else if receipt != nil {
 		t.txLogger.Debug("Executed transaction",
 			zap.String("receipt.txHash", receipt.TxHash.Hex()),
 			zap.Uint64("receipt.status", receipt.Status),
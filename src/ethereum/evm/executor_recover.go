@@ -0,0 +1,43 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/ethereum/go-ethereum/core"
+)
+
+// ErrExecutionPanic is returned when a panic occurs during block execution
+var ErrExecutionPanic = errors.New("execution panicked")
+
+// ExecutionPanicError holds the value and stack of a panic recovered during block execution
+type ExecutionPanicError struct {
+	Value interface{} // Value passed to panic
+	Stack []byte      // Stack trace of the goroutine at the time of the panic
+}
+
+func (e *ExecutionPanicError) Error() string {
+	return fmt.Sprintf("%v: %v\n%s", ErrExecutionPanic, e.Value, e.Stack)
+}
+
+func (e *ExecutionPanicError) Unwrap() error {
+	return ErrExecutionPanic
+}
+
+// ExecutorWithRecover is an executor decorator that recovers from panics during block execution
+// and returns them as an ExecutionPanicError, so a malformed block does not crash the process
+func ExecutorWithRecover() ExecutorDecorator {
+	return func(executor Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, params *ExecParams) (res *core.ProcessResult, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					res, err = nil, &ExecutionPanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+
+			return executor.Execute(ctx, params)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorWithRecover(t *testing.T) {
+	// Executing without a chain makes the executor dereference a nil pointer
+	params := &ExecParams{
+		Block: types.NewBlockWithHeader(&types.Header{}),
+	}
+
+	var res interface{}
+	var err error
+	require.NotPanics(t, func() {
+		res, err = ExecutorWithRecover()(NewExecutor()).Execute(context.Background(), params)
+	})
+	assert.Nil(t, res)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrExecutionPanic))
+
+	var panicErr *ExecutionPanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.NotEmpty(t, panicErr.Stack)
+}
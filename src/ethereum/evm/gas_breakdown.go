@@ -0,0 +1,137 @@
+package evm
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// GasBreakdown is the gas consumption of a block execution broken down by opcode.
+//
+// The gas used by the block equals the sum of the opcodes gas, plus intrinsic and code deposit gas, minus refunds.
+type GasBreakdown struct {
+	Opcodes     map[string]uint64 `json:"opcodes"`     // Gas consumed per opcode, call opcodes only account their own cost and not the gas consumed by the callee frame
+	Intrinsic   uint64            `json:"intrinsic"`   // Intrinsic gas of the transactions
+	CodeDeposit uint64            `json:"codeDeposit"` // Gas paid to store code of created contracts
+	Refund      uint64            `json:"refund"`      // Gas refunded to the transaction senders
+}
+
+// GasBreakdownTracer is a tracer recording the gas consumption of a block execution per opcode.
+type GasBreakdownTracer struct {
+	breakdown *GasBreakdown
+
+	frames       []*gasFrame
+	counted      uint64 // Gas accounted so far, used to compute the own cost of call opcodes
+	inSystemCall bool
+}
+
+// gasFrame tracks the pending call opcode of a call frame.
+// The own cost of a call opcode is known only once execution resumes in the frame.
+type gasFrame struct {
+	lastOp         vm.OpCode
+	executed       bool // Whether the frame executed at least an opcode (precompiles do not)
+	pending        bool
+	pendingGas     uint64
+	pendingCounted uint64
+}
+
+// NewGasBreakdownTracer creates a new GasBreakdownTracer.
+func NewGasBreakdownTracer() *GasBreakdownTracer {
+	return &GasBreakdownTracer{
+		breakdown: &GasBreakdown{
+			Opcodes: make(map[string]uint64),
+		},
+	}
+}
+
+// Hooks returns the tracing hooks to set on the VM configuration.
+func (t *GasBreakdownTracer) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnEnter:           t.onEnter,
+		OnExit:            t.onExit,
+		OnOpcode:          t.onOpcode,
+		OnGasChange:       t.onGasChange,
+		OnSystemCallStart: func() { t.inSystemCall = true },
+		OnSystemCallEnd:   func() { t.inSystemCall = false },
+	}
+}
+
+// Breakdown returns the gas breakdown recorded so far.
+func (t *GasBreakdownTracer) Breakdown() *GasBreakdown {
+	return t.breakdown
+}
+
+func (t *GasBreakdownTracer) onEnter(_ int, _ byte, _, _ common.Address, _ []byte, _ uint64, _ *big.Int) {
+	if t.inSystemCall {
+		return
+	}
+	t.frames = append(t.frames, &gasFrame{})
+}
+
+func (t *GasBreakdownTracer) onExit(_ int, _ []byte, _ uint64, _ error, _ bool) {
+	if t.inSystemCall || len(t.frames) == 0 {
+		return
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+func (t *GasBreakdownTracer) onOpcode(_ uint64, op byte, gas, cost uint64, _ tracing.OpContext, _ []byte, _ int, _ error) {
+	if t.inSystemCall || len(t.frames) == 0 {
+		return
+	}
+
+	frame := t.frames[len(t.frames)-1]
+	if frame.pending {
+		// Gas consumed since the call opcode, minus the gas accounted in the callee frame
+		t.charge(frame.lastOp, frame.pendingGas-gas-(t.counted-frame.pendingCounted))
+		frame.pending = false
+	}
+
+	frame.lastOp = vm.OpCode(op)
+	frame.executed = true
+	switch frame.lastOp {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		frame.pending = true
+		frame.pendingGas = gas
+		frame.pendingCounted = t.counted
+	default:
+		t.charge(frame.lastOp, cost)
+	}
+}
+
+func (t *GasBreakdownTracer) onGasChange(old, new uint64, reason tracing.GasChangeReason) {
+	if t.inSystemCall {
+		return
+	}
+
+	switch reason {
+	case tracing.GasChangeTxIntrinsicGas:
+		t.breakdown.Intrinsic += old - new
+	case tracing.GasChangeTxRefunds:
+		t.breakdown.Refund += new - old
+	case tracing.GasChangeCallCodeStorage:
+		t.breakdown.CodeDeposit += old - new
+		t.counted += old - new
+	case tracing.GasChangeCallFailedExecution:
+		// Remaining gas of a failed frame is burnt, we account it to the last opcode of the frame
+		if len(t.frames) > 0 && t.frames[len(t.frames)-1].executed {
+			t.charge(t.frames[len(t.frames)-1].lastOp, old-new)
+		}
+	}
+}
+
+func (t *GasBreakdownTracer) charge(op vm.OpCode, gas uint64) {
+	t.breakdown.Opcodes[op.String()] += gas
+	t.counted += gas
+}
+
+// Sum returns the total gas used according to the breakdown
+func (b *GasBreakdown) Sum() uint64 {
+	var sum uint64
+	for _, gas := range b.Opcodes {
+		sum += gas
+	}
+	return sum + b.Intrinsic + b.CodeDeposit - b.Refund
+}
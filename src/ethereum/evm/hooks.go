@@ -0,0 +1,142 @@
+package evm
+
+import (
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// MultiHooks combines several tracing hooks into a single one calling each hook in order.
+// Nil hooks are ignored.
+func MultiHooks(hooks ...*tracing.Hooks) *tracing.Hooks {
+	var hs []*tracing.Hooks
+	for _, h := range hooks {
+		if h != nil {
+			hs = append(hs, h)
+		}
+	}
+
+	switch len(hs) {
+	case 0:
+		return nil
+	case 1:
+		return hs[0]
+	}
+
+	return &tracing.Hooks{
+		OnTxStart: func(vm *tracing.VMContext, tx *gethtypes.Transaction, from gethcommon.Address) {
+			for _, h := range hs {
+				if h.OnTxStart != nil {
+					h.OnTxStart(vm, tx, from)
+				}
+			}
+		},
+		OnTxEnd: func(receipt *gethtypes.Receipt, err error) {
+			for _, h := range hs {
+				if h.OnTxEnd != nil {
+					h.OnTxEnd(receipt, err)
+				}
+			}
+		},
+		OnEnter: func(depth int, typ byte, from, to gethcommon.Address, input []byte, gas uint64, value *big.Int) {
+			for _, h := range hs {
+				if h.OnEnter != nil {
+					h.OnEnter(depth, typ, from, to, input, gas, value)
+				}
+			}
+		},
+		OnExit: func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+			for _, h := range hs {
+				if h.OnExit != nil {
+					h.OnExit(depth, output, gasUsed, err, reverted)
+				}
+			}
+		},
+		OnOpcode: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+			for _, h := range hs {
+				if h.OnOpcode != nil {
+					h.OnOpcode(pc, op, gas, cost, scope, rData, depth, err)
+				}
+			}
+		},
+		OnFault: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, depth int, err error) {
+			for _, h := range hs {
+				if h.OnFault != nil {
+					h.OnFault(pc, op, gas, cost, scope, depth, err)
+				}
+			}
+		},
+		OnGasChange: func(old, new uint64, reason tracing.GasChangeReason) {
+			for _, h := range hs {
+				if h.OnGasChange != nil {
+					h.OnGasChange(old, new, reason)
+				}
+			}
+		},
+		OnBlockStart: func(event tracing.BlockEvent) {
+			for _, h := range hs {
+				if h.OnBlockStart != nil {
+					h.OnBlockStart(event)
+				}
+			}
+		},
+		OnBlockEnd: func(err error) {
+			for _, h := range hs {
+				if h.OnBlockEnd != nil {
+					h.OnBlockEnd(err)
+				}
+			}
+		},
+		OnSystemCallStart: func() {
+			for _, h := range hs {
+				if h.OnSystemCallStart != nil {
+					h.OnSystemCallStart()
+				}
+			}
+		},
+		OnSystemCallEnd: func() {
+			for _, h := range hs {
+				if h.OnSystemCallEnd != nil {
+					h.OnSystemCallEnd()
+				}
+			}
+		},
+		OnBalanceChange: func(addr gethcommon.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+			for _, h := range hs {
+				if h.OnBalanceChange != nil {
+					h.OnBalanceChange(addr, prev, new, reason)
+				}
+			}
+		},
+		OnNonceChange: func(addr gethcommon.Address, prev, new uint64) {
+			for _, h := range hs {
+				if h.OnNonceChange != nil {
+					h.OnNonceChange(addr, prev, new)
+				}
+			}
+		},
+		OnCodeChange: func(addr gethcommon.Address, prevCodeHash gethcommon.Hash, prevCode []byte, codeHash gethcommon.Hash, code []byte) {
+			for _, h := range hs {
+				if h.OnCodeChange != nil {
+					h.OnCodeChange(addr, prevCodeHash, prevCode, codeHash, code)
+				}
+			}
+		},
+		OnStorageChange: func(addr gethcommon.Address, slot, prev, new gethcommon.Hash) {
+			for _, h := range hs {
+				if h.OnStorageChange != nil {
+					h.OnStorageChange(addr, slot, prev, new)
+				}
+			}
+		},
+		OnLog: func(l *gethtypes.Log) {
+			for _, h := range hs {
+				if h.OnLog != nil {
+					h.OnLog(l)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb/database"
+	"go.uber.org/zap"
+)
+
+// slowLoadLogger logs the state loads taking longer than a threshold
+type slowLoadLogger struct {
+	logger    *zap.Logger
+	threshold time.Duration
+}
+
+// observe logs the load started at start if it took longer than the threshold
+func (l *slowLoadLogger) observe(start time.Time, kind string, fields ...zap.Field) {
+	if elapsed := time.Since(start); elapsed > l.threshold {
+		l.logger.Warn("Slow state load", append([]zap.Field{zap.String("kind", kind), zap.Duration("duration", elapsed)}, fields...)...)
+	}
+}
+
+// slowNodeDatabase returns the trie database of the underlying database timing its node loads,
+// or nil if slow loads are not logged or the state of the underlying database is not read from its trie database
+func (db *AccessTrackerDatabase) slowNodeDatabase() database.NodeDatabase {
+	if db.slowLoads == nil {
+		return nil
+	}
+	cachingDB, ok := db.Database.(*gethstate.CachingDB)
+	if !ok || cachingDB.Snapshot() != nil || cachingDB.TrieDB().IsVerkle() {
+		return nil
+	}
+	return &slowNodeDatabase{db: cachingDB.TrieDB(), slowLoads: db.slowLoads}
+}
+
+// slowNodeDatabase is a trie node database logging the node loads taking longer than a threshold
+type slowNodeDatabase struct {
+	db        database.NodeDatabase
+	slowLoads *slowLoadLogger
+}
+
+func (db *slowNodeDatabase) NodeReader(stateRoot gethcommon.Hash) (database.NodeReader, error) {
+	reader, err := db.db.NodeReader(stateRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &slowNodeReader{reader: reader, slowLoads: db.slowLoads}, nil
+}
+
+type slowNodeReader struct {
+	reader    database.NodeReader
+	slowLoads *slowLoadLogger
+}
+
+func (r *slowNodeReader) Node(owner gethcommon.Hash, path []byte, hash gethcommon.Hash) ([]byte, error) {
+	defer r.slowLoads.observe(time.Now(), "node", zap.Stringer("owner", owner), zap.String("path", hexutil.Encode(path)), zap.Stringer("hash", hash))
+	return r.reader.Node(owner, path, hash)
+}
+
+// nodeTrieReader is a gethstate.Reader reading accounts and storage slots from tries opened on a trie node database,
+// as the trie reader of gethstate.CachingDB does on its own trie database
+type nodeTrieReader struct {
+	root     gethcommon.Hash
+	db       database.NodeDatabase
+	mainTrie *trie.StateTrie
+	subRoots map[gethcommon.Address]gethcommon.Hash
+	subTries map[gethcommon.Address]*trie.StateTrie
+}
+
+func newNodeTrieReader(root gethcommon.Hash, db database.NodeDatabase) (*nodeTrieReader, error) {
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), db)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeTrieReader{
+		root:     root,
+		db:       db,
+		mainTrie: tr,
+		subRoots: make(map[gethcommon.Address]gethcommon.Hash),
+		subTries: make(map[gethcommon.Address]*trie.StateTrie),
+	}, nil
+}
+
+// Account implements the gethstate.Reader interface.
+func (r *nodeTrieReader) Account(addr gethcommon.Address) (*gethtypes.StateAccount, error) {
+	account, err := r.mainTrie.GetAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		r.subRoots[addr] = gethtypes.EmptyRootHash
+	} else {
+		r.subRoots[addr] = account.Root
+	}
+	return account, nil
+}
+
+// Storage implements the gethstate.Reader interface.
+func (r *nodeTrieReader) Storage(addr gethcommon.Address, slot gethcommon.Hash) (gethcommon.Hash, error) {
+	tr, ok := r.subTries[addr]
+	if !ok {
+		// The storage trie is opened on the root of the account, resolved first if the account has not been read
+		if _, ok := r.subRoots[addr]; !ok {
+			if _, err := r.Account(addr); err != nil {
+				return gethcommon.Hash{}, err
+			}
+		}
+		var err error
+		tr, err = trie.NewStateTrie(trie.StorageTrieID(r.root, crypto.Keccak256Hash(addr.Bytes()), r.subRoots[addr]), r.db)
+		if err != nil {
+			return gethcommon.Hash{}, err
+		}
+		r.subTries[addr] = tr
+	}
+
+	value, err := tr.GetStorage(addr, slot.Bytes())
+	if err != nil {
+		return gethcommon.Hash{}, err
+	}
+	return gethcommon.BytesToHash(value), nil
+}
+
+// Copy implements the gethstate.Reader interface.
+func (r *nodeTrieReader) Copy() gethstate.Reader {
+	subRoots := make(map[gethcommon.Address]gethcommon.Hash, len(r.subRoots))
+	for addr, root := range r.subRoots {
+		subRoots[addr] = root
+	}
+	subTries := make(map[gethcommon.Address]*trie.StateTrie, len(r.subTries))
+	for addr, tr := range r.subTries {
+		subTries[addr] = tr.Copy()
+	}
+	return &nodeTrieReader{
+		root:     r.root,
+		db:       r.db,
+		mainTrie: r.mainTrie.Copy(),
+		subRoots: subRoots,
+		subTries: subTries,
+	}
+}
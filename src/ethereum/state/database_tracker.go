@@ -1,9 +1,18 @@
 package state
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
 	gethcommon "github.com/ethereum/go-ethereum/common"
 	gethstate "github.com/ethereum/go-ethereum/core/state"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+	"go.uber.org/zap"
 )
 
 // StateAccessTracker is a state database that tracks the state access (account, storage, and bytecode) during block execution.
@@ -15,6 +24,8 @@ type AccessTrackerDatabase struct {
 	// TODO: remove the current tarcker that should be useless
 	// as we can use native go-ethereum witness
 	currentTracker *AccessTracker
+
+	slowLoads *slowLoadLogger
 }
 
 // NewAccessTrackerDatabase creates a new state database that tracks the state access during block execution.
@@ -25,9 +36,31 @@ func NewAccessTrackerDatabase(db gethstate.Database, trackers *AccessTrackerMana
 	}
 }
 
+// WithSlowLoadLogging returns a copy of the database logging a warning each time loading a trie node or a code
+// takes longer than threshold, which indicates a slow disk or remote. A zero threshold disables logging.
+//
+// Trie nodes are timed when the underlying database is a trie backed gethstate.CachingDB without snapshot,
+// the state of other databases, such as an RPCDatabase, not being read from trie nodes.
+func (db *AccessTrackerDatabase) WithSlowLoadLogging(logger *zap.Logger, threshold time.Duration) *AccessTrackerDatabase {
+	cpy := *db
+	cpy.slowLoads = nil
+	if threshold > 0 {
+		cpy.slowLoads = &slowLoadLogger{logger: logger, threshold: threshold}
+	}
+	return &cpy
+}
+
 // Reader implements the gethstate.Database interface.
 func (db *AccessTrackerDatabase) Reader(stateRoot gethcommon.Hash) (gethstate.Reader, error) {
-	reader, err := db.Database.Reader(stateRoot)
+	var (
+		reader gethstate.Reader
+		err    error
+	)
+	if nodes := db.slowNodeDatabase(); nodes != nil {
+		reader, err = newNodeTrieReader(stateRoot, nodes)
+	} else {
+		reader, err = db.Database.Reader(stateRoot)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -39,8 +72,27 @@ func (db *AccessTrackerDatabase) Reader(stateRoot gethcommon.Hash) (gethstate.Re
 	return newStateAccessTrackerReader(reader, tracker), nil
 }
 
+// OpenTrie implements the gethstate.Database interface.
+func (db *AccessTrackerDatabase) OpenTrie(root gethcommon.Hash) (gethstate.Trie, error) {
+	if nodes := db.slowNodeDatabase(); nodes != nil {
+		return trie.NewStateTrie(trie.StateTrieID(root), nodes)
+	}
+	return db.Database.OpenTrie(root)
+}
+
+// OpenStorageTrie implements the gethstate.Database interface.
+func (db *AccessTrackerDatabase) OpenStorageTrie(stateRoot gethcommon.Hash, address gethcommon.Address, root gethcommon.Hash, tr gethstate.Trie) (gethstate.Trie, error) {
+	if nodes := db.slowNodeDatabase(); nodes != nil {
+		return trie.NewStateTrie(trie.StorageTrieID(stateRoot, crypto.Keccak256Hash(address.Bytes()), root), nodes)
+	}
+	return db.Database.OpenStorageTrie(stateRoot, address, root, tr)
+}
+
 // ContractCode implements the gethstate.Database interface.
 func (db *AccessTrackerDatabase) ContractCode(addr gethcommon.Address, codeHash gethcommon.Hash) ([]byte, error) {
+	if db.slowLoads != nil {
+		defer db.slowLoads.observe(time.Now(), "code", zap.Stringer("address", addr), zap.Stringer("code.hash", codeHash))
+	}
 	code, err := db.Database.ContractCode(addr, codeHash)
 	if err != nil {
 		return nil, err
@@ -57,6 +109,155 @@ func (db *AccessTrackerDatabase) ContractCodeSize(addr gethcommon.Address, codeH
 type AccessTracker struct {
 	Accounts map[gethcommon.Address]*gethtypes.StateAccount             `json:"accounts"`
 	Storage  map[gethcommon.Address]map[gethcommon.Hash]gethcommon.Hash `json:"storage"`
+
+	// Accesses lists the accounts and storage slots in the order they are first accessed, including missing accounts
+	Accesses []StateAccess `json:"-"`
+	accessed map[StateAccess]struct{}
+}
+
+// StateAccess is an account, or a storage slot of the account when IsSlot is set
+type StateAccess struct {
+	Address gethcommon.Address
+	Slot    gethcommon.Hash
+	IsSlot  bool
+}
+
+// AccessError is returned when reading an account, or a storage slot, from the tracked state fails,
+// typically because a trie node needed to read it is missing
+type AccessError struct {
+	Access StateAccess
+	Err    error
+}
+
+func (e *AccessError) Error() string {
+	if e.Access.IsSlot {
+		return fmt.Sprintf("failed to read storage slot %v of account %v: %v", e.Access.Slot.Hex(), e.Access.Address.Hex(), e.Err)
+	}
+	return fmt.Sprintf("failed to read account %v: %v", e.Access.Address.Hex(), e.Err)
+}
+
+func (e *AccessError) Unwrap() error {
+	return e.Err
+}
+
+func (t *AccessTracker) recordAccess(access StateAccess) {
+	if t.accessed == nil {
+		t.accessed = make(map[StateAccess]struct{})
+	}
+	if _, ok := t.accessed[access]; ok {
+		return
+	}
+	t.accessed[access] = struct{}{}
+	t.Accesses = append(t.Accesses, access)
+}
+
+// SlotAccess holds the storage slots of an account accessed during block execution
+type SlotAccess struct {
+	Read    []gethcommon.Hash `json:"read"`    // Slots read but left unchanged by the execution
+	Written []gethcommon.Hash `json:"written"` // Slots whose value was modified by the execution
+}
+
+// StateReader is the minimal interface to read storage values from a state
+type StateReader interface {
+	GetState(addr gethcommon.Address, slot gethcommon.Hash) gethcommon.Hash
+}
+
+// StorageSlots returns, per account, the storage slots accessed during block execution.
+// Slots are split between read and written slots by comparing the tracked pre-state value with the value in post state.
+// Slots are sorted so the result is deterministic.
+func (t *AccessTracker) StorageSlots(post StateReader) map[gethcommon.Address]SlotAccess {
+	slots := make(map[gethcommon.Address]SlotAccess)
+	for addr, storage := range t.Storage {
+		access := SlotAccess{
+			Read:    []gethcommon.Hash{},
+			Written: []gethcommon.Hash{},
+		}
+		for slot, preValue := range storage {
+			if post.GetState(addr, slot) != preValue {
+				access.Written = append(access.Written, slot)
+			} else {
+				access.Read = append(access.Read, slot)
+			}
+		}
+		sortHashes(access.Read)
+		sortHashes(access.Written)
+		slots[addr] = access
+	}
+	return slots
+}
+
+// AccountStateReader is the minimal interface to read accounts and storage values from a state
+type AccountStateReader interface {
+	StateReader
+	Exist(addr gethcommon.Address) bool
+	GetNonce(addr gethcommon.Address) uint64
+	GetBalance(addr gethcommon.Address) *uint256.Int
+	GetCodeHash(addr gethcommon.Address) gethcommon.Hash
+}
+
+// AccessReport lists the accounts of a pre-state accessed during block execution
+type AccessReport struct {
+	Accounts []*AccountAccess `json:"accounts"` // Accounts sorted by address
+}
+
+// AccountAccess is an account accessed during block execution, with its accessed storage slots
+type AccountAccess struct {
+	Address gethcommon.Address `json:"address"`
+	Exists  bool               `json:"exists"`  // Whether the account exists in the pre-state
+	Written bool               `json:"written"` // Whether the execution modified the account or its storage, otherwise it was only read
+	Storage SlotAccess         `json:"storage"`
+}
+
+// AccessReport returns the accounts and storage slots of the pre-state accessed during block execution,
+// comparing the tracked pre-state values with the values in post state to tell reads from writes.
+// It returns nil if the pre-state has no tracker.
+func (m *AccessTrackerManager) AccessReport(stateRoot gethcommon.Hash, post AccountStateReader) *AccessReport {
+	tracker := m.GetAccessTracker(stateRoot)
+	if tracker == nil {
+		return nil
+	}
+
+	slots := tracker.StorageSlots(post)
+	report := &AccessReport{Accounts: []*AccountAccess{}}
+	for _, access := range tracker.Accesses {
+		if access.IsSlot {
+			continue
+		}
+		account := &AccountAccess{
+			Address: access.Address,
+			Storage: SlotAccess{Read: []gethcommon.Hash{}, Written: []gethcommon.Hash{}},
+		}
+		if storage, ok := slots[access.Address]; ok {
+			account.Storage = storage
+		}
+
+		pre := tracker.Accounts[access.Address]
+		account.Exists = pre != nil
+		account.Written = len(account.Storage.Written) > 0 || accountModified(pre, access.Address, post)
+		report.Accounts = append(report.Accounts, account)
+	}
+
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		return bytes.Compare(report.Accounts[i].Address[:], report.Accounts[j].Address[:]) < 0
+	})
+	return report
+}
+
+// accountModified indicates whether the account differs between the pre-state, where it is nil if missing, and the post state
+func accountModified(pre *gethtypes.StateAccount, addr gethcommon.Address, post AccountStateReader) bool {
+	if pre == nil {
+		return post.Exist(addr)
+	}
+	return !post.Exist(addr) ||
+		post.GetNonce(addr) != pre.Nonce ||
+		!post.GetBalance(addr).Eq(pre.Balance) ||
+		!bytes.Equal(post.GetCodeHash(addr).Bytes(), pre.CodeHash)
+}
+
+func sortHashes(hashes []gethcommon.Hash) {
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
 }
 
 type AccessTrackerManager struct {
@@ -93,6 +294,7 @@ func newStateAccessTracker() *AccessTracker {
 	return &AccessTracker{
 		Accounts: make(map[gethcommon.Address]*gethtypes.StateAccount),
 		Storage:  make(map[gethcommon.Address]map[gethcommon.Hash]gethcommon.Hash),
+		accessed: make(map[StateAccess]struct{}),
 	}
 }
 
@@ -115,12 +317,13 @@ func newStateAccessTrackerReader(reader gethstate.Reader, tracker *AccessTracker
 func (r *stateAccessTrackerReader) Account(addr gethcommon.Address) (*gethtypes.StateAccount, error) {
 	account, err := r.reader.Account(addr)
 	if err != nil {
-		return nil, err
+		return nil, &AccessError{Access: StateAccess{Address: addr}, Err: err}
 	}
 
 	if account != nil {
 		r.tracker.Accounts[addr] = account.Copy()
 	}
+	r.tracker.recordAccess(StateAccess{Address: addr})
 
 	return account, nil
 }
@@ -130,7 +333,7 @@ func (r *stateAccessTrackerReader) Account(addr gethcommon.Address) (*gethtypes.
 func (r *stateAccessTrackerReader) Storage(addr gethcommon.Address, slot gethcommon.Hash) (gethcommon.Hash, error) {
 	value, err := r.reader.Storage(addr, slot)
 	if err != nil {
-		return gethcommon.Hash{}, err
+		return gethcommon.Hash{}, &AccessError{Access: StateAccess{Address: addr, Slot: slot, IsSlot: true}, Err: err}
 	}
 
 	if _, ok := r.tracker.Storage[addr]; !ok {
@@ -138,18 +341,23 @@ func (r *stateAccessTrackerReader) Storage(addr gethcommon.Address, slot gethcom
 	}
 
 	r.tracker.Storage[addr][slot] = value
+	r.tracker.recordAccess(StateAccess{Address: addr, Slot: slot, IsSlot: true})
 
 	return value, nil
 }
 
 // Copy implementing Reader interface, returning a deep-copied state reader.
 func (r *stateAccessTrackerReader) Copy() gethstate.Reader {
+	tracker := newStateAccessTracker()
+	tracker.Accounts = copyAccounts(r.tracker.Accounts)
+	tracker.Storage = copyStorage(r.tracker.Storage)
+	for _, access := range r.tracker.Accesses {
+		tracker.recordAccess(access)
+	}
+
 	return &stateAccessTrackerReader{
-		reader: r.reader.Copy(),
-		tracker: &AccessTracker{
-			Accounts: copyAccounts(r.tracker.Accounts),
-			Storage:  copyStorage(r.tracker.Storage),
-		},
+		reader:  r.reader.Copy(),
+		tracker: tracker,
 	}
 }
 
@@ -1,3 +1,127 @@
 package state
 
+import (
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
 // TODO: write test for AccountState and StorageState
+
+// slowDisk is a key-value store delaying every read, so every trie node and code load is slow
+type slowDisk struct {
+	ethdb.Database
+
+	delay time.Duration
+}
+
+func (db *slowDisk) Get(key []byte) ([]byte, error) {
+	time.Sleep(db.delay)
+	return db.Database.Get(key)
+}
+
+func TestAccessTrackerDatabaseSlowLoads(t *testing.T) {
+	addr := gethcommon.HexToAddress("0xdead")
+	code := []byte{0x60, 0x00}
+
+	// Commit to disk a state holding a contract with a storage slot
+	disk := rawdb.NewMemoryDatabase()
+	tdb := triedb.NewDatabase(disk, triedb.HashDefaults)
+	st, err := gethstate.New(gethtypes.EmptyRootHash, gethstate.NewDatabase(tdb, nil))
+	require.NoError(t, err)
+	st.SetCode(addr, code)
+	st.SetState(addr, gethcommon.Hash{0x01}, gethcommon.Hash{0x0a})
+	root, err := st.Commit(0, true)
+	require.NoError(t, err)
+	require.NoError(t, tdb.Commit(root, false))
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	slowDB := NewAccessTrackerDatabase(gethstate.NewDatabase(triedb.NewDatabase(&slowDisk{Database: disk, delay: 20 * time.Millisecond}, triedb.HashDefaults), nil), NewAccessTrackerManager())
+
+	// Loads faster than the threshold are not logged
+	reader, err := slowDB.WithSlowLoadLogging(zap.New(core), time.Second).Reader(root)
+	require.NoError(t, err)
+	_, err = reader.Account(addr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, logs.Len())
+
+	logging := slowDB.WithSlowLoadLogging(zap.New(core), 10*time.Millisecond)
+	reader, err = logging.Reader(root)
+	require.NoError(t, err)
+	_, err = reader.Account(addr)
+	require.NoError(t, err)
+	value, err := reader.Storage(addr, gethcommon.Hash{0x01})
+	require.NoError(t, err)
+	assert.Equal(t, gethcommon.Hash{0x0a}, value)
+	_, err = logging.ContractCode(addr, crypto.Keccak256Hash(code))
+	require.NoError(t, err)
+
+	// Each state is made of a single leaf node, the account trie node, the storage trie node and the code are logged
+	entries := logs.TakeAll()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "Slow state load", entries[0].Message)
+	assert.Equal(t, "node", entries[0].ContextMap()["kind"])
+	assert.Equal(t, root.Hex(), entries[0].ContextMap()["hash"])
+	assert.Equal(t, "node", entries[1].ContextMap()["kind"])
+	assert.Equal(t, crypto.Keccak256Hash(addr.Bytes()).Hex(), entries[1].ContextMap()["owner"])
+	assert.Equal(t, "code", entries[2].ContextMap()["kind"])
+
+	// Opening and reading a trie also logs its slow node loads
+	tr, err := logging.OpenTrie(root)
+	require.NoError(t, err)
+	_, err = tr.GetAccount(addr)
+	require.NoError(t, err)
+	assert.Equal(t, 1, logs.Len())
+
+	// The database configuring the logging is left unchanged, and a zero threshold disables the logging
+	for _, db := range []*AccessTrackerDatabase{slowDB, logging.WithSlowLoadLogging(zap.New(core), 0)} {
+		reader, err = db.Reader(root)
+		require.NoError(t, err)
+		_, err = reader.Account(addr)
+		require.NoError(t, err)
+		_, err = db.ContractCode(addr, crypto.Keccak256Hash(code))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestAccessTrackerAccesses(t *testing.T) {
+	addr, missing := gethcommon.HexToAddress("0xdead"), gethcommon.HexToAddress("0xbeef")
+	db := gethstate.NewDatabase(triedb.NewDatabase(rawdb.NewMemoryDatabase(), triedb.HashDefaults), nil)
+	st, err := gethstate.New(gethtypes.EmptyRootHash, db)
+	require.NoError(t, err)
+	st.SetState(addr, gethcommon.Hash{0x01}, gethcommon.Hash{0x0a})
+	root, err := st.Commit(0, true)
+	require.NoError(t, err)
+
+	trackers := NewAccessTrackerManager()
+	reader, err := NewAccessTrackerDatabase(db, trackers).Reader(root)
+	require.NoError(t, err)
+	_, err = reader.Account(missing)
+	require.NoError(t, err)
+	_, err = reader.Account(addr)
+	require.NoError(t, err)
+	_, err = reader.Storage(addr, gethcommon.Hash{0x01})
+	require.NoError(t, err)
+	_, err = reader.Account(missing)
+	require.NoError(t, err)
+
+	// Accesses are recorded once, in first access order, including missing accounts
+	assert.Equal(t, []StateAccess{
+		{Address: missing},
+		{Address: addr},
+		{Address: addr, Slot: gethcommon.Hash{0x01}, IsSlot: true},
+	}, trackers.GetAccessTracker(root).Accesses)
+}
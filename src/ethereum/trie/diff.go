@@ -0,0 +1,28 @@
+package trie
+
+import (
+	gethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// DiffNodes returns the nodes of the account trie rooted at root, and of the storage tries its accounts reference,
+// that are not pre-state nodes, in the order they are walked from the root.
+//
+// Nodes are resolved with resolve. The walk stops at the pre-state nodes and at the nodes resolve can not resolve,
+// the subtries below them being unchanged, so resolve must resolve every node created since the pre-state.
+func DiffNodes(root gethcommon.Hash, pre map[gethcommon.Hash]struct{}, resolve func(owner, hash gethcommon.Hash, path []byte) ([]byte, bool)) [][]byte {
+	var diff [][]byte
+	seen := make(map[gethcommon.Hash]struct{})
+	newResolvingNodeWalker(func(owner, hash gethcommon.Hash, path []byte) ([]byte, bool) {
+		if _, ok := pre[hash]; ok {
+			return nil, false
+		}
+		return resolve(owner, hash, path)
+	}, false, func(_, hash gethcommon.Hash, _, node []byte) {
+		// Storage tries with the same content share their nodes
+		if _, ok := seen[hash]; !ok {
+			seen[hash] = struct{}{}
+			diff = append(diff, node)
+		}
+	}).walkHash(AccountTrieOwner(), root, nil)
+	return diff
+}
@@ -39,6 +39,19 @@ func AccountProofFromRPC(res *gethclient.AccountResult) *AccountProof {
 	return proof
 }
 
+// Exists verifies the proof against the account trie with the given root, and indicates whether it proves the account exists
+func (p *AccountProof) Exists(root gethcommon.Hash) (bool, error) {
+	proofDB := memorydb.New()
+	if err := trie.StoreHexProofs(p.Proof, proofDB); err != nil {
+		return false, err
+	}
+	value, err := trie.VerifyProof(root, AccountTrieKey(p.Address), proofDB)
+	if err != nil {
+		return false, err
+	}
+	return len(value) > 0, nil
+}
+
 // AccountsNodeSet is a wrapper around trienode.NodeSet that allows to add
 // accounts to a MPT node set with proof verification
 type AccountsNodeSet struct {
@@ -0,0 +1,88 @@
+package trie
+
+import (
+	"bytes"
+	"sort"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TrieAccess is an access to a key of the account trie (Owner is AccountTrieOwner()) or of a storage trie (Owner is StorageTrieOwner()).
+// Key is the hashed key, the keccak of the account address or of the storage slot.
+type TrieAccess struct {
+	Owner gethcommon.Hash
+	Key   gethcommon.Hash
+}
+
+// AccountAccess returns the access to the account in the account trie
+func AccountAccess(addr gethcommon.Address) TrieAccess {
+	return TrieAccess{Owner: AccountTrieOwner(), Key: crypto.Keccak256Hash(addr.Bytes())}
+}
+
+// StorageAccess returns the access to the storage slot in the storage trie of the account
+func StorageAccess(addr gethcommon.Address, slot gethcommon.Hash) TrieAccess {
+	return TrieAccess{Owner: StorageTrieOwner(addr), Key: crypto.Keccak256Hash(slot.Bytes())}
+}
+
+// OrderNodesByAccess orders the nodes of the tries rooted at root by the first access whose path goes through them,
+// the nodes of an access path being ordered from root to leaf.
+// Nodes on no access path come last. Ties are broken by hash, so the order is deterministic.
+func OrderNodesByAccess(root gethcommon.Hash, nodes [][]byte, accesses []TrieAccess) [][]byte {
+	byPath := make(map[string][]gethcommon.Hash)
+	WalkNodePaths(root, nodes, func(owner, hash gethcommon.Hash, path, _ []byte) {
+		key := string(owner.Bytes()) + string(path)
+		byPath[key] = append(byPath[key], hash)
+	})
+
+	rank := make(map[gethcommon.Hash]int, len(nodes))
+	for _, access := range accesses {
+		nibbles := keyToNibbles(access.Key)
+		for i := 0; i <= len(nibbles); i++ {
+			hashes := byPath[string(access.Owner.Bytes())+string(nibbles[:i])]
+			sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+			for _, hash := range hashes {
+				if _, ok := rank[hash]; !ok {
+					rank[hash] = len(rank)
+				}
+			}
+		}
+	}
+
+	hashes := make([]gethcommon.Hash, len(nodes))
+	for i, node := range nodes {
+		hashes[i] = crypto.Keccak256Hash(node)
+	}
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		hi, hj := hashes[order[i]], hashes[order[j]]
+		ri, iRanked := rank[hi]
+		rj, jRanked := rank[hj]
+		switch {
+		case iRanked && jRanked:
+			return ri < rj
+		case iRanked != jRanked:
+			return iRanked
+		default:
+			return bytes.Compare(hi[:], hj[:]) < 0
+		}
+	})
+
+	ordered := make([][]byte, len(nodes))
+	for i, idx := range order {
+		ordered[i] = nodes[idx]
+	}
+	return ordered
+}
+
+// keyToNibbles splits a hashed trie key into nibbles
+func keyToNibbles(key gethcommon.Hash) []byte {
+	nibbles := make([]byte, 2*gethcommon.HashLength)
+	for i, b := range key {
+		nibbles[2*i], nibbles[2*i+1] = b>>4, b&0x0f
+	}
+	return nibbles
+}
@@ -0,0 +1,201 @@
+package trie
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// GroupNodesByOwner groups trie nodes by the trie that owns them.
+//
+// The nodes are walked from the account trie root, account trie nodes are grouped under AccountTrieOwner()
+// and storage trie nodes under the StorageTrieOwner() of the account they belong to.
+// A node shared by several tries is grouped under each owner.
+// It returns an UnownedNodesError if some nodes are not reachable from root.
+func GroupNodesByOwner(root gethcommon.Hash, nodes [][]byte) (map[gethcommon.Hash][][]byte, error) {
+	groups := make(map[gethcommon.Hash][][]byte)
+	owned := make(map[gethcommon.Hash]struct{})
+	WalkNodes(root, nodes, func(owner, hash gethcommon.Hash, node []byte) {
+		groups[owner] = append(groups[owner], node)
+		owned[hash] = struct{}{}
+	})
+
+	var unowned []gethcommon.Hash
+	for _, node := range nodes {
+		if hash := crypto.Keccak256Hash(node); !HasKey(owned, hash) {
+			unowned = append(unowned, hash)
+		}
+	}
+	if len(unowned) > 0 {
+		return nil, &UnownedNodesError{Hashes: unowned}
+	}
+
+	return groups, nil
+}
+
+// WalkNodes walks the account trie and the storage tries referenced by its accounts starting from root.
+// It only follows references to nodes available in nodes and calls onNode once per node and per owning trie.
+func WalkNodes(root gethcommon.Hash, nodes [][]byte, onNode func(owner, hash gethcommon.Hash, node []byte)) {
+	newNodeWalker(nodes, false, func(owner, hash gethcommon.Hash, _, node []byte) {
+		onNode(owner, hash, node)
+	}).walkHash(AccountTrieOwner(), root, nil)
+}
+
+// WalkNodePaths walks the tries as WalkNodes, but calls onNode once per node and per nibble path in its owning trie.
+func WalkNodePaths(root gethcommon.Hash, nodes [][]byte, onNode func(owner, hash gethcommon.Hash, path, node []byte)) {
+	newNodeWalker(nodes, true, onNode).walkHash(AccountTrieOwner(), root, nil)
+}
+
+func newNodeWalker(nodes [][]byte, byPath bool, onNode func(owner, hash gethcommon.Hash, path, node []byte)) *nodeWalker {
+	byHash := make(map[gethcommon.Hash][]byte, len(nodes))
+	for _, node := range nodes {
+		byHash[crypto.Keccak256Hash(node)] = node
+	}
+	return newResolvingNodeWalker(func(_, hash gethcommon.Hash, _ []byte) ([]byte, bool) {
+		node, ok := byHash[hash]
+		return node, ok
+	}, byPath, onNode)
+}
+
+func newResolvingNodeWalker(resolve func(owner, hash gethcommon.Hash, path []byte) ([]byte, bool), byPath bool, onNode func(owner, hash gethcommon.Hash, path, node []byte)) *nodeWalker {
+	return &nodeWalker{
+		resolve: resolve,
+		visited: make(map[string]struct{}),
+		byPath:  byPath,
+		onNode:  onNode,
+	}
+}
+
+type nodeWalker struct {
+	resolve func(owner, hash gethcommon.Hash, path []byte) ([]byte, bool) // Resolves a node, the walk does not go past the nodes it can not resolve
+	visited map[string]struct{}
+	byPath  bool // Whether a node reachable through several paths of a trie is visited once per path
+	onNode  func(owner, hash gethcommon.Hash, path, node []byte)
+}
+
+// walkHash walks the node with the given hash, path is the nibble path of the node in the trie
+func (w *nodeWalker) walkHash(owner, hash gethcommon.Hash, path []byte) {
+	node, ok := w.resolve(owner, hash, path)
+	if !ok {
+		return
+	}
+
+	key := string(owner.Bytes()) + string(hash.Bytes())
+	if w.byPath {
+		key += string(path)
+	}
+	if _, ok := w.visited[key]; ok {
+		return
+	}
+	w.visited[key] = struct{}{}
+	w.onNode(owner, hash, path, node)
+
+	w.walkNode(owner, node, path)
+}
+
+// walkNode decodes a RLP encoded node and walks its children
+func (w *nodeWalker) walkNode(owner gethcommon.Hash, node, path []byte) {
+	elems, _, err := rlp.SplitList(node)
+	if err != nil {
+		return
+	}
+
+	count, err := rlp.CountValues(elems)
+	if err != nil {
+		return
+	}
+
+	switch count {
+	case 2: // short node
+		kind, compactKey, rest, err := rlp.Split(elems)
+		if err != nil || kind == rlp.List {
+			return
+		}
+		nibbles, isLeaf := compactToNibbles(compactKey)
+		childPath := append(append([]byte{}, path...), nibbles...)
+		if isLeaf {
+			kind, value, _, err := rlp.Split(rest)
+			if err == nil && kind == rlp.String {
+				w.walkLeaf(owner, childPath, value)
+			}
+			return
+		}
+		w.walkRef(owner, rest, childPath)
+	case 17: // full node
+		for i := byte(0); i < 16; i++ {
+			elems = w.walkRef(owner, elems, append(append([]byte{}, path...), i))
+		}
+	}
+}
+
+// walkRef walks the child referenced by the first RLP item of elems and returns the remaining items
+func (w *nodeWalker) walkRef(owner gethcommon.Hash, elems, path []byte) []byte {
+	kind, val, rest, err := rlp.Split(elems)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case kind == rlp.List: // embedded node
+		w.walkNode(owner, elems[:len(elems)-len(rest)], path)
+	case kind == rlp.String && len(val) == gethcommon.HashLength:
+		w.walkHash(owner, gethcommon.BytesToHash(val), path)
+	}
+
+	return rest
+}
+
+// walkLeaf walks the storage trie of an account leaf of the account trie
+func (w *nodeWalker) walkLeaf(owner gethcommon.Hash, path, value []byte) {
+	if owner != AccountTrieOwner() || len(path) != 2*gethcommon.HashLength {
+		return
+	}
+
+	var account gethtypes.StateAccount
+	if err := rlp.DecodeBytes(value, &account); err != nil {
+		return
+	}
+
+	if account.Root != gethtypes.EmptyRootHash {
+		w.walkHash(gethcommon.BytesToHash(nibblesToBytes(path)), account.Root, nil)
+	}
+}
+
+// compactToNibbles converts a compact encoded key into nibbles and indicates whether it is a leaf key
+func compactToNibbles(compact []byte) (nibbles []byte, isLeaf bool) {
+	if len(compact) == 0 {
+		return nil, false
+	}
+
+	flag := compact[0] >> 4
+	isLeaf = flag&2 != 0
+	if flag&1 != 0 {
+		nibbles = append(nibbles, compact[0]&0x0f)
+	}
+	for _, b := range compact[1:] {
+		nibbles = append(nibbles, b>>4, b&0x0f)
+	}
+
+	return nibbles, isLeaf
+}
+
+// nibblesToBytes packs an even number of nibbles into bytes
+func nibblesToBytes(nibbles []byte) []byte {
+	b := make([]byte, len(nibbles)/2)
+	for i := range b {
+		b[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+	return b
+}
+
+// UnownedNodesError is returned when some nodes are not part of the tries they are expected to belong to
+type UnownedNodesError struct {
+	Hashes []gethcommon.Hash // Hashes of the nodes not found in the tries
+}
+
+func (e *UnownedNodesError) Error() string {
+	return fmt.Sprintf("%d nodes do not belong to the tries: %v", len(e.Hashes), e.Hashes)
+}
@@ -0,0 +1,37 @@
+package trie
+
+import (
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactToNibbles(t *testing.T) {
+	tests := []struct {
+		compact []byte
+		nibbles []byte
+		isLeaf  bool
+	}{
+		{compact: []byte{0x00, 0x12}, nibbles: []byte{0x1, 0x2}, isLeaf: false},
+		{compact: []byte{0x16}, nibbles: []byte{0x6}, isLeaf: false},
+		{compact: []byte{0x20, 0xab}, nibbles: []byte{0xa, 0xb}, isLeaf: true},
+		{compact: []byte{0x3c, 0xde}, nibbles: []byte{0xc, 0xd, 0xe}, isLeaf: true},
+	}
+
+	for _, tt := range tests {
+		nibbles, isLeaf := compactToNibbles(tt.compact)
+		assert.Equal(t, tt.nibbles, nibbles)
+		assert.Equal(t, tt.isLeaf, isLeaf)
+	}
+}
+
+func TestGroupNodesByOwnerUnownedNodes(t *testing.T) {
+	node := []byte{0xc2, 0x20, 0x01} // leaf node not referenced by root
+	_, err := GroupNodesByOwner(gethcommon.HexToHash("0x01"), [][]byte{node})
+	var unownedErr *UnownedNodesError
+	require.ErrorAs(t, err, &unownedErr)
+	assert.Equal(t, []gethcommon.Hash{crypto.Keccak256Hash(node)}, unownedErr.Hashes)
+}
@@ -0,0 +1,70 @@
+package trie
+
+import (
+	"bytes"
+	"sort"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PathNode is a trie node keyed as in the path scheme, by its owning trie and its path in the trie.
+// Owner is AccountTrieOwner() for the account trie and StorageTrieOwner() of the account for storage tries.
+type PathNode struct {
+	Owner gethcommon.Hash `json:"owner"`
+	Path  hexutil.Bytes   `json:"path"` // Nibble path of the node in its trie, one nibble per byte
+	Blob  hexutil.Bytes   `json:"blob"` // RLP encoded node
+}
+
+// ToPathScheme keys the hash scheme nodes by owner and path, walking the tries from root.
+// Nodes are sorted by owner and path, so the output is deterministic.
+// It returns an UnownedNodesError if some nodes are not reachable from root.
+func ToPathScheme(root gethcommon.Hash, nodes [][]byte) ([]*PathNode, error) {
+	var pathNodes []*PathNode
+	reached := make(map[gethcommon.Hash]struct{})
+	WalkNodePaths(root, nodes, func(owner, hash gethcommon.Hash, path, node []byte) {
+		pathNodes = append(pathNodes, &PathNode{Owner: owner, Path: append(hexutil.Bytes{}, path...), Blob: node})
+		reached[hash] = struct{}{}
+	})
+
+	var unowned []gethcommon.Hash
+	for _, node := range nodes {
+		if hash := crypto.Keccak256Hash(node); !HasKey(reached, hash) {
+			unowned = append(unowned, hash)
+		}
+	}
+	if len(unowned) > 0 {
+		return nil, &UnownedNodesError{Hashes: unowned}
+	}
+
+	sort.Slice(pathNodes, func(i, j int) bool {
+		if c := pathNodes[i].Owner.Cmp(pathNodes[j].Owner); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(pathNodes[i].Path, pathNodes[j].Path) < 0
+	})
+
+	return pathNodes, nil
+}
+
+// ToHashScheme keys the path scheme nodes by hash.
+// Nodes found at several paths are deduplicated and the output is sorted by hash.
+func ToHashScheme(nodes []*PathNode) [][]byte {
+	byHash := make(map[gethcommon.Hash][]byte, len(nodes))
+	for _, node := range nodes {
+		byHash[crypto.Keccak256Hash(node.Blob)] = node.Blob
+	}
+
+	hashes := make([]gethcommon.Hash, 0, len(byHash))
+	for hash := range byHash {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].Cmp(hashes[j]) < 0 })
+
+	blobs := make([][]byte, 0, len(hashes))
+	for _, hash := range hashes {
+		blobs = append(blobs, byHash[hash])
+	}
+	return blobs
+}
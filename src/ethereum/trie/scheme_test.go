@@ -0,0 +1,75 @@
+package trie
+
+import (
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemeConversion(t *testing.T) {
+	// Commit a state to a path scheme database
+	pathDisk := rawdb.NewMemoryDatabase()
+	pathDB := triedb.NewDatabase(pathDisk, &triedb.Config{PathDB: pathdb.Defaults})
+	st, err := gethstate.New(gethtypes.EmptyRootHash, gethstate.NewDatabase(pathDB, nil))
+	require.NoError(t, err)
+
+	var addrs []gethcommon.Address
+	for i := 0; i < 32; i++ {
+		addr := gethcommon.BytesToAddress(crypto.Keccak256([]byte{byte(i)}))
+		addrs = append(addrs, addr)
+		st.SetBalance(addr, uint256.NewInt(uint64(i+1)), 0)
+		if i%4 == 0 {
+			for j := 0; j < 16; j++ {
+				st.SetState(addr, gethcommon.Hash{byte(j)}, gethcommon.Hash{byte(i), byte(j), 0x01})
+			}
+		}
+	}
+	root, err := st.Commit(0, true)
+	require.NoError(t, err)
+	require.NoError(t, pathDB.Commit(root, false))
+
+	// Collect the path scheme nodes
+	var pathNodes []*PathNode
+	it := pathDisk.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		key, blob := it.Key(), gethcommon.CopyBytes(it.Value())
+		if ok, path := rawdb.ResolveAccountTrieNodeKey(key); ok {
+			pathNodes = append(pathNodes, &PathNode{Owner: AccountTrieOwner(), Path: gethcommon.CopyBytes(path), Blob: blob})
+		} else if ok, owner, path := rawdb.ResolveStorageTrieNode(key); ok {
+			pathNodes = append(pathNodes, &PathNode{Owner: owner, Path: gethcommon.CopyBytes(path), Blob: blob})
+		}
+	}
+	require.NotEmpty(t, pathNodes)
+
+	// Convert to hash scheme and open the state from the hash keyed nodes only
+	blobs := ToHashScheme(pathNodes)
+	hashDisk := rawdb.NewMemoryDatabase()
+	for _, blob := range blobs {
+		rawdb.WriteLegacyTrieNode(hashDisk, crypto.Keccak256Hash(blob), blob)
+	}
+	hashState, err := gethstate.New(root, gethstate.NewDatabase(triedb.NewDatabase(hashDisk, triedb.HashDefaults), nil))
+	require.NoError(t, err)
+	assert.Equal(t, root, hashState.IntermediateRoot(true))
+	for i, addr := range addrs {
+		assert.Equal(t, uint64(i+1), hashState.GetBalance(addr).Uint64())
+	}
+	assert.Equal(t, gethcommon.Hash{4, 3, 0x01}, hashState.GetState(addrs[4], gethcommon.Hash{3}))
+
+	// Converting back to path scheme restores the path keyed nodes
+	converted, err := ToPathScheme(root, blobs)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, pathNodes, converted)
+
+	_, err = ToPathScheme(root, append(blobs, []byte{0xc2, 0x20, 0x01}))
+	assert.IsType(t, &UnownedNodesError{}, err)
+}
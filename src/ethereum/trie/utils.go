@@ -24,3 +24,9 @@ func StorageTrieOwner(addr gethcommon.Address) gethcommon.Hash {
 func AccountTrieOwner() gethcommon.Hash {
 	return gethcommon.Hash{}
 }
+
+// HasKey indicates whether the set of hashes holds key.
+func HasKey(set map[gethcommon.Hash]struct{}, key gethcommon.Hash) bool {
+	_, ok := set[key]
+	return ok
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/kkrt-labs/go-utils/ethereum/rpc/jsonrpc"
+)
+
+// ErrPendingBlock is returned when preflight is requested for the pending block, whose content is not final
+var ErrPendingBlock = errors.New("pending block is not supported")
+
+// ParseBlockNumber parses a block number, decimal or 0x prefixed hexadecimal, or one of the latest, finalized and safe block tags.
+// Tags are parsed to the negative numbers of the go-ethereum rpc package, resolved by preflight. The pending tag is rejected.
+func ParseBlockNumber(s string) (*big.Int, error) {
+	switch s {
+	case "latest":
+		return big.NewInt(int64(gethrpc.LatestBlockNumber)), nil
+	case "finalized":
+		return big.NewInt(int64(gethrpc.FinalizedBlockNumber)), nil
+	case "safe":
+		return big.NewInt(int64(gethrpc.SafeBlockNumber)), nil
+	case "pending":
+		return nil, ErrPendingBlock
+	}
+
+	number, err := jsonrpc.DecodeBig(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number %q: %v", s, err)
+	}
+	if number != nil && number.Sign() < 0 {
+		return nil, fmt.Errorf("invalid block number %q: negative", s)
+	}
+	return number, nil
+}
+
+// blockTag returns the tag a block number stands for, empty for a concrete block number
+func blockTag(number *big.Int) string {
+	if number != nil && number.Sign() >= 0 {
+		return ""
+	}
+	return jsonrpc.ToBlockNumArg(number)
+}
+
+// ReorgError is returned when the chain reorganizes while preflight collects the data of a block resolved from a tag
+type ReorgError struct {
+	Number   *big.Int        // Number of the reorganized block, the resolved block or its parent
+	Expected gethcommon.Hash // Hash of the block when the tag was resolved
+	Actual   gethcommon.Hash // Hash of the block at the same number once the data is collected
+}
+
+func (e *ReorgError) Error() string {
+	return fmt.Sprintf("chain reorganized during preflight: block %v was %v when resolving the block tag, it is now %v", e.Number, e.Expected.Hex(), e.Actual.Hex())
+}
+
+// checkPinned checks the block resolved from a tag and its parent are still canonical, so the state proofs fetched by number
+// are proofs of their state. It returns a ReorgError otherwise.
+func (pf *preflight) checkPinned(ctx context.Context, block *gethtypes.Block) error {
+	parentNumber := new(big.Int).Sub(block.Number(), big.NewInt(1))
+	for _, expected := range []struct {
+		number *big.Int
+		hash   gethcommon.Hash
+	}{{parentNumber, block.ParentHash()}, {block.Number(), block.Hash()}} {
+		header, err := pf.remote.HeaderByNumber(ctx, expected.number)
+		if err != nil {
+			return fmt.Errorf("failed to fetch header %v: %v", expected.number, err)
+		}
+		if hash := header.Hash(); hash != expected.hash {
+			return &ReorgError{Number: expected.number, Expected: expected.hash, Actual: hash}
+		}
+	}
+	return nil
+}
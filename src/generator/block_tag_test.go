@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taggedChain is a testChain whose finalized block is not its latest block, counting the calls resolving a block tag
+type taggedChain struct {
+	*testChain
+
+	finalized uint64
+	reorged   bool // Whether the finalized block is replaced once resolved
+	tagged    int
+}
+
+func (c *taggedChain) resolve(blockNumber *big.Int) *big.Int {
+	if blockNumber != nil && blockNumber.Cmp(big.NewInt(int64(gethrpc.FinalizedBlockNumber))) == 0 {
+		c.tagged++
+		return new(big.Int).SetUint64(c.finalized)
+	}
+	return blockNumber
+}
+
+func (c *taggedChain) BlockByNumber(ctx context.Context, blockNumber *big.Int) (*gethtypes.Block, error) {
+	return c.testChain.BlockByNumber(ctx, c.resolve(blockNumber))
+}
+
+func (c *taggedChain) HeaderByNumber(ctx context.Context, blockNumber *big.Int) (*gethtypes.Header, error) {
+	header, err := c.testChain.HeaderByNumber(ctx, c.resolve(blockNumber))
+	if err == nil && c.reorged && header.Number.Uint64() == c.finalized {
+		header = gethtypes.CopyHeader(header)
+		header.Extra = []byte("reorged")
+	}
+	return header, err
+}
+
+func TestParseBlockNumber(t *testing.T) {
+	for s, expected := range map[string]*big.Int{
+		"latest":    big.NewInt(int64(gethrpc.LatestBlockNumber)),
+		"finalized": big.NewInt(int64(gethrpc.FinalizedBlockNumber)),
+		"safe":      big.NewInt(int64(gethrpc.SafeBlockNumber)),
+		"1234":      big.NewInt(1234),
+		"0x10":      big.NewInt(16),
+	} {
+		number, err := ParseBlockNumber(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, number, s)
+	}
+
+	_, err := ParseBlockNumber("pending")
+	assert.ErrorIs(t, err, ErrPendingBlock)
+	_, err = ParseBlockNumber("earliest")
+	assert.Error(t, err)
+}
+
+func TestPreflightBlockTag(t *testing.T) {
+	testChain, _ := newRangeTestChain(t)
+	chain := &taggedChain{testChain: testChain, finalized: 2}
+
+	// The finalized block is resolved once and pinned
+	data, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(int64(gethrpc.FinalizedBlockNumber)))
+	require.NoError(t, err)
+	assert.Equal(t, 1, chain.tagged)
+	assert.Equal(t, "finalized", data.BlockTag)
+	assert.Equal(t, uint64(2), data.Block.Number.ToInt().Uint64())
+	assert.Equal(t, testChain.block(2).Hash(), data.Block.Hash)
+
+	_, err = NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// Blocks requested by number are not tagged
+	data, err = NewPreflight(chain).Preflight(context.Background(), big.NewInt(2))
+	require.NoError(t, err)
+	assert.Empty(t, data.BlockTag)
+
+	_, err = NewPreflight(chain).Preflight(context.Background(), big.NewInt(int64(gethrpc.PendingBlockNumber)))
+	assert.ErrorIs(t, err, ErrPendingBlock)
+}
+
+func TestPreflightBlockTagReorg(t *testing.T) {
+	testChain, _ := newRangeTestChain(t)
+	chain := &taggedChain{testChain: testChain, finalized: 2, reorged: true}
+
+	_, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(int64(gethrpc.FinalizedBlockNumber)))
+	var reorgErr *ReorgError
+	require.ErrorAs(t, err, &reorgErr)
+	assert.Equal(t, big.NewInt(2), reorgErr.Number)
+	assert.Equal(t, testChain.block(2).Hash(), reorgErr.Expected)
+}
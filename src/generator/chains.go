@@ -1,23 +1,88 @@
 package generator
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
+	"os"
 
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/params"
 )
 
-// ChainConfigs are supported chain configurations.
+// ChainConfigs is the registry of supported chain configurations, keyed by chain ID.
+// Configurations hold the full fork schedule of the chain, so callers only need to provide a chain ID.
 var ChainConfigs = map[string]*params.ChainConfig{
 	params.MainnetChainConfig.ChainID.String(): params.MainnetChainConfig,
 	params.SepoliaChainConfig.ChainID.String(): params.SepoliaChainConfig,
 	params.HoleskyChainConfig.ChainID.String(): params.HoleskyChainConfig,
 }
 
-func getChainConfig(chainID *big.Int) (*params.ChainConfig, error) {
+// ChainGeneses is the registry of the genesis of the chains registered from a genesis file, keyed by chain ID.
+// Only mainnet, Sepolia and Holesky can be prepared without a registered genesis, on their built-in genesis.
+var ChainGeneses = map[string]*core.Genesis{}
+
+// ChainConfig returns the registered configuration of the chain with the given ID.
+func ChainConfig(chainID *big.Int) (*params.ChainConfig, error) {
+	if chainID == nil {
+		return nil, fmt.Errorf("chain ID missing")
+	}
+
 	cfg, ok := ChainConfigs[chainID.String()]
 	if !ok {
 		return nil, fmt.Errorf("unsupported chain ID: %s", chainID)
 	}
 	return cfg, nil
 }
+
+// LoadGenesis loads a genesis from a standard geth genesis.json file.
+func LoadGenesis(path string) (*core.Genesis, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open genesis file: %v", err)
+	}
+	defer f.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(f).Decode(genesis); err != nil {
+		return nil, fmt.Errorf("failed to decode genesis file %q: %v", path, err)
+	}
+	if genesis.Config == nil || genesis.Config.ChainID == nil {
+		return nil, fmt.Errorf("genesis file %q misses the chain ID", path)
+	}
+	return genesis, nil
+}
+
+// RegisterGenesis registers the chain configuration and the genesis of a genesis, replacing any configuration previously registered for its chain ID.
+func RegisterGenesis(genesis *core.Genesis) error {
+	if genesis.Config == nil || genesis.Config.ChainID == nil {
+		return fmt.Errorf("chain ID missing")
+	}
+
+	chainID := genesis.Config.ChainID.String()
+	ChainConfigs[chainID] = genesis.Config
+	ChainGeneses[chainID] = genesis
+	return nil
+}
+
+// chainGenesis returns the genesis registered for the given chain configuration, or the built-in genesis of mainnet, Sepolia and Holesky.
+// Other chains must register their genesis, as preparing on the genesis of another chain keys the pre-state against a wrong genesis.
+func chainGenesis(cfg *params.ChainConfig) (*core.Genesis, error) {
+	if cfg == nil || cfg.ChainID == nil {
+		return nil, fmt.Errorf("chain ID missing")
+	}
+
+	chainID := cfg.ChainID.String()
+	if genesis, ok := ChainGeneses[chainID]; ok {
+		return genesis, nil
+	}
+	switch chainID {
+	case params.MainnetChainConfig.ChainID.String():
+		return core.DefaultGenesisBlock(), nil
+	case params.SepoliaChainConfig.ChainID.String():
+		return core.DefaultSepoliaGenesisBlock(), nil
+	case params.HoleskyChainConfig.ChainID.String():
+		return core.DefaultHoleskyGenesisBlock(), nil
+	}
+	return nil, fmt.Errorf("no genesis registered for chain %v, it must be loaded from a genesis file", cfg.ChainID)
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainConfig(t *testing.T) {
+	cfg, err := ChainConfig(big.NewInt(1))
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewInt(12_965_000), cfg.LondonBlock)
+	require.NotNil(t, cfg.ShanghaiTime)
+	assert.Equal(t, uint64(1_681_338_455), *cfg.ShanghaiTime)
+	require.NotNil(t, cfg.CancunTime)
+	assert.Equal(t, uint64(1_710_338_135), *cfg.CancunTime)
+
+	for _, chainID := range []int64{11155111, 17000} {
+		cfg, err := ChainConfig(big.NewInt(chainID))
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(chainID), cfg.ChainID)
+	}
+
+	_, err = ChainConfig(big.NewInt(123456789))
+	assert.Error(t, err)
+	_, err = ChainConfig(nil)
+	assert.Error(t, err)
+}
+
+func TestChainGenesis(t *testing.T) {
+	for _, genesis := range []*core.Genesis{core.DefaultGenesisBlock(), core.DefaultSepoliaGenesisBlock(), core.DefaultHoleskyGenesisBlock()} {
+		resolved, err := chainGenesis(genesis.Config)
+		require.NoError(t, err)
+		assert.Equal(t, genesis.ToBlock().Hash(), resolved.ToBlock().Hash())
+	}
+
+	// Other chains are not prepared on the mainnet genesis
+	cfg := *params.MergedTestChainConfig
+	cfg.ChainID = big.NewInt(123456789)
+	_, err := chainGenesis(&cfg)
+	assert.ErrorContains(t, err, "no genesis registered for chain 123456789")
+	_, err = chainGenesis(nil)
+	assert.Error(t, err)
+}
+
+func TestLoadGenesis(t *testing.T) {
+	// A custom chain, with no built-in configuration
+	cfg := *testChainConfig()
+	cfg.ChainID = big.NewInt(424242)
+	alloc := gethtypes.GenesisAlloc{
+		testAddr:                          {Balance: testBalance},
+		gethcommon.HexToAddress("0xc0de"): {Code: []byte{0x00}, Balance: big.NewInt(1)},
+	}
+	genesis := &core.Genesis{
+		Config:     &cfg,
+		Alloc:      alloc,
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: new(big.Int),
+	}
+
+	b, err := json.Marshal(genesis)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, &cfg, alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+
+	// The genesis loaded from the file replaces the genesis registered by the test chain
+	loaded, err := LoadGenesis(path)
+	require.NoError(t, err)
+	require.Equal(t, chain.genesis.Hash(), loaded.ToBlock().Hash())
+	require.NoError(t, RegisterGenesis(loaded))
+	t.Cleanup(func() {
+		delete(ChainConfigs, cfg.ChainID.String())
+		delete(ChainGeneses, cfg.ChainID.String())
+	})
+
+	registered, err := ChainConfig(cfg.ChainID)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.CancunTime, registered.CancunTime)
+
+	data := chain.preflightData(t, 1)
+
+	// The header chain is set up on the custom genesis
+	pc, err := NewPreparer().(*preparer).prepareContext(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, chain.genesis.Hash(), pc.hc.GetHeaderByNumber(0).Hash())
+
+	in, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	_, err = NewExecutor().Execute(context.Background(), in)
+	require.NoError(t, err)
+
+	// Genesis files must provide the chain ID
+	require.NoError(t, os.WriteFile(path, []byte(`{"config":{},"alloc":{},"gasLimit":"0x1c9c380","difficulty":"0x0"}`), 0o600))
+	_, err = LoadGenesis(path)
+	assert.ErrorContains(t, err, "misses the chain ID")
+}
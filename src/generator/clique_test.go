@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCliqueTestChain generates a clique chain of n blocks sealed by the given signer, the only authorized signer.
+// The signer is set as coinbase with an authorization vote, which clique ignores for an authorized signer,
+// so the fees are credited to the same account while generating and executing the blocks.
+func newCliqueTestChain(t *testing.T, signer *ecdsa.PrivateKey, n int, gen func(int, *core.BlockGen)) *testChain {
+	config := *params.AllCliqueProtocolChanges
+	config.ChainID = big.NewInt(1338)
+	setRegistry(t, ChainConfigs, config.ChainID.String(), &config)
+
+	signerAddr := crypto.PubkeyToAddress(signer.PublicKey)
+	genesis := &core.Genesis{
+		Config:     &config,
+		Alloc:      gethtypes.GenesisAlloc{testAddr: {Balance: testBalance}},
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: big.NewInt(1),
+		ExtraData:  append(append(make([]byte, 32), signerAddr.Bytes()...), make([]byte, crypto.SignatureLength)...),
+	}
+
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, clique.New(config.Clique, rawdb.NewMemoryDatabase()), n, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(signerAddr)
+		b.SetDifficulty(big.NewInt(2)) // The only signer is always in turn
+		b.SetNonce(gethtypes.BlockNonce{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+		if gen != nil {
+			gen(i, b)
+		}
+	})
+
+	// Seal the blocks, which changes their hashes so the parent hashes are updated
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, 32+crypto.SignatureLength)
+		sig, err := crypto.Sign(clique.SealHash(header).Bytes(), signer)
+		require.NoError(t, err)
+		copy(header.Extra[32:], sig)
+		blocks[i] = block.WithSeal(header)
+	}
+
+	c := &testChain{
+		config:  &config,
+		stateDB: gethstate.NewDatabase(triedb.NewDatabase(db, triedb.HashDefaults), nil),
+		genesis: genesis.ToBlock(),
+		blocks:  blocks,
+		headers: make(map[gethcommon.Hash]*gethtypes.Header),
+	}
+	registered := registerTestGenesis(t, genesis)
+	c.headers[registered.Hash()] = registered
+	c.headers[c.genesis.Hash()] = c.genesis.Header()
+	for _, block := range blocks {
+		c.headers[block.Hash()] = block.Header()
+	}
+
+	return c
+}
+
+func TestPreparerClique(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newCliqueTestChain(t, signer, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	// The fees are credited to the signer recovered from the seal
+	signerAddr := crypto.PubkeyToAddress(signer.PublicKey)
+	var proven []gethcommon.Address
+	for _, proof := range data.PreStateProofs {
+		proven = append(proven, proof.Address)
+	}
+	assert.Contains(t, proven, signerAddr)
+
+	in, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	_, err = NewExecutor().Execute(context.Background(), in)
+	require.NoError(t, err)
+
+	// Blocks sealed by an unauthorized signer are rejected
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	header := chain.block(1).Header()
+	header.Extra = make([]byte, 32+crypto.SignatureLength)
+	sig, err := crypto.Sign(clique.SealHash(header).Bytes(), other)
+	require.NoError(t, err)
+	copy(header.Extra[32:], sig)
+	data.Block.Header.Extra = header.Extra
+	rehash(data)
+	_, err = NewPreparer().Prepare(context.Background(), data)
+	assert.ErrorContains(t, err, "unauthorized signer")
+}
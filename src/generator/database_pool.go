@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// WithDatabaseReuse makes the preparer reuse the in-memory databases of its previous preparations instead of
+// allocating a new database and committing the chain genesis to it for each block.
+// A database is reset to its state right after the genesis commit once a preparation is done, so a preparation
+// does not see any state of the previous ones. Databases are pooled per genesis, the pool holding at most as many databases
+// as the preparations run concurrently.
+func WithDatabaseReuse() PreparerOption {
+	return func(p *preparer) {
+		p.databases = newDatabasePool()
+	}
+}
+
+// databasePool is a pool of in-memory databases on which a chain genesis is committed
+type databasePool struct {
+	mu   sync.Mutex
+	free map[databasePoolKey][]*pooledDatabase
+}
+
+// databasePoolKey identifies the genesis of a chain, as resolved by chainGenesis
+type databasePoolKey struct {
+	chainID string
+	genesis *core.Genesis // Genesis registered for the chain, nil for the default genesis
+}
+
+type pooledDatabase struct {
+	key databasePoolKey
+	mem *memorydb.Database
+	db  ethdb.Database
+
+	genesis map[string][]byte // Entries of the database right after the genesis commit, nil until the genesis is committed
+}
+
+func newDatabasePool() *databasePool {
+	return &databasePool{free: make(map[databasePoolKey][]*pooledDatabase)}
+}
+
+// get returns a database of the pool for the chain, or a new one, to commit the genesis to, if there is none.
+// It returns nil on a nil pool.
+func (p *databasePool) get(cfg *params.ChainConfig) *pooledDatabase {
+	if p == nil {
+		return nil
+	}
+
+	key := databasePoolKey{chainID: cfg.ChainID.String(), genesis: ChainGeneses[cfg.ChainID.String()]}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if free := p.free[key]; len(free) > 0 {
+		db := free[len(free)-1]
+		p.free[key] = free[:len(free)-1]
+		return db
+	}
+
+	mem := memorydb.New()
+	return &pooledDatabase{key: key, mem: mem, db: rawdb.NewDatabase(mem)}
+}
+
+// put resets db and gives it back to the pool. Databases the genesis has not been committed to are dropped.
+func (p *databasePool) put(db *pooledDatabase) {
+	if p == nil || db == nil || db.genesis == nil {
+		return
+	}
+
+	db.reset()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free[db.key] = append(p.free[db.key], db)
+}
+
+// committed returns whether the genesis has been committed to the database
+func (db *pooledDatabase) committed() bool {
+	return db.genesis != nil
+}
+
+// snapshot records the entries of the database once the genesis has been committed
+func (db *pooledDatabase) snapshot() {
+	db.genesis = make(map[string][]byte, db.mem.Len())
+	it := db.mem.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		db.genesis[string(it.Key())] = it.Value()
+	}
+}
+
+// reset deletes the entries written to the database since the genesis commit and restores the ones overwritten
+func (db *pooledDatabase) reset() {
+	var stale [][]byte
+	it := db.mem.NewIterator(nil, nil)
+	for it.Next() {
+		value, ok := db.genesis[string(it.Key())]
+		switch {
+		case !ok:
+			stale = append(stale, it.Key())
+		case !bytes.Equal(value, it.Value()):
+			_ = db.mem.Put(it.Key(), value)
+		}
+	}
+	it.Release()
+
+	for _, key := range stale {
+		_ = db.mem.Delete(key)
+	}
+
+	if db.mem.Len() < len(db.genesis) {
+		// Some genesis entries have been deleted
+		for key, value := range db.genesis {
+			if ok, _ := db.mem.Has([]byte(key)); !ok {
+				_ = db.mem.Put([]byte(key), value)
+			}
+		}
+	}
+}
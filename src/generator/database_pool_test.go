@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerDatabaseReuse(t *testing.T) {
+	p := NewPreparer(WithDatabaseReuse())
+	_, data := newRangeTestChain(t)
+
+	// Sequential preparations on different blocks match the ones on fresh databases
+	for _, d := range data {
+		expected, err := NewPreparer().Prepare(context.Background(), d)
+		require.NoError(t, err)
+		in, err := p.Prepare(context.Background(), d)
+		require.NoError(t, err)
+		equal, diff := input.CompareProverInputWithDiff(expected, in)
+		assert.True(t, equal, diff)
+	}
+	free := p.(*preparer).databases.free
+	require.Len(t, free, 1)
+	for _, dbs := range free {
+		assert.Len(t, dbs, 1)
+	}
+
+	_, err := p.PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+	_, err = p.PrepareTransaction(context.Background(), data[0], 0)
+	require.NoError(t, err)
+}
+
+func TestPreparerDatabaseReuseIsolation(t *testing.T) {
+	chain, contract := newLargeStorageChain(t)
+	p := NewPreparer(WithDatabaseReuse())
+
+	_, err := p.Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	// The state nodes of the previous preparation must not make up for the missing proof
+	data := chain.preflightData(t, 1)
+	dropStorageProof(data, contract, gethcommon.HexToHash("0x05"))
+	_, err = p.Prepare(context.Background(), data)
+	var missing *MissingProofError
+	require.ErrorAs(t, err, &missing)
+}
+
+func BenchmarkPrepareDatabaseReuse(b *testing.B) {
+	data := newTransferChain(b).preflightData(b, 1)
+	for _, bc := range []struct {
+		name string
+		opts []PreparerOption
+	}{
+		{name: "fresh"},
+		{name: "reuse", opts: []PreparerOption{WithDatabaseReuse()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			p := NewPreparer(bc.opts...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.Prepare(context.Background(), data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -4,16 +4,17 @@ import (
 	"context"
 	"fmt"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
-	"github.com/ethereum/go-ethereum/core/rawdb"
 	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
-	"github.com/ethereum/go-ethereum/triedb"
-	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
 	"github.com/kkrt-labs/go-utils/log"
 	"github.com/kkrt-labs/go-utils/tag"
 	"github.com/kkrt-labs/zk-pig/src/ethereum"
 	"github.com/kkrt-labs/zk-pig/src/ethereum/evm"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
 	"go.uber.org/zap"
 )
@@ -24,6 +25,26 @@ import (
 type Executor interface {
 	// Execute runs a full EVM block execution on provable inputs
 	Execute(ctx context.Context, inputs *input.ProverInput) (*core.ProcessResult, error)
+
+	// ExecuteWithCheckpoints runs a full EVM block execution on provable inputs and compares the state root after each transaction with roots.
+	// On divergence it returns an evm.IntermediateRootMismatchError reporting the first diverging transaction.
+	ExecuteWithCheckpoints(ctx context.Context, inputs *input.ProverInput, roots []gethcommon.Hash) (*core.ProcessResult, error)
+
+	// Validate runs a full EVM block execution on provable inputs and reports the outcome as a ValidationResult.
+	// It only returns an error if the execution could not be set up, an invalid block is reported in the result.
+	Validate(ctx context.Context, inputs *input.ProverInput) (*ValidationResult, error)
+}
+
+// ValidationResult reports the outcome of a block execution on provable inputs compared to the block header.
+type ValidationResult struct {
+	Success           bool            `json:"success"`           // Whether the block executed and validated successfully
+	StateRoot         gethcommon.Hash `json:"stateRoot"`         // State root computed after execution
+	ExpectedStateRoot gethcommon.Hash `json:"expectedStateRoot"` // State root of the block header
+	GasUsed           uint64          `json:"gasUsed"`           // Gas used computed by the execution
+	ExpectedGasUsed   uint64          `json:"expectedGasUsed"`   // Gas used of the block header
+	ReceiptsRootMatch bool            `json:"receiptsRootMatch"` // Whether the computed receipts root matches the block header
+	BloomMatch        bool            `json:"bloomMatch"`        // Whether the computed logs bloom matches the block header
+	Divergence        string          `json:"divergence"`        // Details of the failure, empty on success
 }
 
 type executor struct{}
@@ -35,6 +56,11 @@ func NewExecutor() Executor {
 
 // Execute runs the ProvableBlockInputs data for the EVM prover engine.
 func (e *executor) Execute(ctx context.Context, inputs *input.ProverInput) (*core.ProcessResult, error) {
+	return e.ExecuteWithCheckpoints(ctx, inputs, nil)
+}
+
+// ExecuteWithCheckpoints runs the ProvableBlockInputs data for the EVM prover engine, checking intermediate roots.
+func (e *executor) ExecuteWithCheckpoints(ctx context.Context, inputs *input.ProverInput, roots []gethcommon.Hash) (*core.ProcessResult, error) {
 	if len(inputs.Blocks) == 0 {
 		return nil, fmt.Errorf("no blocks provided")
 	}
@@ -49,7 +75,7 @@ func (e *executor) Execute(ctx context.Context, inputs *input.ProverInput) (*cor
 		tag.Key("block.hash").String(block.Header.Hash().Hex()),
 	)
 
-	res, err := e.execute(ctx, inputs)
+	res, err := e.execute(ctx, inputs, roots)
 	if err != nil {
 		log.LoggerFromContext(ctx).Error("Provable execution failed", zap.Error(err))
 		return res, err
@@ -60,13 +86,107 @@ func (e *executor) Execute(ctx context.Context, inputs *input.ProverInput) (*cor
 	return res, err
 }
 
+// Validate runs the ProvableBlockInputs data for the EVM prover engine and reports the validation outcome.
+func (e *executor) Validate(ctx context.Context, inputs *input.ProverInput) (*ValidationResult, error) {
+	if len(inputs.Blocks) == 0 {
+		return nil, fmt.Errorf("no blocks provided")
+	}
+
+	header := inputs.Blocks[0].Header
+	ctx = tag.WithComponent(ctx, "validate")
+	ctx = tag.WithTags(
+		ctx,
+		tag.Key("chain.id").String(inputs.ChainConfig.ChainID.String()),
+		tag.Key("block.number").Int64(header.Number.Int64()),
+		tag.Key("block.hash").String(header.Hash().Hex()),
+	)
+
+	execCtx, err := e.prepareContext(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare execution context: %v", err)
+	}
+
+	if err := e.preparePreState(execCtx, inputs); err != nil {
+		return nil, fmt.Errorf("failed to prepare pre-state: %v", err)
+	}
+
+	execParams, err := e.prepareExecParams(execCtx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare execution exec params: %v", err)
+	}
+	execParams.Validate = false // Validation is run below to collect the result details
+
+	result := &ValidationResult{
+		ExpectedStateRoot: header.Root,
+		ExpectedGasUsed:   header.GasUsed,
+	}
+
+	res, err := e.execEVM(execCtx, execParams)
+	if err != nil && ctx.Err() != nil {
+		// An aborted execution tells nothing about the block
+		return nil, err
+	} else if err != nil {
+		result.Divergence = err.Error()
+		log.LoggerFromContext(ctx).Error("Provable validation failed", zap.Error(err))
+		return result, nil
+	}
+
+	result.StateRoot = execParams.State.IntermediateRoot(inputs.ChainConfig.IsEIP158(header.Number))
+	result.GasUsed = res.GasUsed
+	result.ReceiptsRootMatch = gethtypes.DeriveSha(res.Receipts, gethtrie.NewStackTrie(nil)) == header.ReceiptHash
+	result.BloomMatch = gethtypes.CreateBloom(res.Receipts) == header.Bloom
+
+	if err := core.NewBlockValidator(inputs.ChainConfig, nil).ValidateState(execParams.Block, execParams.State, res, false); err != nil {
+		result.Divergence = err.Error()
+		log.LoggerFromContext(ctx).Error("Provable validation failed", zap.Error(err))
+		return result, nil
+	}
+
+	result.Success = true
+	log.LoggerFromContext(ctx).Info("Provable validation succeeded")
+
+	return result, nil
+}
+
+// ComputeStateRoot executes the block of the prover input on its witness and returns the resulting state root.
+// The root is not compared with the block header, so callers can compare it with whatever source they trust.
+func ComputeStateRoot(inputs *input.ProverInput) (gethcommon.Hash, error) {
+	if len(inputs.Blocks) == 0 {
+		return gethcommon.Hash{}, fmt.Errorf("no blocks provided")
+	}
+
+	e := &executor{}
+	ctx := tag.WithComponent(context.Background(), "compute-state-root")
+	execCtx, err := e.prepareContext(ctx, inputs)
+	if err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("failed to prepare execution context: %v", err)
+	}
+
+	if err := e.preparePreState(execCtx, inputs); err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("failed to prepare pre-state: %v", err)
+	}
+
+	execParams, err := e.prepareExecParams(execCtx, inputs)
+	if err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("failed to prepare execution exec params: %v", err)
+	}
+	execParams.Validate = false
+
+	if _, err := e.execEVM(execCtx, execParams); err != nil {
+		return gethcommon.Hash{}, err
+	}
+
+	return execParams.State.IntermediateRoot(inputs.ChainConfig.IsEIP158(execParams.Block.Number())), nil
+}
+
 type executorContext struct {
-	ctx     context.Context
-	stateDB gethstate.Database
-	hc      *core.HeaderChain
+	ctx         context.Context
+	stateDB     gethstate.Database
+	hc          *core.HeaderChain
+	checkpoints *evm.CheckpointTracer
 }
 
-func (e *executor) execute(ctx context.Context, inputs *input.ProverInput) (*core.ProcessResult, error) {
+func (e *executor) execute(ctx context.Context, inputs *input.ProverInput, roots []gethcommon.Hash) (*core.ProcessResult, error) {
 	log.LoggerFromContext(ctx).Info("Process provable execution...")
 
 	execCtx, err := e.prepareContext(ctx, inputs)
@@ -74,13 +194,20 @@ func (e *executor) execute(ctx context.Context, inputs *input.ProverInput) (*cor
 		return nil, fmt.Errorf("failed to prepare execution context: %v", err)
 	}
 
-	e.preparePreState(execCtx, inputs)
+	if err := e.preparePreState(execCtx, inputs); err != nil {
+		return nil, fmt.Errorf("failed to prepare pre-state: %v", err)
+	}
 
 	execParams, err := e.prepareExecParams(execCtx, inputs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare execution exec params: %v", err)
 	}
 
+	if len(roots) > 0 {
+		execCtx.checkpoints = evm.NewCheckpointTracer(inputs.ChainConfig, execParams.State, roots)
+		execParams.VMConfig.Tracer = execCtx.checkpoints.Hooks()
+	}
+
 	return e.execEVM(execCtx, execParams)
 }
 
@@ -88,11 +215,14 @@ func (e *executor) prepareContext(ctx context.Context, inputs *input.ProverInput
 	log.LoggerFromContext(ctx).Debug("Prepare context...")
 
 	// --- Create necessary database and chain instances ---
-	db := rawdb.NewMemoryDatabase()
-	trieDB := triedb.NewDatabase(db, &triedb.Config{HashDB: &hashdb.Config{}})
-	stateDB := gethstate.NewDatabase(trieDB, nil) // We use a modified trie database to track trie modifications
+	stateDB := newMemoryStateDatabase(nil)
 
-	hc, err := ethereum.NewChain(inputs.ChainConfig, stateDB)
+	genesis, err := chainGenesis(inputs.ChainConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := ethereum.NewChainFromGenesis(inputs.ChainConfig, genesis, stateDB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chain: %v", err)
 	}
@@ -104,25 +234,34 @@ func (e *executor) prepareContext(ctx context.Context, inputs *input.ProverInput
 	}, nil
 }
 
-func (e *executor) preparePreState(ctx *executorContext, inputs *input.ProverInput) {
+func (e *executor) preparePreState(ctx *executorContext, inputs *input.ProverInput) error {
 	log.LoggerFromContext(ctx.ctx).Info("Prepare pre-state...")
 
+	// -- Regenerate the branch nodes omitted from a compacted witness ---
+	witness, err := input.ExpandWitness(inputs.Witness)
+	if err != nil {
+		return fmt.Errorf("failed to expand witness: %v", err)
+	}
+
 	// -- Preload the ancestors of the block into database ---
-	ethereum.WriteHeaders(ctx.stateDB.TrieDB().Disk(), inputs.Witness.Ancestors...)
+	ethereum.WriteHeaders(ctx.stateDB.TrieDB().Disk(), witness.Ancestors...)
 
 	// --- Preload the account bytecodes into the database ---
 	codes := make([][]byte, 0)
-	for _, code := range inputs.Witness.Codes {
+	for _, code := range witness.Codes {
 		codes = append(codes, code)
 	}
 	ethereum.WriteCodes(ctx.stateDB.TrieDB().Disk(), codes...)
 
-	// -- Preload the pre-state nodes to database ---
+	// -- Preload the pre-state nodes to database, path scheme nodes being keyed by hash ---
 	nodes := make([][]byte, 0)
-	for _, node := range inputs.Witness.State {
+	for _, node := range witness.State {
 		nodes = append(nodes, node)
 	}
+	nodes = append(nodes, trie.ToHashScheme(witness.StateByPath)...)
 	ethereum.WriteNodesToHashDB(ctx.stateDB.TrieDB().Disk(), nodes...)
+
+	return nil
 }
 
 func (e *executor) prepareExecParams(ctx *executorContext, inputs *input.ProverInput) (*evm.ExecParams, error) {
@@ -161,9 +300,16 @@ func (e *executor) prepareExecParams(ctx *executorContext, inputs *input.ProverI
 func (e *executor) execEVM(ctx *executorContext, execParams *evm.ExecParams) (*core.ProcessResult, error) {
 	log.LoggerFromContext(ctx.ctx).Info("Execute EVM...")
 
-	res, err := evm.ExecutorWithTags("evm")(evm.ExecutorWithLog()(evm.NewExecutor())).Execute(ctx.ctx, execParams)
+	res, err := evm.ExecutorWithTags("evm")(evm.ExecutorWithLog()(evm.ExecutorWithRecover()(evm.ExecutorWithContext(0)(evm.NewExecutor())))).Execute(ctx.ctx, execParams)
+	if ctx.checkpoints != nil && ctx.checkpoints.Mismatch() != nil {
+		// The first diverging transaction is more informative than the final validation error
+		if err != nil {
+			log.LoggerFromContext(ctx.ctx).Debug("Block execution failed after state divergence", zap.Error(err))
+		}
+		return res, fmt.Errorf("failed to execute block: %w", ctx.checkpoints.Mismatch())
+	}
 	if err != nil {
-		return res, fmt.Errorf("failed to execute block: %v", err)
+		return res, fmt.Errorf("failed to execute block: %w", err)
 	}
 
 	return res, nil
@@ -2,9 +2,19 @@ package generator
 
 import (
 	"context"
+	"math/big"
 	"testing"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/evm"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExecutor(t *testing.T) {
@@ -18,3 +28,178 @@ func TestExecutor(t *testing.T) {
 		})
 	}
 }
+
+// intermediateRoots executes the prover input and returns the state root after each transaction
+func intermediateRoots(t *testing.T, in *input.ProverInput) []gethcommon.Hash {
+	e := NewExecutor().(*executor)
+	ctx, err := e.prepareContext(context.Background(), in)
+	require.NoError(t, err)
+	require.NoError(t, e.preparePreState(ctx, in))
+	params, err := e.prepareExecParams(ctx, in)
+	require.NoError(t, err)
+
+	tracer := evm.NewCheckpointTracer(in.ChainConfig, params.State, nil)
+	params.VMConfig.Tracer = tracer.Hooks()
+	_, err = e.execEVM(ctx, params)
+	require.NoError(t, err)
+
+	return tracer.Roots()
+}
+
+func TestExecutorCheckpoints(t *testing.T) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	roots := intermediateRoots(t, in)
+	require.Len(t, roots, 3)
+
+	_, err = NewExecutor().ExecuteWithCheckpoints(context.Background(), in, roots)
+	require.NoError(t, err)
+
+	// Tampering the contract code only affects the third transaction calling the contract
+	tampered := *in
+	tampered.Witness = &input.Witness{
+		State:     in.Witness.State,
+		Ancestors: in.Witness.Ancestors,
+		Codes:     []hexutil.Bytes{{byte(vm.PUSH1), 2, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}},
+	}
+
+	_, err = NewExecutor().ExecuteWithCheckpoints(context.Background(), &tampered, roots)
+	var mismatch *evm.IntermediateRootMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, 2, mismatch.TxIndex)
+	assert.Equal(t, roots[2], mismatch.Expected)
+}
+
+func TestExecutorValidate(t *testing.T) {
+	chain := newTransferChain(t)
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	header := chain.block(1).Header()
+
+	result, err := NewExecutor().Validate(context.Background(), in)
+	require.NoError(t, err)
+	assert.Equal(t, &ValidationResult{
+		Success:           true,
+		StateRoot:         header.Root,
+		ExpectedStateRoot: header.Root,
+		GasUsed:           header.GasUsed,
+		ExpectedGasUsed:   header.GasUsed,
+		ReceiptsRootMatch: true,
+		BloomMatch:        true,
+	}, result)
+
+	// Block claiming an invalid state root
+	tamperedHeader := gethtypes.CopyHeader(header)
+	tamperedHeader.Root = gethcommon.HexToHash("0x01")
+	tampered := *in
+	tampered.Blocks = []*input.Block{{Header: tamperedHeader, Transactions: in.Blocks[0].Transactions, Withdrawals: in.Blocks[0].Withdrawals}}
+
+	result, err = NewExecutor().Validate(context.Background(), &tampered)
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, header.Root, result.StateRoot)
+	assert.Equal(t, tamperedHeader.Root, result.ExpectedStateRoot)
+	assert.Equal(t, header.GasUsed, result.GasUsed)
+	assert.True(t, result.ReceiptsRootMatch)
+	assert.True(t, result.BloomMatch)
+	assert.Contains(t, result.Divergence, "invalid merkle root")
+}
+
+func TestComputeStateRoot(t *testing.T) {
+	chain := newTransferChain(t)
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	header := chain.block(1).Header()
+
+	root, err := ComputeStateRoot(in)
+	require.NoError(t, err)
+	assert.Equal(t, header.Root, root)
+
+	// The header root is not used
+	tamperedHeader := gethtypes.CopyHeader(header)
+	tamperedHeader.Root = gethcommon.HexToHash("0x01")
+	tampered := *in
+	tampered.Blocks = []*input.Block{{Header: tamperedHeader, Transactions: in.Blocks[0].Transactions, Withdrawals: in.Blocks[0].Withdrawals}}
+
+	root, err = ComputeStateRoot(&tampered)
+	require.NoError(t, err)
+	assert.Equal(t, header.Root, root)
+}
+
+func TestExecutorBLSPrecompile(t *testing.T) {
+	pragueConfig := *testChainConfig()
+	config := &pragueConfig
+	config.ChainID = big.NewInt(1338)
+	config.PragueTime = new(uint64)
+
+	// Contract calling the BLS12-381 G1 addition precompile on points at infinity and storing the size of the result
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x80, byte(vm.PUSH1), 0x00, byte(vm.PUSH2), 0x01, 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x0b, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.RETURNDATASIZE), byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+	chain := newTestChain(t, config, alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	result, err := NewExecutor().Validate(context.Background(), in)
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Divergence)
+
+	// Before Prague the precompile is not available so the call returns no data and the state diverges
+	cancun := *config
+	cancun.PragueTime = nil
+	earlier := *in
+	earlier.ChainConfig = &cancun
+
+	result, err = NewExecutor().Validate(context.Background(), &earlier)
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.NotEqual(t, result.ExpectedStateRoot, result.StateRoot)
+}
+
+func TestExecutorCodeCreatedInBlock(t *testing.T) {
+	// Init code returning a runtime code writing slot 0
+	runtime := []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	initCode := append([]byte{
+		byte(vm.PUSH1), byte(len(runtime)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(runtime)), byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}, runtime...)
+
+	contract := crypto.CreateAddress(testAddr, 0)
+	chain := newTestChain(t, testChainConfig(), nil, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, nil, new(big.Int), 100_000, initCode))
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	postState, _, err := chain.stateAt(big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, runtime, postState.GetCode(contract))
+	require.Equal(t, gethcommon.BigToHash(big.NewInt(1)), postState.GetState(contract, gethcommon.Hash{}))
+
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	// The created code is not part of the pre-state, the execution regenerates it
+	assert.Empty(t, in.Witness.Codes)
+	result, err := NewExecutor().Validate(context.Background(), in)
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Divergence)
+}
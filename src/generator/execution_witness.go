@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// ExecutionWitness is the execution witness of a block in the JSON format of go-ethereum debug_executionWitness.
+// Headers are the ancestors accessed by the block, starting with its parent.
+type ExecutionWitness struct {
+	Headers []*gethtypes.Header `json:"headers"`
+	Codes   []hexutil.Bytes     `json:"codes"`
+	State   []hexutil.Bytes     `json:"state"`
+	Keys    []hexutil.Bytes     `json:"keys,omitempty"` // Preimages of the accessed trie keys, unused
+}
+
+// LoadExecutionWitness loads an execution witness from a JSON file
+func LoadExecutionWitness(path string) (*ExecutionWitness, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := new(ExecutionWitness)
+	if err := json.Unmarshal(b, w); err != nil {
+		return nil, fmt.Errorf("failed to decode execution witness: %v", err)
+	}
+	return w, nil
+}
+
+// PrepareFromExecutionWitness assembles the ProverInput of a block from its execution witness,
+// then validates it by executing the block on the witness only.
+func PrepareFromExecutionWitness(ctx context.Context, config *params.ChainConfig, block *gethtypes.Block, w *ExecutionWitness) (*input.ProverInput, error) {
+	if len(w.Headers) == 0 || w.Headers[0].Hash() != block.ParentHash() {
+		return nil, fmt.Errorf("execution witness must start with the parent header of block %v", block.Number())
+	}
+
+	proverInput := &input.ProverInput{
+		ChainConfig: config,
+		Blocks: []*input.Block{
+			{
+				Header:       block.Header(),
+				Transactions: block.Transactions(),
+				Uncles:       block.Uncles(),
+				Withdrawals:  block.Withdrawals(),
+			},
+		},
+		Witness: &input.Witness{
+			Ancestors: w.Headers,
+			Codes:     append([]hexutil.Bytes{}, w.Codes...),
+			State:     append([]hexutil.Bytes{}, w.State...),
+		},
+	}
+	sortByHash(proverInput.Witness.Codes)
+	sortByHash(proverInput.Witness.State)
+
+	if _, err := NewExecutor().Execute(ctx, proverInput); err != nil {
+		return nil, fmt.Errorf("validation execution on execution witness failed: %w", err)
+	}
+
+	return proverInput, nil
+}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareFromExecutionWitness(t *testing.T) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}}}}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	expected, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// Write the go-ethereum witness collected during the validation execution as a JSON execution witness
+	exec, err := NewPreparer().PrepareExecution(context.Background(), data)
+	require.NoError(t, err)
+	raw := map[string]interface{}{"headers": exec.Witness.Headers}
+	var codes, state []hexutil.Bytes
+	for c := range exec.Witness.Codes {
+		codes = append(codes, []byte(c))
+	}
+	for node := range exec.Witness.State {
+		state = append(state, []byte(node))
+	}
+	raw["codes"], raw["state"] = codes, state
+	b, err := json.Marshal(raw)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "witness.json")
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+
+	w, err := LoadExecutionWitness(path)
+	require.NoError(t, err)
+	in, err := PrepareFromExecutionWitness(context.Background(), data.ChainConfig, chain.block(1), w)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Witness.State, in.Witness.State)
+	assert.Equal(t, expected.Witness.Codes, in.Witness.Codes)
+	assert.Equal(t, chain.block(1).Hash(), in.Blocks[0].Header.Hash())
+
+	// A witness missing the pre-state root node fails the validation execution
+	var pruned []hexutil.Bytes
+	for _, node := range w.State {
+		if crypto.Keccak256Hash(node) != w.Headers[0].Root {
+			pruned = append(pruned, node)
+		}
+	}
+	require.Len(t, pruned, len(w.State)-1)
+	w.State = pruned
+	_, err = PrepareFromExecutionWitness(context.Background(), data.ChainConfig, chain.block(1), w)
+	assert.Error(t, err)
+
+	// A witness of another block is rejected
+	w.Headers = w.Headers[:0]
+	_, err = PrepareFromExecutionWitness(context.Background(), data.ChainConfig, chain.block(1), w)
+	assert.ErrorContains(t, err, "must start with the parent header")
+}
@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	store "github.com/kkrt-labs/go-utils/store"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// FsckResult is the outcome of the check of a prover input file of an archive
+type FsckResult struct {
+	Path      string // Path of the file, relative to the archive directory
+	Err       error  // Set if the file could not be decoded or its input failed validation
+	Rewritten bool   // Whether the file has been rewritten in canonical form
+}
+
+// CanonicalizeProverInput sorts the witness codes and state nodes by hash and drops duplicates, as Prepare outputs them.
+func CanonicalizeProverInput(in *input.ProverInput) {
+	if in.Witness == nil {
+		return
+	}
+	in.Witness.Codes = dedupByHash(in.Witness.Codes)
+	sortByHash(in.Witness.Codes)
+	in.Witness.State = dedupByHash(in.Witness.State)
+	sortByHash(in.Witness.State)
+}
+
+func dedupByHash(blobs []hexutil.Bytes) []hexutil.Bytes {
+	seen := make(map[string]struct{}, len(blobs))
+	unique := blobs[:0]
+	for _, blob := range blobs {
+		key := string(crypto.Keccak256(blob))
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			unique = append(unique, blob)
+		}
+	}
+	return unique
+}
+
+// FsckArchive walks the prover input files of the archive directory, skipping the block hash index, and validates each input with Verify.
+// Files are decompressed according to encoding, the content encoding the archive has been stored with, and decoded with codec.
+// Valid inputs are canonicalized and, if rewrite is set, written back with the same encoding when their canonical encoding differs.
+// It returns a result per file, an error being returned only if the directory could not be walked.
+func FsckArchive(ctx context.Context, dir string, codec input.ProverInputCodec, encoding store.ContentEncoding, rewrite bool) ([]*FsckResult, error) {
+	var results []*FsckResult
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && entry.Name() == "hashes" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		res := &FsckResult{Path: rel}
+		res.Rewritten, res.Err = fsckFile(ctx, path, codec, encoding, rewrite)
+		results = append(results, res)
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("failed to walk archive %v: %v", dir, err)
+	}
+	return results, nil
+}
+
+// fsckFile validates the input of the file and rewrites it canonically if rewrite is set, reporting whether it has been rewritten
+func fsckFile(ctx context.Context, path string, codec input.ProverInputCodec, encoding store.ContentEncoding, rewrite bool) (bool, error) {
+	b, err := readEncodedFile(path, encoding)
+	if err != nil {
+		return false, err
+	}
+
+	in, err := codec.Decode(bytes.NewReader(b))
+	if err != nil {
+		return false, fmt.Errorf("failed to decode prover input: %v", err)
+	}
+	if len(in.Blocks) == 0 {
+		return false, fmt.Errorf("prover input has no blocks")
+	}
+
+	if err := Verify(ctx, in); err != nil {
+		return false, fmt.Errorf("validation failed: %w", err)
+	}
+
+	CanonicalizeProverInput(in)
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, in); err != nil {
+		return false, fmt.Errorf("failed to encode prover input: %v", err)
+	}
+	if !rewrite || bytes.Equal(buf.Bytes(), b) {
+		return false, nil
+	}
+
+	if err := writeEncodedFile(path, buf.Bytes(), encoding); err != nil {
+		return false, fmt.Errorf("failed to rewrite canonical prover input: %v", err)
+	}
+	return true, nil
+}
+
+// readEncodedFile reads the file and decompresses its content according to encoding, as the compress store loads it
+func readEncodedFile(path string, encoding store.ContentEncoding) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader
+	switch encoding {
+	case store.ContentEncodingPlain:
+		r = f
+	case store.ContentEncodingGzip:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip: %v", err)
+		}
+		defer gr.Close()
+		r = gr
+	case store.ContentEncodingZlib:
+		zr, err := zlib.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zlib: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	case store.ContentEncodingFlate:
+		fr := flate.NewReader(f)
+		defer fr.Close()
+		r = fr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %d", encoding)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %v", err)
+	}
+	return b, nil
+}
+
+// writeEncodedFile compresses content according to encoding, as the compress store stores it, and replaces the file with it
+func writeEncodedFile(path string, content []byte, encoding store.ContentEncoding) error {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case store.ContentEncodingPlain:
+		buf.Write(content)
+	case store.ContentEncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case store.ContentEncodingZlib:
+		w = zlib.NewWriter(&buf)
+	case store.ContentEncodingFlate:
+		fw, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			return err
+		}
+		w = fw
+	default:
+		return fmt.Errorf("unsupported content encoding %d", encoding)
+	}
+	if w != nil {
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Writing to a temporary file first so an interrupted rewrite does not corrupt the archive
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	store "github.com/kkrt-labs/go-utils/store"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsckArchive(t *testing.T) {
+	_, data := newRangeTestChain(t)
+	codec, err := input.GetCodec("json")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	write := func(name string, in *input.ProverInput) {
+		var buf bytes.Buffer
+		require.NoError(t, codec.Encode(&buf, in))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644))
+	}
+
+	var canonical []*input.ProverInput
+	for _, d := range data {
+		in, err := NewPreparer().Prepare(context.Background(), d)
+		require.NoError(t, err)
+		canonical = append(canonical, in)
+	}
+
+	// Block 1 is canonical, block 2 is out of order with duplicates and block 3 misses a state node
+	write("1", canonical[0])
+
+	shuffled, err := NewPreparer().Prepare(context.Background(), data[1])
+	require.NoError(t, err)
+	slices.Reverse(shuffled.Witness.State)
+	shuffled.Witness.Codes = append(shuffled.Witness.Codes, shuffled.Witness.Codes...)
+	write("2", shuffled)
+
+	corrupt, err := NewPreparer().Prepare(context.Background(), data[2])
+	require.NoError(t, err)
+	corrupt.Witness.State = corrupt.Witness.State[1:]
+	write("3", corrupt)
+	corruptBytes, err := os.ReadFile(filepath.Join(dir, "3"))
+	require.NoError(t, err)
+
+	// The block hash index is not a prover input
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "hashes"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hashes", "0x01"), []byte("1"), 0o644))
+
+	results, err := FsckArchive(context.Background(), dir, codec, store.ContentEncodingPlain, true)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "1", results[0].Path)
+	assert.NoError(t, results[0].Err)
+	assert.False(t, results[0].Rewritten)
+
+	assert.Equal(t, "2", results[1].Path)
+	assert.NoError(t, results[1].Err)
+	assert.True(t, results[1].Rewritten)
+
+	assert.Equal(t, "3", results[2].Path)
+	assert.ErrorContains(t, results[2].Err, "validation failed")
+	assert.False(t, results[2].Rewritten)
+
+	// Good files are canonical, the corrupt one is left untouched
+	for i, name := range []string{"1", "2"} {
+		var expected bytes.Buffer
+		require.NoError(t, codec.Encode(&expected, canonical[i]))
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), string(b))
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "3"))
+	require.NoError(t, err)
+	assert.Equal(t, corruptBytes, b)
+}
+
+func TestFsckArchiveGzipRange(t *testing.T) {
+	_, data := newRangeTestChain(t)
+	codec, err := input.GetCodec("json")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	write := func(name string, in *input.ProverInput) {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		require.NoError(t, codec.Encode(gw, in))
+		require.NoError(t, gw.Close())
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644))
+	}
+
+	// Range 1 is valid but out of order, range 2 is invalid on its last block only
+	shuffled, err := NewPreparer().PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+	slices.Reverse(shuffled.Witness.State)
+	write("1.json.gzip", shuffled)
+
+	corrupt, err := NewPreparer().PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+	corrupt.Blocks[2].Header.GasUsed++
+	write("2.json.gzip", corrupt)
+
+	results, err := FsckArchive(context.Background(), dir, codec, store.ContentEncodingGzip, true)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.NoError(t, results[0].Err)
+	assert.True(t, results[0].Rewritten)
+	assert.ErrorContains(t, results[1].Err, "validation failed")
+
+	// The rewritten file is still gzip encoded
+	canonical, err := NewPreparer().PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+	var expected bytes.Buffer
+	require.NoError(t, codec.Encode(&expected, canonical))
+
+	f, err := os.Open(filepath.Join(dir, "1.json.gzip"))
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), string(b))
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	"github.com/kkrt-labs/go-utils/tag"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultGenerateConcurrency is the maximum number of blocks GenerateBlocks generates concurrently
+const defaultGenerateConcurrency = 4
+
+// ProverInputStore persists the ProverInputs produced by a Generator.
+// It is satisfied by the prover input stores of the store package.
+type ProverInputStore interface {
+	// StoreProverInput stores the prover input of a block.
+	StoreProverInput(ctx context.Context, in *input.ProverInput) error
+}
+
+// Generator is the single entry point going from a block number to a stored ProverInput.
+type Generator interface {
+	// Generate runs preflight and prepare for the given block number, the latest block if nil,
+	// then stores the ProverInput if the generator has a store.
+	Generate(ctx context.Context, blockNumber *big.Int) (*input.ProverInput, error)
+
+	// GenerateBlocks generates the ProverInputs of an arbitrary, possibly non-contiguous, set of blocks concurrently,
+	// returning the result of each block keyed by block number.
+	GenerateBlocks(ctx context.Context, numbers []uint64) map[uint64]*GenerateResult
+}
+
+type generator struct {
+	preflight Preflight
+	preparer  Preparer
+	store     ProverInputStore
+}
+
+// NewGenerator creates a Generator fetching the block data from remote and preparing it with p.
+// The ProverInputs are stored in store, unless it is nil. Options configure the preflight.
+func NewGenerator(remote ethrpc.Client, p Preparer, store ProverInputStore, opts ...PreflightOption) Generator {
+	return &generator{
+		preflight: NewPreflight(remote, opts...),
+		preparer:  p,
+		store:     store,
+	}
+}
+
+// Generate runs preflight and prepare for the given block number, then stores the ProverInput if the generator has a store.
+func (g *generator) Generate(ctx context.Context, blockNumber *big.Int) (*input.ProverInput, error) {
+	ctx = tag.WithComponent(ctx, "generate")
+	proverInput, err := generateBlock(ctx, g.preflight, g.preparer, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.store != nil {
+		if err := g.store.StoreProverInput(tag.WithComponent(ctx, "store"), proverInput); err != nil {
+			return nil, fmt.Errorf("failed to store prover input of block %v: %v", proverInput.Blocks[0].Header.Number, err)
+		}
+	}
+
+	return proverInput, nil
+}
+
+// GenerateResult is the outcome of the generation of the ProverInput of a block.
+type GenerateResult struct {
+	ProverInput *input.ProverInput
+	Err         error
+}
+
+// GenerateBlocks generates the ProverInputs of an arbitrary set of blocks concurrently, storing each of them if the generator has a store.
+func (g *generator) GenerateBlocks(ctx context.Context, numbers []uint64) map[uint64]*GenerateResult {
+	return GenerateEach(ctx, g, numbers)
+}
+
+// GenerateEach generates the ProverInput of each of the given blocks with gen, at most 4 blocks at a time.
+// It is meant for Generator implementations to implement GenerateBlocks on top of Generate.
+//
+// Blocks do not need to be contiguous, each block is generated independently
+// so a failing block does not affect the others. It returns the results keyed by block number.
+func GenerateEach(ctx context.Context, gen Generator, numbers []uint64) map[uint64]*GenerateResult {
+	var (
+		results = make(map[uint64]*GenerateResult, len(numbers))
+		mu      sync.Mutex
+	)
+
+	// Errors are reported per block, so the group never fails and a failing block does not cancel the others
+	var group errgroup.Group
+	group.SetLimit(defaultGenerateConcurrency)
+	for _, number := range numbers {
+		mu.Lock()
+		_, ok := results[number]
+		if !ok {
+			results[number] = nil
+		}
+		mu.Unlock()
+		if ok {
+			continue
+		}
+
+		group.Go(func() error {
+			proverInput, err := gen.Generate(ctx, new(big.Int).SetUint64(number))
+			mu.Lock()
+			results[number] = &GenerateResult{ProverInput: proverInput, Err: err}
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	return results
+}
+
+func generateBlock(ctx context.Context, pf Preflight, p Preparer, number *big.Int) (*input.ProverInput, error) {
+	data, err := pf.Preflight(tag.WithComponent(ctx, "preflight"), number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preflight block %v: %v", number, err)
+	}
+
+	proverInput, err := p.Prepare(tag.WithComponent(ctx, "prepare"), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare block %v: %v", number, err)
+	}
+
+	return proverInput, nil
+}
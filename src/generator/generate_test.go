@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/kkrt-labs/go-utils/tag"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateBlocks(t *testing.T) {
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), nil, 6, func(i int, b *core.BlockGen) {
+		for j := 0; j <= i; j++ {
+			b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		}
+	})
+
+	results := NewGenerator(chain, NewPreparer(), nil).GenerateBlocks(context.Background(), []uint64{5, 2, 6, 2, 42})
+	require.Len(t, results, 4)
+
+	for _, number := range []uint64{2, 5, 6} {
+		require.Contains(t, results, number)
+		res := results[number]
+		require.NoError(t, res.Err)
+		assert.Equal(t, chain.block(number).Hash(), res.ProverInput.Blocks[0].Header.Hash())
+		assert.Len(t, res.ProverInput.Blocks[0].Transactions, int(number))
+
+		expected, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, number))
+		require.NoError(t, err)
+		equal, diff := input.CompareProverInputWithDiff(expected, res.ProverInput)
+		assert.True(t, equal, diff)
+	}
+
+	require.Contains(t, results, uint64(42))
+	assert.Error(t, results[42].Err)
+	assert.Nil(t, results[42].ProverInput)
+}
+
+// memoryProverInputStore is an in-memory ProverInputStore keyed by block number
+type memoryProverInputStore struct {
+	inputs map[uint64]*input.ProverInput
+	err    error
+}
+
+func (s *memoryProverInputStore) StoreProverInput(_ context.Context, in *input.ProverInput) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.inputs[in.Blocks[0].Header.Number.Uint64()] = in
+	return nil
+}
+
+func TestGenerator(t *testing.T) {
+	chain := newTransferChain(t)
+
+	tests := []struct {
+		name     string
+		number   int64
+		storeErr error
+		wantErr  string
+	}{
+		{name: "happy path", number: 1},
+		{name: "preflight failure", number: 42, wantErr: "failed to preflight block 42"},
+		{name: "store failure", number: 1, storeErr: errors.New("disk full"), wantErr: "failed to store prover input of block 1: disk full"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &memoryProverInputStore{inputs: make(map[uint64]*input.ProverInput), err: test.storeErr}
+			in, err := NewGenerator(chain, NewPreparer(), store).Generate(context.Background(), big.NewInt(test.number))
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				assert.Nil(t, in)
+				assert.Empty(t, store.inputs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, chain.block(uint64(test.number)).Hash(), in.Blocks[0].Header.Hash())
+			_, err = NewExecutor().Execute(context.Background(), in)
+			require.NoError(t, err)
+			assert.Same(t, in, store.inputs[uint64(test.number)])
+		})
+	}
+
+	// The generator does not require a store
+	in, err := NewGenerator(chain, NewPreparer(), nil).Generate(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, chain.block(1).Hash(), in.Blocks[0].Header.Hash())
+}
+
+// contextComponent returns the component the context is tagged with
+func contextComponent(ctx context.Context) string {
+	for _, t := range tag.FromContext(ctx) {
+		if t.Key == "component" {
+			component, _ := t.Value.Interface.(string)
+			return component
+		}
+	}
+	return ""
+}
+
+// componentPreflight is a Preflight recording the component of the context of its preflights
+type componentPreflight struct {
+	preflight Preflight
+	component string
+}
+
+func (pf *componentPreflight) Preflight(ctx context.Context, blockNumber *big.Int) (*PreflightData, error) {
+	pf.component = contextComponent(ctx)
+	return pf.preflight.Preflight(ctx, blockNumber)
+}
+
+func (pf *componentPreflight) EstimatePreflightCost(ctx context.Context, blockNumber *big.Int) (*PreflightCost, error) {
+	return pf.preflight.EstimatePreflightCost(ctx, blockNumber)
+}
+
+// componentPreparer is a Preparer recording the component of the context of its preparations
+type componentPreparer struct {
+	Preparer
+	component string
+}
+
+func (p *componentPreparer) Prepare(ctx context.Context, data *PreflightData) (*input.ProverInput, error) {
+	p.component = contextComponent(ctx)
+	return p.Preparer.Prepare(ctx, data)
+}
+
+func TestGeneratorStageComponents(t *testing.T) {
+	pf := &componentPreflight{preflight: NewPreflight(newTransferChain(t))}
+	p := &componentPreparer{Preparer: NewPreparer()}
+
+	_, err := (&generator{preflight: pf, preparer: p}).Generate(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, "generate.preflight", pf.component)
+	assert.Equal(t, "generate.prepare", p.component)
+}
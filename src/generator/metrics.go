@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "zkpig"
+
+// preparerMetrics are the Prometheus metrics of a preparer, all labeled by chain id
+type preparerMetrics struct {
+	stageDuration *prometheus.HistogramVec
+	witnessNodes  *prometheus.HistogramVec
+	witnessBytes  *prometheus.HistogramVec
+	witnessCodes  *prometheus.HistogramVec
+}
+
+// newPreparerMetrics creates the preparer metrics and registers them on reg.
+// Metrics already registered on reg, by another preparer, are shared with it.
+func newPreparerMetrics(reg prometheus.Registerer) *preparerMetrics {
+	m := &preparerMetrics{
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "prepare",
+			Name:      "stage_duration_seconds",
+			Help:      "Duration of the preparation stages.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, []string{"chain_id", "stage"}),
+		witnessNodes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "prepare",
+			Name:      "witness_nodes",
+			Help:      "Number of state nodes in the prepared witnesses.",
+			Buckets:   prometheus.ExponentialBuckets(64, 2, 14),
+		}, []string{"chain_id"}),
+		witnessBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "prepare",
+			Name:      "witness_bytes",
+			Help:      "Size in bytes of the state nodes, codes and ancestors of the prepared witnesses.",
+			Buckets:   prometheus.ExponentialBuckets(16*1024, 2, 14),
+		}, []string{"chain_id"}),
+		witnessCodes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "prepare",
+			Name:      "witness_codes",
+			Help:      "Number of codes in the prepared witnesses.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{"chain_id"}),
+	}
+
+	m.stageDuration = register(reg, m.stageDuration)
+	m.witnessNodes = register(reg, m.witnessNodes)
+	m.witnessBytes = register(reg, m.witnessBytes)
+	m.witnessCodes = register(reg, m.witnessCodes)
+
+	return m
+}
+
+// register registers c on reg, returning the collector already registered if any
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// observeStage records the duration of a preparation stage started at start
+func (m *preparerMetrics) observeStage(chainID *big.Int, stage string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.stageDuration.WithLabelValues(chainID.String(), stage).Observe(time.Since(start).Seconds())
+}
+
+// observeWitness records the size of a prepared witness
+func (m *preparerMetrics) observeWitness(chainID *big.Int, w *input.Witness) {
+	if m == nil {
+		return
+	}
+	size, err := witnessSize(w)
+	if err != nil {
+		return
+	}
+	nodes := len(w.State) + len(w.CompactBranches) + len(w.StateByPath) + len(w.StateDiff)
+	m.witnessNodes.WithLabelValues(chainID.String()).Observe(float64(nodes))
+	m.witnessBytes.WithLabelValues(chainID.String()).Observe(float64(size))
+	m.witnessCodes.WithLabelValues(chainID.String()).Observe(float64(len(w.Codes)))
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerMetrics(t *testing.T) {
+	to := gethcommon.HexToAddress("0xbeef")
+	chain := newTestChain(t, testChainConfig(), nil, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	reg := prometheus.NewRegistry()
+	in, err := NewPreparer(WithMetrics(reg)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// A second preparer shares the metrics registered on the registry
+	_, err = NewPreparer(WithMetrics(reg)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	byName := make(map[string][]map[string]string)
+	counts := make(map[string]uint64)
+	sums := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			byName[family.GetName()] = append(byName[family.GetName()], labels)
+			counts[family.GetName()] += metric.GetHistogram().GetSampleCount()
+			sums[family.GetName()] += metric.GetHistogram().GetSampleSum()
+		}
+	}
+
+	chainID := data.ChainConfig.ChainID.String()
+	var stages []string
+	for _, labels := range byName["zkpig_prepare_stage_duration_seconds"] {
+		assert.Equal(t, chainID, labels["chain_id"])
+		stages = append(stages, labels["stage"])
+	}
+	assert.ElementsMatch(t, []string{"prepareContext", "preparePreState", "prepareExecParams", "execute", "prepareProverInput"}, stages)
+	assert.Equal(t, uint64(10), counts["zkpig_prepare_stage_duration_seconds"])
+
+	for _, name := range []string{"zkpig_prepare_witness_nodes", "zkpig_prepare_witness_bytes", "zkpig_prepare_witness_codes"} {
+		require.Equal(t, []map[string]string{{"chain_id": chainID}}, byName[name], name)
+		assert.Equal(t, uint64(2), counts[name], name)
+	}
+	size, err := witnessSize(in.Witness)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2*len(in.Witness.State)), sums["zkpig_prepare_witness_nodes"])
+	assert.Equal(t, float64(2*size), sums["zkpig_prepare_witness_bytes"])
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/state"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// MissingProofError is returned when the execution of a block reads an account, or a storage slot,
+// the pre-state proofs do not provide the trie nodes of
+type MissingProofError struct {
+	Number   uint64
+	Access   state.StateAccess
+	Provided bool // Whether PreStateProofs hold a proof of the access, which then misses some nodes
+	Err      error
+}
+
+func (e *MissingProofError) Error() string {
+	needs := fmt.Sprintf("account %v", e.Access.Address.Hex())
+	if e.Access.IsSlot {
+		needs = fmt.Sprintf("storage slot %v of account %v", e.Access.Slot.Hex(), e.Access.Address.Hex())
+	}
+	if e.Provided {
+		return fmt.Sprintf("execution of block %d needs %v but its proof in PreStateProofs is incomplete: %v", e.Number, needs, e.Err)
+	}
+	return fmt.Sprintf("execution of block %d needs %v but no proof was provided in PreStateProofs: %v", e.Number, needs, e.Err)
+}
+
+func (e *MissingProofError) Unwrap() error {
+	return e.Err
+}
+
+// missingProofError returns a MissingProofError if err is caused by reading the state, nil otherwise
+func missingProofError(number uint64, err error, preStateProofs []*trie.AccountProof) error {
+	var accessErr *state.AccessError
+	if !errors.As(err, &accessErr) {
+		return nil
+	}
+	return &MissingProofError{
+		Number:   number,
+		Access:   accessErr.Access,
+		Provided: hasProof(preStateProofs, accessErr.Access),
+		Err:      accessErr.Err,
+	}
+}
+
+func hasProof(proofs []*trie.AccountProof, access state.StateAccess) bool {
+	for _, accountProof := range proofs {
+		if accountProof.Address != access.Address {
+			continue
+		}
+		if !access.IsSlot {
+			return true
+		}
+		for _, storageProof := range accountProof.Storage {
+			if gethcommon.HexToHash(storageProof.Key) == access.Slot {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
 
 	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -12,7 +15,9 @@ import (
 	gethstate "github.com/ethereum/go-ethereum/core/state"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
 	"github.com/ethereum/go-ethereum/params"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/ethereum/go-ethereum/triedb/hashdb"
 	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
@@ -24,6 +29,7 @@ import (
 	"github.com/kkrt-labs/zk-pig/src/ethereum/state"
 	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 // PreflightData contains data expected by an EVM prover engine to execute & prove the block.
@@ -36,6 +42,21 @@ type PreflightData struct {
 	Codes           []hexutil.Bytes      `json:"codes"`           // Contract bytecodes used during the block execution
 	PreStateProofs  []*trie.AccountProof `json:"preStateProofs"`  // Proofs of every accessed account and storage slot accessed during the block processing
 	PostStateProofs []*trie.AccountProof `json:"postStateProofs"` // Proofs of every account and storage slot deleted during the block processing
+
+	Uncles []*gethtypes.Header `json:"uncles,omitempty"` // Uncles of the block, whose rewards are credited on pre-merge chains
+
+	Receipts gethtypes.Receipts `json:"receipts,omitempty"` // Optional, receipts of the block execution, used to locate receipt divergences when preparing
+
+	BlockTag string `json:"blockTag,omitempty"` // Tag the block has been resolved from (latest, finalized or safe), empty if requested by number
+}
+
+// block returns the block to execute, with its uncles
+func (data *PreflightData) block() *gethtypes.Block {
+	block := data.Block.Block()
+	if len(data.Uncles) == 0 {
+		return block
+	}
+	return block.WithBody(gethtypes.Body{Transactions: block.Transactions(), Uncles: data.Uncles, Withdrawals: block.Withdrawals()})
 }
 
 // Preflight is the interface for the preflight block execution which consists of processing an EVM block without final state validation.
@@ -44,27 +65,151 @@ type PreflightData struct {
 type Preflight interface {
 	// Preflight executes a preflight block execution and returns the intermediate PreflightExecInputs data.
 	Preflight(ctx context.Context, blockNumber *big.Int) (*PreflightData, error)
+
+	// EstimatePreflightCost projects the RPC calls the preflight of the block makes, without executing it.
+	EstimatePreflightCost(ctx context.Context, blockNumber *big.Int) (*PreflightCost, error)
 }
 
 // preflight is the implementation of the Preflight interface using an RPC remote to fetch the state datas.
 type preflight struct {
-	remote ethrpc.Client
+	remote  ethrpc.Client
+	archive ethrpc.Client
+
+	perCallTimeout      time.Duration
+	retryPolicy         *RetryPolicy
+	maxProofConcurrency int
+	slowLoadThreshold   time.Duration
+	maxStateNodes       int
+	alwaysInclude       gethtypes.AccessList
+	batcher             BatchCaller
+	batchSize           int
+
+	checkpointPath     string
+	checkpointInterval int
+	checkpoint         *checkpointClient
+
+	cassettePath string
+	recorder     *recordingClient
+}
+
+// PreflightOption configures a Preflight.
+type PreflightOption func(*preflight)
+
+// defaultPerCallTimeout is the default timeout of each individual RPC call used by preflight
+const defaultPerCallTimeout = 5 * time.Second
+
+// WithPerCallTimeout sets a timeout on each individual RPC call, independently of the overall preflight context deadline,
+// 5s by default and disabled if zero or negative.
+// It enables a slow call to fail fast so it can be retried rather than consuming the whole preflight budget.
+func WithPerCallTimeout(timeout time.Duration) PreflightOption {
+	return func(pf *preflight) {
+		pf.perCallTimeout = timeout
+	}
+}
+
+// defaultProofConcurrency is the default maximum number of accounts whose state proofs are fetched concurrently
+const defaultProofConcurrency = 16
+
+// WithProofConcurrency sets the maximum number of accounts whose state proofs are fetched concurrently, 16 by default.
+// The proofs are output in the same order whatever the concurrency, 1 fetching them serially.
+func WithProofConcurrency(concurrency int) PreflightOption {
+	return func(pf *preflight) {
+		pf.maxProofConcurrency = concurrency
+	}
+}
+
+// WithSlowLoadThreshold makes preflight log a warning for each code load taking longer than threshold.
+// Accounts and storage slots are read through RPC rather than loaded from trie nodes, their slowness shows in the RPC retries.
+func WithSlowLoadThreshold(threshold time.Duration) PreflightOption {
+	return func(pf *preflight) {
+		pf.slowLoadThreshold = threshold
+	}
+}
+
+// WithMaxStateNodes limits the number of trie nodes preflight collects through state proofs, protecting against pathological blocks.
+// Preflight fails with a StateLimitExceededError once the limit is exceeded. As each accessed account and storage slot
+// is proven by at least one node, preflight fails before fetching any proof if more are accessed than the limit.
+func WithMaxStateNodes(limit int) PreflightOption {
+	return func(pf *preflight) {
+		pf.maxStateNodes = limit
+	}
+}
+
+// WithAlwaysIncludeProofs makes preflight fetch the pre-state proofs of the given accounts and storage slots,
+// whether the block execution accesses them or not, so the preparer can include them in the witness with WithAlwaysInclude.
+func WithAlwaysIncludeProofs(accounts gethtypes.AccessList) PreflightOption {
+	return func(pf *preflight) {
+		pf.alwaysInclude = accounts
+	}
+}
+
+// StateLimitExceededError is returned when preflight collects more state than the limit set with WithMaxStateNodes
+type StateLimitExceededError struct {
+	Number *big.Int // Number of the block
+	Limit  int      // Maximum number of state nodes
+	Count  int      // Number of state nodes or accessed accounts and slots when the limit was exceeded
+}
+
+func (e *StateLimitExceededError) Error() string {
+	return fmt.Sprintf("state limit exceeded for block %v: collected %d state nodes, limit is %d", e.Number, e.Count, e.Limit)
 }
 
 // NewPreflight creates a new RPC Preflight instance using the provided RPC client.
-func NewPreflight(remote ethrpc.Client) Preflight {
-	return &preflight{
-		remote: remote,
+func NewPreflight(remote ethrpc.Client, opts ...PreflightOption) Preflight {
+	pf := &preflight{
+		remote:         remote,
+		perCallTimeout: defaultPerCallTimeout,
+	}
+	for _, opt := range opts {
+		opt(pf)
 	}
+
+	if pf.perCallTimeout > 0 {
+		pf.remote = withCallTimeout(pf.remote, pf.perCallTimeout)
+		if pf.archive != nil {
+			pf.archive = withCallTimeout(pf.archive, pf.perCallTimeout)
+		}
+	}
+
+	if pf.retryPolicy != nil {
+		pf.remote = withRetry(pf.remote, *pf.retryPolicy)
+		if pf.archive != nil {
+			pf.archive = withRetry(pf.archive, *pf.retryPolicy)
+		}
+	}
+
+	if pf.archive != nil {
+		pf.remote = withArchiveFallback(pf.remote, pf.archive)
+	}
+
+	if pf.checkpointPath != "" {
+		pf.checkpoint = withCheckpoint(pf.remote, pf.checkpointPath, pf.checkpointInterval)
+		pf.remote = pf.checkpoint
+	}
+
+	if pf.cassettePath != "" {
+		pf.recorder = withRecording(pf.remote)
+		pf.remote = pf.recorder
+	}
+
+	return pf
 }
 
 // Preflight executes a preflight block execution, that collect and returns the intermediary preflight data input.
 func (pf *preflight) Preflight(ctx context.Context, blockNumber *big.Int) (*PreflightData, error) {
 	ctx = tag.WithComponent(ctx, "preflight")
+	if pf.recorder != nil {
+		defer func() {
+			if err := pf.recorder.save(pf.cassettePath); err != nil {
+				log.LoggerFromContext(ctx).Error("Failed to save cassette", zap.Error(err))
+			}
+		}()
+	}
+
 	chainCfg, block, err := pf.init(ctx, blockNumber)
 	if err != nil {
 		log.LoggerFromContext(ctx).Error("Failed to initialize preflight", zap.Error(err))
-		return nil, fmt.Errorf("failed to initialize preflight: %v", err)
+		return nil, fmt.Errorf("failed to initialize preflight: %w", err)
 	}
 
 	// Addd preflight tags
@@ -75,11 +220,35 @@ func (pf *preflight) Preflight(ctx context.Context, blockNumber *big.Int) (*Pref
 		tag.Key("block.hash").String(block.Hash().Hex()),
 	)
 
+	if pf.checkpoint != nil {
+		pf.checkpoint.open(ctx, block.Hash())
+	}
+
 	// Execute preflight
 	data, err := pf.preflight(ctx, chainCfg, block)
 	if err != nil {
 		log.LoggerFromContext(ctx).Error("Preflight failed", zap.Error(err))
-		return nil, fmt.Errorf("preflight failed: %v", err)
+		if pf.checkpoint != nil {
+			if saveErr := pf.checkpoint.save(); saveErr != nil {
+				log.LoggerFromContext(ctx).Error("Failed to save checkpoint", zap.Error(saveErr))
+			}
+		}
+		return nil, fmt.Errorf("preflight failed: %w", err)
+	}
+
+	// A block resolved from a tag is pinned, a reorg while collecting its data is detected
+	if tag := blockTag(blockNumber); tag != "" {
+		if err := pf.checkPinned(ctx, block); err != nil {
+			log.LoggerFromContext(ctx).Error("Preflight failed", zap.Error(err))
+			return nil, fmt.Errorf("preflight failed: %w", err)
+		}
+		data.BlockTag = tag
+	}
+
+	if pf.checkpoint != nil {
+		if err := pf.checkpoint.remove(); err != nil {
+			log.LoggerFromContext(ctx).Warn("Failed to remove checkpoint", zap.Error(err))
+		}
 	}
 	log.LoggerFromContext(ctx).Info("Preflight successful")
 	return data, nil
@@ -92,15 +261,23 @@ func (pf *preflight) init(ctx context.Context, blockNumber *big.Int) (*params.Ch
 		return nil, nil, fmt.Errorf("failed to fetch chain ID: %v", err)
 	}
 
-	chainCfg, err := getChainConfig(chainID)
+	chainCfg, err := ChainConfig(chainID)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if blockNumber != nil && blockNumber.Cmp(big.NewInt(int64(gethrpc.PendingBlockNumber))) == 0 {
+		return nil, nil, ErrPendingBlock
+	}
+
+	// The block is resolved once, from its number or tag
 	block, err := pf.remote.BlockByNumber(ctx, blockNumber)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to fetch block: %v", err)
 	}
+	if tag := blockTag(blockNumber); tag != "" {
+		log.LoggerFromContext(ctx).Info("Resolved block tag", zap.String("tag", tag), zap.Uint64("block.number", block.NumberU64()), zap.String("block.hash", block.Hash().Hex()))
+	}
 
 	return chainCfg, block, nil
 }
@@ -117,6 +294,14 @@ type preflightContext struct {
 func (pf *preflight) preflight(ctx context.Context, chainCfg *params.ChainConfig, block *gethtypes.Block) (*PreflightData, error) {
 	log.LoggerFromContext(ctx).Info("Process preflight...")
 
+	if err := ValidateGasLimit(block.Header()); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateTransactionsChainID(chainCfg, block); err != nil {
+		return nil, err
+	}
+
 	genCtx, err := pf.prepareContext(ctx, chainCfg)
 	if err != nil {
 		return nil, err
@@ -131,7 +316,8 @@ func (pf *preflight) preflight(ctx context.Context, chainCfg *params.ChainConfig
 		return nil, err
 	}
 
-	if err := pf.execute(genCtx, execParams); err != nil {
+	receipts, err := pf.execute(genCtx, execParams)
+	if err != nil {
 		return nil, err
 	}
 
@@ -145,12 +331,15 @@ func (pf *preflight) preflight(ctx context.Context, chainCfg *params.ChainConfig
 		Block:           new(ethrpc.Block).FromBlock(block, chainCfg),
 		PreStateProofs:  preStateProofs,
 		PostStateProofs: deletionsPostStateProofs,
+		Uncles:          block.Uncles(),
+		Receipts:        receipts,
 	}
 
 	witness := execParams.State.Witness()
 	for code := range witness.Codes {
 		data.Codes = append(data.Codes, []byte(code))
 	}
+	sortByHash(data.Codes)
 
 	data.Ancestors = witness.Headers
 
@@ -164,9 +353,14 @@ func (pf *preflight) prepareContext(ctx context.Context, chainCfg *params.ChainC
 	db := rpcdb.Hack(rawdb.NewMemoryDatabase(), pf.remote)
 	trieDB := triedb.NewDatabase(db, &triedb.Config{HashDB: &hashdb.Config{}})
 	rpcDB := state.NewRPCDatabase(gethstate.NewDatabase(trieDB, nil), pf.remote)
-	stateDB := state.NewAccessTrackerDatabase(rpcDB, trackers)
+	stateDB := state.NewAccessTrackerDatabase(rpcDB, trackers).WithSlowLoadLogging(log.LoggerFromContext(ctx), pf.slowLoadThreshold)
+
+	genesis, err := chainGenesis(chainCfg)
+	if err != nil {
+		return nil, err
+	}
 
-	hc, err := ethereum.NewChain(chainCfg, stateDB)
+	hc, err := ethereum.NewChainFromGenesis(chainCfg, genesis, stateDB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chain: %v", err)
 	}
@@ -214,14 +408,25 @@ func (pf *preflight) prepareProcessBlockExecParams(ctx *preflightContext, block
 }
 
 // execute runs the actual block EVM execution
-func (pf *preflight) execute(ctx *preflightContext, execParams *evm.ExecParams) error {
+func (pf *preflight) execute(ctx *preflightContext, execParams *evm.ExecParams) (gethtypes.Receipts, error) {
 	log.LoggerFromContext(ctx.ctx).Info("Execute EVM... (this may take a while)")
-	_, err := evm.ExecutorWithTags("evm")(evm.ExecutorWithLog()(evm.NewExecutor())).Execute(ctx.ctx, execParams)
+	res, err := evm.ExecutorWithTags("evm")(evm.ExecutorWithLog()(evm.ExecutorWithRecover()(evm.ExecutorWithContext(0)(evm.NewExecutor())))).Execute(ctx.ctx, execParams)
 	if err != nil {
-		return fmt.Errorf("failed to execute block: %v", err)
+		return nil, fmt.Errorf("failed to execute block: %v", err)
 	}
 
-	return nil
+	// The block is not validated so we check at least the gas used is consistent with the header
+	if err := ValidateGasUsed(execParams.Block.Header(), res.Receipts); err != nil {
+		return nil, err
+	}
+
+	// Receipts without logs must encode an empty list of logs to be decodable
+	for _, receipt := range res.Receipts {
+		if receipt.Logs == nil {
+			receipt.Logs = []*gethtypes.Log{}
+		}
+	}
+	return res.Receipts, nil
 }
 
 // fetchStateProofs for all accounts and storage slots that were accessed during the block execution
@@ -231,40 +436,173 @@ func (pf *preflight) fetchStateProofs(ctx *preflightContext, execParams *evm.Exe
 
 	finalState := execParams.State
 	tracker := ctx.trackers.GetAccessTracker(ctx.parentHeader.Root)
+
+	// Always included accounts and slots are proven in addition to the accessed ones
+	included := make(map[gethcommon.Address]map[gethcommon.Hash]struct{}, len(tracker.Accounts)+len(pf.alwaysInclude))
 	for account := range tracker.Accounts {
-		var (
-			slots       = []string{}
-			deletedSlot = []string{}
-		)
-		if storage, ok := tracker.Storage[account]; ok {
-			for slot, preStateValue := range storage {
-				slots = append(slots, slot.Hex())
-				if (preStateValue != gethcommon.Hash{}) && (finalState.GetState(account, slot) == gethcommon.Hash{}) {
-					deletedSlot = append(deletedSlot, slot.Hex())
-				}
+		included[account] = make(map[gethcommon.Hash]struct{})
+	}
+	for account, storage := range tracker.Storage {
+		if _, ok := included[account]; !ok {
+			included[account] = make(map[gethcommon.Hash]struct{})
+		}
+		for slot := range storage {
+			included[account][slot] = struct{}{}
+		}
+	}
+	for _, tuple := range pf.alwaysInclude {
+		if _, ok := included[tuple.Address]; !ok {
+			included[tuple.Address] = make(map[gethcommon.Hash]struct{})
+		}
+		for _, slot := range tuple.StorageKeys {
+			included[tuple.Address][slot] = struct{}{}
+		}
+	}
+
+	// Accounts and slots are sorted so the proofs and the RPC calls are deterministic
+	accounts := make([]gethcommon.Address, 0, len(included))
+	for account := range included {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Cmp(accounts[j]) < 0 })
+
+	if pf.maxStateNodes > 0 {
+		accessed := len(included)
+		for _, slots := range included {
+			accessed += len(slots)
+		}
+		if accessed > pf.maxStateNodes {
+			return nil, nil, &StateLimitExceededError{Number: execParams.Block.Number(), Limit: pf.maxStateNodes, Count: accessed}
+		}
+	}
+
+	// Slots to prove are collected before fetching the proofs, as the final state is not safe for concurrent use
+	requests := make([]*proofRequest, 0, len(accounts))
+	for _, account := range accounts {
+		req := &proofRequest{account: account, slots: []string{}, deletedSlots: []string{}}
+		for slot := range included[account] {
+			req.slots = append(req.slots, slot.Hex())
+			if preStateValue, ok := tracker.Storage[account][slot]; ok && (preStateValue != gethcommon.Hash{}) && (finalState.GetState(account, slot) == gethcommon.Hash{}) {
+				req.deletedSlots = append(req.deletedSlots, slot.Hex())
 			}
 		}
+		sort.Strings(req.slots)
+		sort.Strings(req.deletedSlots)
+
+		// Accounts are deleted when self-destructed, or when touched while empty once EIP-158 applies,
+		// which is only known once their pre-state proof tells whether they existed at the parent state
+		req.deletedAccount = finalState.HasSelfDestructed(account) || !finalState.Exist(account)
+		req.postState = len(req.deletedSlots) > 0
+		requests = append(requests, req)
+	}
 
-		// Get proofs for every accounts on the initial state (parent state)
-		acc, err := pf.remote.GetProof(ctx.ctx, account, slots, ctx.parentHeader.Number)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get proof for account %v: %v", account, err)
+	var (
+		nodes      atomic.Int64
+		countNodes = func(acc *gethclient.AccountResult) error {
+			count := len(acc.AccountProof)
+			for _, slot := range acc.StorageProof {
+				count += len(slot.Proof)
+			}
+			total := nodes.Add(int64(count))
+			if pf.maxStateNodes > 0 && total > int64(pf.maxStateNodes) {
+				return &StateLimitExceededError{Number: execParams.Block.Number(), Limit: pf.maxStateNodes, Count: int(total)}
+			}
+			return nil
 		}
-		preStateProofs = append(preStateProofs, trie.AccountProofFromRPC(acc))
+	)
 
-		// Also get necessary proofs at final state
-		if len(deletedSlot) == 0 && !finalState.HasSelfDestructed(account) {
-			// Account was not deleted so we don't need to fetch post-state proofs for it
-			continue
+	// Proofs are fetched concurrently, each request storing its proofs at its own index so the output order does not depend on completion order
+	if pf.batcher != nil {
+		if err := pf.fetchProofBatches(ctx.ctx, pf.proofBatches(requests, ctx.parentHeader.Number, execParams.Block.Number()), countNodes); err != nil {
+			return nil, nil, err
+		}
+		// Deleted accounts are batched once their pre-state proofs tell whether they existed at the parent state
+		if err := pf.fetchProofBatches(ctx.ctx, pf.deletedAccountBatches(requests, ctx.parentHeader.Root, execParams.Block.Number()), countNodes); err != nil {
+			return nil, nil, err
 		}
+	} else {
+		group, groupCtx := errgroup.WithContext(ctx.ctx)
+		group.SetLimit(pf.proofConcurrency())
+		for _, req := range requests {
+			group.Go(func() error {
+				return pf.fetchAccountProofs(groupCtx, req, ctx.parentHeader, execParams.Block.Number(), countNodes)
+			})
+		}
+		if err := group.Wait(); err != nil {
+			return nil, nil, err
+		}
+	}
 
-		// Also get proofs at final state for deleted accounts & slots
-		acc, err = pf.remote.GetProof(ctx.ctx, account, deletedSlot, execParams.Block.Number())
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get proof for account %v: %v", account, err)
+	for _, req := range requests {
+		preStateProofs = append(preStateProofs, req.preStateProof)
+		if req.postStateProof != nil {
+			postStateProofs = append(postStateProofs, req.postStateProof)
 		}
-		postStateProofs = append(postStateProofs, trie.AccountProofFromRPC(acc))
 	}
 
 	return preStateProofs, postStateProofs, nil
 }
+
+// proofRequest holds the proofs to fetch for an account, and the fetched proofs
+type proofRequest struct {
+	account      gethcommon.Address
+	slots        []string // Slots to prove at the parent state
+	deletedSlots []string // Slots to prove at the block state
+	postState    bool     // Whether to prove the account at the block state, as it or some of its slots are deleted
+
+	deletedAccount bool // Whether the account is deleted by the block, provided it existed at the parent state
+
+	preStateProof  *trie.AccountProof
+	postStateProof *trie.AccountProof
+}
+
+// provesAccountDeletion indicates whether the account of the request must be proven at the block state only because the block deletes it.
+// An account missing from the parent state is not deleted, its pre-state proof proves its absence. A proof that fails to verify
+// is considered to prove the account exists.
+func (req *proofRequest) provesAccountDeletion(parentRoot gethcommon.Hash) bool {
+	if !req.deletedAccount || req.postState {
+		return false
+	}
+	exists, err := req.preStateProof.Exists(parentRoot)
+	return err != nil || exists
+}
+
+// fetchAccountProofs fetches the proofs of the request at the parent state and, if necessary, at the block state
+func (pf *preflight) fetchAccountProofs(ctx context.Context, req *proofRequest, parent *gethtypes.Header, blockNumber *big.Int, countNodes func(*gethclient.AccountResult) error) error {
+	// Get proofs for every accounts on the initial state (parent state)
+	acc, err := pf.remote.GetProof(ctx, req.account, req.slots, parent.Number)
+	if err != nil {
+		return fmt.Errorf("failed to get proof for account %v: %v", req.account, err)
+	}
+	if err := countNodes(acc); err != nil {
+		return err
+	}
+	req.preStateProof = trie.AccountProofFromRPC(acc)
+
+	if req.provesAccountDeletion(parent.Root) {
+		req.postState = true
+	}
+	if !req.postState {
+		// Account was not deleted so we don't need to fetch post-state proofs for it
+		return nil
+	}
+
+	// Also get proofs at final state for deleted accounts & slots
+	acc, err = pf.remote.GetProof(ctx, req.account, req.deletedSlots, blockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get proof for account %v: %v", req.account, err)
+	}
+	if err := countNodes(acc); err != nil {
+		return err
+	}
+	req.postStateProof = trie.AccountProofFromRPC(acc)
+	return nil
+}
+
+// proofConcurrency returns the maximum number of accounts whose proofs are fetched concurrently
+func (pf *preflight) proofConcurrency() int {
+	if pf.maxProofConcurrency > 0 {
+		return pf.maxProofConcurrency
+	}
+	return defaultProofConcurrency
+}
@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/kkrt-labs/go-utils/log"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// BatchCaller sends JSON-RPC batch calls, as implemented by the go-ethereum rpc.Client
+type BatchCaller interface {
+	BatchCallContext(ctx context.Context, b []gethrpc.BatchElem) error
+}
+
+// WithProofBatching makes preflight fetch the state proofs with JSON-RPC batch calls sent with caller, of at most size eth_getProof requests,
+// amortizing the round trip of each call. Batches are sent concurrently as configured by WithProofConcurrency.
+// The entries of a batch that fail or return a null or empty proof, or all of them if the batch call fails, are fetched again one by one
+// with the retries, timeouts and archive fallback of the preflight RPC client.
+func WithProofBatching(caller BatchCaller, size int) PreflightOption {
+	return func(pf *preflight) {
+		pf.batcher = caller
+		pf.batchSize = size
+	}
+}
+
+// proofResult is the JSON-RPC result of eth_getProof
+type proofResult struct {
+	Address      gethcommon.Address `json:"address"`
+	AccountProof []string           `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     gethcommon.Hash    `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  gethcommon.Hash    `json:"storageHash"`
+	StorageProof []struct {
+		Key   string       `json:"key"`
+		Value *hexutil.Big `json:"value"`
+		Proof []string     `json:"proof"`
+	} `json:"storageProof"`
+}
+
+func (res *proofResult) accountResult() *gethclient.AccountResult {
+	acc := &gethclient.AccountResult{
+		Address:      res.Address,
+		AccountProof: res.AccountProof,
+		Balance:      res.Balance.ToInt(),
+		CodeHash:     res.CodeHash,
+		Nonce:        uint64(res.Nonce),
+		StorageHash:  res.StorageHash,
+		StorageProof: make([]gethclient.StorageResult, 0, len(res.StorageProof)),
+	}
+	for _, st := range res.StorageProof {
+		acc.StorageProof = append(acc.StorageProof, gethclient.StorageResult{Key: st.Key, Value: st.Value.ToInt(), Proof: st.Proof})
+	}
+	return acc
+}
+
+// batchProof is an eth_getProof entry of a batch call
+type batchProof struct {
+	req    *proofRequest
+	post   bool // Whether the entry proves the account at the block state
+	keys   []string
+	number *big.Int
+	result *proofResult // Nil if the batch call returned a null result
+}
+
+// proofBatches splits the proofs of the requests, at the parent state and if necessary at the block state, into batches of at most pf.batchSize entries
+func (pf *preflight) proofBatches(requests []*proofRequest, parentNumber, blockNumber *big.Int) [][]*batchProof {
+	entries := make([]*batchProof, 0, len(requests))
+	for _, req := range requests {
+		entries = append(entries, &batchProof{req: req, keys: req.slots, number: parentNumber})
+		if req.postState {
+			entries = append(entries, &batchProof{req: req, post: true, keys: req.deletedSlots, number: blockNumber})
+		}
+	}
+	return pf.splitBatches(entries)
+}
+
+// deletedAccountBatches splits the proofs at the block state of the accounts deleted by the block, that existed at the parent state,
+// into batches of at most pf.batchSize entries. The pre-state proofs of the requests must have been fetched.
+func (pf *preflight) deletedAccountBatches(requests []*proofRequest, parentRoot gethcommon.Hash, blockNumber *big.Int) [][]*batchProof {
+	var entries []*batchProof
+	for _, req := range requests {
+		if req.provesAccountDeletion(parentRoot) {
+			req.postState = true
+			entries = append(entries, &batchProof{req: req, post: true, keys: req.deletedSlots, number: blockNumber})
+		}
+	}
+	return pf.splitBatches(entries)
+}
+
+func (pf *preflight) splitBatches(entries []*batchProof) [][]*batchProof {
+	size := max(pf.batchSize, 1)
+	batches := make([][]*batchProof, 0, (len(entries)+size-1)/size)
+	for start := 0; start < len(entries); start += size {
+		batches = append(batches, entries[start:min(start+size, len(entries))])
+	}
+	return batches
+}
+
+// fetchProofBatches fetches the batches concurrently, as configured by WithProofConcurrency
+func (pf *preflight) fetchProofBatches(ctx context.Context, batches [][]*batchProof, countNodes func(*gethclient.AccountResult) error) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(pf.proofConcurrency())
+	for _, batch := range batches {
+		group.Go(func() error {
+			return pf.fetchProofBatch(groupCtx, batch, countNodes)
+		})
+	}
+	return group.Wait()
+}
+
+// fetchProofBatch fetches the proofs of a batch in a single batch call, the checkpointed ones being served from the checkpoint
+func (pf *preflight) fetchProofBatch(ctx context.Context, entries []*batchProof, countNodes func(*gethclient.AccountResult) error) error {
+	pending := make([]*batchProof, 0, len(entries))
+	for _, entry := range entries {
+		acc := new(gethclient.AccountResult)
+		if pf.checkpoint == nil || !pf.checkpoint.lookup("eth_getProof", entry.params(), acc) {
+			pending = append(pending, entry)
+			continue
+		}
+		// Checkpointed proofs are recorded as the recording client does when wrapping the checkpoint client
+		if pf.recorder != nil {
+			pf.recorder.record("eth_getProof", entry.params(), acc, nil)
+		}
+		if err := entry.set(acc, countNodes); err != nil {
+			return err
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	elements := make([]gethrpc.BatchElem, len(pending))
+	for i, entry := range pending {
+		elements[i] = gethrpc.BatchElem{
+			Method: "eth_getProof",
+			Args:   []interface{}{entry.req.account, entry.keys, hexutil.EncodeBig(entry.number)},
+			Result: &entry.result,
+		}
+	}
+
+	batchErr := pf.batchCall(ctx, elements)
+	if batchErr != nil {
+		log.LoggerFromContext(ctx).Warn("Batch call failed, fetching its proofs one by one...", zap.Int("size", len(elements)), zap.Error(batchErr))
+	}
+
+	for i, entry := range pending {
+		var acc *gethclient.AccountResult
+		if batchErr == nil && elements[i].Error == nil && entry.result != nil && len(entry.result.AccountProof) > 0 {
+			acc = entry.result.accountResult()
+			// Proofs fetched one by one are recorded and checkpointed by the wrapping clients
+			if pf.recorder != nil {
+				pf.recorder.record("eth_getProof", entry.params(), acc, nil)
+			}
+			if pf.checkpoint != nil {
+				pf.checkpoint.storeLogged(ctx, "eth_getProof", entry.params(), acc)
+			}
+		} else {
+			if batchErr == nil {
+				// Nodes having pruned the state may return a null or empty proof instead of failing, it is fetched again so it can be served by the archive
+				log.LoggerFromContext(ctx).Debug("Batched proof failed or empty, fetching it again...", zap.String("account", entry.req.account.Hex()), zap.Error(elements[i].Error))
+			}
+			var err error
+			if acc, err = pf.remote.GetProof(ctx, entry.req.account, entry.keys, entry.number); err != nil {
+				return fmt.Errorf("failed to get proof for account %v: %v", entry.req.account, err)
+			}
+		}
+
+		if err := entry.set(acc, countNodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// params returns the params of the eth_getProof call of the entry, as recorded by the wrapping clients
+func (entry *batchProof) params() []interface{} {
+	return []interface{}{entry.req.account, entry.keys, entry.number}
+}
+
+// set sets the fetched proof of the entry on its request
+func (entry *batchProof) set(acc *gethclient.AccountResult, countNodes func(*gethclient.AccountResult) error) error {
+	if err := countNodes(acc); err != nil {
+		return err
+	}
+	if entry.post {
+		entry.req.postStateProof = trie.AccountProofFromRPC(acc)
+	} else {
+		entry.req.preStateProof = trie.AccountProofFromRPC(acc)
+	}
+	return nil
+}
+
+// batchCall sends the batch call, with the per-call timeout
+func (pf *preflight) batchCall(ctx context.Context, elements []gethrpc.BatchElem) error {
+	if pf.perCallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pf.perCallTimeout)
+		defer cancel()
+	}
+	return pf.batcher.BatchCallContext(ctx, elements)
+}
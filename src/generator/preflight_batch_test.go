@@ -0,0 +1,167 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proofBatchServer is a JSON-RPC server serving batched eth_getProof calls from a chain, recording the size of each batch
+type proofBatchServer struct {
+	chain interface {
+		GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error)
+	}
+	fail gethcommon.Address // Account whose proofs fail to be served
+	null gethcommon.Address // Account whose proofs are served as null results
+
+	mu      sync.Mutex
+	batches []int
+}
+
+type batchRequestMsg struct {
+	ID     json.RawMessage `json:"id"`
+	Params []json.RawMessage
+}
+
+func (s *proofBatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reqs []*batchRequestMsg
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		http.Error(w, "only batch calls are served", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.batches = append(s.batches, len(reqs))
+	s.mu.Unlock()
+
+	resps := make([]map[string]interface{}, 0, len(reqs))
+	for _, req := range reqs {
+		var (
+			account gethcommon.Address
+			keys    []string
+			number  hexutil.Big
+		)
+		_ = json.Unmarshal(req.Params[0], &account)
+		_ = json.Unmarshal(req.Params[1], &keys)
+		_ = json.Unmarshal(req.Params[2], &number)
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		acc, err := s.chain.GetProof(r.Context(), account, keys, number.ToInt())
+		switch {
+		case err != nil || account == s.fail:
+			resp["error"] = map[string]interface{}{"code": -32000, "message": "proof unavailable"}
+		case account == s.null:
+			resp["result"] = nil
+		default:
+			res := &proofResult{
+				Address:      acc.Address,
+				AccountProof: acc.AccountProof,
+				Balance:      (*hexutil.Big)(acc.Balance),
+				CodeHash:     acc.CodeHash,
+				Nonce:        hexutil.Uint64(acc.Nonce),
+				StorageHash:  acc.StorageHash,
+			}
+			for _, st := range acc.StorageProof {
+				res.StorageProof = append(res.StorageProof, struct {
+					Key   string       `json:"key"`
+					Value *hexutil.Big `json:"value"`
+					Proof []string     `json:"proof"`
+				}{st.Key, (*hexutil.Big)(st.Value), st.Proof})
+			}
+			resp["result"] = res
+		}
+		resps = append(resps, resp)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resps)
+}
+
+func TestPreflightProofBatching(t *testing.T) {
+	chain := newTransferChain(t)
+	expected, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	require.Greater(t, len(expected.PreStateProofs), 2)
+
+	// Proofs of the recipient fail in batches so they are fetched one by one
+	srv := &proofBatchServer{chain: chain, fail: gethcommon.HexToAddress("0xdead")}
+	httpSrv := httptest.NewServer(srv)
+	defer httpSrv.Close()
+	client, err := gethrpc.DialHTTP(httpSrv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := NewPreflight(chain, WithProofBatching(client, 2)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	proofs := len(expected.PreStateProofs) + len(expected.PostStateProofs)
+	require.Len(t, srv.batches, (proofs+1)/2)
+	for _, size := range srv.batches {
+		assert.LessOrEqual(t, size, 2)
+	}
+	assert.Equal(t, expected.PreStateProofs, data.PreStateProofs)
+	assert.Equal(t, expected.PostStateProofs, data.PostStateProofs)
+}
+
+func TestPreflightProofBatchingCallFailure(t *testing.T) {
+	chain := newTransferChain(t)
+	expected, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+
+	// The whole batch call fails, so every proof is fetched one by one
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer httpSrv.Close()
+	client, err := gethrpc.DialHTTP(httpSrv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := NewPreflight(chain, WithProofBatching(client, 16)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, expected.PreStateProofs, data.PreStateProofs)
+}
+
+func TestPreflightProofBatchingEmptyResults(t *testing.T) {
+	chain := newTransferChain(t)
+	expected := chain.preflightData(t, 1)
+
+	dial := func(srv *proofBatchServer) *gethrpc.Client {
+		httpSrv := httptest.NewServer(srv)
+		t.Cleanup(httpSrv.Close)
+		client, err := gethrpc.DialHTTP(httpSrv.URL)
+		require.NoError(t, err)
+		t.Cleanup(client.Close)
+		return client
+	}
+
+	t.Run("null results", func(t *testing.T) {
+		// Null results of the recipient are fetched again one by one
+		client := dial(&proofBatchServer{chain: chain, null: gethcommon.HexToAddress("0xdead")})
+		data, err := NewPreflight(chain, WithProofBatching(client, 2)).Preflight(context.Background(), big.NewInt(1))
+		require.NoError(t, err)
+		assert.Equal(t, expected.PreStateProofs, data.PreStateProofs)
+		assert.Equal(t, expected.PostStateProofs, data.PostStateProofs)
+	})
+
+	t.Run("archive fallback", func(t *testing.T) {
+		// The batches are served by a node having pruned the pre-state, returning empty proofs of the sender
+		primary := &prunedChain{testChain: chain, emptyProofs: testAddr}
+		client := dial(&proofBatchServer{chain: primary})
+
+		archive := &archiveChain{testChain: chain}
+		data, err := NewPreflight(primary, WithArchiveFallback(archive), WithProofBatching(client, 2)).Preflight(context.Background(), big.NewInt(1))
+		require.NoError(t, err)
+		assert.Equal(t, expected.PreStateProofs, data.PreStateProofs)
+		assert.Equal(t, expected.PostStateProofs, data.PostStateProofs)
+		assert.Positive(t, archive.stateCalls.Load())
+	})
+}
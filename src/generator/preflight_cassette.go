@@ -0,0 +1,276 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	geth "github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+)
+
+// Cassette is a recording of the RPC calls made during preflight, in call order.
+// It enables to replay preflight deterministically without network.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded RPC call
+type Interaction struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// LoadCassette loads a cassette from a JSON file
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := new(Cassette)
+	if err := json.Unmarshal(b, cassette); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette: %v", err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette to a JSON file
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// WithCassetteRecording makes preflight record every RPC call and its response to a cassette file at path.
+// The file is written at the end of each preflight, successful or not, and holds every call made so far by the Preflight.
+func WithCassetteRecording(path string) PreflightOption {
+	return func(pf *preflight) {
+		pf.cassettePath = path
+	}
+}
+
+// NewPreflightFromCassette creates a Preflight replaying the RPC calls recorded in the cassette file at path.
+// It does not access the network, replaying a call that has not been recorded fails.
+func NewPreflightFromCassette(path string, opts ...PreflightOption) (Preflight, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cassette: %v", err)
+	}
+	return NewPreflight(newReplayClient(cassette), opts...), nil
+}
+
+// recordingClient is an ethrpc.Client recording the calls used by preflight
+type recordingClient struct {
+	ethrpc.Client
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+func withRecording(remote ethrpc.Client) *recordingClient {
+	return &recordingClient{
+		Client:   remote,
+		cassette: &Cassette{},
+	}
+}
+
+func (c *recordingClient) record(method string, params []interface{}, result interface{}, callErr error) {
+	interaction := &Interaction{Method: method}
+
+	var err error
+	if interaction.Params, err = json.Marshal(params); err != nil {
+		interaction.Error = fmt.Sprintf("failed to encode params: %v", err)
+	}
+	if callErr != nil {
+		interaction.Error = callErr.Error()
+	} else if interaction.Result, err = json.Marshal(result); err != nil {
+		interaction.Error = fmt.Sprintf("failed to encode result: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cassette.Interactions = append(c.cassette.Interactions, interaction)
+}
+
+func (c *recordingClient) save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cassette.Save(path)
+}
+
+func (c *recordingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	chainID, err := c.Client.ChainID(ctx)
+	c.record("eth_chainId", nil, (*hexutil.Big)(chainID), err)
+	return chainID, err
+}
+
+func (c *recordingClient) BlockByNumber(ctx context.Context, number *big.Int) (*gethtypes.Block, error) {
+	block, err := c.Client.BlockByNumber(ctx, number)
+	var blob hexutil.Bytes
+	if err == nil {
+		// Blocks have no JSON encoding so they are recorded RLP encoded
+		if blob, err = rlp.EncodeToBytes(block); err != nil {
+			return nil, fmt.Errorf("failed to encode block: %v", err)
+		}
+	}
+	c.record("eth_getBlockByNumber", []interface{}{number}, blob, err)
+	return block, err
+}
+
+func (c *recordingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*gethtypes.Header, error) {
+	header, err := c.Client.HeaderByNumber(ctx, number)
+	c.record("eth_getHeaderByNumber", []interface{}{number}, header, err)
+	return header, err
+}
+
+func (c *recordingClient) HeaderByHash(ctx context.Context, hash gethcommon.Hash) (*gethtypes.Header, error) {
+	header, err := c.Client.HeaderByHash(ctx, hash)
+	c.record("eth_getHeaderByHash", []interface{}{hash}, header, err)
+	return header, err
+}
+
+func (c *recordingClient) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	code, err := c.Client.CodeAt(ctx, account, blockNumber)
+	c.record("eth_getCode", []interface{}{account, blockNumber}, hexutil.Bytes(code), err)
+	return code, err
+}
+
+func (c *recordingClient) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	value, err := c.Client.StorageAt(ctx, account, key, blockNumber)
+	c.record("eth_getStorageAt", []interface{}{account, key, blockNumber}, hexutil.Bytes(value), err)
+	return value, err
+}
+
+func (c *recordingClient) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	res, err := c.Client.GetProof(ctx, account, keys, blockNumber)
+	c.record("eth_getProof", []interface{}{account, keys, blockNumber}, res, err)
+	return res, err
+}
+
+// replayClient is an ethrpc.Client replaying the calls recorded in a cassette.
+// Only the calls used by preflight are implemented, calling any other method panics.
+type replayClient struct {
+	ethrpc.Client
+
+	interactions map[string]*Interaction
+}
+
+func newReplayClient(cassette *Cassette) *replayClient {
+	c := &replayClient{
+		interactions: make(map[string]*Interaction, len(cassette.Interactions)),
+	}
+	for _, interaction := range cassette.Interactions {
+		key := replayKey(interaction.Method, interaction.Params)
+		if _, ok := c.interactions[key]; !ok {
+			c.interactions[key] = interaction
+		}
+	}
+	return c
+}
+
+// replayKey identifies a call by its method and compacted params, as saved cassettes are indented
+func replayKey(method string, params json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, params); err != nil {
+		return method + string(params)
+	}
+	return method + buf.String()
+}
+
+// replay decodes the recorded result of the call into result
+func (c *replayClient) replay(method string, params []interface{}, result interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode params: %v", err)
+	}
+
+	interaction, ok := c.interactions[replayKey(method, b)]
+	if !ok {
+		return fmt.Errorf("no recorded interaction for %v with params %s", method, b)
+	}
+
+	if interaction.Error != "" {
+		if interaction.Error == geth.NotFound.Error() {
+			return geth.NotFound
+		}
+		return errors.New(interaction.Error)
+	}
+
+	return json.Unmarshal(interaction.Result, result)
+}
+
+func (c *replayClient) ChainID(_ context.Context) (*big.Int, error) {
+	var chainID hexutil.Big
+	if err := c.replay("eth_chainId", nil, &chainID); err != nil {
+		return nil, err
+	}
+	return chainID.ToInt(), nil
+}
+
+func (c *replayClient) BlockByNumber(_ context.Context, number *big.Int) (*gethtypes.Block, error) {
+	var blob hexutil.Bytes
+	if err := c.replay("eth_getBlockByNumber", []interface{}{number}, &blob); err != nil {
+		return nil, err
+	}
+
+	block := new(gethtypes.Block)
+	if err := rlp.DecodeBytes(blob, block); err != nil {
+		return nil, fmt.Errorf("failed to decode block: %v", err)
+	}
+	return block, nil
+}
+
+func (c *replayClient) HeaderByNumber(_ context.Context, number *big.Int) (*gethtypes.Header, error) {
+	header := new(gethtypes.Header)
+	if err := c.replay("eth_getHeaderByNumber", []interface{}{number}, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func (c *replayClient) HeaderByHash(_ context.Context, hash gethcommon.Hash) (*gethtypes.Header, error) {
+	header := new(gethtypes.Header)
+	if err := c.replay("eth_getHeaderByHash", []interface{}{hash}, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func (c *replayClient) CodeAt(_ context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	var code hexutil.Bytes
+	if err := c.replay("eth_getCode", []interface{}{account, blockNumber}, &code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (c *replayClient) StorageAt(_ context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	var value hexutil.Bytes
+	if err := c.replay("eth_getStorageAt", []interface{}{account, key, blockNumber}, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *replayClient) GetProof(_ context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	res := new(gethclient.AccountResult)
+	if err := c.replay("eth_getProof", []interface{}{account, keys, blockNumber}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
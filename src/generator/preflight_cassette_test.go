@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflightCassette(t *testing.T) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}}}}
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorded, err := NewPreflight(chain, WithCassetteRecording(path)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	methods := make(map[string]bool)
+	for _, interaction := range cassette.Interactions {
+		methods[interaction.Method] = true
+	}
+	for _, method := range []string{"eth_chainId", "eth_getBlockByNumber", "eth_getHeaderByHash", "eth_getCode", "eth_getStorageAt", "eth_getProof"} {
+		assert.True(t, methods[method], "missing %v interaction", method)
+	}
+
+	pf, err := NewPreflightFromCassette(path)
+	require.NoError(t, err)
+	replayed, err := pf.Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(recorded)
+	require.NoError(t, err)
+	actual, err := json.Marshal(replayed)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// Blocks that have not been recorded can not be replayed
+	_, err = pf.Preflight(context.Background(), big.NewInt(2))
+	assert.ErrorContains(t, err, "no recorded interaction")
+}
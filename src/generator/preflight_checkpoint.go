@@ -0,0 +1,222 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	"github.com/kkrt-labs/go-utils/log"
+	"go.uber.org/zap"
+)
+
+// defaultCheckpointInterval is the default number of fetched entries between two checkpoint saves
+const defaultCheckpointInterval = 256
+
+// WithCheckpoint makes preflight checkpoint the accounts, storage slots, codes and proofs it fetches to the file at path,
+// saving it every interval fetched entries, 256 if zero, and when preflight fails.
+// A preflight of the block the checkpoint has been saved for resumes from it, fetching only the missing entries,
+// the checkpoint of another block is discarded. The checkpoint is removed once preflight succeeds.
+// A Preflight with a checkpoint must not run concurrent preflights.
+func WithCheckpoint(path string, interval int) PreflightOption {
+	return func(pf *preflight) {
+		pf.checkpointPath = path
+		pf.checkpointInterval = interval
+	}
+}
+
+// preflightCheckpoint holds the results of the calls made during the preflight of a block
+type preflightCheckpoint struct {
+	BlockHash gethcommon.Hash            `json:"blockHash"`
+	Entries   map[string]json.RawMessage `json:"entries"` // Results keyed by method and params of the call
+}
+
+// checkpointClient is an ethrpc.Client serving the state calls used by preflight from a checkpoint,
+// and adding to the checkpoint the results of the calls it forwards to the remote
+type checkpointClient struct {
+	ethrpc.Client
+
+	path     string
+	interval int
+
+	mu         sync.Mutex
+	checkpoint *preflightCheckpoint
+	unsaved    int
+}
+
+func withCheckpoint(remote ethrpc.Client, path string, interval int) *checkpointClient {
+	if interval <= 0 {
+		interval = defaultCheckpointInterval
+	}
+	return &checkpointClient{
+		Client:   remote,
+		path:     path,
+		interval: interval,
+	}
+}
+
+// open loads the checkpoint of the block, starting a new one if the file misses or holds the checkpoint of another block
+func (c *checkpointClient) open(ctx context.Context, blockHash gethcommon.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkpoint = &preflightCheckpoint{BlockHash: blockHash, Entries: make(map[string]json.RawMessage)}
+	c.unsaved = 0
+
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	loaded := new(preflightCheckpoint)
+	if err == nil {
+		err = json.Unmarshal(b, loaded)
+	}
+	switch {
+	case err != nil:
+		log.LoggerFromContext(ctx).Warn("Failed to load checkpoint, starting fresh", zap.String("path", c.path), zap.Error(err))
+	case loaded.BlockHash != blockHash:
+		log.LoggerFromContext(ctx).Info("Discard checkpoint of another block", zap.String("path", c.path), zap.String("checkpoint.block.hash", loaded.BlockHash.Hex()))
+	case loaded.Entries != nil:
+		log.LoggerFromContext(ctx).Info("Resume preflight from checkpoint", zap.String("path", c.path), zap.Int("entries", len(loaded.Entries)))
+		c.checkpoint.Entries = loaded.Entries
+	}
+}
+
+// lookup decodes the checkpointed result of the call into result, it returns false if the call is not checkpointed
+func (c *checkpointClient) lookup(method string, params []interface{}, result interface{}) bool {
+	key, err := checkpointKey(method, params)
+	if err != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	raw, ok := c.checkpoint.Entries[key]
+	c.mu.Unlock()
+
+	return ok && json.Unmarshal(raw, result) == nil
+}
+
+// store adds the result of the call to the checkpoint, saving it every interval entries
+func (c *checkpointClient) store(method string, params []interface{}, result interface{}) error {
+	key, err := checkpointKey(method, params)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoint.Entries[key] = raw
+	if c.unsaved++; c.unsaved < c.interval {
+		return nil
+	}
+	return c.saveLocked()
+}
+
+func (c *checkpointClient) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+// saveLocked writes the checkpoint to a temporary file renamed to the checkpoint file, so a process dying while saving does not corrupt it
+func (c *checkpointClient) saveLocked() error {
+	b, err := json.Marshal(c.checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.unsaved = 0
+	return nil
+}
+
+func (c *checkpointClient) remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// checkpointKey identifies a call by its method and params
+func checkpointKey(method string, params []interface{}) (string, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode params: %v", err)
+	}
+	return method + string(b), nil
+}
+
+// storeLogged stores the result of a call, a failure only losing the ability to resume from it
+func (c *checkpointClient) storeLogged(ctx context.Context, method string, params []interface{}, result interface{}) {
+	if err := c.store(method, params, result); err != nil {
+		log.LoggerFromContext(ctx).Warn("Failed to checkpoint", zap.String("method", method), zap.Error(err))
+	}
+}
+
+func (c *checkpointClient) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	params := []interface{}{account, blockNumber}
+	var code hexutil.Bytes
+	if c.lookup("eth_getCode", params, &code) {
+		return code, nil
+	}
+
+	code, err := c.Client.CodeAt(ctx, account, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.storeLogged(ctx, "eth_getCode", params, code)
+	return code, nil
+}
+
+func (c *checkpointClient) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	params := []interface{}{account, key, blockNumber}
+	var value hexutil.Bytes
+	if c.lookup("eth_getStorageAt", params, &value) {
+		return value, nil
+	}
+
+	value, err := c.Client.StorageAt(ctx, account, key, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.storeLogged(ctx, "eth_getStorageAt", params, value)
+	return value, nil
+}
+
+func (c *checkpointClient) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	params := []interface{}{account, keys, blockNumber}
+	res := new(gethclient.AccountResult)
+	if c.lookup("eth_getProof", params, res) {
+		return res, nil
+	}
+
+	res, err := c.Client.GetProof(ctx, account, keys, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.storeLogged(ctx, "eth_getProof", params, res)
+	return res, nil
+}
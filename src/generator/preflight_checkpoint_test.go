@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// interruptedChain is a testChain counting the state proofs it serves, and failing them once failAfter have been served
+type interruptedChain struct {
+	*testChain
+
+	failAfter int64
+	proofs    atomic.Int64
+}
+
+func (c *interruptedChain) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	// Accounts loaded by the execution are fetched with nil keys, state proofs with the accessed slots
+	if keys != nil {
+		if n := c.proofs.Add(1); c.failAfter > 0 && n > c.failAfter {
+			return nil, errors.New("killed")
+		}
+	}
+	return c.testChain.GetProof(ctx, account, keys, blockNumber)
+}
+
+func TestPreflightCheckpoint(t *testing.T) {
+	chain := newManyAccountsChain(t, 20)
+
+	full := &interruptedChain{testChain: chain}
+	expected, err := NewPreflight(full, WithProofConcurrency(1)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	total := full.proofs.Load()
+	require.Greater(t, total, int64(10))
+
+	// Collection is killed after 10 proofs, the checkpoint holding them
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	killed := &interruptedChain{testChain: chain, failAfter: 10}
+	_, err = NewPreflight(killed, WithProofConcurrency(1), WithCheckpoint(path, 1)).Preflight(context.Background(), big.NewInt(1))
+	require.ErrorContains(t, err, "killed")
+	require.FileExists(t, path)
+
+	// Resuming only fetches the missing proofs
+	resumed := &interruptedChain{testChain: chain}
+	data, err := NewPreflight(resumed, WithProofConcurrency(1), WithCheckpoint(path, 1)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, total-10, resumed.proofs.Load())
+	assert.NoFileExists(t, path)
+
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+
+	// Resuming a batched collection records a cassette holding the checkpointed proofs, so it can be replayed
+	dial := func(chain *interruptedChain) *gethrpc.Client {
+		srv := httptest.NewServer(&proofBatchServer{chain: chain})
+		t.Cleanup(srv.Close)
+		client, err := gethrpc.DialHTTP(srv.URL)
+		require.NoError(t, err)
+		t.Cleanup(client.Close)
+		return client
+	}
+	killed = &interruptedChain{testChain: chain, failAfter: 10}
+	_, err = NewPreflight(killed, WithProofBatching(dial(killed), 2), WithProofConcurrency(1), WithCheckpoint(path, 1)).Preflight(context.Background(), big.NewInt(1))
+	require.ErrorContains(t, err, "killed")
+	require.FileExists(t, path)
+
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	resumed = &interruptedChain{testChain: chain}
+	_, err = NewPreflight(resumed, WithProofBatching(dial(resumed), 2), WithCheckpoint(path, 1), WithCassetteRecording(cassettePath)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Less(t, resumed.proofs.Load(), total)
+
+	pf, err := NewPreflightFromCassette(cassettePath)
+	require.NoError(t, err)
+	replayed, err := pf.Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	replayedJSON, err := json.Marshal(replayed)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(replayedJSON))
+}
+
+func TestPreflightCheckpointOtherBlock(t *testing.T) {
+	chain := newManyAccountsChain(t, 5)
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	// Checkpoint of the block, rewritten for another block
+	killed := &interruptedChain{testChain: chain, failAfter: 3}
+	_, err := NewPreflight(killed, WithCheckpoint(path, 1)).Preflight(context.Background(), big.NewInt(1))
+	require.Error(t, err)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	checkpoint := new(preflightCheckpoint)
+	require.NoError(t, json.Unmarshal(b, checkpoint))
+	require.NotEmpty(t, checkpoint.Entries)
+	checkpoint.BlockHash = gethcommon.Hash{0x01}
+	b, err = json.Marshal(checkpoint)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+
+	full := &interruptedChain{testChain: chain}
+	expected, err := NewPreflight(full).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+
+	fresh := &interruptedChain{testChain: chain}
+	data, err := NewPreflight(fresh, WithCheckpoint(path, 0)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, full.proofs.Load(), fresh.proofs.Load())
+	assert.Equal(t, expected.PreStateProofs, data.PreStateProofs)
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/kkrt-labs/go-utils/tag"
+)
+
+// PreflightCost is the projected number of RPC calls made by the preflight of a block, per method
+type PreflightCost struct {
+	ChainID    int `json:"chainId"`    // eth_chainId
+	GetBlock   int `json:"getBlock"`   // eth_getBlockByNumber
+	GetHeader  int `json:"getHeader"`  // eth_getBlockByHash, for the ancestors
+	GetProof   int `json:"getProof"`   // eth_getProof, to load the accounts during execution then to prove them
+	GetCode    int `json:"getCode"`    // eth_getCode
+	GetStorage int `json:"getStorage"` // eth_getStorageAt
+}
+
+// Total returns the projected number of RPC calls
+func (c *PreflightCost) Total() int {
+	return c.ChainID + c.GetBlock + c.GetHeader + c.GetProof + c.GetCode + c.GetStorage
+}
+
+// EstimatePreflightCost projects the RPC calls made by the preflight of a block from its transactions, without executing it.
+// It only fetches the chain ID and the block.
+//
+// The accounts are the coinbase, the senders, recipients and access lists of the transactions, the withdrawal recipients
+// and the system contracts, which are assumed to be deployed. Calls made by contract execution (nested calls, storage
+// reads outside the access lists, BLOCKHASH), and the post-state proofs of deleted accounts, can not be projected
+// so the estimate is a lower bound for blocks calling contracts.
+func (pf *preflight) EstimatePreflightCost(ctx context.Context, blockNumber *big.Int) (*PreflightCost, error) {
+	ctx = tag.WithComponent(ctx, "preflight")
+
+	chainCfg, block, err := pf.init(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize preflight: %v", err)
+	}
+
+	cost, err := estimatePreflightCost(chainCfg, block)
+	if err != nil {
+		return nil, err
+	}
+	cost.ChainID, cost.GetBlock = 1, 1
+
+	return cost, nil
+}
+
+func estimatePreflightCost(chainCfg *params.ChainConfig, block *gethtypes.Block) (*PreflightCost, error) {
+	var (
+		accounts = map[gethcommon.Address]struct{}{block.Coinbase(): {}}
+		codes    = make(map[gethcommon.Address]struct{})
+		slots    = make(map[gethcommon.Address]map[gethcommon.Hash]struct{})
+	)
+
+	var systemContracts []gethcommon.Address
+	if chainCfg.IsCancun(block.Number(), block.Time()) {
+		accounts[params.SystemAddress] = struct{}{}
+		systemContracts = append(systemContracts, params.BeaconRootsAddress)
+	}
+	if chainCfg.IsPrague(block.Number(), block.Time()) {
+		systemContracts = append(systemContracts, params.HistoryStorageAddress, params.WithdrawalQueueAddress, params.ConsolidationQueueAddress)
+	}
+	for _, addr := range systemContracts {
+		accounts[addr] = struct{}{}
+		codes[addr] = struct{}{}
+	}
+
+	signer := gethtypes.MakeSigner(chainCfg, block.Number(), block.Time())
+	for _, tx := range block.Transactions() {
+		from, err := gethtypes.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover sender of transaction %v: %v", tx.Hash(), err)
+		}
+		accounts[from] = struct{}{}
+
+		switch {
+		case tx.To() == nil:
+			accounts[crypto.CreateAddress(from, tx.Nonce())] = struct{}{}
+		case len(tx.Data()) > 0:
+			// Transactions with call data are assumed to call a contract
+			accounts[*tx.To()] = struct{}{}
+			codes[*tx.To()] = struct{}{}
+		default:
+			accounts[*tx.To()] = struct{}{}
+		}
+
+		for _, tuple := range tx.AccessList() {
+			accounts[tuple.Address] = struct{}{}
+			if _, ok := slots[tuple.Address]; !ok {
+				slots[tuple.Address] = make(map[gethcommon.Hash]struct{})
+			}
+			for _, slot := range tuple.StorageKeys {
+				slots[tuple.Address][slot] = struct{}{}
+			}
+		}
+	}
+
+	for _, withdrawal := range block.Withdrawals() {
+		accounts[withdrawal.Address] = struct{}{}
+	}
+
+	cost := &PreflightCost{
+		// The genesis header is fetched when setting up the chain, then the parent header
+		GetHeader: 2,
+		// Each account is loaded during execution, then proven at the parent state
+		GetProof: 2 * len(accounts),
+		GetCode:  len(codes),
+	}
+	for _, s := range slots {
+		cost.GetStorage += len(s)
+	}
+
+	return cost, nil
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	"github.com/kkrt-labs/go-utils/log"
+	"go.uber.org/zap"
+)
+
+// WithArchiveFallback makes preflight retry the state queries (proofs, storage and code) the remote can not serve against archive,
+// typically an archive node serving the historical state the remote has pruned.
+// Each query falls back independently, the others being still served by the remote. A proof query falls back when it fails
+// or returns an empty proof, other state queries when they fail. The endpoint serving each proof is logged.
+// Per call timeout and retries, if set, apply to both endpoints.
+func WithArchiveFallback(archive ethrpc.Client) PreflightOption {
+	return func(pf *preflight) {
+		pf.archive = archive
+	}
+}
+
+// fallbackClient is an ethrpc.Client serving the state queries used by preflight from archive when the primary client can not.
+// Other calls are served by the primary client only.
+type fallbackClient struct {
+	ethrpc.Client
+
+	archive ethrpc.Client
+}
+
+func withArchiveFallback(primary, archive ethrpc.Client) ethrpc.Client {
+	return &fallbackClient{
+		Client:  primary,
+		archive: archive,
+	}
+}
+
+// fallback calls the archive when the primary call failed, unless the context is done
+func fallback[T any](ctx context.Context, method string, res T, err error, call func() (T, error)) (T, error) {
+	if ctx.Err() != nil {
+		return res, err
+	}
+
+	log.LoggerFromContext(ctx).Warn("Remote can not serve state, falling back to archive", zap.String("method", method), zap.Error(err))
+	archiveRes, archiveErr := call()
+	if archiveErr != nil {
+		return res, errors.Join(err, archiveErr)
+	}
+	return archiveRes, nil
+}
+
+func (c *fallbackClient) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	code, err := c.Client.CodeAt(ctx, account, blockNumber)
+	if err == nil {
+		return code, nil
+	}
+	return fallback(ctx, "eth_getCode", code, err, func() ([]byte, error) {
+		return c.archive.CodeAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *fallbackClient) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	value, err := c.Client.StorageAt(ctx, account, key, blockNumber)
+	if err == nil {
+		return value, nil
+	}
+	return fallback(ctx, "eth_getStorageAt", value, err, func() ([]byte, error) {
+		return c.archive.StorageAt(ctx, account, key, blockNumber)
+	})
+}
+
+func (c *fallbackClient) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	logger := log.LoggerFromContext(ctx).With(zap.String("account", account.Hex()), zap.Any("block.number", blockNumber))
+
+	proof, err := c.Client.GetProof(ctx, account, keys, blockNumber)
+	if err == nil && proof != nil && len(proof.AccountProof) > 0 {
+		logger.Debug("Proof served by remote")
+		return proof, nil
+	}
+	if err == nil {
+		// Nodes having pruned the state may return an empty proof instead of failing
+		err = errors.New("empty account proof")
+	}
+
+	proof, err = fallback(ctx, "eth_getProof", proof, err, func() (*gethclient.AccountResult, error) {
+		return c.archive.GetProof(ctx, account, keys, blockNumber)
+	})
+	if err == nil {
+		logger.Info("Proof served by archive")
+	}
+	return proof, err
+}
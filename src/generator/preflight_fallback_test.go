@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prunedChain is a testChain that pruned the state before its head block.
+// State queries on pruned blocks fail, except proofs of the emptyProofs account that are returned empty.
+type prunedChain struct {
+	*testChain
+
+	emptyProofs gethcommon.Address
+}
+
+func (c *prunedChain) pruned(blockNumber *big.Int) bool {
+	return blockNumber.Uint64() < uint64(len(c.blocks))
+}
+
+func (c *prunedChain) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	if c.pruned(blockNumber) {
+		return nil, httpError(http.StatusNotFound)
+	}
+	return c.testChain.CodeAt(ctx, account, blockNumber)
+}
+
+func (c *prunedChain) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	if c.pruned(blockNumber) {
+		return nil, httpError(http.StatusNotFound)
+	}
+	return c.testChain.StorageAt(ctx, account, key, blockNumber)
+}
+
+func (c *prunedChain) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	if c.pruned(blockNumber) {
+		if account == c.emptyProofs {
+			return &gethclient.AccountResult{Address: account}, nil
+		}
+		return nil, httpError(http.StatusNotFound)
+	}
+	return c.testChain.GetProof(ctx, account, keys, blockNumber)
+}
+
+// archiveChain is a testChain counting the state queries it serves
+type archiveChain struct {
+	*testChain
+
+	stateCalls atomic.Int64
+}
+
+func (c *archiveChain) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	c.stateCalls.Add(1)
+	return c.testChain.CodeAt(ctx, account, blockNumber)
+}
+
+func (c *archiveChain) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	c.stateCalls.Add(1)
+	return c.testChain.StorageAt(ctx, account, key, blockNumber)
+}
+
+func (c *archiveChain) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	c.stateCalls.Add(1)
+	return c.testChain.GetProof(ctx, account, keys, blockNumber)
+}
+
+func TestPreflightArchiveFallback(t *testing.T) {
+	// The block deletes a storage slot, so it is proven at the block state
+	chain := newManyAccountsChain(t, 1)
+	expected := chain.preflightData(t, 1)
+
+	t.Run("pruned state", func(t *testing.T) {
+		primary := &prunedChain{testChain: chain, emptyProofs: testAddr}
+		_, err := NewPreflight(primary).Preflight(context.Background(), big.NewInt(1))
+		require.Error(t, err)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		primary := &prunedChain{testChain: chain, emptyProofs: testAddr}
+		archive := &archiveChain{testChain: chain}
+		data, err := NewPreflight(primary, WithArchiveFallback(archive)).Preflight(context.Background(), big.NewInt(1))
+		require.NoError(t, err)
+		assert.Equal(t, expected, data)
+
+		// Only the pre-state queries are served by the archive, post-state proofs being served by the primary
+		assert.Positive(t, archive.stateCalls.Load())
+		assert.Less(t, archive.stateCalls.Load(), countStateCalls(t, chain))
+	})
+
+	t.Run("archive failing", func(t *testing.T) {
+		primary := &prunedChain{testChain: chain}
+		archive := &prunedChain{testChain: chain}
+		_, err := NewPreflight(primary, WithArchiveFallback(archive)).Preflight(context.Background(), big.NewInt(1))
+		require.Error(t, err)
+	})
+}
+
+// countStateCalls returns the number of state queries of a preflight of the first block of chain
+func countStateCalls(t *testing.T, chain *testChain) int64 {
+	counter := &archiveChain{testChain: chain}
+	_, err := NewPreflight(counter).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	return counter.stateCalls.Load()
+}
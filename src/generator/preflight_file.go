@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// EncodePreflightData writes preflight data to w in JSON format
+func EncodePreflightData(w io.Writer, data *PreflightData) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// DecodePreflightData reads preflight data in JSON format from r.
+// It checks the data holds what prepare needs, so an incomplete capture fails before preparing.
+func DecodePreflightData(r io.Reader) (*PreflightData, error) {
+	data := new(PreflightData)
+	if err := json.NewDecoder(r).Decode(data); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case data.Block == nil:
+		return nil, fmt.Errorf("missing block")
+	case data.ChainConfig == nil || data.ChainConfig.ChainID == nil:
+		return nil, fmt.Errorf("missing chain configuration")
+	case len(data.Ancestors) == 0:
+		return nil, fmt.Errorf("missing parent header")
+	}
+
+	// Empty withdrawals are omitted when encoding, restore them so the block is prepared as it was captured
+	if data.Block.WithdrawalsRoot != nil && data.Block.Withdrawals == nil {
+		data.Block.Withdrawals = gethtypes.Withdrawals{}
+	}
+	return data, nil
+}
+
+// SavePreflightData saves preflight data to a file, so it can be replayed offline with LoadPreflightData
+func SavePreflightData(path string, data *PreflightData) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create preflight data file: %v", err)
+	}
+	if err := EncodePreflightData(f, data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode preflight data: %v", err)
+	}
+	return f.Close()
+}
+
+// LoadPreflightData loads preflight data from a file saved with SavePreflightData or by a preflight data store
+func LoadPreflightData(path string) (*PreflightData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open preflight data file: %v", err)
+	}
+	defer f.Close()
+
+	data, err := DecodePreflightData(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode preflight data file %q: %v", path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflightDataFile(t *testing.T) {
+	data := newManyAccountsChain(t, 5).preflightData(t, 1)
+
+	path := filepath.Join(t.TempDir(), "preflight", "1.json")
+	require.NoError(t, SavePreflightData(path, data))
+
+	loaded, err := LoadPreflightData(path)
+	require.NoError(t, err)
+
+	// Preparing the replayed data gives the same prover input as preparing the live data
+	expected, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	actual, err := NewPreparer().Prepare(context.Background(), loaded)
+	require.NoError(t, err)
+
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+
+	_, err = NewExecutor().Execute(context.Background(), actual)
+	require.NoError(t, err)
+
+	// Incomplete captures are rejected
+	require.NoError(t, os.WriteFile(path, []byte(`{"chainConfig":{"chainId":1}}`), 0o600))
+	_, err = LoadPreflightData(path)
+	assert.ErrorContains(t, err, "missing block")
+
+	_, err = LoadPreflightData(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
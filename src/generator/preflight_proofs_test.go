@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newManyAccountsChain creates a chain whose block transfers to many accounts and deletes a storage slot
+func newManyAccountsChain(t testing.TB, recipients int) *testChain {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}}}}
+
+	return newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		for i := 0; i < recipients; i++ {
+			to := gethcommon.BigToAddress(big.NewInt(int64(0x1000 + i)))
+			b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		}
+	})
+}
+
+func TestPreflightProofConcurrency(t *testing.T) {
+	chain := newManyAccountsChain(t, 50)
+
+	serial, err := NewPreflight(chain, WithProofConcurrency(1)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	require.NotEmpty(t, serial.PostStateProofs)
+
+	for _, concurrency := range []int{0, 4, 64} {
+		concurrent, err := NewPreflight(chain, WithProofConcurrency(concurrency)).Preflight(context.Background(), big.NewInt(1))
+		require.NoError(t, err)
+
+		expected, err := json.Marshal(serial)
+		require.NoError(t, err)
+		actual, err := json.Marshal(concurrent)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual), "concurrency %d", concurrency)
+	}
+}
+
+// failingProofChain is a testChain failing the eth_getProof calls for an account at the parent state
+type failingProofChain struct {
+	*testChain
+
+	account gethcommon.Address
+}
+
+func (c *failingProofChain) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	if account == c.account && keys != nil && blockNumber.Sign() == 0 {
+		return nil, errors.New("proof unavailable")
+	}
+	return c.testChain.GetProof(ctx, account, keys, blockNumber)
+}
+
+func TestPreflightProofConcurrencyFailure(t *testing.T) {
+	account := gethcommon.BigToAddress(big.NewInt(0x1010))
+	chain := &failingProofChain{testChain: newManyAccountsChain(t, 50), account: account}
+
+	_, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(1))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "failed to get proof for account "+account.Hex())
+	assert.ErrorContains(t, err, "proof unavailable")
+}
+
+// latencyChain is a testChain whose eth_getProof calls fetching state proofs take some time, as remote calls do
+type latencyChain struct {
+	*testChain
+
+	latency time.Duration
+}
+
+func (c *latencyChain) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	if keys != nil {
+		time.Sleep(c.latency)
+	}
+	return c.testChain.GetProof(ctx, account, keys, blockNumber)
+}
+
+func BenchmarkPreflightStateProofs(b *testing.B) {
+	chain := &latencyChain{testChain: newManyAccountsChain(b, 100), latency: 5 * time.Millisecond}
+
+	for _, concurrency := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			pf := NewPreflight(chain, WithProofConcurrency(concurrency))
+			for i := 0; i < b.N; i++ {
+				if _, err := pf.Preflight(context.Background(), big.NewInt(1)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
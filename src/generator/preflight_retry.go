@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	"github.com/kkrt-labs/go-utils/jsonrpc"
+	"github.com/kkrt-labs/go-utils/log"
+	"go.uber.org/zap"
+)
+
+// RetryPolicy configures the retries of the failed RPC calls used by preflight.
+// The delay before the n-th retry is BaseDelay * 2^(n-1), capped to MaxDelay, then randomized by up to Jitter of its value.
+type RetryPolicy struct {
+	MaxAttempts int              // Maximum number of attempts of a call, including the first one
+	BaseDelay   time.Duration    // Delay before the first retry
+	MaxDelay    time.Duration    // Optional, maximum delay between two attempts
+	Jitter      float64          // Fraction of the delay that is randomized, between 0 and 1
+	Retryable   func(error) bool // Optional, whether a failed call is retried, IsRetryableError by default
+}
+
+// WithRetry makes preflight retry the failed RPC calls with an exponential backoff, as configured by policy.
+// Retries stop when the preflight context is done. When combined with WithPerCallTimeout, the timeout applies to each attempt.
+func WithRetry(policy RetryPolicy) PreflightOption {
+	return func(pf *preflight) {
+		pf.retryPolicy = &policy
+	}
+}
+
+// IsRetryableError indicates whether a failed RPC call may succeed when retried.
+// Rate limiting, server side HTTP errors, timeouts and network errors are retryable, while other HTTP errors,
+// malformed responses, JSON-RPC errors returned by the node and missing data are not.
+func IsRetryableError(err error) bool {
+	var (
+		netErr    net.Error
+		rpcErr    *jsonrpc.ErrorMsg
+		syntaxErr *json.SyntaxError
+		decodeErr *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, ethereum.NotFound):
+		return false
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return true
+	case errors.As(err, &rpcErr):
+		return rpcErr.Code == -32005 // Limit exceeded
+	case errors.As(err, &syntaxErr), errors.As(err, &decodeErr):
+		return false
+	case strings.Contains(err.Error(), "JSON-RPC response"), strings.Contains(err.Error(), "JSON-RPC result"):
+		return false // Malformed response, the JSON-RPC client does not wrap decoding errors
+	}
+	if statusCode, ok := httpStatusCode(err); ok {
+		return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// httpStatusCodeRegexp matches the HTTP status code of the response reported by the errors of the JSON-RPC HTTP client
+var httpStatusCodeRegexp = regexp.MustCompile(`StatusCode=(\d+)`)
+
+// httpStatusCode returns the HTTP status code of the response the JSON-RPC HTTP client failed on, if any
+func httpStatusCode(err error) (int, bool) {
+	match := httpStatusCodeRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+	statusCode, err := strconv.Atoi(match[1])
+	return statusCode, err == nil
+}
+
+// retryClient is an ethrpc.Client retrying the failed calls used by preflight.
+// Other calls are forwarded to the underlying client without retry.
+type retryClient struct {
+	ethrpc.Client
+
+	policy RetryPolicy
+}
+
+func withRetry(remote ethrpc.Client, policy RetryPolicy) ethrpc.Client {
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryableError
+	}
+	return &retryClient{
+		Client: remote,
+		policy: policy,
+	}
+}
+
+// retry calls f until it succeeds, fails with a non retryable error, the attempts are exhausted or ctx is done
+func retry[T any](ctx context.Context, policy *RetryPolicy, method string, f func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := f()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) || ctx.Err() != nil {
+			return res, err
+		}
+
+		delay := policy.delay(attempt)
+		log.LoggerFromContext(ctx).Warn("RPC call failed, retrying...",
+			zap.String("method", method),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the delay before the retry following the given attempt
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay < p.BaseDelay {
+		delay = math.MaxInt64 // Overflow
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+func (c *retryClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return retry(ctx, &c.policy, "eth_chainId", func() (*big.Int, error) {
+		return c.Client.ChainID(ctx)
+	})
+}
+
+func (c *retryClient) BlockByNumber(ctx context.Context, number *big.Int) (*gethtypes.Block, error) {
+	return retry(ctx, &c.policy, "eth_getBlockByNumber", func() (*gethtypes.Block, error) {
+		return c.Client.BlockByNumber(ctx, number)
+	})
+}
+
+func (c *retryClient) HeaderByNumber(ctx context.Context, number *big.Int) (*gethtypes.Header, error) {
+	return retry(ctx, &c.policy, "eth_getBlockByNumber", func() (*gethtypes.Header, error) {
+		return c.Client.HeaderByNumber(ctx, number)
+	})
+}
+
+func (c *retryClient) HeaderByHash(ctx context.Context, hash gethcommon.Hash) (*gethtypes.Header, error) {
+	return retry(ctx, &c.policy, "eth_getBlockByHash", func() (*gethtypes.Header, error) {
+		return c.Client.HeaderByHash(ctx, hash)
+	})
+}
+
+func (c *retryClient) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	return retry(ctx, &c.policy, "eth_getCode", func() ([]byte, error) {
+		return c.Client.CodeAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *retryClient) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	return retry(ctx, &c.policy, "eth_getStorageAt", func() ([]byte, error) {
+		return c.Client.StorageAt(ctx, account, key, blockNumber)
+	})
+}
+
+func (c *retryClient) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	return retry(ctx, &c.policy, "eth_getProof", func() (*gethclient.AccountResult, error) {
+		return c.Client.GetProof(ctx, account, keys, blockNumber)
+	})
+}
@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/kkrt-labs/go-utils/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyChain is a testChain whose first eth_getBlockByNumber calls fail with an HTTP error
+type flakyChain struct {
+	*testChain
+
+	statusCode int
+	failures   int64
+	calls      atomic.Int64
+}
+
+func (c *flakyChain) BlockByNumber(ctx context.Context, number *big.Int) (*gethtypes.Block, error) {
+	if c.calls.Add(1) <= c.failures {
+		return nil, httpError(c.statusCode)
+	}
+	return c.testChain.BlockByNumber(ctx, number)
+}
+
+// httpError returns an error as reported by the JSON-RPC HTTP client on a response with the given status code
+func httpError(statusCode int) error {
+	return fmt.Errorf("jsonrpchttp.Client#Call: Inspect Response: StatusCode=%d -- Original Error: %s", statusCode, http.StatusText(statusCode))
+}
+
+func TestPreflightRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, Jitter: 0.5}
+
+	t.Run("transient failures", func(t *testing.T) {
+		chain := &flakyChain{testChain: newTransferChain(t), statusCode: http.StatusServiceUnavailable, failures: 2}
+		data, err := NewPreflight(chain, WithRetry(policy)).Preflight(context.Background(), big.NewInt(1))
+		require.NoError(t, err)
+		assert.Equal(t, chain.block(1).Hash(), data.Block.Hash)
+		assert.Equal(t, int64(3), chain.calls.Load())
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		chain := &flakyChain{testChain: newTransferChain(t), statusCode: http.StatusTooManyRequests, failures: 3}
+		_, err := NewPreflight(chain, WithRetry(policy)).Preflight(context.Background(), big.NewInt(1))
+		require.Error(t, err)
+		assert.Equal(t, int64(3), chain.calls.Load())
+	})
+
+	t.Run("non retryable failure", func(t *testing.T) {
+		chain := &flakyChain{testChain: newTransferChain(t), statusCode: http.StatusBadRequest, failures: 1}
+		_, err := NewPreflight(chain, WithRetry(policy)).Preflight(context.Background(), big.NewInt(1))
+		require.Error(t, err)
+		assert.Equal(t, int64(1), chain.calls.Load())
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		chain := &flakyChain{testChain: newTransferChain(t), statusCode: http.StatusBadGateway, failures: 3}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := NewPreflight(chain, WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute})).Preflight(ctx, big.NewInt(1))
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.Equal(t, int64(1), chain.calls.Load())
+	})
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, IsRetryableError(httpError(http.StatusInternalServerError)))
+	assert.True(t, IsRetryableError(httpError(http.StatusTooManyRequests)))
+	assert.False(t, IsRetryableError(httpError(http.StatusBadRequest)))
+	assert.True(t, IsRetryableError(context.DeadlineExceeded))
+	assert.True(t, IsRetryableError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}))
+	assert.False(t, IsRetryableError(context.Canceled))
+	assert.False(t, IsRetryableError(&jsonrpc.ErrorMsg{Code: -32602, Message: "invalid params"}))
+	assert.True(t, IsRetryableError(&jsonrpc.ErrorMsg{Code: -32005, Message: "limit exceeded"}))
+	assert.False(t, IsRetryableError(errors.New("failed to unmarshal JSON-RPC result \"0x\" into *types.Block")))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	assert.Equal(t, 100*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 400*time.Millisecond, policy.delay(3))
+	assert.Equal(t, time.Second, policy.delay(10))
+	assert.Equal(t, time.Second, policy.delay(100))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.delay(2)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.LessOrEqual(t, delay, 200*time.Millisecond)
+	}
+}
@@ -1,11 +1,20 @@
 package generator
 
 import (
+	"context"
 	"encoding/json"
+	"math/big"
+	"net/http/httptest"
 	"os"
 	"testing"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -33,3 +42,153 @@ func TestUnmarshal(t *testing.T) {
 
 // TODO: Add unit-tests for the preflight block execution
 // It is probably possible to create a mock ethrpc.Client that uses some preloaded preflight data
+
+// gasUsedChain is a testChain serving blocks whose header claims a different gas used
+type gasUsedChain struct {
+	*testChain
+
+	delta uint64
+}
+
+func (c *gasUsedChain) BlockByNumber(ctx context.Context, number *big.Int) (*gethtypes.Block, error) {
+	block, err := c.testChain.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	header := block.Header()
+	header.GasUsed += c.delta
+	return block.WithSeal(header), nil
+}
+
+func TestPreflightEmptyAccountDeletion(t *testing.T) {
+	// Empty account of the genesis, touched by a zero value transfer, and absent accounts only read by the block:
+	// one is the recipient of a zero value transfer, the other is the target of a BALANCE of a contract
+	empty := gethcommon.HexToAddress("0xe0")
+	absent := gethcommon.HexToAddress("0xab")
+	read := gethcommon.HexToAddress("0xac")
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := append([]byte{byte(vm.PUSH20)}, read.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP), byte(vm.STOP))
+	alloc := gethtypes.GenesisAlloc{
+		empty:                               {Balance: new(big.Int)},
+		gethcommon.HexToAddress("0xe1"):     {Balance: big.NewInt(1)},
+		gethcommon.HexToAddress("0xc0ffee"): {Balance: big.NewInt(1)},
+		contract:                            {Code: code, Balance: new(big.Int)},
+	}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &empty, new(big.Int), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &absent, new(big.Int), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	preState, _, err := chain.stateAt(big.NewInt(0))
+	require.NoError(t, err)
+	require.True(t, preState.Exist(empty))
+	postState, _, err := chain.stateAt(big.NewInt(1))
+	require.NoError(t, err)
+	require.False(t, postState.Exist(empty), "touched empty account must be deleted under EIP-158")
+
+	// Preflight fetches the post-state proof of the deleted account only, the absent accounts are proven by their pre-state proof
+	data := chain.preflightData(t, 1)
+	var proven, deleted []gethcommon.Address
+	for _, proof := range data.PreStateProofs {
+		proven = append(proven, proof.Address)
+	}
+	for _, proof := range data.PostStateProofs {
+		deleted = append(deleted, proof.Address)
+	}
+	assert.Subset(t, proven, []gethcommon.Address{absent, read})
+	assert.Contains(t, deleted, empty)
+	assert.NotContains(t, deleted, absent)
+	assert.NotContains(t, deleted, read)
+
+	// Batched proofs prove the same deletions
+	srv := httptest.NewServer(&proofBatchServer{chain: chain})
+	defer srv.Close()
+	client, err := gethrpc.DialHTTP(srv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+	batched, err := NewPreflight(chain, WithProofBatching(client, 2)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, data.PreStateProofs, batched.PreStateProofs)
+	assert.Equal(t, data.PostStateProofs, batched.PostStateProofs)
+
+	in, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// The post-state root computed from the witness only reflects the deletion
+	result, err := NewExecutor().Validate(context.Background(), in)
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Divergence)
+	assert.Equal(t, chain.block(1).Root(), result.StateRoot)
+}
+
+func TestPreflightGasUsedMismatch(t *testing.T) {
+	chain := &gasUsedChain{testChain: newTransferChain(t), delta: 1}
+	_, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(1))
+	assert.ErrorContains(t, err, "invalid gas used for block 1: header has 21001, transactions used 21000")
+
+	chain.delta = 30_000_000
+	_, err = NewPreflight(chain).Preflight(context.Background(), big.NewInt(1))
+	assert.ErrorContains(t, err, "exceeds gas limit")
+}
+
+func TestPreflightMaxStateNodes(t *testing.T) {
+	// The contract reads 8 storage slots
+	contract := gethcommon.HexToAddress("0xc0de")
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	var code []byte
+	for i := byte(0); i < 8; i++ {
+		storage[gethcommon.Hash{31: i}] = gethcommon.Hash{31: i + 1}
+		code = append(code, byte(vm.PUSH1), i, byte(vm.SLOAD), byte(vm.POP))
+	}
+	code = append(code, byte(vm.STOP))
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: storage}}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	data, err := NewPreflight(chain).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	var accessed, nodes int
+	for _, proof := range data.PreStateProofs {
+		accessed += 1 + len(proof.Storage)
+	}
+	for _, proof := range append(data.PreStateProofs, data.PostStateProofs...) {
+		nodes += len(proof.Proof)
+		for _, slot := range proof.Storage {
+			nodes += len(slot.Proof)
+		}
+	}
+	require.Greater(t, nodes, accessed)
+
+	// Preflight fails before fetching proofs when more accounts and slots are accessed than the limit
+	_, err = NewPreflight(chain, WithMaxStateNodes(2)).Preflight(context.Background(), big.NewInt(1))
+	var limitErr *StateLimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, big.NewInt(1), limitErr.Number)
+	assert.Equal(t, 2, limitErr.Limit)
+	assert.Equal(t, accessed, limitErr.Count)
+
+	// Preflight fails while fetching proofs when they hold more nodes than the limit
+	_, err = NewPreflight(chain, WithMaxStateNodes(nodes-1)).Preflight(context.Background(), big.NewInt(1))
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, nodes-1, limitErr.Limit)
+	assert.Greater(t, limitErr.Count, nodes-1)
+
+	_, err = NewPreflight(chain, WithMaxStateNodes(nodes)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+}
+
+func TestEstimatePreflightCost(t *testing.T) {
+	chain := newTransferChain(t)
+	counter := &countingClient{Client: chain}
+
+	cost, err := NewPreflight(counter).EstimatePreflightCost(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+
+	// The accounts are the coinbase, the sender, the recipient, the system address and the beacon roots contract
+	assert.Equal(t, &PreflightCost{ChainID: 1, GetBlock: 1, GetHeader: 2, GetProof: 10, GetCode: 1}, cost)
+	assert.Equal(t, 15, cost.Total())
+	assert.Equal(t, int64(2), counter.calls.Load(), "estimate should only fetch the chain ID and the block")
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+)
+
+// timeoutClient is an ethrpc.Client setting a timeout on each call used by preflight.
+// Other calls are forwarded to the underlying client without timeout.
+type timeoutClient struct {
+	ethrpc.Client
+
+	timeout time.Duration
+}
+
+func withCallTimeout(remote ethrpc.Client, timeout time.Duration) ethrpc.Client {
+	return &timeoutClient{
+		Client:  remote,
+		timeout: timeout,
+	}
+}
+
+func (c *timeoutClient) ChainID(ctx context.Context) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.ChainID(ctx)
+}
+
+func (c *timeoutClient) BlockByNumber(ctx context.Context, number *big.Int) (*gethtypes.Block, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.BlockByNumber(ctx, number)
+}
+
+func (c *timeoutClient) HeaderByNumber(ctx context.Context, number *big.Int) (*gethtypes.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.HeaderByNumber(ctx, number)
+}
+
+func (c *timeoutClient) HeaderByHash(ctx context.Context, hash gethcommon.Hash) (*gethtypes.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.HeaderByHash(ctx, hash)
+}
+
+func (c *timeoutClient) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.CodeAt(ctx, account, blockNumber)
+}
+
+func (c *timeoutClient) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.StorageAt(ctx, account, key, blockNumber)
+}
+
+func (c *timeoutClient) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.GetProof(ctx, account, keys, blockNumber)
+}
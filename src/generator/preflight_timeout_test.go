@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowChain is a testChain whose first eth_getProof call fetching state proofs hangs
+type slowChain struct {
+	*testChain
+
+	delay   time.Duration
+	delayed atomic.Bool
+}
+
+func (c *slowChain) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	// State proofs are fetched with a non-nil list of keys, accounts read during execution are not
+	if keys != nil && c.delayed.CompareAndSwap(false, true) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.delay):
+		}
+	}
+	return c.testChain.GetProof(ctx, account, keys, blockNumber)
+}
+
+func TestPreflightPerCallTimeout(t *testing.T) {
+	chain := &slowChain{testChain: newTransferChain(t), delay: time.Minute}
+	pf := NewPreflight(chain, WithPerCallTimeout(100*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := pf.Preflight(ctx, big.NewInt(1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.NoError(t, ctx.Err(), "overall deadline should not be consumed")
+
+	// Only the first call hangs so the preflight succeeds on retry
+	data, err := pf.Preflight(ctx, big.NewInt(1))
+	require.NoError(t, err)
+	assert.NotEmpty(t, data.PreStateProofs)
+}
+
+func TestPreflightPerCallTimeoutRetry(t *testing.T) {
+	chain := &slowChain{testChain: newTransferChain(t), delay: time.Minute}
+	pf := NewPreflight(chain,
+		WithPerCallTimeout(100*time.Millisecond),
+		WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: 10 * time.Millisecond}),
+	)
+
+	// The hanging call is cut off then retried, rather than failing the block
+	start := time.Now()
+	data, err := pf.Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	assert.NotEmpty(t, data.PreStateProofs)
+	assert.True(t, chain.delayed.Load())
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestPreflightDefaultPerCallTimeout(t *testing.T) {
+	chain := newTransferChain(t)
+	assert.Equal(t, defaultPerCallTimeout, NewPreflight(chain).(*preflight).perCallTimeout)
+	assert.Zero(t, NewPreflight(chain, WithPerCallTimeout(0)).(*preflight).perCallTimeout)
+}
@@ -1,13 +1,30 @@
 package generator
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie/trienode"
 	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/ethereum/go-ethereum/triedb/hashdb"
 	"github.com/kkrt-labs/go-utils/log"
@@ -17,33 +34,311 @@ import (
 	"github.com/kkrt-labs/zk-pig/src/ethereum/state"
 	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Preparer is the interface for preparing the prover inputs that serves as the input for the EVM prover engine.
 // It runs a full "execution + final state validation" of the block ensuring that the necessary data is available.
 // It bases on the preflight data collected during preflight to prepare the final prover inputs
+//
+// A failing preparation stage returns a PrepareError, telling with its kind whether the preflight data misses state,
+// the block is invalid or the chain cannot be set up.
 type Preparer interface {
 	// Prepare prepares the ProvableBlockInputs data for the EVM prover engine.
 	Prepare(ctx context.Context, inputs *PreflightData) (*input.ProverInput, error)
+
+	// PrepareExecution runs the validation execution of the block and returns its result without assembling the ProverInput.
+	// The result can be cached and passed to AssembleProverInput any number of times.
+	PrepareExecution(ctx context.Context, inputs *PreflightData) (*PreparedExecution, error)
+
+	// AssembleProverInput assembles the ProverInput from the result of a previous validation execution.
+	AssembleProverInput(exec *PreparedExecution) (*input.ProverInput, error)
+
+	// PrepareTransaction prepares a ProverInput scoped to the transaction at index txIndex of the block.
+	// The transaction is executed on the intermediate state resulting from the preceding transactions of the block.
+	PrepareTransaction(ctx context.Context, inputs *PreflightData, txIndex int) (*input.ProverInput, error)
+
+	// PrepareRange prepares a single ProverInput for consecutive blocks, executed in sequence each on the post-state of the previous one.
+	// The ProverInput holds the blocks in order with a merged witness for the pre-state of the first block.
+	// It fails with a RangeBlockError reporting the index of the block that failed.
+	PrepareRange(ctx context.Context, inputs []*PreflightData) (*input.ProverInput, error)
+
+	// PrepareMany prepares the ProverInputs of independent blocks concurrently, running at most concurrency preparations at once.
+	// It returns the ProverInputs and the errors in the order of inputs.
+	PrepareMany(ctx context.Context, inputs []*PreflightData, concurrency int) ([]*input.ProverInput, []error)
+}
+
+// PreparedExecution is the result of the validation execution of a block.
+// It holds everything necessary to assemble the ProverInput, so witness assembly can be re-run without re-executing the block.
+type PreparedExecution struct {
+	ChainConfig *params.ChainConfig
+	Block       *gethtypes.Block
+	Witness     *stateless.Witness // Witness collected during the execution
+
+	PreStateProofs  []*trie.AccountProof // Preflight proofs the witness state was built from
+	PostStateProofs []*trie.AccountProof
+
+	CodeHashes []gethcommon.Hash // Code hashes of the pre-state accounts whose code is executed, their code must be in the witness
+
+	Accesses []state.StateAccess // Accounts and storage slots of the pre-state in the order they are first accessed during the execution
+
+	Receipts gethtypes.Receipts // Receipts of the executed transactions, unset on the simple transfer fast path
+
+	AccessReport *state.AccessReport // Accessed accounts and storage slots, set with WithAccessReport for single block executions
+
+	StateDiff [][]byte // Trie nodes created by the block, set with WithStateDiff for single block executions
+
+	TxScope *input.TransactionScope // Set when the execution is scoped to a single transaction
+}
+
+type preparer struct {
+	groupWitnessByOwner bool
+	gasBreakdownDir     string
+	transferFastPath    bool
+	validateCoinbase    bool
+	retainStateProofs   bool
+	embedSenders        bool
+	compactWitness      bool
+	zkPigVersion        string
+	witnessScheme       string
+	alwaysInclude       gethtypes.AccessList
+	validateReceipts    bool
+	postProcess         []func(*input.ProverInput) error
+	accessOrder         bool
+	skipExtraData       bool
+	embedLogs           bool
+	skipValidation      bool
+	embedReceipts       bool
+	trieDBConfig        *triedb.Config
+	accessReport        bool
+	stateDiff           bool
+	metrics             *preparerMetrics
+	progress            func(ProgressEvent)
+	databases           *databasePool
+	tracer              *tracing.Hooks
+	logger              *zap.Logger
+	logLevel            *zapcore.Level
+}
+
+// PreparerOption is an option to configure a Preparer.
+type PreparerOption func(*preparer)
+
+// WithTracer makes the validation execution of the preparer drive tracer, for instance to capture an opcode trace
+// of the block to correlate with its witness. The trace is collected by the tracer, which must be safe for concurrent use
+// when preparing blocks concurrently. It disables the simple transfer fast path, that does not execute the block.
+func WithTracer(tracer *tracing.Hooks) PreparerOption {
+	return func(p *preparer) {
+		p.tracer = tracer
+	}
+}
+
+// WithLogger sets the logger of the preparer, used when the context of a preparation carries no logger.
+// Without it, preparations not carrying a logger log with the global zap logger, a no-op unless replaced.
+func WithLogger(logger *zap.Logger) PreparerOption {
+	return func(p *preparer) {
+		p.logger = logger
+	}
+}
+
+// WithLogLevel sets the minimum level of the preparer logs, whether they go to the logger of the context or to the one set with WithLogger.
+// It can only raise the level of the logger, for example to only get the warnings of library usage.
+func WithLogLevel(level zapcore.Level) PreparerOption {
+	return func(p *preparer) {
+		p.logLevel = &level
+	}
+}
+
+// WithWitnessGroupedByOwner makes the preparer also emit the witness state nodes grouped by the trie that owns them.
+// This layout is convenient for provers processing the account trie and each storage trie separately.
+func WithWitnessGroupedByOwner() PreparerOption {
+	return func(p *preparer) {
+		p.groupWitnessByOwner = true
+	}
+}
+
+// WithGasBreakdownDir makes the preparer record the gas consumed per opcode during execution
+// and write the breakdown as JSON to a <block number>.gas.json file in dir.
+func WithGasBreakdownDir(dir string) PreparerOption {
+	return func(p *preparer) {
+		p.gasBreakdownDir = dir
+	}
+}
+
+// WithSimpleTransferFastPath enables a fast path for blocks made of a single simple value transfer.
+// Such blocks are not re-executed, the witness is assembled directly from the preflight proofs,
+// so the post-state root is not validated. Other blocks go through the general path.
+func WithSimpleTransferFastPath() PreparerOption {
+	return func(p *preparer) {
+		p.transferFastPath = true
+	}
+}
+
+// WithCoinbaseFeesValidation makes the preparer validate, after execution, that the coinbase balance delta
+// equals the priority fees and rewards of the block.
+// It should only be enabled on chains where the coinbase does not transfer value within blocks (e.g. to pay a proposer).
+func WithCoinbaseFeesValidation() PreparerOption {
+	return func(p *preparer) {
+		p.validateCoinbase = true
+	}
+}
+
+// WithStateProofs makes the preparer retain the preflight pre-state and post-state proofs in the ProverInput,
+// so consumers can verify them independently of the flattened witness state.
+func WithStateProofs() PreparerOption {
+	return func(p *preparer) {
+		p.retainStateProofs = true
+	}
+}
+
+// WithSenders makes the preparer embed the sender address of each transaction in the ProverInput blocks.
+func WithSenders() PreparerOption {
+	return func(p *preparer) {
+		p.embedSenders = true
+	}
+}
+
+// WithCompactWitness makes the preparer omit from the witness state the branch nodes that can be regenerated from their children.
+// Executors regenerate the omitted nodes before execution, see input.CompactWitness.
+func WithCompactWitness() PreparerOption {
+	return func(p *preparer) {
+		p.compactWitness = true
+	}
+}
+
+// WithVersionMetadata makes the preparer stamp the ProverInput with the zk-pig version and the go-ethereum version producing it.
+func WithVersionMetadata(zkPigVersion string) PreparerOption {
+	return func(p *preparer) {
+		p.zkPigVersion = zkPigVersion
+	}
+}
+
+// WithWitnessScheme makes the preparer emit the witness state nodes keyed as in the given state scheme, rawdb.HashScheme (default) or rawdb.PathScheme.
+// With the path scheme, the nodes are emitted in the witness StateByPath instead of State.
+func WithWitnessScheme(scheme string) PreparerOption {
+	return func(p *preparer) {
+		p.witnessScheme = scheme
+	}
+}
+
+// WithAlwaysInclude makes the preparer include in the witness the state nodes of the given accounts and storage slots,
+// whether the block execution accesses them or not. Their proofs must be part of the preflight data, see WithAlwaysIncludeProofs.
+// It does not apply to inputs scoped to a transaction.
+func WithAlwaysInclude(accounts gethtypes.AccessList) PreparerOption {
+	return func(p *preparer) {
+		p.alwaysInclude = accounts
+	}
+}
+
+// WithReceiptsRootValidation makes the preparer recompute the receipts root from the execution receipts and check it against the block header,
+// failing with a ReceiptsRootMismatchError on mismatch, even when the block validation is skipped with WithValidation.
+// It catches receipt divergences independently of the state.
+func WithReceiptsRootValidation() PreparerOption {
+	return func(p *preparer) {
+		p.validateReceipts = true
+	}
+}
+
+// WithPostProcess registers a hook run on every ProverInput once assembled, before it is returned.
+// Hooks can transform the witness, run additional validations or attach metadata. They run in registration order
+// and the preparation fails if one of them errors.
+func WithPostProcess(hook func(*input.ProverInput) error) PreparerOption {
+	return func(p *preparer) {
+		p.postProcess = append(p.postProcess, hook)
+	}
+}
+
+// WithWitnessAccessOrder makes the preparer order the witness state nodes by the order the execution first accesses them,
+// instead of by hash, which can improve the cache locality of provers. Nodes the execution does not access through
+// an account or a storage slot come last, ordered by hash.
+func WithWitnessAccessOrder() PreparerOption {
+	return func(p *preparer) {
+		p.accessOrder = true
+	}
 }
 
-type preparer struct{}
+// WithoutExtraDataValidation makes the preparer accept blocks whose extra-data exceeds the maximum length,
+// as produced by some testnets and forks. Every other header validation still applies.
+func WithoutExtraDataValidation() PreparerOption {
+	return func(p *preparer) {
+		p.skipExtraData = true
+	}
+}
+
+// WithLogs makes the preparer embed the logs emitted by each transaction during the execution in the ProverInput blocks,
+// so consumers can read them without re-executing the block.
+func WithLogs() PreparerOption {
+	return func(p *preparer) {
+		p.embedLogs = true
+	}
+}
+
+// WithValidation sets whether the preparer validates the block execution against the block header, which is the default.
+// Without validation the witness is still fully collected, but the post-state root, gas used, bloom and receipts root
+// are not checked, so the produced ProverInput is not self-verified. It is meant for pipelines trusting their preflight source.
+func WithValidation(validate bool) PreparerOption {
+	return func(p *preparer) {
+		p.skipValidation = !validate
+	}
+}
+
+// WithReceipts makes the preparer embed the receipts produced by the execution in the ProverInput blocks,
+// so provers can cross-check their own execution. The receipts root is checked against the block header.
+// Blocks are always executed, bypassing the simple transfer fast path which produces no receipts.
+func WithReceipts() PreparerOption {
+	return func(p *preparer) {
+		p.embedReceipts = true
+	}
+}
+
+// WithTrieDBConfig sets the configuration of the trie database the block is executed on, the default being an hash based database.
+// A path based database (PathDB set) stores the nodes of the pre-state keyed by path, which is more compact for large blocks.
+func WithTrieDBConfig(config *triedb.Config) PreparerOption {
+	return func(p *preparer) {
+		p.trieDBConfig = config
+	}
+}
+
+// WithAccessReport makes the preparer report the accounts and storage slots of the pre-state accessed by the execution,
+// and whether they were read or written. The report is set on the PreparedExecution returned by PrepareExecution.
+// Blocks are always executed, bypassing the simple transfer fast path which tracks no access.
+func WithAccessReport() PreparerOption {
+	return func(p *preparer) {
+		p.accessReport = true
+	}
+}
+
+// WithStateDiff makes the preparer emit the state diff of the block in the witness StateDiff instead of the witness state,
+// for provers maintaining their own state: the trie nodes created by the block, whose addition to the pre-state holds the post-state.
+// The diff is checked to hold the post-state of the accounts and storage slots accessed by the execution.
+// Blocks are always executed, bypassing the simple transfer fast path. Transaction scoped and range inputs are not supported.
+func WithStateDiff() PreparerOption {
+	return func(p *preparer) {
+		p.stateDiff = true
+	}
+}
+
+// WithMetrics makes the preparer record Prometheus metrics on reg: the duration of each preparation stage,
+// and the node count, size and code count of the prepared witnesses. Metrics are labeled by chain id.
+func WithMetrics(reg prometheus.Registerer) PreparerOption {
+	return func(p *preparer) {
+		p.metrics = newPreparerMetrics(reg)
+	}
+}
 
 // NewPreparer creates a new Preparer.
-func NewPreparer() Preparer {
-	return &preparer{}
+func NewPreparer(opts ...PreparerOption) Preparer {
+	p := &preparer{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Prepare prepares the ProvableBlockInputs data for the EVM prover engine.
 func (p *preparer) Prepare(ctx context.Context, data *PreflightData) (*input.ProverInput, error) {
-	ctx = tag.WithComponent(ctx, "prepare")
-	ctx = tag.WithTags(
-		ctx,
-		tag.Key("chain.id").String(data.ChainConfig.ChainID.String()),
-		tag.Key("block.number").Int64(data.Block.Number.ToInt().Int64()),
-		tag.Key("block.hash").String(data.Block.Hash.Hex()),
-	)
+	ctx = prepareTags(p.logContext(ctx), data)
 
 	inputs, err := p.prepare(ctx, data)
 	if err != nil {
@@ -55,49 +350,311 @@ func (p *preparer) Prepare(ctx context.Context, data *PreflightData) (*input.Pro
 	return inputs, nil
 }
 
+// PrepareExecution runs the validation execution of the block and returns its result.
+func (p *preparer) PrepareExecution(ctx context.Context, data *PreflightData) (*PreparedExecution, error) {
+	ctx = prepareTags(p.logContext(ctx), data)
+
+	exec, err := p.prepareExecution(ctx, data)
+	if err != nil {
+		log.LoggerFromContext(ctx).Error("Validation execution failed", zap.Error(err))
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info("Validation execution succeeded")
+
+	return exec, nil
+}
+
+// AssembleProverInput assembles the ProverInput from the result of a previous validation execution.
+func (p *preparer) AssembleProverInput(exec *PreparedExecution) (*input.ProverInput, error) {
+	return p.prepareProverInput(exec)
+}
+
+// untaggedNamespace is a tags namespace no tag is set on, to get the logger of a context without tags
+const untaggedNamespace = "generator.untagged"
+
+// logContext attaches the logger set with WithLogger to ctx, if ctx carries no logger, restricted to the level set with WithLogLevel
+func (p *preparer) logContext(ctx context.Context) context.Context {
+	if p.logger == nil && p.logLevel == nil {
+		return ctx
+	}
+
+	logger := log.LoggerWithFieldsFromNamespaceContext(ctx, untaggedNamespace)
+	if p.logger != nil && logger == zap.L() {
+		// The context falls back to the global logger when it carries none
+		logger = p.logger
+	}
+	if p.logLevel != nil {
+		logger = logger.WithOptions(zap.IncreaseLevel(*p.logLevel))
+	}
+	return log.WithLogger(ctx, logger)
+}
+
+func prepareTags(ctx context.Context, data *PreflightData) context.Context {
+	ctx = tag.WithComponent(ctx, "prepare")
+	return tag.WithTags(
+		ctx,
+		tag.Key("chain.id").String(data.ChainConfig.ChainID.String()),
+		tag.Key("block.number").Int64(data.Block.Number.ToInt().Int64()),
+		tag.Key("block.hash").String(data.Block.Hash.Hex()),
+	)
+}
+
 type preparerContext struct {
-	ctx      context.Context
-	trackers *state.AccessTrackerManager
-	stateDB  gethstate.Database
-	hc       *core.HeaderChain
+	ctx          context.Context
+	trackers     *state.AccessTrackerManager
+	stateDB      gethstate.Database
+	hc           *core.HeaderChain
+	parentHeader *gethtypes.Header
+	state        *gethstate.StateDB // State the block is executed on (it holds the post-state once the block has been executed)
+	gasTracer    *evm.GasBreakdownTracer
+	result       *core.ProcessResult             // Result of the block execution
+	executed     map[gethcommon.Address]struct{} // Accounts whose code is executed during the block
+
+	expectedReceipts gethtypes.Receipts // Receipts of the preflight execution of the block, if recorded
+
+	// Gas pool and gas used of the transactions applied so far, when transactions are applied one by one
+	gasPool *core.GasPool
+	usedGas uint64
+
+	db *pooledDatabase // Database of the context, if taken from the preparer database pool
+}
+
+// StorageSlots returns, per contract address, the storage slots read and written during the block execution.
+// It must be called after the block has been executed.
+func (ctx *preparerContext) StorageSlots() map[gethcommon.Address]state.SlotAccess {
+	tracker := ctx.trackers.GetAccessTracker(ctx.parentHeader.Root)
+	if tracker == nil {
+		return make(map[gethcommon.Address]state.SlotAccess)
+	}
+	return tracker.StorageSlots(ctx.state)
 }
 
 func (p *preparer) prepare(ctx context.Context, inputs *PreflightData) (*input.ProverInput, error) {
 	log.LoggerFromContext(ctx).Info("Process provable inputs preparation...")
 
+	exec, err := p.prepareExecution(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.prepareProverInput(exec)
+}
+
+func (p *preparer) prepareExecution(ctx context.Context, inputs *PreflightData) (*PreparedExecution, error) {
+	if err := p.validateBlock(ctx, inputs); err != nil {
+		return nil, err
+	}
+	p.reportProgress(ProgressPreflightLoaded, inputs.block())
+
+	if p.transferFastPath && !p.embedReceipts && !p.accessReport && !p.stateDiff && p.tracer == nil {
+		if exec, ok := p.prepareSimpleTransfer(inputs); ok {
+			log.LoggerFromContext(ctx).Info("Prepare simple transfer block using fast path")
+			p.reportProgress(ProgressWitnessCollected, exec.Block)
+			return exec, nil
+		}
+	}
+
+	start := time.Now()
 	valCtx, err := p.prepareContext(ctx, inputs)
+	p.metrics.observeStage(inputs.ChainConfig.ChainID, "prepareContext", start)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare validation context: %v", err)
+		return nil, prepareError(ErrChainSetup, "failed to prepare validation context", err)
 	}
+	defer p.releaseContext(valCtx)
 
-	if err := p.preparePreState(valCtx, inputs); err != nil {
-		return nil, fmt.Errorf("failed to prefill validation database: %v", err)
+	return p.executeBlock(valCtx, inputs)
+}
+
+// validateBlock runs the validations of the block that do not require executing it
+func (p *preparer) validateBlock(ctx context.Context, inputs *PreflightData) error {
+	if err := p.validateHeader(ctx, inputs); err != nil {
+		return prepareError(ErrValidationMismatch, "", err)
+	}
+	return nil
+}
+
+func (p *preparer) validateHeader(ctx context.Context, inputs *PreflightData) error {
+	header := inputs.Block.Header.Header()
+	if err := ValidateAncestors(header, inputs.Ancestors); err != nil {
+		return err
 	}
 
+	if err := ValidateBlockHash(inputs.ChainConfig, inputs.Ancestors[0], inputs.block(), inputs.Block.Hash); err != nil {
+		return err
+	}
+
+	if err := ValidateGasLimit(header); err != nil {
+		return err
+	}
+
+	if err := ValidateUncles(inputs.block()); err != nil {
+		return err
+	}
+
+	if err := ValidateTransactionsChainID(inputs.ChainConfig, inputs.block()); err != nil {
+		return err
+	}
+
+	if err := validateBaseFee(inputs.ChainConfig, inputs.Ancestors[0], header); err != nil {
+		return err
+	}
+
+	if err := ValidateBlobGas(inputs.ChainConfig, inputs.Ancestors[0], inputs.block()); err != nil {
+		return err
+	}
+
+	if !p.skipExtraData {
+		if err := ValidateExtraData(inputs.ChainConfig, header); err != nil {
+			return err
+		}
+	}
+
+	if inputs.ChainConfig.Clique != nil {
+		if err := validateCliqueHeader(ctx, inputs.ChainConfig, inputs.Ancestors, header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// executeBlock runs the validation execution of the block in the given context, leaving the post-state of the block in the context state
+func (p *preparer) executeBlock(valCtx *preparerContext, inputs *PreflightData) (*PreparedExecution, error) {
+	chainID := inputs.ChainConfig.ChainID
+
+	start := time.Now()
+	err := p.preparePreState(valCtx, inputs)
+	p.metrics.observeStage(chainID, "preparePreState", start)
+	if err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "failed to prefill validation database", err)
+	}
+	p.reportProgress(ProgressPreStatePrepared, inputs.block())
+
+	start = time.Now()
 	execParams, err := p.prepareExecParams(valCtx, inputs)
+	p.metrics.observeStage(chainID, "prepareExecParams", start)
+	if err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "failed to prepare validation exec params", err)
+	}
+
+	valCtx.expectedReceipts = inputs.Receipts
+	start = time.Now()
+	err = p.execute(valCtx, execParams)
+	p.metrics.observeStage(chainID, "execute", start)
+	// A state read failing does not stop the execution, which may even produce the expected post-state,
+	// so the missing proofs are reported first, as the cause of any other failure
+	if missingErr := missingProofError(execParams.Block.NumberU64(), valCtx.state.Error(), inputs.PreStateProofs); missingErr != nil {
+		return nil, prepareError(ErrIncompleteWitness, "validation execution failed", missingErr)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare validation exec params: %v", err)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// An aborted execution says nothing of the block
+			return nil, fmt.Errorf("validation execution failed: %w", err)
+		}
+		return nil, prepareError(ErrValidationMismatch, "validation execution failed", err)
+	}
+	p.reportProgress(ProgressExecuted, execParams.Block)
+
+	// Opening the pre-state again resets its tracker, so accesses are collected right after the execution
+	var accesses []state.StateAccess
+	if tracker := valCtx.trackers.GetAccessTracker(valCtx.parentHeader.Root); tracker != nil {
+		accesses = tracker.Accesses
+	}
+	var accessReport *state.AccessReport
+	if p.accessReport || p.stateDiff {
+		accessReport = valCtx.trackers.AccessReport(valCtx.parentHeader.Root, valCtx.state)
 	}
 
-	if err := p.execute(valCtx, execParams); err != nil {
-		return nil, fmt.Errorf("validation execution failed: %v", err)
+	if p.validateCoinbase {
+		if err := p.validateCoinbaseFees(valCtx, execParams.Block); err != nil {
+			return nil, prepareError(ErrValidationMismatch, "", err)
+		}
 	}
 
-	return p.prepareProverInput(valCtx, execParams), nil
+	if valCtx.gasTracer != nil {
+		if err := p.writeGasBreakdown(valCtx, execParams.Block); err != nil {
+			return nil, fmt.Errorf("failed to write gas breakdown: %v", err)
+		}
+	}
+
+	codeHashes, err := p.executedCodeHashes(valCtx, valCtx.parentHeader.Root)
+	if err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "failed to collect executed code hashes", err)
+	}
+
+	witness := execParams.State.Witness().Copy()
+	if !execParams.Chain.Config().IsByzantium(execParams.Block.Number()) {
+		if err := addAccessedProofs(witness, inputs.PreStateProofs, accesses); err != nil {
+			return nil, prepareError(ErrIncompleteWitness, "failed to complete pre-Byzantium witness", err)
+		}
+	}
+	removeCreatedCodes(witness, inputs.PreStateProofs)
+
+	var diff [][]byte
+	if p.stateDiff {
+		// The post-state is committed, so the diff is computed once the execution results are collected
+		if diff, err = stateDiff(valCtx, execParams.Block, witness, inputs.PreStateProofs, accessReport); err != nil {
+			return nil, fmt.Errorf("failed to compute state diff: %w", err)
+		}
+	}
+	if !p.accessReport {
+		accessReport = nil
+	}
+	if err := includeAlways(witness, inputs.PreStateProofs, p.alwaysInclude); err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "", err)
+	}
+	p.reportProgress(ProgressWitnessCollected, execParams.Block)
+
+	return &PreparedExecution{
+		ChainConfig: execParams.Chain.Config(),
+		Block:       execParams.Block,
+		Witness:     witness,
+
+		PreStateProofs:  inputs.PreStateProofs,
+		PostStateProofs: inputs.PostStateProofs,
+
+		CodeHashes: codeHashes,
+
+		Receipts: valCtx.result.Receipts,
+
+		Accesses: accesses,
+
+		AccessReport: accessReport,
+
+		StateDiff: diff,
+	}, nil
 }
 
 func (p *preparer) prepareContext(ctx context.Context, inputs *PreflightData) (*preparerContext, error) {
 	log.LoggerFromContext(ctx).Debug("Prepare context...")
 
+	genesis, err := chainGenesis(inputs.ChainConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// --- Create necessary database and chain instances ---
+	disk := rawdb.NewMemoryDatabase()
+	pooled := p.databases.get(inputs.ChainConfig)
+	if pooled != nil {
+		disk = pooled.db
+	}
+
 	trackers := state.NewAccessTrackerManager()
-	db := rawdb.NewMemoryDatabase()
-	trieDB := triedb.NewDatabase(db, &triedb.Config{HashDB: &hashdb.Config{}})
-	stateDB := state.NewAccessTrackerDatabase(gethstate.NewDatabase(trieDB, nil), trackers) // We use a modified trie database to track trie modifications
+	stateDB := state.NewAccessTrackerDatabase(newStateDatabase(disk, p.trieDBConfig), trackers) // We use a modified trie database to track trie modifications
 
-	hc, err := ethereum.NewChain(inputs.ChainConfig, stateDB)
+	var hc *core.HeaderChain
+	if pooled != nil && pooled.committed() {
+		hc, err = ethereum.NewChainOnCommittedGenesis(inputs.ChainConfig, stateDB)
+	} else {
+		hc, err = ethereum.NewChainFromGenesis(inputs.ChainConfig, genesis, stateDB)
+		if err == nil && pooled != nil {
+			pooled.snapshot()
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chain: %v", err)
+		p.databases.put(pooled)
+		return nil, fmt.Errorf("failed to create chain: %w", err)
 	}
 
 	return &preparerContext{
@@ -105,9 +662,29 @@ func (p *preparer) prepareContext(ctx context.Context, inputs *PreflightData) (*
 		trackers: trackers,
 		stateDB:  stateDB,
 		hc:       hc,
+		db:       pooled,
 	}, nil
 }
 
+// releaseContext gives the database of ctx back to the pool, once the preparation is done
+func (p *preparer) releaseContext(ctx *preparerContext) {
+	p.databases.put(ctx.db)
+	ctx.db = nil
+}
+
+// newMemoryStateDatabase creates a state database on an in-memory trie database with the given configuration, an hash based one if nil
+func newMemoryStateDatabase(trieDBConfig *triedb.Config) gethstate.Database {
+	return newStateDatabase(rawdb.NewMemoryDatabase(), trieDBConfig)
+}
+
+// newStateDatabase creates a state database on a trie database on disk with the given configuration, an hash based one if nil
+func newStateDatabase(disk ethdb.Database, trieDBConfig *triedb.Config) gethstate.Database {
+	if trieDBConfig == nil {
+		trieDBConfig = &triedb.Config{HashDB: &hashdb.Config{}}
+	}
+	return gethstate.NewDatabase(triedb.NewDatabase(disk, trieDBConfig), nil)
+}
+
 func (p *preparer) preparePreState(ctx *preparerContext, inputs *PreflightData) error {
 	log.LoggerFromContext(ctx.ctx).Info("Prepare pre-state...")
 
@@ -115,17 +692,26 @@ func (p *preparer) preparePreState(ctx *preparerContext, inputs *PreflightData)
 	ethereum.WriteHeaders(ctx.stateDB.TrieDB().Disk(), inputs.Ancestors...)
 
 	// -- Preload the pre-state with the nodes obtained from the state proofs ---
+	// When preparing a range, the pre-state follows the pre-state of the previous block, otherwise the genesis state
+	stateParent := ctx.hc.GetHeaderByNumber(0)
+	if ctx.parentHeader != nil {
+		stateParent = ctx.parentHeader
+	}
 	parentHeader := inputs.Ancestors[0]
-	genesisHeader := ctx.hc.GetHeaderByNumber(0)
+	ctx.parentHeader = parentHeader
 
 	nodeSet, err := trie.NodeSetFromStateTransitionProofs(parentHeader.Root, inputs.Block.Root, inputs.PreStateProofs, inputs.PostStateProofs)
 	if err != nil {
-		return fmt.Errorf("failed to create state nodes: %v", err)
+		return fmt.Errorf("failed to create state nodes: %w", err)
 	}
 
-	err = ctx.stateDB.TrieDB().Update(parentHeader.Root, genesisHeader.Root, 0, nodeSet, triedb.NewStateSet())
+	if ctx.stateDB.TrieDB().Scheme() == rawdb.PathScheme {
+		err = updatePathDB(ctx, parentHeader, nodeSet)
+	} else {
+		err = ctx.stateDB.TrieDB().Update(parentHeader.Root, stateParent.Root, stateParent.Number.Uint64(), nodeSet, triedb.NewStateSet())
+	}
 	if err != nil {
-		return fmt.Errorf("failed to update trie db with state nodes: %v", err)
+		return fmt.Errorf("failed to update trie db with state nodes: %w", err)
 	}
 
 	// --- Preload the account bytecodes into the database ---
@@ -138,21 +724,51 @@ func (p *preparer) preparePreState(ctx *preparerContext, inputs *PreflightData)
 	return nil
 }
 
+// updatePathDB stacks the pre-state nodes as a layer on top of the genesis state, which is the only state on disk.
+// A path database layer must modify the state, so there is nothing to stack when the pre-state is the genesis state.
+func updatePathDB(ctx *preparerContext, parentHeader *gethtypes.Header, nodeSet *trienode.MergedNodeSet) error {
+	genesisHeader := ctx.hc.GetHeaderByNumber(0)
+	if parentHeader.Root == genesisHeader.Root {
+		return nil
+	}
+	return ctx.stateDB.TrieDB().Update(parentHeader.Root, genesisHeader.Root, 0, nodeSet, triedb.NewStateSet())
+}
+
 func (p *preparer) prepareExecParams(ctx *preparerContext, inputs *PreflightData) (*evm.ExecParams, error) {
 	log.LoggerFromContext(ctx.ctx).Debug("Prepare execution parameters...")
 
 	parentHeader := inputs.Ancestors[0]
 	preState, err := gethstate.New(parentHeader.Root, ctx.stateDB)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pre-state from parent root %v: %v", parentHeader.Root, err)
+		return nil, fmt.Errorf("failed to create pre-state from parent root %v: %w", parentHeader.Root, err)
 	}
+	ctx.state = preState
 
-	return &evm.ExecParams{
-		VMConfig: &vm.Config{
-			StatelessSelfValidation: true,
+	vmConfig := &vm.Config{
+		StatelessSelfValidation: true,
+	}
+	block := inputs.block()
+
+	ctx.executed = make(map[gethcommon.Address]struct{})
+	hooks := []*tracing.Hooks{{
+		OnEnter: func(_ int, _ byte, _, to gethcommon.Address, _ []byte, _ uint64, _ *big.Int) {
+			ctx.executed[to] = struct{}{}
 		},
-		Block:    inputs.Block.Block(),
-		Validate: true, // We validate the block execution to ensure the result and final state are correct
+	}}
+	if p.gasBreakdownDir != "" {
+		ctx.gasTracer = evm.NewGasBreakdownTracer()
+		hooks = append(hooks, ctx.gasTracer.Hooks())
+	}
+	if p.progress != nil {
+		hooks = append(hooks, p.progressHooks(block))
+	}
+	hooks = append(hooks, p.tracer)
+	vmConfig.Tracer = evm.MultiHooks(hooks...)
+
+	return &evm.ExecParams{
+		VMConfig: vmConfig,
+		Block:    block,
+		Validate: !p.skipValidation, // We validate the block execution to ensure the result and final state are correct
 		Chain:    ctx.hc,
 		State:    preState,
 	}, nil
@@ -160,39 +776,413 @@ func (p *preparer) prepareExecParams(ctx *preparerContext, inputs *PreflightData
 
 func (p *preparer) execute(ctx *preparerContext, execParams *evm.ExecParams) error {
 	log.LoggerFromContext(ctx.ctx).Info("Execute EVM...")
-	_, err := evm.ExecutorWithTags("evm")(evm.ExecutorWithLog()(evm.NewExecutor())).Execute(ctx.ctx, execParams)
+	res, err := evm.ExecutorWithTags("evm")(evm.ExecutorWithLog()(evm.ExecutorWithRecover()(evm.ExecutorWithContext(0)(evm.NewExecutor())))).Execute(ctx.ctx, execParams)
+	if (p.validateReceipts || execParams.Validate) && res != nil {
+		// The result is returned whenever the block has been processed, so receipt divergences are reported
+		// before the block validation errors they also cause
+		if err := ValidateReceipts(execParams.Block.Header(), res.Receipts, ctx.expectedReceipts); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to execute block: %w", err)
+	}
+	ctx.result = res
+
+	if !execParams.Validate {
+		// Hashing the post-state adds the nodes it requires to the witness, as the validation does
+		ctx.state.IntermediateRoot(execParams.Chain.Config().IsEIP158(execParams.Block.Number()))
+	}
+
+	return nil
+}
+
+func (p *preparer) validateCoinbaseFees(ctx *preparerContext, block *gethtypes.Block) error {
+	preState, err := gethstate.New(ctx.parentHeader.Root, ctx.stateDB)
+	if err != nil {
+		return fmt.Errorf("failed to open pre-state: %v", err)
+	}
+
+	return ValidateCoinbaseFees(
+		ctx.hc.Config(),
+		block,
+		ctx.result.Receipts,
+		preState.GetBalance(block.Coinbase()).ToBig(),
+		ctx.state.GetBalance(block.Coinbase()).ToBig(),
+	)
+}
+
+// executedCodeHashes returns the code hashes at the pre-state root of the accounts whose code has been executed
+// Accounts deployed during the execution are ignored as their code is not part of the pre-state
+func (p *preparer) executedCodeHashes(ctx *preparerContext, root gethcommon.Hash) ([]gethcommon.Hash, error) {
+	preState, err := gethstate.New(root, ctx.stateDB)
 	if err != nil {
-		return fmt.Errorf("failed to execute block: %v", err)
+		return nil, fmt.Errorf("failed to open pre-state: %v", err)
+	}
+
+	var codeHashes []gethcommon.Hash
+	for addr := range ctx.executed {
+		if codeHash := preState.GetCodeHash(addr); codeHash != (gethcommon.Hash{}) && codeHash != gethtypes.EmptyCodeHash {
+			codeHashes = append(codeHashes, codeHash)
+		}
+	}
+	sort.Slice(codeHashes, func(i, j int) bool { return codeHashes[i].Cmp(codeHashes[j]) < 0 })
+
+	return codeHashes, nil
+}
+
+// removeCreatedCodes removes from the witness the codes of no pre-state account.
+// Such codes are deployed during the block, so the execution regenerates them and they are not part of the pre-state.
+func removeCreatedCodes(witness *stateless.Witness, preStateProofs []*trie.AccountProof) {
+	preStateCodes := make(map[gethcommon.Hash]struct{}, len(preStateProofs))
+	for _, proof := range preStateProofs {
+		preStateCodes[proof.CodeHash] = struct{}{}
+	}
+
+	for code := range witness.Codes {
+		if _, ok := preStateCodes[crypto.Keccak256Hash([]byte(code))]; !ok {
+			delete(witness.Codes, code)
+		}
+	}
+}
+
+// addAccessedProofs adds to the witness the pre-state proofs of the accessed accounts and storage slots.
+// Before Byzantium, the witness collected by the execution misses the state that is only read.
+func addAccessedProofs(witness *stateless.Witness, preStateProofs []*trie.AccountProof, accesses []state.StateAccess) error {
+	proofs := make(map[gethcommon.Address]*trie.AccountProof, len(preStateProofs))
+	for _, proof := range preStateProofs {
+		proofs[proof.Address] = proof
+	}
+
+	for _, access := range accesses {
+		proof, ok := proofs[access.Address]
+		if !ok {
+			return fmt.Errorf("missing pre-state proof for accessed account %v", access.Address)
+		}
+		if !access.IsSlot {
+			if !addProofNodes(witness, proof.Proof) {
+				return fmt.Errorf("invalid pre-state proof for account %v", access.Address)
+			}
+			continue
+		}
+		found := false
+		for _, storage := range proof.Storage {
+			if gethcommon.HexToHash(storage.Key) != access.Slot {
+				continue
+			}
+			if !addProofNodes(witness, storage.Proof) {
+				return fmt.Errorf("invalid pre-state proof for slot %v of account %v", access.Slot.Hex(), access.Address)
+			}
+			found = true
+			break
+		}
+		if !found {
+			return fmt.Errorf("missing pre-state proof for accessed slot %v of account %v", access.Slot.Hex(), access.Address)
+		}
 	}
 
 	return nil
 }
 
-func (p *preparer) prepareProverInput(_ *preparerContext, execParams *evm.ExecParams) *input.ProverInput {
+// includeAlways adds to the witness the pre-state proof nodes of the given accounts and storage slots
+func includeAlways(witness *stateless.Witness, preStateProofs []*trie.AccountProof, accounts gethtypes.AccessList) error {
+	proofs := make(map[gethcommon.Address]*trie.AccountProof, len(preStateProofs))
+	for _, proof := range preStateProofs {
+		proofs[proof.Address] = proof
+	}
+
+	for _, tuple := range accounts {
+		proof, ok := proofs[tuple.Address]
+		if !ok {
+			return fmt.Errorf("missing pre-state proof for always included account %v", tuple.Address)
+		}
+		if !addProofNodes(witness, proof.Proof) {
+			return fmt.Errorf("invalid pre-state proof for account %v", tuple.Address)
+		}
+
+		slots := make(map[gethcommon.Hash]*trie.StorageProof, len(proof.Storage))
+		for _, storage := range proof.Storage {
+			slots[gethcommon.HexToHash(storage.Key)] = storage
+		}
+		for _, slot := range tuple.StorageKeys {
+			storage, ok := slots[slot]
+			if !ok {
+				return fmt.Errorf("missing pre-state proof for always included slot %v of account %v", slot.Hex(), tuple.Address)
+			}
+			if !addProofNodes(witness, storage.Proof) {
+				return fmt.Errorf("invalid pre-state proof for slot %v of account %v", slot.Hex(), tuple.Address)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *preparer) writeGasBreakdown(ctx *preparerContext, block *gethtypes.Block) error {
+	if err := os.MkdirAll(p.gasBreakdownDir, 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(ctx.gasTracer.Breakdown(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(gasBreakdownPath(p.gasBreakdownDir, block.NumberU64()), b, 0o600)
+}
+
+func gasBreakdownPath(dir string, blockNumber uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.gas.json", blockNumber))
+}
+
+// prepareProverInput assembles the ProverInput from the execution result.
+// The blocks of next executions, following the block of exec, are appended to the ProverInput blocks, the witness being the one of exec.
+// Witness codes and state nodes are sorted by hash so the output is deterministic.
+func (p *preparer) prepareProverInput(exec *PreparedExecution, next ...*PreparedExecution) (*input.ProverInput, error) {
+	defer p.metrics.observeStage(exec.ChainConfig.ChainID, "prepareProverInput", time.Now())
+
 	proverInput := &input.ProverInput{
-		ChainConfig: execParams.Chain.Config(),
-		Blocks: []*input.Block{
-			{
-				Header:       execParams.Block.Header(),
-				Transactions: execParams.Block.Transactions(),
-				Uncles:       execParams.Block.Uncles(),
-				Withdrawals:  execParams.Block.Withdrawals(),
-			},
-		},
+		ChainConfig: exec.ChainConfig,
 		Witness: &input.Witness{
-			Ancestors: execParams.State.Witness().Headers,
+			Ancestors: exec.Witness.Headers,
 		},
 	}
 
-	witness := execParams.State.Witness()
-	for code := range witness.Codes {
+	for _, e := range append([]*PreparedExecution{exec}, next...) {
+		block, err := p.inputBlock(e)
+		if err != nil {
+			return nil, err
+		}
+		proverInput.Blocks = append(proverInput.Blocks, block)
+	}
+
+	for code := range exec.Witness.Codes {
 		proverInput.Witness.Codes = append(proverInput.Witness.Codes, []byte(code))
 	}
+	sortByHash(proverInput.Witness.Codes)
+
+	for node := range exec.Witness.State {
+		proverInput.Witness.State = append(proverInput.Witness.State, []byte(node))
+	}
+	sortByHash(proverInput.Witness.State)
+
+	if err := input.VerifyWitnessCodes(proverInput.Witness, exec.CodeHashes); err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "", err)
+	}
+
+	if p.retainStateProofs {
+		proverInput.PreStateProofs = exec.PreStateProofs
+		proverInput.PostStateProofs = exec.PostStateProofs
+	}
+
+	preStateRoot := exec.Witness.Root()
+	if exec.TxScope != nil {
+		proverInput.TxScope = exec.TxScope
+		preStateRoot = exec.TxScope.PreStateRoot
+	}
+
+	if p.accessOrder {
+		proverInput.Witness.State = orderByAccess(preStateRoot, proverInput.Witness.State, exec.Accesses)
+	}
+
+	if p.groupWitnessByOwner {
+		stateByOwner, err := groupWitnessByOwner(preStateRoot, proverInput.Witness.State)
+		if err != nil {
+			return nil, prepareError(ErrIncompleteWitness, "failed to group witness by owner", err)
+		}
+		proverInput.Witness.StateByOwner = stateByOwner
+	}
 
-	for node := range witness.State {
-		blob := []byte(node)
-		proverInput.Witness.State = append(proverInput.Witness.State, blob)
+	switch p.witnessScheme {
+	case "", rawdb.HashScheme:
+	case rawdb.PathScheme:
+		stateByPath, err := witnessToPathScheme(preStateRoot, proverInput.Witness.State)
+		if err != nil {
+			return nil, prepareError(ErrIncompleteWitness, "failed to convert witness to path scheme", err)
+		}
+		proverInput.Witness.State, proverInput.Witness.StateByPath = nil, stateByPath
+	default:
+		return nil, fmt.Errorf("unsupported witness state scheme %q", p.witnessScheme)
 	}
 
-	return proverInput
+	if p.compactWitness {
+		proverInput.Witness = input.CompactWitness(proverInput.Witness)
+	}
+
+	if p.stateDiff {
+		if exec.TxScope != nil || len(next) > 0 {
+			return nil, fmt.Errorf("state diff is only supported for single block inputs")
+		}
+		w := proverInput.Witness
+		w.State, w.StateByOwner, w.StateByPath, w.CompactBranches = nil, nil, nil, nil
+		w.StateDiff = make([]hexutil.Bytes, 0, len(exec.StateDiff))
+		for _, node := range exec.StateDiff {
+			w.StateDiff = append(w.StateDiff, node)
+		}
+		sortByHash(w.StateDiff)
+	}
+
+	if p.zkPigVersion != "" {
+		proverInput.Metadata = input.NewMetadata(p.zkPigVersion)
+	}
+
+	for i, hook := range p.postProcess {
+		if err := hook(proverInput); err != nil {
+			return nil, fmt.Errorf("post-process hook %d failed: %w", i, err)
+		}
+	}
+
+	p.metrics.observeWitness(exec.ChainConfig.ChainID, proverInput.Witness)
+	p.reportProgress(ProgressInputAssembled, exec.Block)
+
+	return proverInput, nil
+}
+
+// inputBlock returns the ProverInput block of the execution
+func (p *preparer) inputBlock(exec *PreparedExecution) (*input.Block, error) {
+	block := &input.Block{
+		Header:       exec.Block.Header(),
+		Transactions: exec.Block.Transactions(),
+		Uncles:       exec.Block.Uncles(),
+		Withdrawals:  exec.Block.Withdrawals(),
+	}
+
+	if p.embedSenders {
+		senders, err := transactionSenders(exec.ChainConfig, exec.Block)
+		if err != nil {
+			return nil, prepareError(ErrValidationMismatch, "failed to recover transaction senders", err)
+		}
+		block.Senders = senders
+	}
+
+	if p.embedLogs {
+		block.Logs = transactionLogs(exec)
+	}
+
+	if p.embedReceipts {
+		// The receipts root of a transaction scoped block header covers every transaction of the block
+		if exec.TxScope == nil {
+			if err := ValidateReceiptsRoot(exec.Block.Header(), exec.Receipts); err != nil {
+				return nil, prepareError(ErrValidationMismatch, "", err)
+			}
+		}
+		block.Receipts = exec.Receipts
+	}
+
+	return block, nil
+}
+
+// transactionLogs returns the logs emitted by each transaction of the executed block
+// The simple transfer fast path has no receipts, its single transfer emits no log
+func transactionLogs(exec *PreparedExecution) [][]*input.Log {
+	logs := make([][]*input.Log, len(exec.Block.Transactions()))
+	for i := range logs {
+		logs[i] = []*input.Log{}
+		if i >= len(exec.Receipts) {
+			continue
+		}
+		for _, entry := range exec.Receipts[i].Logs {
+			logs[i] = append(logs[i], &input.Log{
+				Address: entry.Address,
+				Topics:  entry.Topics,
+				Data:    entry.Data,
+			})
+		}
+	}
+	return logs
+}
+
+// transactionSenders returns the sender of each transaction of the block
+// Senders recovered during the execution are cached on the transactions so they are not recovered again
+func transactionSenders(config *params.ChainConfig, block *gethtypes.Block) ([]gethcommon.Address, error) {
+	signer := gethtypes.MakeSigner(config, block.Number(), block.Time())
+	senders := make([]gethcommon.Address, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		sender, err := gethtypes.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		senders = append(senders, sender)
+	}
+	return senders, nil
+}
+
+// groupWitnessByOwner groups the witness state nodes by the trie that owns them
+func groupWitnessByOwner(root gethcommon.Hash, state []hexutil.Bytes) (map[gethcommon.Hash][]hexutil.Bytes, error) {
+	nodes := make([][]byte, 0, len(state))
+	for _, node := range state {
+		nodes = append(nodes, node)
+	}
+
+	groups, err := trie.GroupNodesByOwner(root, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	stateByOwner := make(map[gethcommon.Hash][]hexutil.Bytes, len(groups))
+	for owner, group := range groups {
+		for _, node := range group {
+			stateByOwner[owner] = append(stateByOwner[owner], node)
+		}
+		sortByHash(stateByOwner[owner])
+	}
+
+	return stateByOwner, nil
+}
+
+// witnessToPathScheme keys the witness state nodes by owner and path in the tries of root.
+// Nodes not reachable from root, such as the post-state nodes of the tries collapsed by a deletion, are not needed by the execution and are left out.
+func witnessToPathScheme(root gethcommon.Hash, state []hexutil.Bytes) ([]*trie.PathNode, error) {
+	nodes := make([][]byte, 0, len(state))
+	for _, node := range state {
+		nodes = append(nodes, node)
+	}
+
+	reached := make(map[gethcommon.Hash]struct{}, len(nodes))
+	trie.WalkNodes(root, nodes, func(_, hash gethcommon.Hash, _ []byte) {
+		reached[hash] = struct{}{}
+	})
+	reachable := nodes[:0]
+	for _, node := range nodes {
+		if _, ok := reached[crypto.Keccak256Hash(node)]; ok {
+			reachable = append(reachable, node)
+		}
+	}
+
+	return trie.ToPathScheme(root, reachable)
+}
+
+// orderByAccess orders the witness state nodes by the order the accounts and storage slots are first accessed
+func orderByAccess(root gethcommon.Hash, state []hexutil.Bytes, accesses []state.StateAccess) []hexutil.Bytes {
+	trieAccesses := make([]trie.TrieAccess, 0, len(accesses))
+	for _, access := range accesses {
+		if access.IsSlot {
+			trieAccesses = append(trieAccesses, trie.StorageAccess(access.Address, access.Slot))
+		} else {
+			trieAccesses = append(trieAccesses, trie.AccountAccess(access.Address))
+		}
+	}
+
+	nodes := make([][]byte, 0, len(state))
+	for _, node := range state {
+		nodes = append(nodes, node)
+	}
+
+	ordered := make([]hexutil.Bytes, 0, len(state))
+	for _, node := range trie.OrderNodesByAccess(root, nodes, trieAccesses) {
+		ordered = append(ordered, node)
+	}
+	return ordered
+}
+
+// sortByHash sorts blobs by their keccak hash
+func sortByHash(blobs []hexutil.Bytes) {
+	hashes := make(map[string]gethcommon.Hash, len(blobs))
+	for _, blob := range blobs {
+		hashes[string(blob)] = crypto.Keccak256Hash(blob)
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		hi, hj := hashes[string(blobs[i])], hashes[string(blobs[j])]
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
 }
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blobContract stores the versioned hash of the first blob of the transaction in slot 0x00 and the blob base fee in slot 0x01
+var blobContract = gethcommon.HexToAddress("0xb10b")
+
+// newBlobChain creates a Cancun chain whose blocks each hold a blob transaction with the given number of blobs calling blobContract.
+// Blocks using more blobs than the target raise the excess blob gas, hence the blob base fee, of the next blocks.
+func newBlobChain(t testing.TB, blobs ...int) *testChain {
+	code := []byte{
+		byte(vm.PUSH0), byte(vm.BLOBHASH), byte(vm.PUSH0), byte(vm.SSTORE),
+		byte(vm.BLOBBASEFEE), byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{blobContract: {Code: code, Balance: new(big.Int)}}
+
+	return newTestChain(t, testChainConfig(), alloc, len(blobs), func(i int, b *core.BlockGen) {
+		b.SetParentBeaconRoot(gethcommon.Hash{byte(i + 1)})
+		b.AddTx(signBlobTx(t, b, blobs[i]))
+	})
+}
+
+// signBlobTx signs a blob transaction calling blobContract, carrying the versioned hashes of n blobs without sidecar as included in blocks
+func signBlobTx(t testing.TB, b *core.BlockGen, n int) *gethtypes.Transaction {
+	hashes := make([]gethcommon.Hash, n)
+	for i := range hashes {
+		hashes[i] = kzg4844.CalcBlobHashV1(sha256.New(), &kzg4844.Commitment{byte(i + 1)})
+	}
+
+	tx, err := gethtypes.SignNewTx(testKey, b.Signer(), &gethtypes.BlobTx{
+		ChainID:    uint256.MustFromBig(b.Signer().ChainID()),
+		Nonce:      b.TxNonce(crypto.PubkeyToAddress(testKey.PublicKey)),
+		GasTipCap:  uint256.NewInt(params.GWei),
+		GasFeeCap:  uint256.MustFromBig(new(big.Int).Add(b.BaseFee(), big.NewInt(2*params.GWei))),
+		Gas:        100_000,
+		To:         blobContract,
+		BlobFeeCap: uint256.NewInt(params.GWei),
+		BlobHashes: hashes,
+	})
+	require.NoError(t, err)
+	return tx
+}
+
+func TestPreparerBlobTransactions(t *testing.T) {
+	// The first block uses the maximum number of blobs, so the second one has a non zero excess blob gas
+	chain := newBlobChain(t, 6, 1)
+
+	for number := uint64(1); number <= 2; number++ {
+		data := chain.preflightData(t, number)
+		block := data.Block.Block()
+		require.Equal(t, uint8(gethtypes.BlobTxType), block.Transactions()[0].Type())
+		require.NotNil(t, block.BeaconRoot())
+		require.Equal(t, gethtypes.ReceiptStatusSuccessful, data.Receipts[0].Status)
+
+		in, err := NewPreparer().Prepare(context.Background(), data)
+		require.NoError(t, err)
+		_, err = NewExecutor().Execute(context.Background(), in)
+		require.NoError(t, err)
+	}
+
+	header := chain.block(2).Header()
+	require.NotNil(t, header.ExcessBlobGas)
+	assert.Equal(t, uint64(params.BlobTxTargetBlobGasPerBlock), *header.ExcessBlobGas)
+	assert.Equal(t, uint64(params.BlobTxBlobGasPerBlob), *header.BlobGasUsed)
+}
+
+func TestPreparerBlobGasValidation(t *testing.T) {
+	chain := newBlobChain(t, 6, 1)
+
+	// The excess blob gas sets the blob base fee, it must follow from the parent header
+	data := chain.preflightData(t, 2)
+	data.Block.Header.ExcessBlobGas = new(hexutil.Uint64)
+	rehash(data)
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	assert.ErrorContains(t, err, "invalid excessBlobGas")
+
+	data = chain.preflightData(t, 2)
+	blobGasUsed := hexutil.Uint64(2 * params.BlobTxBlobGasPerBlob)
+	data.Block.Header.BlobGasUsed = &blobGasUsed
+	rehash(data)
+	_, err = NewPreparer().Prepare(context.Background(), data)
+	var mismatchErr *BlobGasUsedMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, uint64(params.BlobTxBlobGasPerBlob), mismatchErr.Actual)
+
+	data = chain.preflightData(t, 2)
+	data.Block.Header.ParentBeaconRoot = nil
+	rehash(data)
+	_, err = NewPreparer().Prepare(context.Background(), data)
+	assert.ErrorContains(t, err, "missing parent beacon block root")
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/evm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLoopChain returns a chain whose block 1 holds transactions looping until they run out of gas
+func newLoopChain(t testing.TB, txs int) *testChain {
+	loop := gethcommon.HexToAddress("0x1000")
+	code := []byte{byte(vm.JUMPDEST), byte(vm.PUSH1), 0, byte(vm.JUMP)}
+	alloc := gethtypes.GenesisAlloc{loop: {Code: code, Balance: new(big.Int)}}
+
+	return newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		for i := 0; i < txs; i++ {
+			b.AddTx(signTx(t, b, testKey, &loop, new(big.Int), 1_000_000, nil))
+		}
+	})
+}
+
+func TestPreparerCancellation(t *testing.T) {
+	data := newLoopChain(t, 5).preflightData(t, 1)
+
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name     string
+		cancelAt int // Number of the transaction whose start cancels the context, 0 to cancel before preparing
+	}{
+		{name: "before execution", cancelAt: 0},
+		{name: "first transaction", cancelAt: 1},
+		{name: "later transaction", cancelAt: 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAt == 0 {
+				cancel()
+			}
+
+			var started int
+			tracer := &tracing.Hooks{
+				OnTxStart: func(_ *tracing.VMContext, _ *gethtypes.Transaction, _ gethcommon.Address) {
+					if started++; started == tt.cancelAt {
+						cancel()
+					}
+				},
+			}
+
+			_, err := NewPreparer(WithTracer(tracer)).Prepare(ctx, data)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.Canceled), err.Error())
+			assert.False(t, errors.Is(err, evm.ErrExecutionPanic))
+
+			// No transaction is executed once the context is cancelled
+			assert.Equal(t, tt.cancelAt, started)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrChainSetup is returned, wrapped in a PrepareError, when the chain the block is executed on cannot be set up,
+	// for instance on an unsupported chain configuration
+	ErrChainSetup = errors.New("chain setup failed")
+
+	// ErrIncompleteWitness is returned, wrapped in a PrepareError, when the preflight data misses, or holds invalid,
+	// state the block execution needs. Running preflight again may fix it.
+	ErrIncompleteWitness = errors.New("incomplete witness")
+
+	// ErrValidationMismatch is returned, wrapped in a PrepareError, when the block is inconsistent with its ancestors,
+	// its header or the result of its execution. Running preflight again does not fix it.
+	ErrValidationMismatch = errors.New("validation mismatch")
+)
+
+// PrepareError is returned when a stage of the preparation fails.
+// It matches its Kind, one of ErrChainSetup, ErrIncompleteWitness and ErrValidationMismatch, and its cause with errors.Is and errors.As.
+type PrepareError struct {
+	Kind  error
+	Stage string // Failure message of the stage, empty when the cause describes it
+	Err   error
+}
+
+func (e *PrepareError) Error() string {
+	if e.Stage == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %v", e.Stage, e.Err)
+}
+
+func (e *PrepareError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func prepareError(kind error, stage string, err error) error {
+	return &PrepareError{Kind: kind, Stage: stage, Err: err}
+}
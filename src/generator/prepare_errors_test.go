@@ -0,0 +1,155 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerErrorKinds(t *testing.T) {
+	transfer := newTransferChain(t)
+	storage, contract := newLargeStorageChain(t)
+
+	tests := []struct {
+		desc    string
+		data    func() *PreflightData
+		opts    []PreparerOption
+		tx      bool // Whether to prepare the first transaction of the block instead of the block
+		kind    error
+		message string
+	}{
+		{
+			desc: "chain setup",
+			data: func() *PreflightData {
+				data := transfer.preflightData(t, 1)
+				// Pre-merge chains are not supported by the header chain
+				config := *data.ChainConfig
+				config.TerminalTotalDifficulty = nil
+				data.ChainConfig = &config
+				return data
+			},
+			kind:    ErrChainSetup,
+			message: "failed to prepare validation context",
+		},
+		{
+			desc: "ancestors",
+			data: func() *PreflightData {
+				data := transfer.preflightData(t, 1)
+				data.Ancestors[0] = gethtypes.CopyHeader(data.Ancestors[0])
+				data.Ancestors[0].Extra = []byte("tampered")
+				return data
+			},
+			kind: ErrValidationMismatch,
+		},
+		{
+			desc: "missing proof",
+			data: func() *PreflightData {
+				data := storage.preflightData(t, 1)
+				dropStorageProof(data, contract, gethcommon.HexToHash("0x05"))
+				return data
+			},
+			kind:    ErrIncompleteWitness,
+			message: "validation execution failed",
+		},
+		{
+			desc: "always included account",
+			data: func() *PreflightData {
+				return transfer.preflightData(t, 1)
+			},
+			opts:    []PreparerOption{WithAlwaysInclude(gethtypes.AccessList{{Address: gethcommon.HexToAddress("0xabcd")}})},
+			kind:    ErrIncompleteWitness,
+			message: "missing pre-state proof for always included account",
+		},
+		{
+			desc: "execution",
+			data: func() *PreflightData {
+				data := transfer.preflightData(t, 1)
+				data.Block.Header.GasUsed = hexutil.Uint64(uint64(data.Block.Header.GasUsed) + 1)
+				rehash(data)
+				return data
+			},
+			kind:    ErrValidationMismatch,
+			message: "validation execution failed",
+		},
+		{
+			desc: "transaction missing proof",
+			data: func() *PreflightData {
+				data := storage.preflightData(t, 1)
+				dropStorageProof(data, contract, gethcommon.HexToHash("0x05"))
+				return data
+			},
+			tx:      true,
+			kind:    ErrIncompleteWitness,
+			message: "failed to execute transaction 0",
+		},
+		{
+			desc: "transaction execution",
+			data: func() *PreflightData {
+				// The block gas limit is too low for its transaction
+				data := transfer.preflightData(t, 1)
+				data.Block.Header.GasLimit = hexutil.Uint64(20_000)
+				data.Block.Header.GasUsed = hexutil.Uint64(20_000)
+				rehash(data)
+				return data
+			},
+			tx:      true,
+			kind:    ErrValidationMismatch,
+			message: "failed to execute transaction 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var err error
+			if tt.tx {
+				_, err = NewPreparer(tt.opts...).PrepareTransaction(context.Background(), tt.data(), 0)
+			} else {
+				_, err = NewPreparer(tt.opts...).Prepare(context.Background(), tt.data())
+			}
+			require.Error(t, err)
+
+			var prepareErr *PrepareError
+			require.ErrorAs(t, err, &prepareErr)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.Equal(t, tt.kind, prepareErr.Kind)
+			assert.ErrorContains(t, err, tt.message)
+			for _, kind := range []error{ErrChainSetup, ErrIncompleteWitness, ErrValidationMismatch} {
+				if kind != tt.kind {
+					assert.NotErrorIs(t, err, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestPreparerErrorKindsKeepCause(t *testing.T) {
+	storage, contract := newLargeStorageChain(t)
+	data := storage.preflightData(t, 1)
+	dropStorageProof(data, contract, gethcommon.HexToHash("0x05"))
+
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	assert.ErrorIs(t, err, ErrIncompleteWitness)
+	var missing *MissingProofError
+	assert.ErrorAs(t, err, &missing)
+
+	data = newTransferChain(t).preflightData(t, 1)
+	config := *data.ChainConfig
+	config.TerminalTotalDifficulty = nil
+	data.ChainConfig = &config
+	_, err = NewPreparer().PrepareTransaction(context.Background(), data, 0)
+	assert.ErrorIs(t, err, ErrChainSetup)
+
+	_, chainData := newRangeTestChain(t)
+	chainData[1].Ancestors[0] = gethtypes.CopyHeader(chainData[1].Ancestors[0])
+	chainData[1].Ancestors[0].Extra = []byte("tampered")
+	_, err = NewPreparer().PrepareRange(context.Background(), chainData)
+	var rangeErr *RangeBlockError
+	require.ErrorAs(t, err, &rangeErr)
+	assert.Equal(t, 1, rangeErr.Index)
+	assert.ErrorIs(t, err, ErrValidationMismatch)
+}
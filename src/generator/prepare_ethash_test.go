@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEthashTestChain generates a pre-merge proof-of-work chain of n blocks with the given configuration, on top of a genesis with the given allocation.
+// The test account is always funded and the configuration is registered in ChainConfigs for the duration of the test.
+// The blocks are not sealed, so they are only valid for the ethash faker, as used by the preparer.
+func newEthashTestChain(t *testing.T, config *params.ChainConfig, alloc gethtypes.GenesisAlloc, n int, gen func(int, *core.BlockGen)) *testChain {
+	if alloc == nil {
+		alloc = gethtypes.GenesisAlloc{}
+	}
+	alloc[testAddr] = gethtypes.Account{Balance: testBalance}
+	setRegistry(t, ChainConfigs, config.ChainID.String(), config)
+
+	genesis := &core.Genesis{
+		Config:     config,
+		Alloc:      alloc,
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: big.NewInt(params.MinimumDifficulty.Int64()),
+	}
+
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, beacon.New(ethash.NewFaker()), n, gen)
+	require.Len(t, blocks, n)
+
+	c := &testChain{
+		config:  config,
+		stateDB: gethstate.NewDatabase(triedb.NewDatabase(db, triedb.HashDefaults), nil),
+		genesis: genesis.ToBlock(),
+		blocks:  blocks,
+		headers: make(map[gethcommon.Hash]*gethtypes.Header),
+	}
+	registered := registerTestGenesis(t, genesis)
+	c.headers[registered.Hash()] = registered
+	c.headers[c.genesis.Hash()] = c.genesis.Header()
+	for _, block := range blocks {
+		c.headers[block.Hash()] = block.Header()
+	}
+
+	return c
+}
+
+// ethashTestChainConfig returns a pre-merge chain configuration, whose terminal total difficulty is never reached
+func ethashTestChainConfig(chainID int64) *params.ChainConfig {
+	config := *params.AllEthashProtocolChanges
+	config.ChainID = big.NewInt(chainID)
+	return &config
+}
+
+func TestPreparerEthashUncles(t *testing.T) {
+	miner := gethcommon.HexToAddress("0xa1")
+	uncleMiner := gethcommon.HexToAddress("0xa2")
+	to := gethcommon.HexToAddress("0xdead")
+
+	// The block reward changes from Frontier to Byzantium and Constantinople, the base fee is burnt from London
+	forks := map[string]func(*params.ChainConfig){
+		"frontier": func(c *params.ChainConfig) {
+			c.ByzantiumBlock, c.ConstantinopleBlock, c.PetersburgBlock, c.IstanbulBlock, c.MuirGlacierBlock = nil, nil, nil, nil, nil
+			c.BerlinBlock, c.LondonBlock, c.ArrowGlacierBlock, c.GrayGlacierBlock = nil, nil, nil, nil
+		},
+		"byzantium": func(c *params.ChainConfig) {
+			c.ConstantinopleBlock, c.PetersburgBlock, c.IstanbulBlock, c.MuirGlacierBlock = nil, nil, nil, nil
+			c.BerlinBlock, c.LondonBlock, c.ArrowGlacierBlock, c.GrayGlacierBlock = nil, nil, nil, nil
+		},
+		"london": func(*params.ChainConfig) {},
+	}
+
+	chainID := int64(1339)
+	for name, fork := range forks {
+		t.Run(name, func(t *testing.T) {
+			config := ethashTestChainConfig(chainID)
+			chainID++
+			fork(config)
+
+			chain := newEthashTestChain(t, config, nil, 3, func(i int, b *core.BlockGen) {
+				b.SetCoinbase(miner)
+				// Legacy transactions are valid on every fork
+				tx, err := gethtypes.SignNewTx(testKey, b.Signer(), &gethtypes.LegacyTx{
+					Nonce:    b.TxNonce(testAddr),
+					GasPrice: big.NewInt(2 * params.GWei),
+					Gas:      21_000,
+					To:       &to,
+					Value:    big.NewInt(1),
+				})
+				require.NoError(t, err)
+				b.AddTx(tx)
+				if i == 2 {
+					// Uncle at the height of the parent, mined on the grand parent
+					b.AddUncle(&gethtypes.Header{
+						ParentHash: b.PrevBlock(0).Hash(),
+						Number:     big.NewInt(2),
+						Coinbase:   uncleMiner,
+						GasLimit:   b.PrevBlock(0).GasLimit(),
+					})
+				}
+			})
+			block := chain.block(3)
+			require.Len(t, block.Uncles(), 1)
+			require.NotZero(t, block.Difficulty().Sign())
+
+			data := chain.preflightData(t, 3)
+			require.Len(t, data.Uncles, 1)
+
+			// The coinbase is credited the block reward and the uncle inclusion reward
+			in, err := NewPreparer(WithCoinbaseFeesValidation()).Prepare(context.Background(), data)
+			require.NoError(t, err)
+			require.Len(t, in.Blocks, 1)
+			assert.Equal(t, block.Root(), in.Blocks[0].Header.Root)
+			require.Len(t, in.Blocks[0].Uncles, 1)
+			assert.Equal(t, uncleMiner, in.Blocks[0].Uncles[0].Coinbase)
+
+			// The uncle miner is only credited its uncle reward, so its balance proves the rewards are applied
+			st, _, err := chain.stateAt(big.NewInt(3))
+			require.NoError(t, err)
+			assert.NotZero(t, st.GetBalance(uncleMiner).Sign())
+
+			require.NoError(t, Verify(context.Background(), in))
+
+			// Without its uncles, the block would not produce its post-state
+			data.Uncles = nil
+			_, err = NewPreparer().Prepare(context.Background(), data)
+			var unclesErr *UnclesMismatchError
+			require.ErrorAs(t, err, &unclesErr)
+			assert.Equal(t, 0, unclesErr.Count)
+			assert.Equal(t, block.UncleHash(), unclesErr.Expected)
+		})
+	}
+}
+
+func TestPreparerFrontierMultipleTransactions(t *testing.T) {
+	config := ethashTestChainConfig(1342)
+	config.HomesteadBlock, config.EIP150Block, config.EIP155Block, config.EIP158Block = nil, nil, nil, nil
+	config.ByzantiumBlock, config.ConstantinopleBlock, config.PetersburgBlock, config.IstanbulBlock, config.MuirGlacierBlock = nil, nil, nil, nil, nil
+	config.BerlinBlock, config.LondonBlock, config.ArrowGlacierBlock, config.GrayGlacierBlock = nil, nil, nil, nil
+
+	// The contract stores in its slot 0 the sum of its slot 5 and the balance of 0xbeef, which the second transaction only reads.
+	// Before Byzantium, the witness collected by the execution misses them.
+	contract := gethcommon.HexToAddress("0xc0de")
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	for i := int64(0); i < 16; i++ {
+		storage[gethcommon.BigToHash(big.NewInt(i))] = gethcommon.BigToHash(big.NewInt(i + 1))
+	}
+	alloc := gethtypes.GenesisAlloc{
+		contract:                          {Code: []byte{0x60, 0x05, 0x54, 0x61, 0xbe, 0xef, 0x31, 0x01, 0x60, 0x00, 0x55, 0x00}, Storage: storage},
+		gethcommon.HexToAddress("0xbeef"): {Balance: big.NewInt(7)},
+	}
+	to := gethcommon.HexToAddress("0xdead")
+
+	chain := newEthashTestChain(t, config, alloc, 1, func(_ int, b *core.BlockGen) {
+		for _, tx := range []*gethtypes.LegacyTx{
+			{Nonce: 0, GasPrice: big.NewInt(params.GWei), Gas: 21_000, To: &to, Value: big.NewInt(1)},
+			{Nonce: 1, GasPrice: big.NewInt(params.GWei), Gas: 100_000, To: &contract},
+		} {
+			signed, err := gethtypes.SignNewTx(testKey, b.Signer(), tx)
+			require.NoError(t, err)
+			b.AddTx(signed)
+		}
+	})
+	require.Len(t, chain.block(1).Transactions(), 2)
+
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	require.NoError(t, Verify(context.Background(), in))
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kkrt-labs/go-utils/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPreparerLogger(t *testing.T) {
+	data := newTransferChain(t).preflightData(t, 1)
+
+	// Records going to the global logger would be emitted by a preparer without logger
+	globalCore, globalLogs := observer.New(zapcore.DebugLevel)
+	defer zap.ReplaceGlobals(zap.New(globalCore))()
+
+	t.Run("no-op logger", func(t *testing.T) {
+		_, err := NewPreparer(WithLogger(zap.NewNop())).Prepare(context.Background(), data)
+		require.NoError(t, err)
+		assert.Zero(t, globalLogs.TakeAll())
+	})
+
+	t.Run("injected logger", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		_, err := NewPreparer(WithLogger(zap.New(core))).Prepare(context.Background(), data)
+		require.NoError(t, err)
+		assert.Zero(t, globalLogs.TakeAll())
+
+		for _, msg := range []string{"Process provable inputs preparation...", "Prepare pre-state...", "Execute EVM...", "Provable inputs preparation succeeded"} {
+			assert.Equal(t, 1, logs.FilterMessage(msg).Len(), msg)
+		}
+		assert.Equal(t, "1337", logs.FilterMessage("Execute EVM...").All()[0].ContextMap()["chain.id"])
+	})
+
+	t.Run("context logger", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		ctxCore, ctxLogs := observer.New(zapcore.DebugLevel)
+		ctx := log.WithLogger(context.Background(), zap.New(ctxCore))
+		_, err := NewPreparer(WithLogger(zap.New(core))).Prepare(ctx, data)
+		require.NoError(t, err)
+		assert.Zero(t, logs.Len())
+		assert.NotZero(t, ctxLogs.FilterMessage("Provable inputs preparation succeeded").Len())
+	})
+
+	t.Run("level", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		p := NewPreparer(WithLogger(zap.New(core)), WithLogLevel(zapcore.WarnLevel))
+		_, err := p.Prepare(context.Background(), data)
+		require.NoError(t, err)
+		assert.Zero(t, logs.Len())
+
+		tampered := *data
+		tampered.Ancestors = nil
+		_, err = p.Prepare(context.Background(), &tampered)
+		require.Error(t, err)
+		require.Equal(t, 1, logs.Len())
+		assert.Equal(t, "Provable inputs preparation failed", logs.All()[0].Message)
+	})
+}
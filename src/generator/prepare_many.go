@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"context"
+	"sync"
+
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// PrepareMany prepares the ProverInputs of independent blocks, running at most concurrency preparations at once.
+//
+// Each block is prepared on its own context and database, as by Prepare, so a failing block does not affect the others.
+// It returns the ProverInputs and the errors in the order of inputs. Once ctx is cancelled, the inputs whose preparation
+// has not started are reported with the context error. The progress callback, if set, may be called concurrently.
+func (p *preparer) PrepareMany(ctx context.Context, inputs []*PreflightData, concurrency int) ([]*input.ProverInput, []error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		results = make([]*input.ProverInput, len(inputs))
+		errs    = make([]error, len(inputs))
+		sem     = make(chan struct{}, concurrency)
+		wg      sync.WaitGroup
+	)
+
+	for i, data := range inputs {
+		select {
+		case sem <- struct{}{}:
+			if err := ctx.Err(); err != nil {
+				<-sem
+				errs[i] = err
+				continue
+			}
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, data *PreflightData) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i], errs[i] = p.Prepare(ctx, data)
+		}(i, data)
+	}
+	wg.Wait()
+
+	return results, errs
+}
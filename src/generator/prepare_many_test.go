@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerPrepareMany(t *testing.T) {
+	_, blocks := newRangeTestChain(t)
+	chain, contract := newLargeStorageChain(t)
+	failing := chain.preflightData(t, 1)
+	dropStorageProof(failing, contract, gethcommon.HexToHash("0x05"))
+	inputs := []*PreflightData{blocks[2], blocks[0], failing, blocks[1]}
+
+	// The preparation of the first input, block 3, completes after the other ones
+	var (
+		mu        sync.Mutex
+		assembled []uint64
+		others    = make(chan struct{}, 2)
+	)
+	p := NewPreparer(WithProgress(func(e ProgressEvent) {
+		switch {
+		case e.Stage == ProgressPreflightLoaded && e.BlockNumber == 3:
+			for i := 0; i < 2; i++ {
+				select {
+				case <-others:
+				case <-time.After(10 * time.Second):
+				}
+			}
+		case e.Stage == ProgressInputAssembled:
+			mu.Lock()
+			assembled = append(assembled, e.BlockNumber)
+			mu.Unlock()
+			if e.BlockNumber != 3 {
+				others <- struct{}{}
+			}
+		}
+	}))
+
+	results, errs := p.PrepareMany(context.Background(), inputs, len(inputs))
+	require.Len(t, results, len(inputs))
+	require.Len(t, errs, len(inputs))
+	require.ElementsMatch(t, []uint64{1, 2, 3}, assembled)
+	assert.Equal(t, uint64(3), assembled[2])
+
+	for i, data := range inputs {
+		expected, expectedErr := NewPreparer().Prepare(context.Background(), data)
+		if data == failing {
+			var missing *MissingProofError
+			assert.ErrorAs(t, errs[i], &missing)
+			assert.Nil(t, results[i])
+			continue
+		}
+		require.NoError(t, expectedErr)
+		require.NoError(t, errs[i])
+		equal, diff := input.CompareProverInputWithDiff(expected, results[i])
+		assert.True(t, equal, diff)
+	}
+}
+
+func TestPreparerPrepareManyCancelled(t *testing.T) {
+	_, blocks := newRangeTestChain(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, errs := NewPreparer().PrepareMany(ctx, blocks, 2)
+	for i := range blocks {
+		assert.Nil(t, results[i])
+		assert.ErrorIs(t, errs[i], context.Canceled)
+	}
+}
@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerHistoryStorage(t *testing.T) {
+	pragueConfig := *testChainConfig()
+	config := &pragueConfig
+	config.ChainID = big.NewInt(1339)
+	config.PragueTime = new(uint64)
+
+	// Contract reading the hash of block 1 from the EIP-2935 history contract and storing it in its first slot
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.MSTORE)}
+	code = append(code, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH20))
+	code = append(code, params.HistoryStorageAddress.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP), byte(vm.PUSH1), 0x00, byte(vm.MLOAD), byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP))
+
+	alloc := gethtypes.GenesisAlloc{
+		params.HistoryStorageAddress: {Code: params.HistoryStorageCode, Balance: new(big.Int), Nonce: 1},
+		contract:                     {Code: code, Balance: new(big.Int)},
+	}
+	chain := newTestChain(t, config, alloc, 3, func(i int, b *core.BlockGen) {
+		if i == 2 {
+			b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		}
+	})
+
+	// The history contract served the hash of block 1, stored by the system call of block 2
+	postState, _, err := chain.stateAt(big.NewInt(3))
+	require.NoError(t, err)
+	require.Equal(t, chain.block(1).Hash(), postState.GetState(contract, gethcommon.Hash{}))
+
+	for n := uint64(1); n <= 3; n++ {
+		in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, n))
+		require.NoError(t, err, "block %d", n)
+
+		result, err := NewExecutor().Validate(context.Background(), in)
+		require.NoError(t, err, "block %d", n)
+		assert.True(t, result.Success, "block %d: %v", n, result.Divergence)
+
+		// Every block writes the history contract, the last one also reads it
+		assert.Contains(t, in.Witness.Codes, hexutil.Bytes(params.HistoryStorageCode), "block %d", n)
+	}
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/kkrt-labs/go-utils/log"
+	"github.com/kkrt-labs/go-utils/tag"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/state"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"go.uber.org/zap"
+)
+
+// RangeBlockError is returned when preparing a block of a range fails
+type RangeBlockError struct {
+	Index  int      // Index of the block in the range
+	Number *big.Int // Number of the block
+	Err    error
+}
+
+func (e *RangeBlockError) Error() string {
+	return fmt.Sprintf("block %d of range (number %v) failed: %v", e.Index, e.Number, e.Err)
+}
+
+func (e *RangeBlockError) Unwrap() error {
+	return e.Err
+}
+
+// PrepareRange prepares a single ProverInput for the given consecutive blocks.
+// The blocks share a single witness, each state node and code being included once, that Verify executes the blocks in sequence on.
+func (p *preparer) PrepareRange(ctx context.Context, inputs []*PreflightData) (*input.ProverInput, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no blocks provided")
+	}
+	ctx = prepareTags(p.logContext(ctx), inputs[0])
+	ctx = tag.WithTags(ctx, tag.Key("range.size").Int64(int64(len(inputs))))
+
+	in, err := p.prepareRange(ctx, inputs)
+	if err != nil {
+		log.LoggerFromContext(ctx).Error("Provable inputs range preparation failed", zap.Error(err))
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info("Provable inputs range preparation succeeded")
+
+	return in, nil
+}
+
+func (p *preparer) prepareRange(ctx context.Context, inputs []*PreflightData) (*input.ProverInput, error) {
+	log.LoggerFromContext(ctx).Info("Process provable inputs range preparation...")
+
+	valCtx, err := p.prepareContext(ctx, inputs[0])
+	if err != nil {
+		return nil, prepareError(ErrChainSetup, "failed to prepare validation context", err)
+	}
+	defer p.releaseContext(valCtx)
+
+	execs := make([]*PreparedExecution, 0, len(inputs))
+	for i, data := range inputs {
+		p.reportProgress(ProgressPreflightLoaded, data.block())
+		if err := p.prepareRangeBlock(valCtx, inputs, i); err != nil {
+			return nil, &RangeBlockError{Index: i, Number: data.Block.Number.ToInt(), Err: err}
+		}
+
+		exec, err := p.executeBlock(valCtx, data)
+		if err != nil {
+			return nil, &RangeBlockError{Index: i, Number: data.Block.Number.ToInt(), Err: err}
+		}
+		execs = append(execs, exec)
+
+		// Committing the post-state to the trie database makes it the pre-state of the next block
+		root, err := valCtx.state.Commit(exec.Block.NumberU64(), exec.ChainConfig.IsEIP158(exec.Block.Number()))
+		if err != nil {
+			return nil, &RangeBlockError{Index: i, Number: data.Block.Number.ToInt(), Err: fmt.Errorf("failed to commit post-state: %v", err)}
+		}
+		if root != exec.Block.Root() {
+			return nil, &RangeBlockError{Index: i, Number: data.Block.Number.ToInt(), Err: prepareError(ErrValidationMismatch, "", fmt.Errorf("committed post-state root %v does not match block root %v", root, exec.Block.Root()))}
+		}
+	}
+
+	return p.prepareProverInput(mergeRangeExecutions(execs), execs[1:]...)
+}
+
+// prepareRangeBlock checks the block at index i of the range follows the previous one and validates its header
+func (p *preparer) prepareRangeBlock(valCtx *preparerContext, inputs []*PreflightData, i int) error {
+	data := inputs[i]
+	if i > 0 && data.Block.ParentHash != inputs[i-1].Block.Hash {
+		return fmt.Errorf("block %v does not follow block %v", data.Block.Hash.Hex(), inputs[i-1].Block.Hash.Hex())
+	}
+	if data.ChainConfig.ChainID.Cmp(inputs[0].ChainConfig.ChainID) != 0 {
+		return fmt.Errorf("chain ID %v does not match chain ID %v of the range", data.ChainConfig.ChainID, inputs[0].ChainConfig.ChainID)
+	}
+
+	return p.validateBlock(valCtx.ctx, data)
+}
+
+// mergeRangeExecutions merges the executions of consecutive blocks into the execution of the first block,
+// whose witness holds the nodes and codes of every block of the range that are part of the pre-state of the first block.
+// Nodes created by a block are dropped, as they are recomputed when executing the blocks in sequence.
+// The ancestors are the ones of the first block, extended with the older ancestors accessed by the next blocks.
+func mergeRangeExecutions(execs []*PreparedExecution) *PreparedExecution {
+	first := execs[0]
+	merged := *first
+	merged.Witness = first.Witness.Copy()
+	merged.CodeHashes = append([]gethcommon.Hash{}, first.CodeHashes...)
+	merged.Accesses = append([]state.StateAccess{}, first.Accesses...)
+	merged.AccessReport = nil
+	merged.StateDiff = nil
+
+	for _, exec := range execs[1:] {
+		for code := range exec.Witness.Codes {
+			merged.Witness.Codes[code] = struct{}{}
+		}
+		for node := range exec.Witness.State {
+			merged.Witness.State[node] = struct{}{}
+		}
+		merged.CodeHashes = append(merged.CodeHashes, exec.CodeHashes...)
+		merged.Accesses = append(merged.Accesses, exec.Accesses...)
+
+		var older []*gethtypes.Header
+		for _, header := range exec.Witness.Headers {
+			if header.Number.Cmp(first.Block.Number()) < 0 {
+				older = append(older, header)
+			}
+		}
+		if len(older) > len(merged.Witness.Headers) {
+			merged.Witness.Headers = older
+		}
+	}
+
+	nodes := make([][]byte, 0, len(merged.Witness.State))
+	for node := range merged.Witness.State {
+		nodes = append(nodes, []byte(node))
+	}
+	merged.Witness.State = make(map[string]struct{}, len(nodes))
+	trie.WalkNodes(merged.Witness.Root(), nodes, func(_, _ gethcommon.Hash, node []byte) {
+		merged.Witness.State[string(node)] = struct{}{}
+	})
+
+	return &merged
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRangeTestChain(t *testing.T) (*testChain, []*PreflightData) {
+	// The contract increments its first storage slot, so each block reads the slot written by the previous one
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}}}}
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 3, func(i int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		if i == 1 {
+			b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		}
+	})
+
+	var data []*PreflightData
+	for n := uint64(1); n <= 3; n++ {
+		data = append(data, chain.preflightData(t, n))
+	}
+	return chain, data
+}
+
+func TestPreparerPrepareRange(t *testing.T) {
+	chain, data := newRangeTestChain(t)
+
+	in, err := NewPreparer().PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+
+	require.Len(t, in.Blocks, 3)
+	for i, block := range in.Blocks {
+		assert.Equal(t, chain.block(uint64(i+1)).Hash(), block.Header.Hash())
+	}
+
+	// Ancestors are the ones of the first block only
+	first, err := NewPreparer().Prepare(context.Background(), data[0])
+	require.NoError(t, err)
+	assert.Equal(t, first.Witness.Ancestors, in.Witness.Ancestors)
+
+	// The merged witness covers the pre-state accessed by the first block
+	assert.Subset(t, in.Witness.State, first.Witness.State)
+	_, err = NewExecutor().Execute(context.Background(), &input.ProverInput{
+		ChainConfig: in.ChainConfig,
+		Blocks:      in.Blocks[:1],
+		Witness:     in.Witness,
+	})
+	require.NoError(t, err)
+}
+
+func TestPreparerPrepareRangeFailure(t *testing.T) {
+	_, data := newRangeTestChain(t)
+
+	t.Run("not consecutive", func(t *testing.T) {
+		_, err := NewPreparer().PrepareRange(context.Background(), []*PreflightData{data[0], data[2]})
+		var rangeErr *RangeBlockError
+		require.ErrorAs(t, err, &rangeErr)
+		assert.Equal(t, 1, rangeErr.Index)
+		assert.ErrorContains(t, err, "does not follow")
+	})
+
+	t.Run("execution failure", func(t *testing.T) {
+		data[1].Block.Header.GasUsed++
+		_, err := NewPreparer().PrepareRange(context.Background(), data)
+		var rangeErr *RangeBlockError
+		require.ErrorAs(t, err, &rangeErr)
+		assert.Equal(t, 1, rangeErr.Index)
+		assert.Equal(t, big.NewInt(2), rangeErr.Number)
+	})
+}
+
+func TestPreparerPrepareRangeWitnessDeduplication(t *testing.T) {
+	// Every block calls the same contract and pays the same coinbase, so their witnesses share most nodes and the code
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}}}}
+	chain := newTestChain(t, testChainConfig(), alloc, 10, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	var (
+		data  []*PreflightData
+		naive int
+	)
+	for n := uint64(1); n <= 10; n++ {
+		data = append(data, chain.preflightData(t, n))
+		in, err := NewPreparer().Prepare(context.Background(), data[n-1])
+		require.NoError(t, err)
+		size, err := witnessSize(in.Witness)
+		require.NoError(t, err)
+		naive += size
+	}
+
+	in, err := NewPreparer().PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+	require.Len(t, in.Blocks, 10)
+
+	// The blocks share a single witness holding each node and code once
+	assert.Len(t, dedupByHash(in.Witness.State), len(in.Witness.State))
+	assert.Len(t, dedupByHash(in.Witness.Codes), len(in.Witness.Codes))
+	size, err := witnessSize(in.Witness)
+	require.NoError(t, err)
+	t.Logf("shared witness %d bytes, concatenated witnesses %d bytes", size, naive)
+	assert.Less(t, 3*size, naive)
+
+	require.NoError(t, Verify(context.Background(), in))
+}
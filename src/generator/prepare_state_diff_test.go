@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/kkrt-labs/zk-pig/src/ethereum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateNodes returns every node of the account trie and of the storage tries of the state with the given root
+func stateNodes(t *testing.T, db *triedb.Database, root gethcommon.Hash) [][]byte {
+	var nodes [][]byte
+	collect := func(id *gethtrie.ID, onLeaf func(key, value []byte)) {
+		tr, err := gethtrie.New(id, db)
+		require.NoError(t, err)
+		it, err := tr.NodeIterator(nil)
+		require.NoError(t, err)
+		for it.Next(true) {
+			if it.Leaf() {
+				onLeaf(it.LeafKey(), it.LeafBlob())
+			} else if it.Hash() != (gethcommon.Hash{}) {
+				nodes = append(nodes, it.NodeBlob())
+			}
+		}
+		require.NoError(t, it.Error())
+	}
+
+	collect(gethtrie.StateTrieID(root), func(key, value []byte) {
+		var account gethtypes.StateAccount
+		require.NoError(t, rlp.DecodeBytes(value, &account))
+		if account.Root != gethtypes.EmptyRootHash {
+			collect(gethtrie.StorageTrieID(root, gethcommon.BytesToHash(key), account.Root), func(_, _ []byte) {})
+		}
+	})
+	return nodes
+}
+
+func TestPreparerStateDiff(t *testing.T) {
+	// Contract clearing slot 0x01 and writing slot 0x30
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x30, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+	storage := map[gethcommon.Hash]gethcommon.Hash{
+		gethcommon.BigToHash(big.NewInt(1)): gethcommon.BigToHash(big.NewInt(1)),
+		gethcommon.BigToHash(big.NewInt(2)): gethcommon.BigToHash(big.NewInt(2)),
+	}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: storage}}
+
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		for j := 0; j < 5; j++ {
+			to := gethcommon.BigToAddress(big.NewInt(int64(0x1000 + j)))
+			b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		}
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	in, err := NewPreparer(WithStateDiff()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Empty(t, in.Witness.State)
+	require.NotEmpty(t, in.Witness.StateDiff)
+
+	root := in.Blocks[0].Header.Root
+	hashes := make(map[gethcommon.Hash]struct{}, len(in.Witness.StateDiff))
+	for _, node := range in.Witness.StateDiff {
+		hashes[crypto.Keccak256Hash(node)] = struct{}{}
+	}
+	assert.Contains(t, hashes, root)
+
+	// Adding the diff to the pre-state holds the post-state
+	pre := stateNodes(t, chain.stateDB.TrieDB(), data.Ancestors[0].Root)
+	db := newMemoryStateDatabase(nil)
+	ethereum.WriteNodesToHashDB(db.TrieDB().Disk(), pre...)
+	diff := make([][]byte, 0, len(in.Witness.StateDiff))
+	for _, node := range in.Witness.StateDiff {
+		diff = append(diff, node)
+	}
+	ethereum.WriteNodesToHashDB(db.TrieDB().Disk(), diff...)
+	assert.ElementsMatch(t, stateNodes(t, chain.stateDB.TrieDB(), root), stateNodes(t, db.TrieDB(), root))
+
+	// The diff only holds the nodes created by the block
+	for _, node := range pre {
+		assert.NotContains(t, hashes, crypto.Keccak256Hash(node))
+	}
+}
@@ -1,10 +1,33 @@
 package generator
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
 	"testing"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/evm"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/state"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +52,928 @@ func TestPreparer(t *testing.T) {
 func testDataInputsPath(filename string) string {
 	return "testdata/" + filename
 }
+
+// rehash sets the hash of the block to the one of its tampered header, as if the node served the tampered header,
+// so the block passes the block hash check
+func rehash(data *PreflightData) {
+	data.Block.Hash = data.Block.Block().Hash()
+}
+
+func TestPreparerStorageSlots(t *testing.T) {
+	// Contract writing slots 0x00 and 0x01, and reading slot 0x05
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x02, byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x05, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+
+	chain := newTestChain(t, testChainConfig(), alloc, 2, func(i int, b *core.BlockGen) {
+		if i == 1 {
+			b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		}
+	})
+	data := chain.preflightData(t, 2)
+
+	p := NewPreparer().(*preparer)
+	ctx, err := p.prepareContext(context.Background(), data)
+	require.NoError(t, err)
+	require.NoError(t, p.preparePreState(ctx, data))
+	execParams, err := p.prepareExecParams(ctx, data)
+	require.NoError(t, err)
+	require.NoError(t, p.execute(ctx, execParams))
+
+	slots := ctx.StorageSlots()
+	require.Contains(t, slots, contract)
+	assert.Equal(t, []gethcommon.Hash{gethcommon.HexToHash("0x00"), gethcommon.HexToHash("0x01")}, slots[contract].Written)
+	assert.Equal(t, []gethcommon.Hash{gethcommon.HexToHash("0x05")}, slots[contract].Read)
+}
+
+func TestPreparerAccessReport(t *testing.T) {
+	// Writer contract writing slot 0x00 and reading slot 0x05, reader contract only reading slot 0x01 of its storage
+	writer, reader := gethcommon.HexToAddress("0xc0de"), gethcommon.HexToAddress("0xbeef")
+	writerCode := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x05, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	readerCode := []byte{byte(vm.PUSH1), 0x01, byte(vm.SLOAD), byte(vm.POP), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{
+		writer: {Code: writerCode, Balance: new(big.Int)},
+		reader: {Code: readerCode, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{gethcommon.HexToHash("0x01"): {0x2a}}},
+	}
+
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &writer, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &reader, new(big.Int), 100_000, nil))
+	})
+
+	exec, err := NewPreparer(WithAccessReport()).PrepareExecution(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	require.NotNil(t, exec.AccessReport)
+
+	accounts := make(map[gethcommon.Address]*state.AccountAccess)
+	for _, account := range exec.AccessReport.Accounts {
+		accounts[account.Address] = account
+	}
+
+	require.Contains(t, accounts, writer)
+	assert.True(t, accounts[writer].Exists)
+	assert.True(t, accounts[writer].Written)
+	assert.Equal(t, []gethcommon.Hash{gethcommon.HexToHash("0x00")}, accounts[writer].Storage.Written)
+	assert.Equal(t, []gethcommon.Hash{gethcommon.HexToHash("0x05")}, accounts[writer].Storage.Read)
+
+	require.Contains(t, accounts, reader)
+	assert.False(t, accounts[reader].Written)
+	assert.Empty(t, accounts[reader].Storage.Written)
+	assert.Equal(t, []gethcommon.Hash{gethcommon.HexToHash("0x01")}, accounts[reader].Storage.Read)
+
+	require.Contains(t, accounts, testAddr)
+	assert.True(t, accounts[testAddr].Written, "sender nonce and balance are modified")
+
+	// The report is only built on demand
+	exec, err = NewPreparer().PrepareExecution(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	assert.Nil(t, exec.AccessReport)
+}
+
+func TestPreparerAssembleFromCachedExecution(t *testing.T) {
+	for _, name := range testcases {
+		t.Run(name, func(t *testing.T) {
+			testDataInputs := loadTestDataInputs(t, testDataInputsPath(name))
+			p := NewPreparer()
+			exec, err := p.PrepareExecution(context.Background(), &testDataInputs.PreflightData)
+			require.NoError(t, err)
+
+			first, err := p.AssembleProverInput(exec)
+			require.NoError(t, err)
+			second, err := p.AssembleProverInput(exec)
+			require.NoError(t, err)
+			assert.Equal(t, first, second)
+			assert.True(t, input.CompareProverInput(&testDataInputs.ProverInput, first))
+		})
+	}
+}
+
+func TestPreparerWitnessGroupedByOwner(t *testing.T) {
+	// Contract reading 2 pre-existing storage slots
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 0x02, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{
+		contract: {
+			Code:    code,
+			Balance: new(big.Int),
+			Storage: map[gethcommon.Hash]gethcommon.Hash{
+				gethcommon.HexToHash("0x01"): gethcommon.HexToHash("0x0a"),
+				gethcommon.HexToHash("0x02"): gethcommon.HexToHash("0x0b"),
+			},
+		},
+	}
+
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	result, err := NewPreparer(WithWitnessGroupedByOwner()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	preState, _, err := chain.stateAt(big.NewInt(0))
+	require.NoError(t, err)
+	storageOwner := trie.StorageTrieOwner(contract)
+	require.Len(t, result.Witness.StateByOwner, 2)
+	require.Contains(t, result.Witness.StateByOwner, trie.AccountTrieOwner())
+	require.Contains(t, result.Witness.StateByOwner, storageOwner)
+
+	// Each trie root is grouped under its owner
+	assert.Contains(t, hashes(result.Witness.StateByOwner[trie.AccountTrieOwner()]), chain.genesis.Root())
+	assert.Contains(t, hashes(result.Witness.StateByOwner[storageOwner]), preState.GetStorageRoot(contract))
+	assert.NotContains(t, hashes(result.Witness.StateByOwner[trie.AccountTrieOwner()]), preState.GetStorageRoot(contract))
+
+	// Every witness node is grouped
+	var grouped []hexutil.Bytes
+	for _, nodes := range result.Witness.StateByOwner {
+		grouped = append(grouped, nodes...)
+	}
+	assert.ElementsMatch(t, result.Witness.State, grouped)
+}
+
+func hashes(nodes []hexutil.Bytes) []gethcommon.Hash {
+	res := make([]gethcommon.Hash, 0, len(nodes))
+	for _, node := range nodes {
+		res = append(res, crypto.Keccak256Hash(node))
+	}
+	return res
+}
+
+func TestPreparerGasBreakdown(t *testing.T) {
+	for _, name := range testcases {
+		t.Run(name, func(t *testing.T) {
+			testDataInputs := loadTestDataInputs(t, testDataInputsPath(name))
+			dir := t.TempDir()
+			_, err := NewPreparer(WithGasBreakdownDir(dir)).Prepare(context.Background(), &testDataInputs.PreflightData)
+			require.NoError(t, err)
+
+			b, err := os.ReadFile(gasBreakdownPath(dir, testDataInputs.PreflightData.Block.Number.ToInt().Uint64()))
+			require.NoError(t, err)
+
+			var breakdown evm.GasBreakdown
+			require.NoError(t, json.Unmarshal(b, &breakdown))
+			assert.NotEmpty(t, breakdown.Opcodes)
+			assert.Equal(t, uint64(testDataInputs.PreflightData.Block.GasUsed), breakdown.Sum())
+		})
+	}
+}
+
+func TestPreparerCoinbaseFees(t *testing.T) {
+	coinbase := gethcommon.HexToAddress("0xc014ba5e")
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), nil, 1, func(_ int, b *core.BlockGen) {
+		b.SetCoinbase(coinbase)
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	_, err := NewPreparer(WithCoinbaseFeesValidation()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// Each transfer pays a 1 gwei priority fee
+	postState, _, err := chain.stateAt(big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2*21_000*params.GWei), postState.GetBalance(coinbase).ToBig())
+}
+
+func TestPreparerDepositContractStorage(t *testing.T) {
+	// Beacon deposit contract with a large storage trie, the deposit increments the count at slot 0x20 after reading branch slot 0x05
+	depositContract := gethcommon.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+	code := []byte{
+		byte(vm.PUSH1), 0x05, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x20, byte(vm.SLOAD), byte(vm.ADD), byte(vm.PUSH1), 0x20, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	for i := int64(0); i < 1024; i++ {
+		storage[gethcommon.BigToHash(big.NewInt(i))] = crypto.Keccak256Hash(big.NewInt(i).Bytes())
+	}
+	alloc := gethtypes.GenesisAlloc{depositContract: {Code: code, Balance: new(big.Int), Storage: storage}}
+
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &depositContract, big.NewInt(params.Ether), 100_000, nil))
+	})
+
+	result, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	groups, err := trie.GroupNodesByOwner(chain.genesis.Root(), bytesList(result.Witness.State))
+	require.NoError(t, err)
+	var depositNodes []hexutil.Bytes
+	for _, node := range groups[trie.StorageTrieOwner(depositContract)] {
+		depositNodes = append(depositNodes, node)
+	}
+
+	// Only the proofs of the touched slots are in the witness
+	proof, err := chain.GetProof(context.Background(), depositContract, []string{"0x05", "0x20"}, big.NewInt(0))
+	require.NoError(t, err)
+	expected := make(map[gethcommon.Hash]struct{})
+	for _, slot := range proof.StorageProof {
+		for _, node := range slot.Proof {
+			expected[crypto.Keccak256Hash(hexutil.MustDecode(node))] = struct{}{}
+		}
+	}
+	assert.Len(t, depositNodes, len(expected))
+	for _, hash := range hashes(depositNodes) {
+		assert.Contains(t, expected, hash)
+	}
+}
+
+func bytesList(nodes []hexutil.Bytes) [][]byte {
+	res := make([][]byte, 0, len(nodes))
+	for _, node := range nodes {
+		res = append(res, node)
+	}
+	return res
+}
+
+func TestPreparerStateProofs(t *testing.T) {
+	// Contract reading slot 0x01 and clearing slot 0x02
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x02, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{
+		contract: {
+			Code:    code,
+			Balance: new(big.Int),
+			Storage: map[gethcommon.Hash]gethcommon.Hash{
+				gethcommon.HexToHash("0x01"): gethcommon.HexToHash("0x0a"),
+				gethcommon.HexToHash("0x02"): gethcommon.HexToHash("0x0b"),
+				gethcommon.HexToHash("0x03"): gethcommon.HexToHash("0x0c"),
+			},
+		},
+	}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	result, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Nil(t, result.PreStateProofs)
+	assert.Nil(t, result.PostStateProofs)
+
+	result, err = NewPreparer(WithStateProofs()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.PreStateProofs)
+	require.NotEmpty(t, result.PostStateProofs)
+
+	b, err := json.Marshal(result)
+	require.NoError(t, err)
+	decoded, err := input.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, result.PreStateProofs, decoded.PreStateProofs)
+	assert.Equal(t, result.PostStateProofs, decoded.PostStateProofs)
+
+	for _, proof := range decoded.PreStateProofs {
+		verifyAccountProof(t, chain.genesis.Root(), proof)
+	}
+	for _, proof := range decoded.PostStateProofs {
+		verifyAccountProof(t, chain.block(1).Root(), proof)
+	}
+}
+
+// verifyAccountProof verifies an account proof and its storage proofs against the state root
+func verifyAccountProof(t *testing.T, root gethcommon.Hash, proof *trie.AccountProof) {
+	proofDB := memorydb.New()
+	require.NoError(t, gethtrie.StoreHexProofs(proof.Proof, proofDB))
+	value, err := gethtrie.VerifyProof(root, trie.AccountTrieKey(proof.Address), proofDB)
+	require.NoError(t, err)
+	if value == nil {
+		// Proof of absence
+		return
+	}
+
+	var account gethtypes.StateAccount
+	require.NoError(t, rlp.DecodeBytes(value, &account))
+	assert.Equal(t, proof.StorageHash, account.Root)
+
+	for _, slot := range proof.Storage {
+		storageDB := memorydb.New()
+		require.NoError(t, gethtrie.StoreHexProofs(slot.Proof, storageDB))
+		_, err := gethtrie.VerifyProof(account.Root, trie.StorageTrieKey(gethcommon.HexToHash(slot.Key).Bytes()), storageDB)
+		require.NoError(t, err)
+	}
+}
+
+func TestPreparerNonInclusion(t *testing.T) {
+	absent := gethcommon.HexToAddress("0xab5e47")
+	absentSlot := gethcommon.Hash{31: 0x05}
+
+	// The contract reads the balance of an absent account and an absent slot of its storage
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := append([]byte{byte(vm.PUSH20)}, absent.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP), byte(vm.PUSH1), 0x05, byte(vm.SLOAD), byte(vm.POP), byte(vm.STOP))
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	for i := byte(0); i < 16; i++ {
+		storage[gethcommon.Hash{31: 0x10 + i}] = gethcommon.Hash{31: 0x01}
+	}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: storage}}
+	for i := byte(0); i < 16; i++ {
+		alloc[gethcommon.Address{0xaa, i}] = gethtypes.Account{Balance: big.NewInt(1)}
+	}
+
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	in, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	witnessDB := memorydb.New()
+	for _, node := range in.Witness.State {
+		require.NoError(t, witnessDB.Put(crypto.Keccak256(node), node))
+	}
+
+	// The witness proves the account is absent from the pre-state
+	value, err := gethtrie.VerifyProof(chain.genesis.Root(), trie.AccountTrieKey(absent), witnessDB)
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	// And the slot is absent from the contract storage
+	st, _, err := chain.stateAt(big.NewInt(0))
+	require.NoError(t, err)
+	value, err = gethtrie.VerifyProof(st.GetStorageRoot(contract), trie.StorageTrieKey(absentSlot.Bytes()), witnessDB)
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestPreparerSenders(t *testing.T) {
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	otherAddr := crypto.PubkeyToAddress(otherKey.PublicKey)
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), gethtypes.GenesisAlloc{otherAddr: {Balance: testBalance}}, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, otherKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	result, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Nil(t, result.Blocks[0].Senders)
+
+	result, err = NewPreparer(WithSenders()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, []gethcommon.Address{testAddr, otherAddr, testAddr}, result.Blocks[0].Senders)
+
+	block := chain.block(1)
+	signer := gethtypes.MakeSigner(chain.config, block.Number(), block.Time())
+	for i, tx := range block.Transactions() {
+		sender, err := gethtypes.Sender(signer, tx)
+		require.NoError(t, err)
+		assert.Equal(t, sender, result.Blocks[0].Senders[i])
+	}
+}
+
+func TestPreparerMissingCode(t *testing.T) {
+	// Contract calling a library contract
+	library := gethcommon.HexToAddress("0x11b")
+	libraryCode := []byte{byte(vm.PUSH1), 0x01, byte(vm.POP), byte(vm.STOP)}
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH2), 0x01, 0x1b, byte(vm.GAS), byte(vm.DELEGATECALL), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{
+		contract: {Code: code, Balance: new(big.Int)},
+		library:  {Code: libraryCode, Balance: new(big.Int)},
+	}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	p := NewPreparer()
+	exec, err := p.PrepareExecution(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []gethcommon.Hash{crypto.Keccak256Hash(code), crypto.Keccak256Hash(libraryCode)}, exec.CodeHashes)
+
+	_, err = p.AssembleProverInput(exec)
+	require.NoError(t, err)
+
+	// Drop the library code from the witness
+	delete(exec.Witness.Codes, string(libraryCode))
+	_, err = p.AssembleProverInput(exec)
+	var missing *input.MissingCodeError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, crypto.Keccak256Hash(libraryCode), missing.CodeHash)
+}
+
+// newLargeStorageChain creates a chain whose block calls a contract writing slot 0x00 and reading slot 0x05
+// of a storage large enough for their proofs not to overlap
+func newLargeStorageChain(t testing.TB) (*testChain, gethcommon.Address) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0x02, byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x05, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	for i := int64(0); i < 256; i++ {
+		storage[gethcommon.BigToHash(big.NewInt(i))] = gethcommon.HexToHash("0x01")
+	}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: storage}}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	return chain, contract
+}
+
+// dropStorageProof removes the pre-state proof of a storage slot from data
+func dropStorageProof(data *PreflightData, account gethcommon.Address, slot gethcommon.Hash) {
+	for _, accountProof := range data.PreStateProofs {
+		for i, storageProof := range accountProof.Storage {
+			if accountProof.Address == account && gethcommon.HexToHash(storageProof.Key) == slot {
+				accountProof.Storage = append(accountProof.Storage[:i:i], accountProof.Storage[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func TestPreparerMissingProof(t *testing.T) {
+	chain, contract := newLargeStorageChain(t)
+	data := chain.preflightData(t, 1)
+
+	// Drop the proof of slot 0x05, whose value does not change the execution result
+	slot := gethcommon.HexToHash("0x05")
+	dropStorageProof(data, contract, slot)
+
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	var missing *MissingProofError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, uint64(1), missing.Number)
+	assert.Equal(t, state.StateAccess{Address: contract, Slot: slot, IsSlot: true}, missing.Access)
+	assert.False(t, missing.Provided)
+	assert.Contains(t, err.Error(), fmt.Sprintf("needs storage slot %v of account %v but no proof was provided in PreStateProofs", slot.Hex(), contract.Hex()))
+
+	var missingNode *gethtrie.MissingNodeError
+	assert.ErrorAs(t, err, &missingNode)
+}
+
+func TestPreparerCompactWitness(t *testing.T) {
+	for _, name := range testcases {
+		t.Run(name, func(t *testing.T) {
+			testDataInputs := loadTestDataInputs(t, testDataInputsPath(name))
+			in, err := NewPreparer(WithCompactWitness()).Prepare(context.Background(), &testDataInputs.PreflightData)
+			require.NoError(t, err)
+
+			full := testDataInputs.ProverInput.Witness
+			require.NotEmpty(t, in.Witness.CompactBranches)
+			assert.Equal(t, len(full.State), len(in.Witness.State)+len(in.Witness.CompactBranches))
+
+			expanded, err := input.ExpandWitness(in.Witness)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, hashes(full.State), hashes(expanded.State))
+
+			// Compacted inputs execute without being expanded by the caller
+			_, err = NewExecutor().Execute(context.Background(), in)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPreparerVersionMetadata(t *testing.T) {
+	data := newTransferChain(t).preflightData(t, 1)
+
+	in, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Nil(t, in.Metadata)
+
+	in, err = NewPreparer(WithVersionMetadata("v1.2.3")).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	require.NotNil(t, in.Metadata)
+	assert.Equal(t, "v1.2.3", in.Metadata.ZkPigVersion)
+	assert.Equal(t, input.GethVersion, in.Metadata.GethVersion)
+}
+
+func TestPreparerWitnessScheme(t *testing.T) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.SLOAD), byte(vm.PUSH1), 0x02, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{
+		contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{0x01}: {0x0a}, {0x03}: {0x0b}}},
+	}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	hashIn, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	pathIn, err := NewPreparer(WithWitnessScheme(rawdb.PathScheme)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Empty(t, pathIn.Witness.State)
+	require.NotEmpty(t, pathIn.Witness.StateByPath)
+
+	// Account and storage trie nodes are keyed by their owner and path
+	owners := make(map[gethcommon.Hash]bool)
+	for _, node := range pathIn.Witness.StateByPath {
+		owners[node.Owner] = true
+	}
+	assert.True(t, owners[trie.AccountTrieOwner()])
+	assert.True(t, owners[trie.StorageTrieOwner(contract)])
+
+	// Converting back to the hash scheme gives the hash scheme witness
+	assert.ElementsMatch(t, bytesList(hashIn.Witness.State), trie.ToHashScheme(pathIn.Witness.StateByPath))
+	_, err = NewExecutor().Execute(context.Background(), pathIn)
+	require.NoError(t, err)
+
+	_, err = NewPreparer(WithWitnessScheme("unknown")).Prepare(context.Background(), data)
+	assert.Error(t, err)
+}
+
+func TestPreparerWitnessSchemeDeletions(t *testing.T) {
+	// The contract clears a slot, collapsing its storage trie, and the empty account is deleted once touched
+	contract := gethcommon.HexToAddress("0xc0de")
+	empty := gethcommon.HexToAddress("0xe0")
+	code := []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x01, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{
+		contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{0x01}: {0x0a}, {0x03}: {0x0b}}},
+		empty:    {Balance: new(big.Int)},
+	}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &empty, new(big.Int), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+	require.NotEmpty(t, data.PostStateProofs)
+
+	pathIn, err := NewPreparer(WithWitnessScheme(rawdb.PathScheme)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	require.NotEmpty(t, pathIn.Witness.StateByPath)
+
+	result, err := NewExecutor().Validate(context.Background(), pathIn)
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Divergence)
+
+	// The post-state nodes of the deletions are not reachable from the pre-state root, so they are left out of the path scheme witness
+	hashIn, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	state := append([]hexutil.Bytes{}, hashIn.Witness.State...)
+	for _, proof := range data.PostStateProofs {
+		for _, node := range proof.Proof {
+			state = append(state, hexutil.MustDecode(node))
+		}
+		for _, storage := range proof.Storage {
+			for _, node := range storage.Proof {
+				state = append(state, hexutil.MustDecode(node))
+			}
+		}
+	}
+	preStateRoot := chain.block(0).Root()
+	_, err = trie.ToPathScheme(preStateRoot, bytesList(state))
+	var unowned *trie.UnownedNodesError
+	require.ErrorAs(t, err, &unowned)
+
+	stateByPath, err := witnessToPathScheme(preStateRoot, state)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, bytesList(hashIn.Witness.State), trie.ToHashScheme(stateByPath))
+}
+
+func TestPreparerAlwaysInclude(t *testing.T) {
+	// The contract is never accessed by the block
+	contract := gethcommon.HexToAddress("0xc0de")
+	slot := gethcommon.BigToHash(big.NewInt(1))
+	alloc := gethtypes.GenesisAlloc{
+		contract: {Code: []byte{byte(vm.STOP)}, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{slot: {0x0a}, {0x03}: {0x0b}}},
+	}
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	accounts := gethtypes.AccessList{{Address: contract, StorageKeys: []gethcommon.Hash{slot}}}
+
+	proof, err := chain.GetProof(context.Background(), contract, []string{slot.Hex()}, big.NewInt(0))
+	require.NoError(t, err)
+	nodes := append([]string{}, proof.AccountProof...)
+	nodes = append(nodes, proof.StorageProof[0].Proof...)
+
+	data, err := NewPreflight(chain, WithAlwaysIncludeProofs(accounts)).Preflight(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+
+	in, err := NewPreparer(WithAlwaysInclude(accounts)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	witnessNodes := hashes(in.Witness.State)
+	for _, node := range nodes {
+		assert.Contains(t, witnessNodes, crypto.Keccak256Hash(hexutil.MustDecode(node)))
+	}
+	_, err = NewExecutor().Execute(context.Background(), in)
+	require.NoError(t, err)
+
+	// Without the option, the untouched storage trie is not part of the witness
+	in, err = NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.NotContains(t, hashes(in.Witness.State), crypto.Keccak256Hash(hexutil.MustDecode(proof.StorageProof[0].Proof[0])))
+
+	// Always included accounts must have been proven during preflight
+	_, err = NewPreparer(WithAlwaysInclude(accounts)).Prepare(context.Background(), chain.preflightData(t, 1))
+	assert.ErrorContains(t, err, "missing pre-state proof for always included account")
+}
+
+func TestPreparerReceiptsRootValidation(t *testing.T) {
+	chain := newTransferChain(t)
+	data := chain.preflightData(t, 1)
+
+	_, err := NewPreparer(WithReceiptsRootValidation()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// The block claims receipts diverging from the execution ones
+	expected := data.Block.Header.ReceiptsRoot
+	data.Block.Header.ReceiptsRoot = gethcommon.Hash{0x01}
+	rehash(data)
+	_, err = NewPreparer(WithReceiptsRootValidation()).Prepare(context.Background(), data)
+	var mismatchErr *ReceiptsRootMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, gethcommon.Hash{0x01}, mismatchErr.Expected)
+	assert.Equal(t, expected, mismatchErr.Actual)
+}
+
+func TestPreparerReceiptsDivergence(t *testing.T) {
+	// The second transaction calls a contract emitting a log
+	to, logger := gethcommon.HexToAddress("0xdead"), gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.LOG0), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{logger: {Code: code, Balance: new(big.Int)}}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		b.AddTx(signTx(t, b, testKey, &logger, new(big.Int), 100_000, nil))
+	})
+
+	data := chain.preflightData(t, 1)
+	require.Len(t, data.Receipts, 2)
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	t.Run("receipts root located with preflight receipts", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		data.Block.Header.ReceiptsRoot = gethcommon.Hash{0x01}
+		rehash(data)
+		data.Receipts[1].Status = gethtypes.ReceiptStatusFailed
+
+		_, err := NewPreparer().Prepare(context.Background(), data)
+		var mismatchErr *ReceiptsMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, "receiptsRoot", mismatchErr.Field)
+		assert.Equal(t, 1, mismatchErr.TxIndex)
+		assert.ErrorContains(t, err, "first diverging at tx 1: status: expected 0, got 1")
+
+		var rootErr *ReceiptsRootMismatchError
+		assert.ErrorAs(t, err, &rootErr)
+	})
+
+	t.Run("logs bloom located with header bloom", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		data.Block.Header.LogsBloom = gethtypes.Bloom{}
+		rehash(data)
+		data.Receipts = nil
+
+		_, err := NewPreparer().Prepare(context.Background(), data)
+		var mismatchErr *ReceiptsMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, "logsBloom", mismatchErr.Field)
+		assert.Equal(t, 1, mismatchErr.TxIndex)
+	})
+}
+
+func TestPreparerWithoutExtraDataValidation(t *testing.T) {
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), nil, 1, func(_ int, b *core.BlockGen) {
+		b.SetExtra(make([]byte, 64))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	var tooLongErr *ExtraDataTooLongError
+	require.ErrorAs(t, err, &tooLongErr)
+	assert.Equal(t, 64, tooLongErr.Length)
+
+	in, err := NewPreparer(WithoutExtraDataValidation()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	_, err = NewExecutor().Execute(context.Background(), in)
+	require.NoError(t, err)
+}
+
+func TestPreparerLogs(t *testing.T) {
+	// The contract emits a log with topic 0x01 and data 0xaa
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{
+		byte(vm.PUSH1), 0xaa, byte(vm.PUSH1), 0x00, byte(vm.MSTORE8),
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.LOG1),
+		byte(vm.STOP),
+	}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+
+	in, err := NewPreparer(WithLogs()).Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	res, err := NewExecutor().Execute(context.Background(), in)
+	require.NoError(t, err)
+	require.Len(t, res.Receipts, 2)
+	require.Len(t, res.Receipts[0].Logs, 1)
+	emitted := res.Receipts[0].Logs[0]
+
+	assert.Equal(t, [][]*input.Log{
+		{{Address: emitted.Address, Topics: emitted.Topics, Data: emitted.Data}},
+		{},
+	}, in.Blocks[0].Logs)
+	assert.Equal(t, contract, emitted.Address)
+	assert.Equal(t, []gethcommon.Hash{{31: 0x01}}, emitted.Topics)
+	assert.Equal(t, []byte{0xaa}, emitted.Data)
+}
+
+func TestPreparerWithoutValidation(t *testing.T) {
+	// The contract clears its storage slot, so hashing the post-state requires the proofs of the deleted slot
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}, {0x01}: {0x01}}}}
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	validated, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	unvalidated, err := NewPreparer(WithValidation(false)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	equal, diff := input.CompareProverInputWithDiff(validated, unvalidated)
+	assert.True(t, equal, diff)
+
+	// The block is not checked against its header
+	data.Block.Header.ReceiptsRoot = gethcommon.Hash{0x01}
+	rehash(data)
+	_, err = NewPreparer().Prepare(context.Background(), data)
+	require.Error(t, err)
+	_, err = NewPreparer(WithValidation(false)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+}
+
+func TestPreparerChainIDMismatch(t *testing.T) {
+	data := newTransferChain(t).preflightData(t, 1)
+
+	// The transaction is signed for mainnet while the chain configuration is the test chain one
+	to := gethcommon.HexToAddress("0xdead")
+	tx, err := gethtypes.SignNewTx(testKey, gethtypes.LatestSignerForChainID(big.NewInt(1)), &gethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(params.GWei),
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+	require.NoError(t, err)
+	data.Block.Transactions[0].Transaction = tx
+
+	_, err = NewPreparer().Prepare(context.Background(), data)
+	require.ErrorIs(t, err, ErrChainIDMismatch)
+	var mismatchErr *ChainIDMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, 0, mismatchErr.TxIndex)
+	assert.Equal(t, big.NewInt(1337), mismatchErr.Expected)
+	assert.Equal(t, big.NewInt(1), mismatchErr.Actual)
+}
+
+func TestPreparerReceipts(t *testing.T) {
+	chain := newTransferChain(t)
+	block := chain.block(1)
+
+	// The transfer is eligible to the fast path, which is bypassed to produce the receipts
+	in, err := NewPreparer(WithReceipts(), WithSimpleTransferFastPath()).Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	receipts := in.Blocks[0].Receipts
+	require.Len(t, receipts, 1)
+	assert.Equal(t, block.Transactions()[0].Hash(), receipts[0].TxHash)
+	assert.Equal(t, gethtypes.ReceiptStatusSuccessful, receipts[0].Status)
+	assert.Equal(t, block.GasUsed(), receipts[0].CumulativeGasUsed)
+	assert.Equal(t, block.GasUsed(), receipts[0].GasUsed)
+	assert.Equal(t, block.Bloom(), gethtypes.CreateBloom(receipts))
+	require.NoError(t, ValidateReceiptsRoot(block.Header(), receipts))
+
+	// Receipts are not embedded by default
+	in, err = NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	assert.Nil(t, in.Blocks[0].Receipts)
+}
+
+func TestPreparerPostProcess(t *testing.T) {
+	data := newTransferChain(t).preflightData(t, 1)
+
+	var calls []string
+	label := func(in *input.ProverInput) error {
+		calls = append(calls, "label")
+		in.Metadata = &input.Metadata{ZkPigVersion: "custom-pipeline"}
+		return nil
+	}
+	check := func(in *input.ProverInput) error {
+		calls = append(calls, "check")
+		if in.Metadata == nil {
+			return fmt.Errorf("missing label")
+		}
+		return nil
+	}
+
+	in, err := NewPreparer(WithPostProcess(label), WithPostProcess(check)).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"label", "check"}, calls)
+	require.NotNil(t, in.Metadata)
+	assert.Equal(t, "custom-pipeline", in.Metadata.ZkPigVersion)
+
+	hookErr := errors.New("rejected")
+	_, err = NewPreparer(WithPostProcess(func(*input.ProverInput) error { return hookErr })).Prepare(context.Background(), data)
+	assert.ErrorIs(t, err, hookErr)
+}
+
+func TestPreparerWitnessAccessOrder(t *testing.T) {
+	// The contract reads its storage slots in decreasing order
+	contract := gethcommon.HexToAddress("0xc0de")
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	var code []byte
+	for i := 16; i > 0; i-- {
+		storage[gethcommon.BigToHash(big.NewInt(int64(i)))] = gethcommon.BigToHash(big.NewInt(int64(i)))
+		code = append(code, byte(vm.PUSH1), byte(i), byte(vm.SLOAD), byte(vm.POP))
+	}
+	code = append(code, byte(vm.STOP))
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: storage}}
+	for i := 0; i < 16; i++ {
+		alloc[gethcommon.BytesToAddress(crypto.Keccak256([]byte{byte(i)}))] = gethtypes.Account{Balance: big.NewInt(1)}
+	}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	hashOrdered, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	accessOrdered, err := NewPreparer(WithWitnessAccessOrder()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	again, err := NewPreparer(WithWitnessAccessOrder()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	// The order is deterministic, holds the same nodes as the hash order but differs from it
+	assert.Equal(t, accessOrdered.Witness.State, again.Witness.State)
+	assert.ElementsMatch(t, hashOrdered.Witness.State, accessOrdered.Witness.State)
+	assert.NotEqual(t, hashOrdered.Witness.State, accessOrdered.Witness.State)
+
+	// The pre-state root is on every access path, so it comes first
+	assert.Equal(t, data.Ancestors[0].Root, crypto.Keccak256Hash(accessOrdered.Witness.State[0]))
+
+	_, err = NewExecutor().Execute(context.Background(), accessOrdered)
+	require.NoError(t, err)
+}
+
+func TestPreparerPathDB(t *testing.T) {
+	// The contract increments its first storage slot and deletes its second one
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 1, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int), Storage: map[gethcommon.Hash]gethcommon.Hash{{}: {0x01}, {31: 1}: {0x01}, {31: 2}: {0x01}}}}
+
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	in, err := NewPreparer(WithTrieDBConfig(&triedb.Config{PathDB: &pathdb.Config{}})).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	_, err = NewExecutor().Execute(context.Background(), in)
+	require.NoError(t, err)
+
+	// The witness does not depend on the trie database scheme
+	expected, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	equal, diff := input.CompareProverInputWithDiff(expected, in)
+	assert.True(t, equal, diff)
+}
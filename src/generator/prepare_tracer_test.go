@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerTracer(t *testing.T) {
+	// Contract executing 4 opcodes
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+	})
+
+	var opcodes []vm.OpCode
+	tracer := &tracing.Hooks{
+		OnOpcode: func(_ uint64, op byte, _, _ uint64, _ tracing.OpContext, _ []byte, _ int, _ error) {
+			opcodes = append(opcodes, vm.OpCode(op))
+		},
+	}
+
+	in, err := NewPreparer(WithTracer(tracer)).Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+	require.NotNil(t, in)
+	assert.Equal(t, []vm.OpCode{vm.PUSH1, vm.PUSH1, vm.SSTORE, vm.STOP}, opcodes)
+}
+
+func TestPreparerTracerSimpleTransfer(t *testing.T) {
+	// The fast path does not execute the block so it is disabled with a tracer
+	var txs int
+	tracer := &tracing.Hooks{
+		OnTxStart: func(_ *tracing.VMContext, _ *gethtypes.Transaction, _ gethcommon.Address) { txs++ },
+	}
+
+	_, err := NewPreparer(WithSimpleTransferFastPath(), WithTracer(tracer)).Prepare(context.Background(), newTransferChain(t).preflightData(t, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, txs)
+}
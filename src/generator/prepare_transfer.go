@@ -0,0 +1,97 @@
+package generator
+
+import (
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// prepareSimpleTransfer is a fast path preparing blocks made of a single simple value transfer.
+//
+// For such blocks, every node of the pre-state proofs collected during preflight is accessed,
+// so the witness can be assembled directly from the proofs without tracking the state accesses of a validation execution.
+// The witness is only checked by a stateless execution recomputing the post-state root of the header.
+// It returns false if the block is not eligible or the check fails, in which case the general path must be used.
+func (p *preparer) prepareSimpleTransfer(inputs *PreflightData) (*PreparedExecution, bool) {
+	block := inputs.block()
+	if !isSimpleTransfer(block, inputs.PreStateProofs) {
+		return nil, false
+	}
+
+	witness := &stateless.Witness{
+		Headers: []*gethtypes.Header{inputs.Ancestors[0]},
+		Codes:   make(map[string]struct{}),
+		State:   make(map[string]struct{}),
+	}
+
+	for _, code := range inputs.Codes {
+		witness.Codes[string(code)] = struct{}{}
+	}
+
+	for _, account := range inputs.PreStateProofs {
+		if !addProofNodes(witness, account.Proof) {
+			return nil, false
+		}
+		for _, storage := range account.Storage {
+			if !addProofNodes(witness, storage.Proof) {
+				return nil, false
+			}
+		}
+	}
+
+	if !executesToHeader(inputs.ChainConfig, block, witness) {
+		return nil, false
+	}
+
+	return &PreparedExecution{
+		ChainConfig: inputs.ChainConfig,
+		Block:       block,
+		Witness:     witness,
+
+		PreStateProofs:  inputs.PreStateProofs,
+		PostStateProofs: inputs.PostStateProofs,
+	}, true
+}
+
+// isSimpleTransfer indicates whether the block is made of a single value transfer to an account without code
+func isSimpleTransfer(block *gethtypes.Block, proofs []*trie.AccountProof) bool {
+	if len(block.Transactions()) != 1 || len(block.Withdrawals()) != 0 || block.GasUsed() != params.TxGas {
+		return false
+	}
+
+	tx := block.Transactions()[0]
+	if tx.To() == nil || len(tx.Data()) != 0 || len(tx.AccessList()) != 0 || tx.Type() == gethtypes.BlobTxType {
+		return false
+	}
+
+	for _, proof := range proofs {
+		if proof.Address == *tx.To() {
+			return proof.CodeHash == gethtypes.EmptyCodeHash || proof.CodeHash == (gethcommon.Hash{})
+		}
+	}
+
+	return false
+}
+
+// executesToHeader indicates whether the stateless execution of the block on the witness results in the state and receipts roots of its header
+func executesToHeader(config *params.ChainConfig, block *gethtypes.Block, witness *stateless.Witness) bool {
+	header := block.Header()
+	header.Root, header.ReceiptHash = gethcommon.Hash{}, gethcommon.Hash{} // The stateless execution computes them
+	root, receiptsRoot, err := core.ExecuteStateless(config, block.WithSeal(header), witness)
+	return err == nil && root == block.Root() && receiptsRoot == block.ReceiptHash()
+}
+
+func addProofNodes(witness *stateless.Witness, proof []string) bool {
+	for _, node := range proof {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return false
+		}
+		witness.State[string(blob)] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransferChain(t testing.TB) *testChain {
+	to := gethcommon.HexToAddress("0xdead")
+	return newTestChain(t, testChainConfig(), nil, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+}
+
+func TestPreparerSimpleTransferFastPath(t *testing.T) {
+	data := newTransferChain(t).preflightData(t, 1)
+
+	_, ok := NewPreparer().(*preparer).prepareSimpleTransfer(data)
+	require.True(t, ok, "block should be eligible to fast path")
+
+	general, err := NewPreparer().Prepare(context.Background(), data)
+	require.NoError(t, err)
+	fast, err := NewPreparer(WithSimpleTransferFastPath()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	equal, diff := input.CompareProverInputWithDiff(general, fast)
+	assert.True(t, equal, diff)
+}
+
+func TestPreparerSimpleTransferFastPathFallback(t *testing.T) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	alloc := gethtypes.GenesisAlloc{contract: {Code: []byte{byte(vm.STOP)}, Balance: new(big.Int)}}
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &contract, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	_, ok := NewPreparer().(*preparer).prepareSimpleTransfer(data)
+	assert.False(t, ok, "transfer to a contract should not be eligible to fast path")
+
+	result, err := NewPreparer(WithSimpleTransferFastPath()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Witness.Codes)
+}
+
+func TestPreparerSimpleTransferFastPathPostState(t *testing.T) {
+	data := newTransferChain(t).preflightData(t, 1)
+
+	// The witness assembled from the proofs must recompute the post-state root of the header
+	data.Block.Header.Root = gethcommon.Hash{0x01}
+	rehash(data)
+	_, ok := NewPreparer().(*preparer).prepareSimpleTransfer(data)
+	assert.False(t, ok, "block whose post-state root is not recomputed should not be eligible to fast path")
+
+	// The general path then fails on the post-state of the header
+	_, err := NewPreparer(WithSimpleTransferFastPath()).Prepare(context.Background(), data)
+	assert.Error(t, err)
+}
+
+func BenchmarkPrepareSimpleTransfer(b *testing.B) {
+	data := newTransferChain(b).preflightData(b, 1)
+
+	benchmarks := []struct {
+		name string
+		opts []PreparerOption
+	}{
+		{name: "general"},
+		{name: "fast-path", opts: []PreparerOption{WithSimpleTransferFastPath()}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			p := NewPreparer(bm.opts...)
+			for i := 0; i < b.N; i++ {
+				if _, err := p.Prepare(context.Background(), data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/kkrt-labs/go-utils/log"
+	"github.com/kkrt-labs/go-utils/tag"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/evm"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"go.uber.org/zap"
+)
+
+// PrepareTransaction prepares a ProverInput scoped to the transaction at index txIndex of the block.
+//
+// The pre-block system calls and the preceding transactions are executed to reach the intermediate state,
+// then only the reads and writes of the scoped transaction are collected in the witness.
+// The gas breakdown, coinbase fees validation and state proofs options do not apply to transaction scoped inputs.
+func (p *preparer) PrepareTransaction(ctx context.Context, data *PreflightData, txIndex int) (*input.ProverInput, error) {
+	ctx = tag.WithTags(prepareTags(p.logContext(ctx), data), tag.Key("tx.index").Int64(int64(txIndex)))
+
+	exec, err := p.prepareTransactionExecution(ctx, data, txIndex)
+	if err != nil {
+		log.LoggerFromContext(ctx).Error("Transaction inputs preparation failed", zap.Error(err))
+		return nil, err
+	}
+
+	proverInput, err := p.prepareProverInput(exec)
+	if err != nil {
+		log.LoggerFromContext(ctx).Error("Transaction inputs preparation failed", zap.Error(err))
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info("Transaction inputs preparation succeeded")
+
+	return proverInput, nil
+}
+
+func (p *preparer) prepareTransactionExecution(ctx context.Context, inputs *PreflightData, txIndex int) (*PreparedExecution, error) {
+	block := inputs.block()
+	if txIndex < 0 || txIndex >= len(block.Transactions()) {
+		return nil, fmt.Errorf("transaction index %d out of range, block has %d transactions", txIndex, len(block.Transactions()))
+	}
+
+	if err := p.validateBlock(ctx, inputs); err != nil {
+		return nil, err
+	}
+
+	valCtx, err := p.prepareContext(ctx, inputs)
+	if err != nil {
+		return nil, prepareError(ErrChainSetup, "failed to prepare validation context", err)
+	}
+	defer p.releaseContext(valCtx)
+
+	if err := p.preparePreState(valCtx, inputs); err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "failed to prefill validation database", err)
+	}
+
+	preRoot, err := p.executeTransactionPrefix(valCtx, block, txIndex)
+	if err := transactionExecutionError(valCtx, inputs, fmt.Sprintf("failed to execute transactions preceding transaction %d", txIndex), err); err != nil {
+		return nil, err
+	}
+
+	witness, postRoot, err := p.executeTransaction(valCtx, block, txIndex, preRoot)
+	if err := transactionExecutionError(valCtx, inputs, fmt.Sprintf("failed to execute transaction %d", txIndex), err); err != nil {
+		return nil, err
+	}
+
+	codeHashes, err := p.executedCodeHashes(valCtx, preRoot)
+	if err != nil {
+		return nil, prepareError(ErrIncompleteWitness, "failed to collect executed code hashes", err)
+	}
+
+	return &PreparedExecution{
+		ChainConfig: inputs.ChainConfig,
+		Block: gethtypes.NewBlockWithHeader(block.Header()).WithBody(gethtypes.Body{
+			Transactions: []*gethtypes.Transaction{block.Transactions()[txIndex]},
+		}),
+		Witness:    witness,
+		CodeHashes: codeHashes,
+		Receipts:   valCtx.result.Receipts,
+		TxScope: &input.TransactionScope{
+			Index:         uint64(txIndex),
+			PreStateRoot:  preRoot,
+			PostStateRoot: postRoot,
+			GasUsed:       valCtx.result.GasUsed,
+		},
+	}, nil
+}
+
+// executeTransactionPrefix applies the pre-block system calls and the transactions preceding txIndex on the parent state
+// It commits the resulting intermediate state to the database and returns its root
+func (p *preparer) executeTransactionPrefix(ctx *preparerContext, block *gethtypes.Block, txIndex int) (gethcommon.Hash, error) {
+	log.LoggerFromContext(ctx.ctx).Info("Execute preceding transactions...")
+
+	config := ctx.hc.Config()
+	st, err := gethstate.New(ctx.parentHeader.Root, ctx.stateDB)
+	if err != nil {
+		return gethcommon.Hash{}, prepareError(ErrIncompleteWitness, "", fmt.Errorf("failed to create pre-state from parent root %v: %v", ctx.parentHeader.Root, err))
+	}
+	ctx.state = st
+
+	if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(st)
+	}
+
+	vmenv := vm.NewEVM(core.NewEVMBlockContext(block.Header(), ctx.hc, nil), vm.TxContext{}, st, config, vm.Config{})
+	if beaconRoot := block.BeaconRoot(); beaconRoot != nil {
+		core.ProcessBeaconBlockRoot(*beaconRoot, vmenv, st)
+	}
+	if config.IsPrague(block.Number(), block.Time()) {
+		core.ProcessParentBlockHash(block.ParentHash(), vmenv, st)
+	}
+
+	ctx.gasPool = new(core.GasPool).AddGas(block.GasLimit())
+	ctx.usedGas = 0
+	for i, tx := range block.Transactions()[:txIndex] {
+		if err := ctx.ctx.Err(); err != nil {
+			return gethcommon.Hash{}, fmt.Errorf("execution aborted: %w", err)
+		}
+		if _, err := applyTransaction(ctx, vmenv, st, block, i, tx); err != nil {
+			return gethcommon.Hash{}, err
+		}
+	}
+
+	return st.Commit(block.NumberU64(), config.IsEIP158(block.Number()))
+}
+
+// executeTransaction applies the transaction at index txIndex on the intermediate state with the given root
+// It returns the witness of the state accessed by the transaction, including the nodes necessary to compute the post-state root, and the post-state root
+func (p *preparer) executeTransaction(ctx *preparerContext, block *gethtypes.Block, txIndex int, root gethcommon.Hash) (*stateless.Witness, gethcommon.Hash, error) {
+	log.LoggerFromContext(ctx.ctx).Info("Execute transaction...")
+
+	witness, err := stateless.NewWitness(block.Header(), ctx.hc)
+	if err != nil {
+		return nil, gethcommon.Hash{}, prepareError(ErrIncompleteWitness, "failed to create witness", err)
+	}
+
+	st, err := gethstate.New(root, ctx.stateDB)
+	if err != nil {
+		return nil, gethcommon.Hash{}, prepareError(ErrIncompleteWitness, "", fmt.Errorf("failed to create intermediate state from root %v: %v", root, err))
+	}
+	st.StartPrefetcher("tx", witness)
+	defer st.StopPrefetcher()
+	ctx.state = st
+
+	ctx.executed = make(map[gethcommon.Address]struct{})
+	vmConfig := vm.Config{
+		Tracer: evm.MultiHooks(&tracing.Hooks{
+			OnEnter: func(_ int, _ byte, _, to gethcommon.Address, _ []byte, _ uint64, _ *big.Int) {
+				ctx.executed[to] = struct{}{}
+			},
+		}, p.tracer),
+	}
+
+	vmenv := vm.NewEVM(core.NewEVMBlockContext(block.Header(), ctx.hc, nil), vm.TxContext{}, st, ctx.hc.Config(), vmConfig)
+	receipt, err := applyTransaction(ctx, vmenv, st, block, txIndex, block.Transactions()[txIndex])
+	if err != nil {
+		return nil, gethcommon.Hash{}, err
+	}
+	ctx.result = &core.ProcessResult{
+		Receipts: gethtypes.Receipts{receipt},
+		Logs:     receipt.Logs,
+		GasUsed:  receipt.GasUsed,
+	}
+
+	// Hashing the post-state adds the nodes it requires to the witness
+	postRoot := st.IntermediateRoot(ctx.hc.Config().IsEIP158(block.Number()))
+
+	return st.Witness().Copy(), postRoot, nil
+}
+
+// transactionExecutionError returns the error of the execution of transactions wrapped with its kind, as the block execution errors are.
+// A state read failing does not stop the execution, so missing proofs are reported first, even if the execution succeeded.
+func transactionExecutionError(ctx *preparerContext, inputs *PreflightData, stage string, err error) error {
+	if ctx.state != nil {
+		if missingErr := missingProofError(inputs.block().NumberU64(), ctx.state.Error(), inputs.PreStateProofs); missingErr != nil {
+			return prepareError(ErrIncompleteWitness, stage, missingErr)
+		}
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	// Errors already wrapped with their kind keep it, and an aborted execution says nothing of the block
+	var prepareErr *PrepareError
+	if errors.As(err, &prepareErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%v: %w", stage, err)
+	}
+	return prepareError(ErrValidationMismatch, stage, err)
+}
+
+func applyTransaction(ctx *preparerContext, vmenv *vm.EVM, st *gethstate.StateDB, block *gethtypes.Block, i int, tx *gethtypes.Transaction) (*gethtypes.Receipt, error) {
+	config := ctx.hc.Config()
+	msg, err := core.TransactionToMessage(tx, gethtypes.MakeSigner(config, block.Number(), block.Time()), block.BaseFee())
+	if err != nil {
+		return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+	}
+	st.SetTxContext(tx.Hash(), i)
+
+	receipt, err := core.ApplyTransactionWithEVM(msg, config, ctx.gasPool, st, block.Number(), block.Hash(), tx, &ctx.usedGas, vmenv)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+	}
+
+	return receipt, nil
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/kkrt-labs/zk-pig/src/ethereum"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerPrepareTransaction(t *testing.T) {
+	contract := gethcommon.HexToAddress("0xc0de")
+	code := []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	other := gethcommon.HexToAddress("0x0de")
+	otherCode := []byte{byte(vm.PUSH1), 2, byte(vm.PUSH1), 1, byte(vm.SSTORE), byte(vm.STOP)}
+	alloc := gethtypes.GenesisAlloc{
+		contract: {Code: code, Balance: new(big.Int)},
+		other:    {Code: otherCode, Balance: new(big.Int)},
+	}
+
+	// Only the transactions surrounding the scoped transaction call the other contract
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, testChainConfig(), alloc, 1, func(_ int, b *core.BlockGen) {
+		b.AddTx(signTx(t, b, testKey, &other, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &contract, new(big.Int), 100_000, nil))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	data := chain.preflightData(t, 1)
+
+	p := NewPreparer()
+	full, err := p.Prepare(context.Background(), data)
+	require.NoError(t, err)
+	roots := intermediateRoots(t, full)
+	require.Len(t, roots, 3)
+
+	in, err := p.PrepareTransaction(context.Background(), data, 1)
+	require.NoError(t, err)
+	require.NotNil(t, in.TxScope)
+	assert.Equal(t, uint64(1), in.TxScope.Index)
+	assert.Equal(t, roots[0], in.TxScope.PreStateRoot)
+	assert.Equal(t, roots[1], in.TxScope.PostStateRoot)
+	require.Len(t, in.Blocks[0].Transactions, 1)
+	assert.Equal(t, chain.block(1).Transactions()[1].Hash(), in.Blocks[0].Transactions[0].Hash())
+	assert.Len(t, full.Witness.Codes, 2)
+	assert.Equal(t, []hexutil.Bytes{code}, in.Witness.Codes)
+
+	// Execute the transaction on the witness only
+	st, hc := witnessState(t, in)
+	var usedGas uint64
+	receipt, err := core.ApplyTransaction(
+		in.ChainConfig, hc, nil,
+		new(core.GasPool).AddGas(in.Blocks[0].Header.GasLimit),
+		st, in.Blocks[0].Header, in.Blocks[0].Transactions[0], &usedGas, vm.Config{},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, gethtypes.ReceiptStatusSuccessful, receipt.Status)
+	assert.Equal(t, in.TxScope.GasUsed, receipt.GasUsed)
+	assert.Equal(t, in.TxScope.PostStateRoot, st.IntermediateRoot(true))
+
+	_, err = p.PrepareTransaction(context.Background(), data, 3)
+	assert.Error(t, err)
+}
+
+// witnessState opens the transaction pre-state of a transaction scoped input from its witness only
+func witnessState(t *testing.T, in *input.ProverInput) (*gethstate.StateDB, *core.HeaderChain) {
+	db := rawdb.NewMemoryDatabase()
+	stateDB := gethstate.NewDatabase(triedb.NewDatabase(db, triedb.HashDefaults), nil)
+	hc, err := ethereum.NewChain(in.ChainConfig, stateDB)
+	require.NoError(t, err)
+
+	ethereum.WriteHeaders(db, in.Witness.Ancestors...)
+	ethereum.WriteCodes(db, bytesList(in.Witness.Codes)...)
+	ethereum.WriteNodesToHashDB(db, bytesList(in.Witness.State)...)
+
+	st, err := gethstate.New(in.TxScope.PreStateRoot, stateDB)
+	require.NoError(t, err)
+	return st, hc
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Profile is a named set of preparer options, tuning the witness format for a target chain or prover.
+type Profile struct {
+	Name    string
+	Options []PreparerOption
+}
+
+// DefaultProfile is the profile used for chains without a profile, it applies no option.
+var DefaultProfile = &Profile{Name: "default"}
+
+// Profiles are the available profiles, keyed by name.
+var Profiles = map[string]*Profile{
+	DefaultProfile.Name: DefaultProfile,
+	"grouped-witness": {
+		Name:    "grouped-witness",
+		Options: []PreparerOption{WithWitnessGroupedByOwner()},
+	},
+	"verifiable": {
+		Name:    "verifiable",
+		Options: []PreparerOption{WithStateProofs(), WithSenders()},
+	},
+}
+
+// ChainProfiles selects the profile name used to prepare the blocks of a chain, keyed by chain ID.
+var ChainProfiles = map[string]string{}
+
+// GetProfile returns the profile with the given name.
+func GetProfile(name string) (*Profile, error) {
+	profile, ok := Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	return profile, nil
+}
+
+// ChainProfile returns the profile selected for the chain with the given ID, or DefaultProfile if none is selected.
+func ChainProfile(chainID *big.Int) (*Profile, error) {
+	name, ok := ChainProfiles[chainID.String()]
+	if !ok {
+		return DefaultProfile, nil
+	}
+	return GetProfile(name)
+}
+
+// NewPreparerFromProfile creates a new Preparer with the options of the profile.
+// Additional options are applied after the profile ones.
+func NewPreparerFromProfile(profile *Profile, opts ...PreparerOption) Preparer {
+	return NewPreparer(append(append([]PreparerOption{}, profile.Options...), opts...)...)
+}
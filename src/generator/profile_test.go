@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainProfile(t *testing.T) {
+	chain := newTransferChain(t)
+	data := chain.preflightData(t, 1)
+
+	profile, err := ChainProfile(chain.config.ChainID)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultProfile, profile)
+
+	result, err := NewPreparerFromProfile(profile).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Nil(t, result.Blocks[0].Senders)
+	assert.Nil(t, result.PreStateProofs)
+
+	ChainProfiles[chain.config.ChainID.String()] = "verifiable"
+	t.Cleanup(func() { delete(ChainProfiles, chain.config.ChainID.String()) })
+
+	profile, err = ChainProfile(chain.config.ChainID)
+	require.NoError(t, err)
+	assert.Equal(t, "verifiable", profile.Name)
+
+	result, err = NewPreparerFromProfile(profile, WithWitnessGroupedByOwner()).Prepare(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, testAddr, result.Blocks[0].Senders[0])
+	assert.NotEmpty(t, result.PreStateProofs)
+	assert.NotEmpty(t, result.Witness.StateByOwner)
+
+	ChainProfiles[chain.config.ChainID.String()] = "unknown"
+	_, err = ChainProfile(chain.config.ChainID)
+	assert.Error(t, err)
+}
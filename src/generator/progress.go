@@ -0,0 +1,54 @@
+package generator
+
+import (
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// ProgressStage is a stage of the preparation of a block reported to the progress callback
+type ProgressStage string
+
+const (
+	ProgressPreflightLoaded  ProgressStage = "preflight loaded"  // Preflight data of the block accepted, preparation begins
+	ProgressPreStatePrepared ProgressStage = "prestate prepared" // Pre-state loaded from the preflight proofs
+	ProgressTxExecuting      ProgressStage = "executing tx"      // Transaction Tx of the block starts executing
+	ProgressExecuted         ProgressStage = "executed"          // Block executed and validated
+	ProgressWitnessCollected ProgressStage = "witness collected" // Witness of the execution collected
+	ProgressInputAssembled   ProgressStage = "input assembled"   // ProverInput assembled from the execution
+)
+
+// ProgressEvent is reported to the progress callback as the preparation of a block goes through its stages
+type ProgressEvent struct {
+	Stage       ProgressStage
+	BlockNumber uint64
+	Tx          int // Index of the executing transaction, set for ProgressTxExecuting
+	Txs         int // Number of transactions of the block
+}
+
+// WithProgress makes the preparer call fn as the preparation of a block goes through its stages, in order.
+// Transaction progress is reported from the execution, as each transaction starts.
+// fn is called synchronously, so it should return promptly.
+func WithProgress(fn func(ProgressEvent)) PreparerOption {
+	return func(p *preparer) {
+		p.progress = fn
+	}
+}
+
+func (p *preparer) reportProgress(stage ProgressStage, block *gethtypes.Block) {
+	if p.progress == nil {
+		return
+	}
+	p.progress(ProgressEvent{Stage: stage, BlockNumber: block.NumberU64(), Txs: len(block.Transactions())})
+}
+
+// progressHooks returns the tracing hooks reporting the progress of the transactions of block
+func (p *preparer) progressHooks(block *gethtypes.Block) *tracing.Hooks {
+	tx := 0
+	return &tracing.Hooks{
+		OnTxStart: func(_ *tracing.VMContext, _ *gethtypes.Transaction, _ gethcommon.Address) {
+			p.progress(ProgressEvent{Stage: ProgressTxExecuting, BlockNumber: block.NumberU64(), Tx: tx, Txs: len(block.Transactions())})
+			tx++
+		},
+	}
+}
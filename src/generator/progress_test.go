@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparerProgress(t *testing.T) {
+	chain := newTestChain(t, testChainConfig(), nil, 1, func(_ int, b *core.BlockGen) {
+		for i := 0; i < 3; i++ {
+			to := gethcommon.BigToAddress(big.NewInt(int64(0x1000 + i)))
+			b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+		}
+	})
+	data := chain.preflightData(t, 1)
+
+	var events []ProgressEvent
+	_, err := NewPreparer(WithProgress(func(event ProgressEvent) {
+		events = append(events, event)
+	})).Prepare(context.Background(), data)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ProgressEvent{
+		{Stage: ProgressPreflightLoaded, BlockNumber: 1, Txs: 3},
+		{Stage: ProgressPreStatePrepared, BlockNumber: 1, Txs: 3},
+		{Stage: ProgressTxExecuting, BlockNumber: 1, Tx: 0, Txs: 3},
+		{Stage: ProgressTxExecuting, BlockNumber: 1, Tx: 1, Txs: 3},
+		{Stage: ProgressTxExecuting, BlockNumber: 1, Tx: 2, Txs: 3},
+		{Stage: ProgressExecuted, BlockNumber: 1, Txs: 3},
+		{Stage: ProgressWitnessCollected, BlockNumber: 1, Txs: 3},
+		{Stage: ProgressInputAssembled, BlockNumber: 1, Txs: 3},
+	}, events)
+}
@@ -0,0 +1,160 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// Report records the time and resources used to generate the prover input of a block
+type Report struct {
+	ChainID           string        `json:"chainId"`
+	BlockNumber       uint64        `json:"blockNumber"`
+	PreflightDuration time.Duration `json:"preflightDuration"` // In nanoseconds
+	PrepareDuration   time.Duration `json:"prepareDuration"`   // In nanoseconds
+	PeakMemory        uint64        `json:"peakMemory"`        // Estimate of the peak heap size in bytes, sampled after each step
+	WitnessSize       int           `json:"witnessSize"`       // Size in bytes of the witness state nodes, codes and RLP encoded ancestors
+	RPCCalls          int64         `json:"rpcCalls"`          // Number of RPC calls made during preflight
+}
+
+// ReportSummary aggregates the reports of a range of blocks
+type ReportSummary struct {
+	Blocks            int           `json:"blocks"`
+	PreflightDuration time.Duration `json:"preflightDuration"` // Total, in nanoseconds
+	PrepareDuration   time.Duration `json:"prepareDuration"`   // Total, in nanoseconds
+	PeakMemory        uint64        `json:"peakMemory"`        // Maximum over the blocks
+	WitnessSize       int           `json:"witnessSize"`       // Total
+	RPCCalls          int64         `json:"rpcCalls"`          // Total
+}
+
+// SummarizeReports aggregates the reports of a range of blocks
+func SummarizeReports(reports []*Report) *ReportSummary {
+	summary := &ReportSummary{Blocks: len(reports)}
+	for _, r := range reports {
+		summary.PreflightDuration += r.PreflightDuration
+		summary.PrepareDuration += r.PrepareDuration
+		summary.WitnessSize += r.WitnessSize
+		summary.RPCCalls += r.RPCCalls
+		if r.PeakMemory > summary.PeakMemory {
+			summary.PeakMemory = r.PeakMemory
+		}
+	}
+	return summary
+}
+
+// PrepareWithReport runs the preflight of the block on remote then prepares its prover input,
+// reporting the time and resources used by each step.
+func PrepareWithReport(ctx context.Context, remote ethrpc.Client, preparer Preparer, blockNumber *big.Int, opts ...PreflightOption) (*PreflightData, *input.ProverInput, *Report, error) {
+	counter := &countingClient{Client: remote}
+	report := new(Report)
+
+	start := time.Now()
+	data, err := NewPreflight(counter, opts...).Preflight(ctx, blockNumber)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	report.PreflightDuration = time.Since(start)
+	report.PeakMemory = heapSize()
+
+	start = time.Now()
+	in, err := preparer.Prepare(ctx, data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	report.PrepareDuration = time.Since(start)
+	report.PeakMemory = max(report.PeakMemory, heapSize())
+
+	report.ChainID = data.ChainConfig.ChainID.String()
+	report.BlockNumber = data.Block.Number.ToInt().Uint64()
+	report.RPCCalls = counter.calls.Load()
+	if report.WitnessSize, err = witnessSize(in.Witness); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to compute witness size: %v", err)
+	}
+
+	return data, in, report, nil
+}
+
+func heapSize() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func witnessSize(w *input.Witness) (int, error) {
+	size := 0
+	for _, node := range w.State {
+		size += len(node)
+	}
+	for _, node := range w.CompactBranches {
+		size += len(node)
+	}
+	for _, node := range w.StateByPath {
+		size += len(node.Blob)
+	}
+	for _, node := range w.StateDiff {
+		size += len(node)
+	}
+	for _, code := range w.Codes {
+		size += len(code)
+	}
+	for _, header := range w.Ancestors {
+		enc, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return 0, err
+		}
+		size += len(enc)
+	}
+	return size, nil
+}
+
+// countingClient is an ethrpc.Client counting the calls used by preflight
+type countingClient struct {
+	ethrpc.Client
+
+	calls atomic.Int64
+}
+
+func (c *countingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	c.calls.Add(1)
+	return c.Client.ChainID(ctx)
+}
+
+func (c *countingClient) BlockByNumber(ctx context.Context, number *big.Int) (*gethtypes.Block, error) {
+	c.calls.Add(1)
+	return c.Client.BlockByNumber(ctx, number)
+}
+
+func (c *countingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*gethtypes.Header, error) {
+	c.calls.Add(1)
+	return c.Client.HeaderByNumber(ctx, number)
+}
+
+func (c *countingClient) HeaderByHash(ctx context.Context, hash gethcommon.Hash) (*gethtypes.Header, error) {
+	c.calls.Add(1)
+	return c.Client.HeaderByHash(ctx, hash)
+}
+
+func (c *countingClient) CodeAt(ctx context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	c.calls.Add(1)
+	return c.Client.CodeAt(ctx, account, blockNumber)
+}
+
+func (c *countingClient) StorageAt(ctx context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	c.calls.Add(1)
+	return c.Client.StorageAt(ctx, account, key, blockNumber)
+}
+
+func (c *countingClient) GetProof(ctx context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	c.calls.Add(1)
+	return c.Client.GetProof(ctx, account, keys, blockNumber)
+}
@@ -0,0 +1,34 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareWithReport(t *testing.T) {
+	chain := newTransferChain(t)
+
+	data, in, report, err := PrepareWithReport(context.Background(), chain, NewPreparer(), big.NewInt(1))
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	require.NotNil(t, in)
+
+	assert.Equal(t, "1337", report.ChainID)
+	assert.Equal(t, uint64(1), report.BlockNumber)
+	assert.Positive(t, report.PreflightDuration)
+	assert.Positive(t, report.PrepareDuration)
+	assert.Positive(t, report.PeakMemory)
+	assert.Positive(t, report.WitnessSize)
+	assert.Positive(t, report.RPCCalls)
+
+	summary := SummarizeReports([]*Report{report, report})
+	assert.Equal(t, 2, summary.Blocks)
+	assert.Equal(t, 2*report.PreflightDuration, summary.PreflightDuration)
+	assert.Equal(t, 2*report.WitnessSize, summary.WitnessSize)
+	assert.Equal(t, 2*report.RPCCalls, summary.RPCCalls)
+	assert.Equal(t, report.PeakMemory, summary.PeakMemory)
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kkrt-labs/zk-pig/src/ethereum"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/state"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// StateDiffError is returned when the state diff of a block added to its pre-state does not hold its post-state
+type StateDiffError struct {
+	Number  uint64
+	Address gethcommon.Address
+	Reason  string
+}
+
+func (e *StateDiffError) Error() string {
+	return fmt.Sprintf("state diff of block %d does not hold the post-state of account %v: %v", e.Number, e.Address.Hex(), e.Reason)
+}
+
+// stateDiff commits the post-state of the executed block and returns the trie nodes created by the block,
+// the post-state nodes that are neither in the witness nor in the pre-state proofs.
+// The diff is checked to hold the post-state of the accounts and storage slots of the access report.
+func stateDiff(ctx *preparerContext, block *gethtypes.Block, witness *stateless.Witness, preStateProofs []*trie.AccountProof, report *state.AccessReport) ([][]byte, error) {
+	root, err := ctx.state.Commit(block.NumberU64(), ctx.hc.Config().IsEIP158(block.Number()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit post-state: %v", err)
+	}
+	if root != block.Root() {
+		return nil, fmt.Errorf("post-state root %v does not match block root %v", root.Hex(), block.Root().Hex())
+	}
+
+	reader, err := ctx.stateDB.TrieDB().NodeReader(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open post-state: %v", err)
+	}
+
+	preNodes := make([][]byte, 0, len(witness.State))
+	for node := range witness.State {
+		preNodes = append(preNodes, []byte(node))
+	}
+	for _, accountProof := range preStateProofs {
+		nodes, err := decodeProofNodes(accountProof.Proof)
+		if err != nil {
+			return nil, err
+		}
+		preNodes = append(preNodes, nodes...)
+		for _, storageProof := range accountProof.Storage {
+			nodes, err := decodeProofNodes(storageProof.Proof)
+			if err != nil {
+				return nil, err
+			}
+			preNodes = append(preNodes, nodes...)
+		}
+	}
+	pre := make(map[gethcommon.Hash]struct{}, len(preNodes))
+	for _, node := range preNodes {
+		pre[crypto.Keccak256Hash(node)] = struct{}{}
+	}
+
+	diff := trie.DiffNodes(root, pre, func(owner, hash gethcommon.Hash, path []byte) ([]byte, bool) {
+		node, err := reader.Node(owner, path, hash)
+		return node, err == nil && len(node) > 0
+	})
+
+	post, err := gethstate.New(root, ctx.stateDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open post-state: %v", err)
+	}
+	if err := verifyStateDiff(block.NumberU64(), root, append(preNodes, diff...), post, report); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// verifyStateDiff checks the state with the given root built from the pre-state nodes and the diff
+// holds the post-state of every account and storage slot of the report
+func verifyStateDiff(number uint64, root gethcommon.Hash, nodes [][]byte, post *gethstate.StateDB, report *state.AccessReport) error {
+	db := newMemoryStateDatabase(nil)
+	ethereum.WriteNodesToHashDB(db.TrieDB().Disk(), nodes...)
+	applied, err := gethstate.New(root, db)
+	if err != nil {
+		return fmt.Errorf("state diff of block %d misses the post-state root: %v", number, err)
+	}
+
+	for _, account := range report.Accounts {
+		addr := account.Address
+		reason := ""
+		switch {
+		case applied.Exist(addr) != post.Exist(addr):
+			reason = "existence differs"
+		case applied.GetNonce(addr) != post.GetNonce(addr):
+			reason = "nonce differs"
+		case !applied.GetBalance(addr).Eq(post.GetBalance(addr)):
+			reason = "balance differs"
+		case applied.GetCodeHash(addr) != post.GetCodeHash(addr):
+			reason = "code hash differs"
+		}
+		for _, slot := range append(append([]gethcommon.Hash{}, account.Storage.Read...), account.Storage.Written...) {
+			if reason == "" && applied.GetState(addr, slot) != post.GetState(addr, slot) {
+				reason = fmt.Sprintf("storage slot %v differs", slot.Hex())
+			}
+		}
+		if err := applied.Error(); err != nil {
+			reason = err.Error()
+		}
+		if reason != "" {
+			return &StateDiffError{Number: number, Address: addr, Reason: reason}
+		}
+	}
+
+	return nil
+}
+
+func decodeProofNodes(proof []string) ([][]byte, error) {
+	nodes := make([][]byte, 0, len(proof))
+	for _, node := range proof {
+		b, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode proof node: %v", err)
+		}
+		nodes = append(nodes, b)
+	}
+	return nodes, nil
+}
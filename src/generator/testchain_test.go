@@ -0,0 +1,308 @@
+package generator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	geth "github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/params"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+)
+
+// testChainConfig returns a post-merge chain configuration with every fork up to Cancun activated at genesis.
+func testChainConfig() *params.ChainConfig {
+	cfg := *params.MergedTestChainConfig
+	cfg.ChainID = big.NewInt(1337)
+	cfg.PragueTime = nil
+	return &cfg
+}
+
+// testChain is an in-memory chain generated with go-ethereum chain maker.
+// It implements ethrpc.Client so preflight can run against synthetic blocks without a remote node.
+// Only the methods used by preflight are implemented, calling any other method panics.
+type testChain struct {
+	ethrpc.Client
+
+	config  *params.ChainConfig
+	stateDB gethstate.Database
+	genesis *gethtypes.Block
+	blocks  []*gethtypes.Block
+	headers map[gethcommon.Hash]*gethtypes.Header
+}
+
+// newTestChain generates a chain of n blocks on top of a genesis with the given allocation.
+// The test account is always funded. The configuration is registered in ChainConfigs for the duration of the test,
+// so preflight can resolve it from the chain ID returned by the test chain.
+func newTestChain(t testing.TB, config *params.ChainConfig, alloc gethtypes.GenesisAlloc, n int, gen func(int, *core.BlockGen)) *testChain {
+	if alloc == nil {
+		alloc = gethtypes.GenesisAlloc{}
+	}
+	alloc[testAddr] = gethtypes.Account{Balance: testBalance}
+
+	genesis := &core.Genesis{
+		Config:     config,
+		Alloc:      alloc,
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: new(big.Int),
+	}
+
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, beacon.New(ethash.NewFaker()), n, func(i int, b *core.BlockGen) {
+		b.SetPoS()
+		if config.IsPrague(b.Number(), b.Timestamp()) {
+			processParentBlockHash(b, config, b.PrevBlock(i-1).Hash())
+		}
+		if gen != nil {
+			gen(i, b)
+		}
+	})
+	require.Len(t, blocks, n)
+
+	c := &testChain{
+		config:  config,
+		stateDB: gethstate.NewDatabase(triedb.NewDatabase(db, triedb.HashDefaults), nil),
+		genesis: genesis.ToBlock(),
+		blocks:  blocks,
+		headers: make(map[gethcommon.Hash]*gethtypes.Header),
+	}
+
+	setRegistry(t, ChainConfigs, config.ChainID.String(), config)
+
+	// Preparing a block of the chain commits the registered genesis, whose header is then fetched from the remote
+	registered := registerTestGenesis(t, genesis)
+	c.headers[registered.Hash()] = registered
+	c.headers[c.genesis.Hash()] = c.genesis.Header()
+	for _, block := range blocks {
+		c.headers[block.Hash()] = block.Header()
+	}
+
+	return c
+}
+
+// registerTestGenesis registers for the duration of the test a genesis of the chain holding no state, and returns its header.
+// Preparing then commits a small genesis which can not serve the pre-state of a block in place of its witness.
+func registerTestGenesis(t testing.TB, genesis *core.Genesis) *gethtypes.Header {
+	registered := *genesis
+	registered.Alloc = gethtypes.GenesisAlloc{}
+	setRegistry(t, ChainGeneses, genesis.Config.ChainID.String(), &registered)
+	return registered.ToBlock().Header()
+}
+
+// setRegistry sets the entry of a global registry for the duration of the test, restoring the previous entry on cleanup
+func setRegistry[K comparable, V any](t testing.TB, registry map[K]V, key K, value V) {
+	previous, ok := registry[key]
+	registry[key] = value
+	t.Cleanup(func() {
+		if ok {
+			registry[key] = previous
+		} else {
+			delete(registry, key)
+		}
+	})
+}
+
+// processParentBlockHash runs the EIP-2935 system call storing the parent hash in the history contract,
+// which the go-ethereum block processor runs before the transactions of Prague blocks but the chain maker misses.
+func processParentBlockHash(b *core.BlockGen, config *params.ChainConfig, parentHash gethcommon.Hash) {
+	// The chain maker does not expose the state of the block being generated
+	statedb := (*gethstate.StateDB)(unsafe.Pointer(reflect.ValueOf(b).Elem().FieldByName("statedb").Pointer()))
+	blockCtx := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: b.Number(),
+		Time:        b.Timestamp(),
+		BaseFee:     b.BaseFee(),
+		GasLimit:    b.Gas(),
+		Random:      &gethcommon.Hash{},
+	}
+	core.ProcessParentBlockHash(parentHash, vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, vm.Config{}), statedb)
+}
+
+// block returns the block with the given number
+func (c *testChain) block(number uint64) *gethtypes.Block {
+	if number == 0 {
+		return c.genesis
+	}
+	return c.blocks[number-1]
+}
+
+func (c *testChain) blockAt(blockNumber *big.Int) (*gethtypes.Block, error) {
+	if blockNumber == nil || blockNumber.Sign() < 0 {
+		return c.blocks[len(c.blocks)-1], nil
+	}
+	if blockNumber.Uint64() > uint64(len(c.blocks)) {
+		return nil, geth.NotFound
+	}
+	return c.block(blockNumber.Uint64()), nil
+}
+
+func (c *testChain) stateAt(blockNumber *big.Int) (*gethstate.StateDB, *gethtypes.Block, error) {
+	block, err := c.blockAt(blockNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := gethstate.New(block.Root(), c.stateDB)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, block, nil
+}
+
+// preflightData runs a preflight on the given block of the test chain
+func (c *testChain) preflightData(t testing.TB, number uint64) *PreflightData {
+	data, err := NewPreflight(c).Preflight(context.Background(), new(big.Int).SetUint64(number))
+	require.NoError(t, err)
+	return data
+}
+
+func (c *testChain) ChainID(_ context.Context) (*big.Int, error) {
+	return c.config.ChainID, nil
+}
+
+func (c *testChain) BlockByNumber(_ context.Context, blockNumber *big.Int) (*gethtypes.Block, error) {
+	return c.blockAt(blockNumber)
+}
+
+func (c *testChain) HeaderByNumber(_ context.Context, blockNumber *big.Int) (*gethtypes.Header, error) {
+	block, err := c.blockAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (c *testChain) HeaderByHash(_ context.Context, hash gethcommon.Hash) (*gethtypes.Header, error) {
+	if header, ok := c.headers[hash]; ok {
+		return header, nil
+	}
+	return nil, geth.NotFound
+}
+
+func (c *testChain) CodeAt(_ context.Context, account gethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	st, _, err := c.stateAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetCode(account), nil
+}
+
+func (c *testChain) StorageAt(_ context.Context, account gethcommon.Address, key gethcommon.Hash, blockNumber *big.Int) ([]byte, error) {
+	st, _, err := c.stateAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetState(account, key).Bytes(), nil
+}
+
+// GetProof mimics go-ethereum eth_getProof implementation
+func (c *testChain) GetProof(_ context.Context, account gethcommon.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	st, block, err := c.stateAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	codeHash := st.GetCodeHash(account)
+	if codeHash == (gethcommon.Hash{}) {
+		codeHash = gethtypes.EmptyCodeHash
+	}
+	storageRoot := st.GetStorageRoot(account)
+	if storageRoot == (gethcommon.Hash{}) {
+		storageRoot = gethtypes.EmptyRootHash
+	}
+
+	res := &gethclient.AccountResult{
+		Address:      account,
+		Balance:      st.GetBalance(account).ToBig(),
+		CodeHash:     codeHash,
+		Nonce:        st.GetNonce(account),
+		StorageHash:  storageRoot,
+		StorageProof: make([]gethclient.StorageResult, 0, len(keys)),
+	}
+
+	var storageTrie *gethtrie.StateTrie
+	if storageRoot != gethtypes.EmptyRootHash {
+		id := gethtrie.StorageTrieID(block.Root(), crypto.Keccak256Hash(account.Bytes()), storageRoot)
+		if storageTrie, err = gethtrie.NewStateTrie(id, c.stateDB.TrieDB()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		slot := gethcommon.HexToHash(key)
+		proof := proofList{}
+		if storageTrie != nil {
+			if err := storageTrie.Prove(crypto.Keccak256(slot.Bytes()), &proof); err != nil {
+				return nil, err
+			}
+		}
+		res.StorageProof = append(res.StorageProof, gethclient.StorageResult{
+			Key:   key,
+			Value: st.GetState(account, slot).Big(),
+			Proof: proof,
+		})
+	}
+
+	accountTrie, err := gethtrie.NewStateTrie(gethtrie.StateTrieID(block.Root()), c.stateDB.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	accountProof := proofList{}
+	if err := accountTrie.Prove(crypto.Keccak256(account.Bytes()), &accountProof); err != nil {
+		return nil, err
+	}
+	res.AccountProof = accountProof
+
+	return res, nil
+}
+
+// proofList collects the nodes of a trie proof as hex strings
+type proofList []string
+
+func (l *proofList) Put(_, value []byte) error {
+	*l = append(*l, hexutil.Encode(value))
+	return nil
+}
+
+func (l *proofList) Delete(_ []byte) error {
+	return fmt.Errorf("delete not supported")
+}
+
+// signTx signs a transaction from the test account
+func signTx(t testing.TB, b *core.BlockGen, key *ecdsa.PrivateKey, to *gethcommon.Address, value *big.Int, gas uint64, data []byte) *gethtypes.Transaction {
+	tx, err := gethtypes.SignNewTx(key, b.Signer(), &gethtypes.DynamicFeeTx{
+		ChainID:   b.Signer().ChainID(),
+		Nonce:     b.TxNonce(crypto.PubkeyToAddress(key.PublicKey)),
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: new(big.Int).Add(b.BaseFee(), big.NewInt(2*params.GWei)),
+		Gas:       gas,
+		To:        to,
+		Value:     value,
+		Data:      data,
+	})
+	require.NoError(t, err)
+	return tx
+}
@@ -0,0 +1,671 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// BaseFeeMismatchError is returned when the base fee of a block header does not match the value computed with the EIP-1559 formula
+type BaseFeeMismatchError struct {
+	Number   *big.Int // Number of the block
+	Expected *big.Int // Base fee computed from the parent header
+	Actual   *big.Int // Base fee recorded in the header
+}
+
+func (e *BaseFeeMismatchError) Error() string {
+	return fmt.Sprintf("invalid base fee for block %v: expected %v, got %v", e.Number, e.Expected, e.Actual)
+}
+
+// validateBaseFee checks the base fee of a London+ block header against the EIP-1559 formula applied to its parent
+func validateBaseFee(config *params.ChainConfig, parent, header *gethtypes.Header) error {
+	if !config.IsLondon(header.Number) {
+		return nil
+	}
+
+	if header.BaseFee == nil {
+		return fmt.Errorf("missing base fee for London block %v", header.Number)
+	}
+
+	expected := eip1559.CalcBaseFee(config, parent)
+	if header.BaseFee.Cmp(expected) != 0 {
+		return &BaseFeeMismatchError{
+			Number:   header.Number,
+			Expected: expected,
+			Actual:   header.BaseFee,
+		}
+	}
+
+	return nil
+}
+
+// BlobGasUsedMismatchError is returned when the blob gas used of a block header does not match the blobs of its transactions
+type BlobGasUsedMismatchError struct {
+	Number   *big.Int // Number of the block
+	Expected uint64   // Blob gas used recorded in the header
+	Actual   uint64   // Blob gas of the blobs of the transactions
+}
+
+func (e *BlobGasUsedMismatchError) Error() string {
+	return fmt.Sprintf("invalid blob gas used for block %v: header has %d, transactions use %d", e.Number, e.Expected, e.Actual)
+}
+
+// ValidateBlobGas checks the EIP-4844 fields of a Cancun block: the excess blob gas follows from the parent header,
+// which sets the blob base fee of the execution, and the blob gas used matches the blobs of the transactions.
+// It also checks the parent beacon block root used by the EIP-4788 system call is set.
+func ValidateBlobGas(config *params.ChainConfig, parent *gethtypes.Header, block *gethtypes.Block) error {
+	header := block.Header()
+	if !config.IsCancun(header.Number, header.Time) {
+		return nil
+	}
+
+	if header.ParentBeaconRoot == nil {
+		return fmt.Errorf("missing parent beacon block root for Cancun block %v", header.Number)
+	}
+	if err := eip4844.VerifyEIP4844Header(parent, header); err != nil {
+		return fmt.Errorf("invalid blob gas for block %v: %w", header.Number, err)
+	}
+
+	var blobGas uint64
+	for _, tx := range block.Transactions() {
+		blobGas += tx.BlobGas()
+	}
+	if blobGas != *header.BlobGasUsed {
+		return &BlobGasUsedMismatchError{
+			Number:   header.Number,
+			Expected: *header.BlobGasUsed,
+			Actual:   blobGas,
+		}
+	}
+	return nil
+}
+
+// AncestorsLinkError is returned when the ancestors of a block do not form a contiguous hash-linked chain back from the block
+type AncestorsLinkError struct {
+	Number *big.Int // Number of the block
+	Index  int      // Index of the first ancestor not linked to the block or to the previous ancestor
+	Reason string
+}
+
+func (e *AncestorsLinkError) Error() string {
+	return fmt.Sprintf("invalid ancestors for block %v: ancestor %d %v", e.Number, e.Index, e.Reason)
+}
+
+// ValidateAncestors checks the ancestors of a block are ordered from its parent, by descending number,
+// each one being the parent of the previous one
+func ValidateAncestors(header *gethtypes.Header, ancestors []*gethtypes.Header) error {
+	if len(ancestors) == 0 {
+		return &AncestorsLinkError{Number: header.Number, Index: 0, Reason: "is missing, the parent header is required"}
+	}
+
+	child := header
+	for i, ancestor := range ancestors {
+		if ancestor == nil {
+			return &AncestorsLinkError{Number: header.Number, Index: i, Reason: "is nil"}
+		}
+		if ancestor.Number == nil || new(big.Int).Add(ancestor.Number, big.NewInt(1)).Cmp(child.Number) != 0 {
+			return &AncestorsLinkError{Number: header.Number, Index: i, Reason: fmt.Sprintf("has number %v, expected %v", ancestor.Number, new(big.Int).Sub(child.Number, big.NewInt(1)))}
+		}
+		if hash := ancestor.Hash(); hash != child.ParentHash {
+			return &AncestorsLinkError{Number: header.Number, Index: i, Reason: fmt.Sprintf("has hash %v, expected parent hash %v of block %v", hash.Hex(), child.ParentHash.Hex(), child.Number)}
+		}
+		child = ancestor
+	}
+	return nil
+}
+
+// GasLimitExceededError is returned when the gas used of a block header exceeds its gas limit
+type GasLimitExceededError struct {
+	Number   *big.Int // Number of the block
+	GasUsed  uint64   // Gas used recorded in the header
+	GasLimit uint64   // Gas limit recorded in the header
+}
+
+func (e *GasLimitExceededError) Error() string {
+	return fmt.Sprintf("gas used %d exceeds gas limit %d for block %v", e.GasUsed, e.GasLimit, e.Number)
+}
+
+// ValidateGasLimit checks the gas used of a block header does not exceed its gas limit
+func ValidateGasLimit(header *gethtypes.Header) error {
+	if header.GasUsed > header.GasLimit {
+		return &GasLimitExceededError{
+			Number:   header.Number,
+			GasUsed:  header.GasUsed,
+			GasLimit: header.GasLimit,
+		}
+	}
+	return nil
+}
+
+// ExtraDataTooLongError is returned when the extra-data of a block header exceeds the maximum length
+type ExtraDataTooLongError struct {
+	Number *big.Int // Number of the block
+	Length int      // Length of the extra-data recorded in the header
+}
+
+func (e *ExtraDataTooLongError) Error() string {
+	return fmt.Sprintf("extra-data too long for block %v: %d bytes, maximum is %d", e.Number, e.Length, params.MaximumExtraDataSize)
+}
+
+// ValidateExtraData checks the extra-data of a block header does not exceed the maximum length.
+// Clique headers are skipped, their extra-data also holds the signers and the seal and is checked by the clique engine.
+func ValidateExtraData(config *params.ChainConfig, header *gethtypes.Header) error {
+	if config.Clique != nil {
+		return nil
+	}
+	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+		return &ExtraDataTooLongError{
+			Number: header.Number,
+			Length: len(header.Extra),
+		}
+	}
+	return nil
+}
+
+// UnclesMismatchError is returned when the uncles of a block do not hash to the uncles hash of its header
+type UnclesMismatchError struct {
+	Number   *big.Int        // Number of the block
+	Count    int             // Number of uncles of the block
+	Expected gethcommon.Hash // Uncles hash recorded in the header
+	Actual   gethcommon.Hash // Hash of the uncles
+}
+
+func (e *UnclesMismatchError) Error() string {
+	return fmt.Sprintf("invalid uncles for block %v: %d uncles hash to %v, header has %v", e.Number, e.Count, e.Actual.Hex(), e.Expected.Hex())
+}
+
+// ValidateUncles checks the uncles of a block hash to the uncles hash of its header.
+// On pre-merge chains the uncles are credited rewards, so a block missing them would not produce its post-state.
+func ValidateUncles(block *gethtypes.Block) error {
+	if hash := gethtypes.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return &UnclesMismatchError{
+			Number:   block.Number(),
+			Count:    len(block.Uncles()),
+			Expected: block.UncleHash(),
+			Actual:   hash,
+		}
+	}
+	return nil
+}
+
+// ErrChainIDMismatch is returned when a transaction is signed for another chain than the configured one
+var ErrChainIDMismatch = errors.New("chain ID mismatch")
+
+// ChainIDMismatchError reports a transaction signed for another chain than the configured one
+type ChainIDMismatchError struct {
+	TxIndex  int      // Index of the transaction in the block
+	Expected *big.Int // Chain ID of the chain configuration
+	Actual   *big.Int // Chain ID of the transaction
+}
+
+func (e *ChainIDMismatchError) Error() string {
+	return fmt.Sprintf("%v: transaction %d has chain ID %v, chain configuration has %v", ErrChainIDMismatch, e.TxIndex, e.Actual, e.Expected)
+}
+
+func (e *ChainIDMismatchError) Unwrap() error {
+	return ErrChainIDMismatch
+}
+
+// ValidateTransactionsChainID checks the replay protected transactions of a block are signed for the configured chain,
+// so a misconfigured chain fails with a ChainIDMismatchError rather than on sender recovery
+func ValidateTransactionsChainID(config *params.ChainConfig, block *gethtypes.Block) error {
+	for i, tx := range block.Transactions() {
+		if !tx.Protected() {
+			continue
+		}
+		if tx.ChainId().Cmp(config.ChainID) != 0 {
+			return &ChainIDMismatchError{
+				TxIndex:  i,
+				Expected: config.ChainID,
+				Actual:   tx.ChainId(),
+			}
+		}
+	}
+	return nil
+}
+
+// GasUsedMismatchError is returned when the gas used by the transactions of a block does not match its header
+type GasUsedMismatchError struct {
+	Number   *big.Int // Number of the block
+	Expected uint64   // Gas used recorded in the header
+	Actual   uint64   // Sum of the gas used by the transactions
+}
+
+func (e *GasUsedMismatchError) Error() string {
+	return fmt.Sprintf("invalid gas used for block %v: header has %d, transactions used %d", e.Number, e.Expected, e.Actual)
+}
+
+// ValidateGasUsed checks the sum of the gas used by the transactions matches the gas used of the block header
+// and the cumulative gas used of each receipt
+func ValidateGasUsed(header *gethtypes.Header, receipts gethtypes.Receipts) error {
+	var gasUsed uint64
+	for i, receipt := range receipts {
+		gasUsed += receipt.GasUsed
+		if receipt.CumulativeGasUsed != gasUsed {
+			return fmt.Errorf("invalid cumulative gas used for tx %d of block %v: expected %d, got %d", i, header.Number, gasUsed, receipt.CumulativeGasUsed)
+		}
+	}
+
+	if gasUsed != header.GasUsed {
+		return &GasUsedMismatchError{
+			Number:   header.Number,
+			Expected: header.GasUsed,
+			Actual:   gasUsed,
+		}
+	}
+
+	return nil
+}
+
+// ReceiptsRootMismatchError is returned when the receipts root recomputed from the execution receipts does not match the block header
+type ReceiptsRootMismatchError struct {
+	Number   *big.Int        // Number of the block
+	Expected gethcommon.Hash // Receipts root recorded in the header
+	Actual   gethcommon.Hash // Receipts root recomputed from the receipts
+}
+
+func (e *ReceiptsRootMismatchError) Error() string {
+	return fmt.Sprintf("invalid receipts root for block %v: header has %v, receipts have %v", e.Number, e.Expected.Hex(), e.Actual.Hex())
+}
+
+// ValidateReceiptsRoot recomputes the receipts trie root from the receipts and checks it matches the receipts root of the block header
+func ValidateReceiptsRoot(header *gethtypes.Header, receipts gethtypes.Receipts) error {
+	root := gethtypes.DeriveSha(receipts, gethtrie.NewStackTrie(nil))
+	if root != header.ReceiptHash {
+		return &ReceiptsRootMismatchError{
+			Number:   header.Number,
+			Expected: header.ReceiptHash,
+			Actual:   root,
+		}
+	}
+	return nil
+}
+
+// ReceiptsMismatchError is returned when the execution receipts do not match the receipts root or the logs bloom of the block header
+type ReceiptsMismatchError struct {
+	Number  *big.Int // Number of the block
+	Field   string   // Header field the receipts do not match, receiptsRoot or logsBloom
+	TxIndex int      // Index of the transaction whose receipt first diverges, -1 if it can not be located
+	Reason  string   // How the receipt of the transaction diverges
+	Err     error    // Underlying error, a ReceiptsRootMismatchError for the receipts root
+}
+
+func (e *ReceiptsMismatchError) Error() string {
+	msg := fmt.Sprintf("receipts do not match the %v of block %v", e.Field, e.Number)
+	if e.TxIndex >= 0 {
+		msg += fmt.Sprintf(", first diverging at tx %d: %v", e.TxIndex, e.Reason)
+	}
+	if e.Err != nil {
+		msg += fmt.Sprintf(" (%v)", e.Err)
+	}
+	return msg
+}
+
+func (e *ReceiptsMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateReceipts checks the receipts root and the logs bloom recomputed from the receipts match the block header.
+// On mismatch, it locates the first diverging receipt by comparing with the expected receipts when known,
+// otherwise by looking for the first receipt whose logs are not part of the header logs bloom.
+func ValidateReceipts(header *gethtypes.Header, receipts, expected gethtypes.Receipts) error {
+	mismatch := &ReceiptsMismatchError{Number: header.Number, TxIndex: -1}
+	if err := ValidateReceiptsRoot(header, receipts); err != nil {
+		mismatch.Field, mismatch.Err = "receiptsRoot", err
+	} else if bloom := gethtypes.CreateBloom(receipts); bloom != header.Bloom {
+		mismatch.Field = "logsBloom"
+	} else {
+		return nil
+	}
+
+	if expected != nil {
+		mismatch.TxIndex, mismatch.Reason = firstReceiptDiff(receipts, expected)
+	} else {
+		for i, receipt := range receipts {
+			if new(big.Int).And(receipt.Bloom.Big(), header.Bloom.Big()).Cmp(receipt.Bloom.Big()) != 0 {
+				mismatch.TxIndex, mismatch.Reason = i, "logs are not part of the header logs bloom"
+				break
+			}
+		}
+	}
+	return mismatch
+}
+
+// firstReceiptDiff returns the index of the first receipt differing from the expected one and how it differs, -1 if none differs
+func firstReceiptDiff(receipts, expected gethtypes.Receipts) (index int, reason string) {
+	for i := 0; i < len(receipts) && i < len(expected); i++ {
+		r, e := receipts[i], expected[i]
+		switch {
+		case r.Status != e.Status:
+			return i, fmt.Sprintf("status: expected %d, got %d", e.Status, r.Status)
+		case r.CumulativeGasUsed != e.CumulativeGasUsed:
+			return i, fmt.Sprintf("cumulative gas used: expected %d, got %d", e.CumulativeGasUsed, r.CumulativeGasUsed)
+		case len(r.Logs) != len(e.Logs):
+			return i, fmt.Sprintf("logs: expected %d, got %d", len(e.Logs), len(r.Logs))
+		case r.Bloom != e.Bloom:
+			return i, "logs differ"
+		}
+	}
+	if len(receipts) != len(expected) {
+		return min(len(receipts), len(expected)), fmt.Sprintf("receipts: expected %d, got %d", len(expected), len(receipts))
+	}
+	return -1, ""
+}
+
+// WithdrawalsMismatchError is returned when the withdrawals of a block do not match the expected ones
+type WithdrawalsMismatchError struct {
+	Diffs []string // Differences between the block and the expected withdrawals
+}
+
+func (e *WithdrawalsMismatchError) Error() string {
+	return fmt.Sprintf("withdrawals mismatch:\n%v", strings.Join(e.Diffs, "\n"))
+}
+
+// ValidateWithdrawals validates the withdrawals of a block against an expected list
+// It returns a WithdrawalsMismatchError listing every difference on mismatch
+func ValidateWithdrawals(block *gethtypes.Block, expected gethtypes.Withdrawals) error {
+	actual := block.Withdrawals()
+
+	var diffs []string
+	if len(actual) != len(expected) {
+		diffs = append(diffs, fmt.Sprintf("count: expected %d, got %d", len(expected), len(actual)))
+	}
+
+	for i := 0; i < len(actual) && i < len(expected); i++ {
+		a, e := actual[i], expected[i]
+		if a.Index != e.Index {
+			diffs = append(diffs, fmt.Sprintf("withdrawal %d: index: expected %d, got %d", i, e.Index, a.Index))
+		}
+		if a.Validator != e.Validator {
+			diffs = append(diffs, fmt.Sprintf("withdrawal %d: validator: expected %d, got %d", i, e.Validator, a.Validator))
+		}
+		if a.Address != e.Address {
+			diffs = append(diffs, fmt.Sprintf("withdrawal %d: address: expected %v, got %v", i, e.Address, a.Address))
+		}
+		if a.Amount != e.Amount {
+			diffs = append(diffs, fmt.Sprintf("withdrawal %d: amount: expected %d, got %d", i, e.Amount, a.Amount))
+		}
+	}
+
+	if len(diffs) > 0 {
+		return &WithdrawalsMismatchError{Diffs: diffs}
+	}
+
+	return nil
+}
+
+// HeaderMismatchError is returned when the header of a prover input does not match a trusted header
+type HeaderMismatchError struct {
+	Diffs []string // Differences between the input and the trusted header
+}
+
+func (e *HeaderMismatchError) Error() string {
+	return fmt.Sprintf("header mismatch:\n%v", strings.Join(e.Diffs, "\n"))
+}
+
+// MatchHeader checks the block header embedded in a prover input matches a trusted header field by field,
+// binding the input to a header obtained from a separate source.
+// It returns a HeaderMismatchError listing every differing field on mismatch
+func MatchHeader(in *input.ProverInput, trusted *gethtypes.Header) error {
+	if len(in.Blocks) == 0 || in.Blocks[0].Header == nil {
+		return fmt.Errorf("prover input has no block header")
+	}
+	header := in.Blocks[0].Header
+
+	var diffs []string
+	match := func(field, expected, actual string) {
+		if expected != actual {
+			diffs = append(diffs, fmt.Sprintf("%v: expected %v, got %v", field, expected, actual))
+		}
+	}
+
+	match("parentHash", trusted.ParentHash.Hex(), header.ParentHash.Hex())
+	match("sha3Uncles", trusted.UncleHash.Hex(), header.UncleHash.Hex())
+	match("miner", trusted.Coinbase.Hex(), header.Coinbase.Hex())
+	match("stateRoot", trusted.Root.Hex(), header.Root.Hex())
+	match("transactionsRoot", trusted.TxHash.Hex(), header.TxHash.Hex())
+	match("receiptsRoot", trusted.ReceiptHash.Hex(), header.ReceiptHash.Hex())
+	match("logsBloom", hexutil.Encode(trusted.Bloom[:]), hexutil.Encode(header.Bloom[:]))
+	match("difficulty", bigString(trusted.Difficulty), bigString(header.Difficulty))
+	match("number", bigString(trusted.Number), bigString(header.Number))
+	match("gasLimit", fmt.Sprint(trusted.GasLimit), fmt.Sprint(header.GasLimit))
+	match("gasUsed", fmt.Sprint(trusted.GasUsed), fmt.Sprint(header.GasUsed))
+	match("timestamp", fmt.Sprint(trusted.Time), fmt.Sprint(header.Time))
+	match("extraData", hexutil.Encode(trusted.Extra), hexutil.Encode(header.Extra))
+	match("mixHash", trusted.MixDigest.Hex(), header.MixDigest.Hex())
+	match("nonce", hexutil.Encode(trusted.Nonce[:]), hexutil.Encode(header.Nonce[:]))
+	match("baseFeePerGas", bigString(trusted.BaseFee), bigString(header.BaseFee))
+	match("withdrawalsRoot", hashString(trusted.WithdrawalsHash), hashString(header.WithdrawalsHash))
+	match("blobGasUsed", uint64String(trusted.BlobGasUsed), uint64String(header.BlobGasUsed))
+	match("excessBlobGas", uint64String(trusted.ExcessBlobGas), uint64String(header.ExcessBlobGas))
+	match("parentBeaconBlockRoot", hashString(trusted.ParentBeaconRoot), hashString(header.ParentBeaconRoot))
+	match("requestsRoot", hashString(trusted.RequestsHash), hashString(header.RequestsHash))
+
+	if len(diffs) > 0 {
+		return &HeaderMismatchError{Diffs: diffs}
+	}
+
+	return nil
+}
+
+// bigString, hashString and uint64String format optional header fields, nil fields are formatted as <nil>
+func bigString(v *big.Int) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return v.String()
+}
+
+func hashString(v *gethcommon.Hash) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return v.Hex()
+}
+
+func uint64String(v *uint64) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(*v)
+}
+
+// Block rewards of the pre-merge ethash consensus engine
+var (
+	frontierBlockReward       = big.NewInt(5e18)
+	byzantiumBlockReward      = big.NewInt(3e18)
+	constantinopleBlockReward = big.NewInt(2e18)
+)
+
+// CoinbaseFeesMismatchError is returned when the coinbase balance delta does not match the fees and rewards of the block
+type CoinbaseFeesMismatchError struct {
+	Coinbase gethcommon.Address
+	Expected *big.Int // Sum of priority fees, withdrawals and block rewards credited to the coinbase
+	Actual   *big.Int // Balance delta of the coinbase
+}
+
+func (e *CoinbaseFeesMismatchError) Error() string {
+	return fmt.Sprintf("invalid coinbase %v balance delta: expected %v, got %v", e.Coinbase, e.Expected, e.Actual)
+}
+
+// CoinbaseReward computes the amount credited to the coinbase by a block.
+// It is the sum of the transactions priority fees, the withdrawals to the coinbase and the ethash block rewards pre-merge.
+//
+// It assumes the coinbase neither sends nor receives value through the block transactions.
+func CoinbaseReward(config *params.ChainConfig, block *gethtypes.Block, receipts gethtypes.Receipts) (*big.Int, error) {
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts count %d does not match transactions count %d", len(receipts), len(block.Transactions()))
+	}
+
+	reward := new(big.Int)
+	for i, tx := range block.Transactions() {
+		tip, err := tx.EffectiveGasTip(block.BaseFee())
+		if err != nil {
+			return nil, fmt.Errorf("invalid effective tip for tx %d: %v", i, err)
+		}
+		reward.Add(reward, new(big.Int).Mul(tip, new(big.Int).SetUint64(receipts[i].GasUsed)))
+	}
+
+	for _, w := range block.Withdrawals() {
+		if w.Address == block.Coinbase() {
+			reward.Add(reward, new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(params.GWei)))
+		}
+	}
+
+	// Clique blocks have a non-zero difficulty but no block reward
+	if block.Difficulty().Sign() != 0 && config.Clique == nil {
+		reward.Add(reward, ethashReward(config, block.Header(), block.Uncles()))
+	}
+
+	return reward, nil
+}
+
+// ethashReward computes the block and uncle inclusion rewards credited to the coinbase by the ethash engine
+func ethashReward(config *params.ChainConfig, header *gethtypes.Header, uncles []*gethtypes.Header) *big.Int {
+	blockReward := frontierBlockReward
+	if config.IsByzantium(header.Number) {
+		blockReward = byzantiumBlockReward
+	}
+	if config.IsConstantinople(header.Number) {
+		blockReward = constantinopleBlockReward
+	}
+
+	reward := new(big.Int).Set(blockReward)
+	for _, uncle := range uncles {
+		// Coinbase gets 1/32 of the block reward per uncle
+		reward.Add(reward, new(big.Int).Div(blockReward, big.NewInt(32)))
+
+		// Uncle reward in case the coinbase also mined the uncle
+		if uncle.Coinbase == header.Coinbase {
+			uncleReward := new(big.Int).Add(uncle.Number, big.NewInt(8))
+			uncleReward.Sub(uncleReward, header.Number)
+			uncleReward.Mul(uncleReward, blockReward)
+			uncleReward.Div(uncleReward, big.NewInt(8))
+			reward.Add(reward, uncleReward)
+		}
+	}
+
+	return reward
+}
+
+// ValidateCoinbaseFees validates that the coinbase balance delta matches the fees and rewards of the block
+func ValidateCoinbaseFees(config *params.ChainConfig, block *gethtypes.Block, receipts gethtypes.Receipts, preBalance, postBalance *big.Int) error {
+	expected, err := CoinbaseReward(config, block, receipts)
+	if err != nil {
+		return err
+	}
+
+	actual := new(big.Int).Sub(postBalance, preBalance)
+	if actual.Cmp(expected) != 0 {
+		return &CoinbaseFeesMismatchError{
+			Coinbase: block.Coinbase(),
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+
+	return nil
+}
+
+// BlockHashMismatchError is returned when the header of a block does not hash to the hash of the block
+type BlockHashMismatchError struct {
+	Number   *big.Int        // Number of the block
+	Expected gethcommon.Hash // Hash of the block, as returned by the node
+	Actual   gethcommon.Hash // Hash of the header
+	Diffs    []string        // Header fields differing from the ones recomputed from the parent header and the block body
+}
+
+func (e *BlockHashMismatchError) Error() string {
+	msg := fmt.Sprintf("invalid header for block %v: hash is %v, expected %v", e.Number, e.Actual.Hex(), e.Expected.Hex())
+	if len(e.Diffs) == 0 {
+		return msg + ", no field differs from the recomputed header"
+	}
+	return fmt.Sprintf("%v, fields differing from the recomputed header:\n%v", msg, strings.Join(e.Diffs, "\n"))
+}
+
+// ValidateBlockHash checks the header of a block hashes to the expected block hash, so a header assembled with wrong fields
+// fails before executing the block. A zero expected hash is not checked.
+// On mismatch, it returns a BlockHashMismatchError listing the fields differing from the ones recomputed from the parent header
+// and the block body: the number, parent hash, transactions and withdrawals roots, base fee and blob gas fields.
+func ValidateBlockHash(config *params.ChainConfig, parent *gethtypes.Header, block *gethtypes.Block, expected gethcommon.Hash) error {
+	if expected == (gethcommon.Hash{}) || block.Hash() == expected {
+		return nil
+	}
+
+	header := block.Header()
+	recomputed := recomputeHeader(config, parent, block)
+
+	var diffs []string
+	match := func(field, expected, actual string) {
+		if expected != actual {
+			diffs = append(diffs, fmt.Sprintf("%v: expected %v, got %v", field, expected, actual))
+		}
+	}
+
+	match("number", bigString(recomputed.Number), bigString(header.Number))
+	match("parentHash", recomputed.ParentHash.Hex(), header.ParentHash.Hex())
+	match("transactionsRoot", recomputed.TxHash.Hex(), header.TxHash.Hex())
+	match("baseFeePerGas", bigString(recomputed.BaseFee), bigString(header.BaseFee))
+	match("withdrawalsRoot", hashString(recomputed.WithdrawalsHash), hashString(header.WithdrawalsHash))
+	match("blobGasUsed", uint64String(recomputed.BlobGasUsed), uint64String(header.BlobGasUsed))
+	match("excessBlobGas", uint64String(recomputed.ExcessBlobGas), uint64String(header.ExcessBlobGas))
+	if (recomputed.ParentBeaconRoot == nil) != (header.ParentBeaconRoot == nil) {
+		match("parentBeaconBlockRoot", hashString(recomputed.ParentBeaconRoot), hashString(header.ParentBeaconRoot))
+	}
+
+	return &BlockHashMismatchError{
+		Number:   header.Number,
+		Expected: expected,
+		Actual:   block.Hash(),
+		Diffs:    diffs,
+	}
+}
+
+// recomputeHeader returns a copy of the block header whose fields derived from the parent header and the block body are recomputed.
+// The parent beacon block root can not be recomputed, it is only set or unset depending on the fork.
+func recomputeHeader(config *params.ChainConfig, parent *gethtypes.Header, block *gethtypes.Block) *gethtypes.Header {
+	header := gethtypes.CopyHeader(block.Header())
+	header.Number = new(big.Int).Add(parent.Number, big.NewInt(1))
+	header.ParentHash = parent.Hash()
+	header.TxHash = gethtypes.DeriveSha(block.Transactions(), gethtrie.NewStackTrie(nil))
+
+	header.BaseFee = nil
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, parent)
+	}
+
+	header.WithdrawalsHash = nil
+	if config.IsShanghai(header.Number, header.Time) {
+		withdrawalsHash := gethtypes.DeriveSha(block.Withdrawals(), gethtrie.NewStackTrie(nil))
+		header.WithdrawalsHash = &withdrawalsHash
+	}
+
+	header.BlobGasUsed, header.ExcessBlobGas = nil, nil
+	if config.IsCancun(header.Number, header.Time) {
+		var blobGasUsed, excessBlobGas uint64
+		for _, tx := range block.Transactions() {
+			blobGasUsed += tx.BlobGas()
+		}
+		if parent.ExcessBlobGas != nil && parent.BlobGasUsed != nil {
+			excessBlobGas = eip4844.CalcExcessBlobGas(*parent.ExcessBlobGas, *parent.BlobGasUsed)
+		}
+		header.BlobGasUsed, header.ExcessBlobGas = &blobGasUsed, &excessBlobGas
+		if header.ParentBeaconRoot == nil {
+			header.ParentBeaconRoot = &gethcommon.Hash{}
+		}
+	} else {
+		header.ParentBeaconRoot = nil
+	}
+
+	return header
+}
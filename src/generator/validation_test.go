@@ -0,0 +1,260 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWithdrawals(t *testing.T) {
+	withdrawals := gethtypes.Withdrawals{
+		{Index: 1, Validator: 10, Address: gethcommon.HexToAddress("0xa"), Amount: 100},
+		{Index: 2, Validator: 20, Address: gethcommon.HexToAddress("0xb"), Amount: 200},
+	}
+	block := gethtypes.NewBlockWithHeader(&gethtypes.Header{}).WithBody(gethtypes.Body{Withdrawals: withdrawals})
+
+	t.Run("match", func(t *testing.T) {
+		assert.NoError(t, ValidateWithdrawals(block, withdrawals))
+	})
+
+	t.Run("amount mismatch", func(t *testing.T) {
+		expected := gethtypes.Withdrawals{
+			withdrawals[0],
+			{Index: 2, Validator: 20, Address: gethcommon.HexToAddress("0xb"), Amount: 201},
+		}
+		err := ValidateWithdrawals(block, expected)
+
+		var mismatchErr *WithdrawalsMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, []string{"withdrawal 1: amount: expected 201, got 200"}, mismatchErr.Diffs)
+	})
+
+	t.Run("count mismatch", func(t *testing.T) {
+		var mismatchErr *WithdrawalsMismatchError
+		require.ErrorAs(t, ValidateWithdrawals(block, withdrawals[:1]), &mismatchErr)
+		assert.Equal(t, []string{"count: expected 1, got 2"}, mismatchErr.Diffs)
+	})
+}
+
+func TestValidateCoinbaseFeesMismatch(t *testing.T) {
+	config := testChainConfig()
+	to := gethcommon.HexToAddress("0xdead")
+	chain := newTestChain(t, config, nil, 1, func(_ int, b *core.BlockGen) {
+		b.SetCoinbase(gethcommon.HexToAddress("0xc014ba5e"))
+		b.AddTx(signTx(t, b, testKey, &to, big.NewInt(1), 21_000, nil))
+	})
+	block := chain.block(1)
+	receipts := gethtypes.Receipts{{GasUsed: 21_000}}
+	fees := big.NewInt(21_000 * params.GWei)
+
+	require.NoError(t, ValidateCoinbaseFees(config, block, receipts, big.NewInt(0), fees))
+
+	err := ValidateCoinbaseFees(config, block, receipts, big.NewInt(0), new(big.Int).Add(fees, big.NewInt(1)))
+	var mismatchErr *CoinbaseFeesMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, fees, mismatchErr.Expected)
+	assert.Equal(t, new(big.Int).Add(fees, big.NewInt(1)), mismatchErr.Actual)
+}
+
+func TestPreparerBaseFeeMismatch(t *testing.T) {
+	chain := newTransferChain(t)
+
+	// The base fee of a London block must follow from the gas used and the base fee of its parent
+	data := chain.preflightData(t, 1)
+	expected := data.Block.Header.BaseFee.ToInt()
+	data.Block.Header.BaseFee = (*hexutil.Big)(new(big.Int).Add(expected, big.NewInt(1)))
+	rehash(data)
+
+	_, err := NewPreparer().Prepare(context.Background(), data)
+	var mismatchErr *BaseFeeMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, big.NewInt(1), mismatchErr.Number)
+	assert.Equal(t, expected, mismatchErr.Expected)
+	assert.Equal(t, new(big.Int).Add(expected, big.NewInt(1)), mismatchErr.Actual)
+	assert.Equal(t, eip1559.CalcBaseFee(chain.config, chain.genesis.Header()), mismatchErr.Expected)
+}
+
+func TestValidateGasLimit(t *testing.T) {
+	assert.NoError(t, ValidateGasLimit(&gethtypes.Header{Number: big.NewInt(1), GasUsed: 30_000_000, GasLimit: 30_000_000}))
+
+	err := ValidateGasLimit(&gethtypes.Header{Number: big.NewInt(1), GasUsed: 30_000_001, GasLimit: 30_000_000})
+	var exceededErr *GasLimitExceededError
+	require.ErrorAs(t, err, &exceededErr)
+	assert.Equal(t, uint64(30_000_001), exceededErr.GasUsed)
+	assert.Equal(t, uint64(30_000_000), exceededErr.GasLimit)
+}
+
+func TestValidateExtraData(t *testing.T) {
+	config := testChainConfig()
+	assert.NoError(t, ValidateExtraData(config, &gethtypes.Header{Number: big.NewInt(1), Extra: make([]byte, 32)}))
+
+	err := ValidateExtraData(config, &gethtypes.Header{Number: big.NewInt(1), Extra: make([]byte, 33)})
+	var tooLongErr *ExtraDataTooLongError
+	require.ErrorAs(t, err, &tooLongErr)
+	assert.Equal(t, 33, tooLongErr.Length)
+}
+
+func TestValidateGasUsed(t *testing.T) {
+	receipts := gethtypes.Receipts{
+		{GasUsed: 21_000, CumulativeGasUsed: 21_000},
+		{GasUsed: 50_000, CumulativeGasUsed: 71_000},
+	}
+
+	t.Run("match", func(t *testing.T) {
+		assert.NoError(t, ValidateGasUsed(&gethtypes.Header{Number: big.NewInt(1), GasUsed: 71_000}, receipts))
+	})
+
+	t.Run("header mismatch", func(t *testing.T) {
+		err := ValidateGasUsed(&gethtypes.Header{Number: big.NewInt(1), GasUsed: 70_000}, receipts)
+		var mismatchErr *GasUsedMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, uint64(70_000), mismatchErr.Expected)
+		assert.Equal(t, uint64(71_000), mismatchErr.Actual)
+	})
+
+	t.Run("cumulative mismatch", func(t *testing.T) {
+		invalid := gethtypes.Receipts{receipts[0], {GasUsed: 50_000, CumulativeGasUsed: 50_000}}
+		err := ValidateGasUsed(&gethtypes.Header{Number: big.NewInt(1), GasUsed: 71_000}, invalid)
+		assert.ErrorContains(t, err, "invalid cumulative gas used for tx 1")
+	})
+}
+
+func TestValidateReceiptsRoot(t *testing.T) {
+	receipts := gethtypes.Receipts{
+		{Type: gethtypes.DynamicFeeTxType, Status: gethtypes.ReceiptStatusSuccessful, GasUsed: 21_000, CumulativeGasUsed: 21_000, Logs: []*gethtypes.Log{}},
+		{Type: gethtypes.LegacyTxType, Status: gethtypes.ReceiptStatusSuccessful, GasUsed: 50_000, CumulativeGasUsed: 71_000, Logs: []*gethtypes.Log{}},
+	}
+	header := &gethtypes.Header{Number: big.NewInt(1), ReceiptHash: gethtypes.DeriveSha(receipts, gethtrie.NewStackTrie(nil))}
+
+	t.Run("match", func(t *testing.T) {
+		assert.NoError(t, ValidateReceiptsRoot(header, receipts))
+	})
+
+	t.Run("modified receipt", func(t *testing.T) {
+		modified := gethtypes.Receipts{receipts[0], {Type: gethtypes.LegacyTxType, Status: gethtypes.ReceiptStatusFailed, GasUsed: 50_000, CumulativeGasUsed: 71_000, Logs: []*gethtypes.Log{}}}
+		err := ValidateReceiptsRoot(header, modified)
+		var mismatchErr *ReceiptsRootMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, header.ReceiptHash, mismatchErr.Expected)
+		assert.Equal(t, gethtypes.DeriveSha(modified, gethtrie.NewStackTrie(nil)), mismatchErr.Actual)
+	})
+}
+
+func TestMatchHeader(t *testing.T) {
+	chain := newTransferChain(t)
+	in, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	trusted := chain.block(1).Header()
+	require.NoError(t, MatchHeader(in, trusted))
+
+	tampered := gethtypes.CopyHeader(trusted)
+	tampered.ReceiptHash = gethcommon.HexToHash("0x01")
+	tampered.GasUsed++
+
+	var mismatchErr *HeaderMismatchError
+	require.ErrorAs(t, MatchHeader(in, tampered), &mismatchErr)
+	require.Len(t, mismatchErr.Diffs, 2)
+	assert.Contains(t, mismatchErr.Diffs[0], "receiptsRoot")
+	assert.Contains(t, mismatchErr.Diffs[1], "gasUsed")
+}
+
+func TestValidateAncestors(t *testing.T) {
+	chain := newTestChain(t, testChainConfig(), nil, 4, func(int, *core.BlockGen) {})
+	headers := make([]*gethtypes.Header, 0, len(chain.blocks))
+	for _, block := range chain.blocks {
+		headers = append(headers, block.Header())
+	}
+	header := headers[3]
+
+	assert.NoError(t, ValidateAncestors(header, []*gethtypes.Header{headers[2], headers[1], headers[0]}))
+
+	for _, tt := range []struct {
+		name      string
+		ancestors []*gethtypes.Header
+		index     int
+	}{
+		{name: "missing", ancestors: nil, index: 0},
+		{name: "reordered", ancestors: []*gethtypes.Header{headers[1], headers[2], headers[0]}, index: 0},
+		{name: "gap", ancestors: []*gethtypes.Header{headers[2], headers[0]}, index: 1},
+		{name: "broken hash link", ancestors: []*gethtypes.Header{headers[2], {Number: headers[1].Number}}, index: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAncestors(header, tt.ancestors)
+			var linkErr *AncestorsLinkError
+			require.ErrorAs(t, err, &linkErr)
+			assert.Equal(t, tt.index, linkErr.Index)
+			assert.Equal(t, header.Number, linkErr.Number)
+		})
+	}
+
+	t.Run("prepare", func(t *testing.T) {
+		data := chain.preflightData(t, 4)
+		data.Ancestors = []*gethtypes.Header{headers[1], headers[2]}
+		_, err := NewPreparer().Prepare(context.Background(), data)
+		var linkErr *AncestorsLinkError
+		require.ErrorAs(t, err, &linkErr)
+		assert.Equal(t, 0, linkErr.Index)
+	})
+}
+
+func TestValidateBlockHash(t *testing.T) {
+	chain := newTransferChain(t)
+
+	t.Run("match", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		assert.NoError(t, ValidateBlockHash(data.ChainConfig, data.Ancestors[0], data.Block.Block(), data.Block.Hash))
+	})
+
+	t.Run("base fee", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		baseFee := data.Block.Header.BaseFee.ToInt()
+		data.Block.Header.BaseFee = (*hexutil.Big)(new(big.Int).Add(baseFee, big.NewInt(1)))
+
+		// The mismatch is reported before the block is executed
+		_, err := NewPreparer().Prepare(context.Background(), data)
+		var mismatchErr *BlockHashMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, chain.block(1).Hash(), mismatchErr.Expected)
+		assert.Equal(t, data.Block.Block().Hash(), mismatchErr.Actual)
+		assert.Equal(t, []string{fmt.Sprintf("baseFeePerGas: expected %v, got %v", baseFee, new(big.Int).Add(baseFee, big.NewInt(1)))}, mismatchErr.Diffs)
+	})
+
+	t.Run("missing withdrawals root", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		data.Block.Header.WithdrawalsRoot = nil
+
+		err := ValidateBlockHash(data.ChainConfig, data.Ancestors[0], data.Block.Block(), data.Block.Hash)
+		var mismatchErr *BlockHashMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, []string{fmt.Sprintf("withdrawalsRoot: expected %v, got <nil>", gethtypes.EmptyWithdrawalsHash.Hex())}, mismatchErr.Diffs)
+	})
+
+	t.Run("field not recomputed", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		data.Block.Header.GasLimit++
+
+		err := ValidateBlockHash(data.ChainConfig, data.Ancestors[0], data.Block.Block(), data.Block.Hash)
+		var mismatchErr *BlockHashMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Empty(t, mismatchErr.Diffs)
+		assert.ErrorContains(t, err, "no field differs from the recomputed header")
+	})
+
+	t.Run("no expected hash", func(t *testing.T) {
+		data := chain.preflightData(t, 1)
+		data.Block.Header.GasLimit++
+		assert.NoError(t, ValidateBlockHash(data.ChainConfig, data.Ancestors[0], data.Block.Block(), gethcommon.Hash{}))
+	})
+}
@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/kkrt-labs/zk-pig/src/ethereum"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/evm"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// Verify checks the ProverInput is self-consistent, independently of the chain it has been prepared from.
+//
+// The state database is rebuilt from the witness, then the blocks are executed in sequence with validation,
+// each block on the post-state of the previous one. It returns a RangeBlockError for the first block whose execution fails,
+// or whose post-state root, gas used, receipts root or embedded receipts do not match its header.
+// Transaction scoped inputs are not supported.
+func Verify(ctx context.Context, in *input.ProverInput) error {
+	if len(in.Blocks) == 0 {
+		return fmt.Errorf("no blocks provided")
+	}
+	if in.TxScope != nil {
+		return fmt.Errorf("verification of transaction scoped inputs is not supported")
+	}
+
+	e := &executor{}
+	execCtx, err := e.prepareContext(ctx, in)
+	if err != nil {
+		return fmt.Errorf("failed to prepare execution context: %v", err)
+	}
+	if err := e.preparePreState(execCtx, in); err != nil {
+		return fmt.Errorf("failed to prepare pre-state: %v", err)
+	}
+	execParams, err := e.prepareExecParams(execCtx, in)
+	if err != nil {
+		return fmt.Errorf("failed to prepare execution exec params: %v", err)
+	}
+
+	for i, block := range in.Blocks {
+		if i > 0 {
+			if execParams, err = nextExecParams(execCtx, in.Blocks[i-1], block); err != nil {
+				return &RangeBlockError{Index: i, Number: block.Header.Number, Err: err}
+			}
+		}
+
+		if _, err := e.execEVM(execCtx, execParams); err != nil {
+			return &RangeBlockError{Index: i, Number: block.Header.Number, Err: err}
+		}
+		if block.Receipts != nil {
+			// Validation checked the computed receipts against the header, so embedded receipts must match it too
+			if err := ValidateReceiptsRoot(block.Header, block.Receipts); err != nil {
+				return &RangeBlockError{Index: i, Number: block.Header.Number, Err: fmt.Errorf("embedded receipts: %w", err)}
+			}
+		}
+
+		if i < len(in.Blocks)-1 {
+			// Committing the post-state makes it the pre-state of the next block
+			if _, err := execParams.State.Commit(block.Header.Number.Uint64(), in.ChainConfig.IsEIP158(block.Header.Number)); err != nil {
+				return &RangeBlockError{Index: i, Number: block.Header.Number, Err: fmt.Errorf("failed to commit post-state: %v", err)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// nextExecParams prepares the execution of block on the committed post-state of its parent, the previous block of the input
+func nextExecParams(ctx *executorContext, parent, block *input.Block) (*evm.ExecParams, error) {
+	if block.Header.ParentHash != parent.Header.Hash() {
+		return nil, fmt.Errorf("block %v does not follow block %v", block.Header.Hash().Hex(), parent.Header.Hash().Hex())
+	}
+	ethereum.WriteHeaders(ctx.stateDB.TrieDB().Disk(), parent.Header)
+
+	st, err := gethstate.New(parent.Header.Root, ctx.stateDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pre-state from parent root %v: %v", parent.Header.Root, err)
+	}
+
+	return &evm.ExecParams{
+		VMConfig: &vm.Config{
+			StatelessSelfValidation: true,
+		},
+		Block:    block.Block(),
+		Validate: true,
+		Chain:    ctx.hc,
+		State:    st,
+	}, nil
+}
+
+// VerifyResult is the outcome of the validation of a ProverInput of a batch.
+type VerifyResult struct {
+	Result *ValidationResult // Validation outcome, nil if the validation could not run
+	Err    error             // Set if the validation could not run, or was not started because the context was cancelled
+}
+
+// Success indicates whether the input has been validated successfully
+func (r *VerifyResult) Success() bool {
+	return r.Err == nil && r.Result != nil && r.Result.Success
+}
+
+// VerifyBatch validates a batch of ProverInputs, running at most concurrency validations at once.
+//
+// It returns the results in the order of inputs, and whether every input has been validated successfully.
+// Once ctx is cancelled, the inputs whose validation has not started are reported with the context error.
+func VerifyBatch(ctx context.Context, inputs []*input.ProverInput, concurrency int) ([]*VerifyResult, bool) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		results = make([]*VerifyResult, len(inputs))
+		sem     = make(chan struct{}, concurrency)
+		wg      sync.WaitGroup
+		e       = NewExecutor()
+	)
+
+	for i, in := range inputs {
+		select {
+		case sem <- struct{}{}:
+			if err := ctx.Err(); err != nil {
+				<-sem
+				results[i] = &VerifyResult{Err: err}
+				continue
+			}
+		case <-ctx.Done():
+			results[i] = &VerifyResult{Err: ctx.Err()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, in *input.ProverInput) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := e.Validate(ctx, in)
+			results[i] = &VerifyResult{Result: res, Err: err}
+		}(i, in)
+	}
+	wg.Wait()
+
+	success := true
+	for _, res := range results {
+		success = success && res.Success()
+	}
+
+	return results, success
+}
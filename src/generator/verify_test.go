@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	chain := newTransferChain(t)
+	good, err := NewPreparer().Prepare(context.Background(), chain.preflightData(t, 1))
+	require.NoError(t, err)
+
+	// Input claiming an invalid state root
+	header := gethtypes.CopyHeader(good.Blocks[0].Header)
+	header.Root = gethcommon.HexToHash("0x01")
+	bad := *good
+	bad.Blocks = []*input.Block{{Header: header, Transactions: good.Blocks[0].Transactions, Withdrawals: good.Blocks[0].Withdrawals}}
+
+	// Input that can not be validated
+	empty := &input.ProverInput{ChainConfig: good.ChainConfig}
+
+	inputs := []*input.ProverInput{good, &bad, good, empty, good}
+	results, success := VerifyBatch(context.Background(), inputs, 2)
+	assert.False(t, success)
+	require.Len(t, results, len(inputs))
+	for _, i := range []int{0, 2, 4} {
+		assert.True(t, results[i].Success(), "input %d", i)
+	}
+	assert.NoError(t, results[1].Err)
+	assert.False(t, results[1].Success())
+	assert.Contains(t, results[1].Result.Divergence, "invalid merkle root")
+	assert.Error(t, results[3].Err)
+	assert.False(t, results[3].Success())
+
+	results, success = VerifyBatch(context.Background(), []*input.ProverInput{good, good}, 0)
+	assert.True(t, success)
+	assert.Len(t, results, 2)
+
+	// Inputs are not validated once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, success = VerifyBatch(ctx, inputs, 2)
+	assert.False(t, success)
+	for _, res := range results {
+		assert.ErrorIs(t, res.Err, context.Canceled)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	_, data := newRangeTestChain(t)
+	in, err := NewPreparer().Prepare(context.Background(), data[0])
+	require.NoError(t, err)
+	require.NoError(t, Verify(context.Background(), in))
+
+	// Mutating a witness node breaks the pre-state
+	node := append([]byte{}, in.Witness.State[0]...)
+	node[len(node)-1] ^= 0xff
+	in.Witness.State[0] = node
+	assert.ErrorContains(t, Verify(context.Background(), in), "missing trie node")
+}
+
+func TestVerifyJSONRoundTrip(t *testing.T) {
+	_, data := newRangeTestChain(t)
+	in, err := NewPreparer().Prepare(context.Background(), data[0])
+	require.NoError(t, err)
+
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	decoded, err := input.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.NoError(t, Verify(context.Background(), decoded))
+}
+
+func TestVerifyRange(t *testing.T) {
+	_, data := newRangeTestChain(t)
+	in, err := NewPreparer(WithReceipts()).PrepareRange(context.Background(), data)
+	require.NoError(t, err)
+	require.NoError(t, Verify(context.Background(), in))
+
+	// Embedded receipts of the last block do not match its execution
+	in.Blocks[2].Receipts[0].CumulativeGasUsed++
+	var blockErr *RangeBlockError
+	require.ErrorAs(t, Verify(context.Background(), in), &blockErr)
+	assert.Equal(t, 2, blockErr.Index)
+	assert.ErrorContains(t, blockErr, "embedded receipts")
+}
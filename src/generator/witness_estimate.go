@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// WitnessEstimate is the projected size of the witness of a block
+type WitnessEstimate struct {
+	Nodes int `json:"nodes"` // Number of distinct state nodes
+	Codes int `json:"codes"` // Number of distinct contract codes
+	Bytes int `json:"bytes"` // Size in bytes of the state nodes, codes and RLP encoded ancestors, as reported by Report.WitnessSize
+}
+
+// EstimateWitnessSize projects the size of the witness prepared from the preflight data of a block, without executing it.
+//
+// It sums the distinct nodes of the pre-state and post-state proofs, the codes and the ancestors. The prepared witness
+// only holds the nodes the block execution accesses, so the estimate is an upper bound.
+func EstimateWitnessSize(data *PreflightData) WitnessEstimate {
+	var estimate WitnessEstimate
+
+	nodes := make(map[string]struct{})
+	addNode := func(hex string) {
+		node, err := hexutil.Decode(hex)
+		if err != nil {
+			return
+		}
+		if _, ok := nodes[string(node)]; ok {
+			return
+		}
+		nodes[string(node)] = struct{}{}
+		estimate.Nodes++
+		estimate.Bytes += len(node)
+	}
+	for _, proofs := range [][]*trie.AccountProof{data.PreStateProofs, data.PostStateProofs} {
+		for _, proof := range proofs {
+			for _, node := range proof.Proof {
+				addNode(node)
+			}
+			for _, storage := range proof.Storage {
+				for _, node := range storage.Proof {
+					addNode(node)
+				}
+			}
+		}
+	}
+
+	codes := make(map[string]struct{})
+	for _, code := range data.Codes {
+		if _, ok := codes[string(code)]; ok {
+			continue
+		}
+		codes[string(code)] = struct{}{}
+		estimate.Codes++
+		estimate.Bytes += len(code)
+	}
+
+	for _, header := range data.Ancestors {
+		if enc, err := rlp.EncodeToBytes(header); err == nil {
+			estimate.Bytes += len(enc)
+		}
+	}
+
+	return estimate
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateWitnessSize(t *testing.T) {
+	type fixture struct {
+		data *PreflightData
+		in   *input.ProverInput
+	}
+	fixtures := map[string]func(t *testing.T) fixture{
+		"transfer": func(t *testing.T) fixture {
+			data := newTransferChain(t).preflightData(t, 1)
+			in, err := NewPreparer().Prepare(context.Background(), data)
+			require.NoError(t, err)
+			return fixture{data, in}
+		},
+		"large storage": func(t *testing.T) fixture {
+			chain, _ := newLargeStorageChain(t)
+			data := chain.preflightData(t, 1)
+			in, err := NewPreparer().Prepare(context.Background(), data)
+			require.NoError(t, err)
+			return fixture{data, in}
+		},
+	}
+	for _, name := range testcases {
+		fixtures[name] = func(t *testing.T) fixture {
+			testDataInputs := loadTestDataInputs(t, testDataInputsPath(name))
+			return fixture{&testDataInputs.PreflightData, &testDataInputs.ProverInput}
+		}
+	}
+
+	for name, load := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			f := load(t)
+			estimate := EstimateWitnessSize(f.data)
+			size, err := witnessSize(f.in.Witness)
+			require.NoError(t, err)
+			t.Logf("estimate %d bytes (%d nodes, %d codes), witness %d bytes (%d nodes, %d codes)",
+				estimate.Bytes, estimate.Nodes, estimate.Codes, size, len(f.in.Witness.State), len(f.in.Witness.Codes))
+
+			// The estimate is an upper bound in the ballpark of the witness size
+			assert.GreaterOrEqual(t, estimate.Nodes, len(f.in.Witness.State))
+			assert.GreaterOrEqual(t, estimate.Codes, len(f.in.Witness.Codes))
+			assert.GreaterOrEqual(t, estimate.Bytes, size)
+			assert.Less(t, estimate.Bytes, 2*size)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package input
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// BlobCompressor compresses the witness blobs of a ProverInput
+type BlobCompressor interface {
+	// Compress returns a writer compressing to w, the compressed data being flushed on Close
+	Compress(w io.Writer) (io.WriteCloser, error)
+
+	// Decompress returns a reader decompressing r
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+// CompressionGzip is the name of the gzip blob compressor
+const CompressionGzip = "gzip"
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[string]BlobCompressor{
+		CompressionGzip: &gzipCompressor{},
+	}
+)
+
+// RegisterBlobCompressor registers a blob compressor under the given name, replacing any compressor previously registered with this name
+func RegisterBlobCompressor(name string, compressor BlobCompressor) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	compressors[name] = compressor
+}
+
+// GetBlobCompressor returns the blob compressor registered under the given name
+func GetBlobCompressor(name string) (BlobCompressor, error) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	compressor, ok := compressors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown blob compressor %q", name)
+	}
+	return compressor, nil
+}
+
+// BlobCompressors returns the names of the registered blob compressors in alphabetical order
+func BlobCompressors() []string {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	names := make([]string, 0, len(compressors))
+	for name := range compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type gzipCompressor struct{}
+
+func (c *gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, gzip.BestCompression)
+}
+
+func (c *gzipCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// compressedCodec is the JSON codec with the witness state nodes and codes compressed,
+// each list being RLP encoded then compressed into a single blob.
+// The name of the compressor is recorded so the input is decoded with the right decompressor, whatever the compressor of the decoding codec.
+type compressedCodec struct {
+	compression string
+}
+
+// NewCompressedCodec creates a codec compressing the witness blobs with the blob compressor registered under the given name
+func NewCompressedCodec(compression string) (ProverInputCodec, error) {
+	if _, err := GetBlobCompressor(compression); err != nil {
+		return nil, err
+	}
+	return &compressedCodec{compression: compression}, nil
+}
+
+type compressedProverInput struct {
+	Compression string        `json:"compression"`
+	State       hexutil.Bytes `json:"state"` // Compressed RLP list of the witness state nodes
+	Codes       hexutil.Bytes `json:"codes"` // Compressed RLP list of the witness codes
+	Input       *ProverInput  `json:"input"` // Input without the witness state nodes and codes
+
+	Checksum *gethcommon.Hash `json:"checksum,omitempty"` // Checksum of the input with its witness state nodes and codes, see Checksum
+}
+
+func (c *compressedCodec) Encode(w io.Writer, in *ProverInput) error {
+	compressor, err := GetBlobCompressor(c.compression)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := Checksum(in)
+	if err != nil {
+		return err
+	}
+
+	enc := &compressedProverInput{Compression: c.compression, Input: in, Checksum: &checksum}
+	if in.Witness != nil {
+		if enc.State, err = compressBlobs(compressor, in.Witness.State); err != nil {
+			return fmt.Errorf("failed to compress witness state: %v", err)
+		}
+		if enc.Codes, err = compressBlobs(compressor, in.Witness.Codes); err != nil {
+			return fmt.Errorf("failed to compress witness codes: %v", err)
+		}
+		witness := *in.Witness
+		witness.State, witness.Codes = nil, nil
+		stripped := *in
+		stripped.Witness = &witness
+		enc.Input = &stripped
+	}
+
+	return json.NewEncoder(w).Encode(enc)
+}
+
+func (c *compressedCodec) Decode(r io.Reader) (*ProverInput, error) {
+	var dec compressedProverInput
+	if err := json.NewDecoder(r).Decode(&dec); err != nil {
+		return nil, err
+	}
+	if dec.Input == nil {
+		return nil, fmt.Errorf("missing prover input")
+	}
+
+	compressor, err := GetBlobCompressor(dec.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	in := dec.Input
+	if in.Witness != nil {
+		if in.Witness.State, err = decompressBlobs(compressor, dec.State); err != nil {
+			return nil, fmt.Errorf("failed to decompress witness state: %v", err)
+		}
+		if in.Witness.Codes, err = decompressBlobs(compressor, dec.Codes); err != nil {
+			return nil, fmt.Errorf("failed to decompress witness codes: %v", err)
+		}
+	}
+	if dec.Checksum != nil {
+		if err := VerifyChecksum(in, *dec.Checksum); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+func compressBlobs(compressor BlobCompressor, blobs []hexutil.Bytes) ([]byte, error) {
+	var buf bytes.Buffer
+	cw, err := compressor.Compress(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(cw, toBytesList(blobs)); err != nil {
+		cw.Close()
+		return nil, err
+	}
+	if err := cw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressBlobs(compressor BlobCompressor, data []byte) ([]hexutil.Bytes, error) {
+	cr, err := compressor.Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer cr.Close()
+
+	var blobs [][]byte
+	if err := rlp.Decode(cr, &blobs); err != nil {
+		return nil, err
+	}
+	return fromBytesList(blobs), nil
+}
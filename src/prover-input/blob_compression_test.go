@@ -0,0 +1,77 @@
+package input
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flateCompressor struct{}
+
+func (c *flateCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.BestSpeed)
+}
+
+func (c *flateCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+// registerTestBlobCompressor registers a blob compressor for the duration of the test
+func registerTestBlobCompressor(t *testing.T, name string, compressor BlobCompressor) {
+	RegisterBlobCompressor(name, compressor)
+	t.Cleanup(func() {
+		compressorsMu.Lock()
+		defer compressorsMu.Unlock()
+		delete(compressors, name)
+	})
+}
+
+func TestCompressedCodec(t *testing.T) {
+	registerTestBlobCompressor(t, "flate", &flateCompressor{})
+	assert.Equal(t, []string{"flate", CompressionGzip}, BlobCompressors())
+
+	// Nodes sharing prefixes, as trie nodes do
+	in := testProverInput()
+	for i := 0; i < 1000; i++ {
+		node := make([]byte, 64)
+		binary.BigEndian.PutUint64(node[56:], uint64(i))
+		in.Witness.State = append(in.Witness.State, node)
+	}
+	in.Witness.Codes = append(in.Witness.Codes, bytes.Repeat([]byte{0x60, 0x00}, 512))
+
+	var plain bytes.Buffer
+	require.NoError(t, json.NewEncoder(&plain).Encode(in))
+
+	for _, compression := range []string{CompressionGzip, "flate"} {
+		t.Run(compression, func(t *testing.T) {
+			codec, err := NewCompressedCodec(compression)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, codec.Encode(&buf, in))
+			assert.Less(t, buf.Len(), plain.Len()/3)
+
+			// The compression is recorded, so any compressed codec decodes the input
+			gzipCodec, err := GetCodec(CodecGzip)
+			require.NoError(t, err)
+			decoded, err := gzipCodec.Decode(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, in.Witness.State, decoded.Witness.State)
+			assert.Equal(t, in.Witness.Codes, decoded.Witness.Codes)
+			assert.Equal(t, in.Witness.Ancestors[0].Hash(), decoded.Witness.Ancestors[0].Hash())
+			assert.Equal(t, in.Blocks[0].Header.Hash(), decoded.Blocks[0].Header.Hash())
+		})
+	}
+
+	_, err := NewCompressedCodec("unknown")
+	assert.Error(t, err)
+
+	// The encoded input is left untouched
+	assert.Len(t, in.Witness.State, 1000+len(testProverInput().Witness.State))
+}
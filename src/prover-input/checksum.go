@@ -0,0 +1,72 @@
+package input
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ChecksumError is returned when decoding a ProverInput whose blocks and witness do not match the checksum it has been encoded with,
+// which means the serialized input has been corrupted
+type ChecksumError struct {
+	Expected gethcommon.Hash // Checksum the input has been encoded with
+	Actual   gethcommon.Hash // Checksum of the decoded input
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("prover input checksum mismatch, it is corrupted: encoded with %v, got %v", e.Expected.Hex(), e.Actual.Hex())
+}
+
+// checksumContent is the canonical encoding of the content covered by the checksum
+type checksumContent struct {
+	Blocks  []*rlpBlock
+	Witness *rlpWitness
+}
+
+// Checksum returns the integrity checksum of a ProverInput, the Keccak256 hash of the RLP encoding of its blocks and witness.
+//
+// Codecs compute it when encoding an input and verify it when decoding, see VerifyChecksum.
+// It depends on the order of the witness nodes and codes, that serialization preserves and the preparer sorts by hash.
+func Checksum(in *ProverInput) (gethcommon.Hash, error) {
+	// Some encodings do not distinguish a missing witness and an empty one
+	witness := in.Witness
+	if witness == nil {
+		witness = &Witness{}
+	}
+
+	content := &checksumContent{
+		Blocks:  make([]*rlpBlock, 0, len(in.Blocks)),
+		Witness: toRLPWitness(witness),
+	}
+	for _, block := range in.Blocks {
+		b, err := toRLPBlock(block)
+		if err != nil {
+			return gethcommon.Hash{}, err
+		}
+		content.Blocks = append(content.Blocks, b)
+	}
+
+	b, err := rlp.EncodeToBytes(content)
+	if err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("failed to encode prover input: %v", err)
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// VerifyChecksum verifies the blocks and witness of in match the checksum expected, returning a ChecksumError if not.
+// A zero checksum, for inputs encoded before checksums were introduced, is not verified.
+func VerifyChecksum(in *ProverInput, expected gethcommon.Hash) error {
+	if expected == (gethcommon.Hash{}) {
+		return nil
+	}
+	actual, err := Checksum(in)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum: %v", err)
+	}
+	if actual != expected {
+		return &ChecksumError{Expected: expected, Actual: actual}
+	}
+	return nil
+}
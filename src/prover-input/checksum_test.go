@@ -0,0 +1,94 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	in := testProverInput()
+	checksum, err := Checksum(in)
+	require.NoError(t, err)
+	require.NoError(t, VerifyChecksum(in, checksum))
+
+	in.Witness.State = []hexutil.Bytes{in.Witness.State[1], in.Witness.State[0]}
+	var checksumErr *ChecksumError
+	require.ErrorAs(t, VerifyChecksum(in, checksum), &checksumErr)
+	assert.Equal(t, checksum, checksumErr.Expected)
+}
+
+// flipBit flips the lowest bit of the first occurrence of old in b
+func flipBit(t *testing.T, b, old []byte) []byte {
+	i := bytes.Index(b, old)
+	require.GreaterOrEqual(t, i, 0, "%x not found", old)
+	corrupted := bytes.Clone(b)
+	corrupted[i+len(old)-1] ^= 0x01
+	return corrupted
+}
+
+func TestCodecsChecksum(t *testing.T) {
+	in := testProverInput()
+	in.Witness.Codes = []hexutil.Bytes{{0x60, 0xab, 0xcd, 0xef}}
+
+	for _, tc := range []struct {
+		codec string
+		flip  []byte // Serialized bytes, in the encoding of the codec, whose last bit is flipped
+	}{
+		{codec: CodecJSON, flip: []byte(`"0x60abcde`)},
+		{codec: CodecRLP, flip: []byte{0x60, 0xab, 0xcd, 0xef}},
+		{codec: CodecGzip, flip: []byte(`"gasLimit":"0x0`)},
+	} {
+		t.Run(tc.codec, func(t *testing.T) {
+			codec, err := GetCodec(tc.codec)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, codec.Encode(&buf, in))
+			_, err = codec.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+
+			_, err = codec.Decode(bytes.NewReader(flipBit(t, buf.Bytes(), tc.flip)))
+			var checksumErr *ChecksumError
+			require.ErrorAs(t, err, &checksumErr)
+		})
+	}
+}
+
+func TestDecodeWithoutChecksum(t *testing.T) {
+	in := testProverInput()
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+
+	// Inputs encoded before checksums were introduced are not verified
+	decoded, err := Decode(bytes.NewReader(b), WithDisallowUnknownFields())
+	require.NoError(t, err)
+	assert.True(t, CompareProverInput(in, decoded))
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, in, OutputFormatJSON))
+	assert.Contains(t, buf.String(), `"checksum":"0x`)
+	_, err = Decode(&buf, WithDisallowUnknownFields())
+	require.NoError(t, err)
+}
+
+func TestSplitChecksum(t *testing.T) {
+	dir := t.TempDir()
+	in := testProverInput()
+	require.NoError(t, SaveSplit(dir, in))
+
+	path := filepath.Join(dir, splitCodesFile)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, flipBit(t, b, []byte{0x60, 0x00}), 0o600))
+
+	_, err = LoadSplit(dir)
+	var checksumErr *ChecksumError
+	require.ErrorAs(t, err, &checksumErr)
+}
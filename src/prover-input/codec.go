@@ -0,0 +1,321 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// ProverInputCodec serializes ProverInputs in a given format
+type ProverInputCodec interface {
+	// Encode writes the ProverInput to w
+	Encode(w io.Writer, in *ProverInput) error
+
+	// Decode reads a ProverInput from r
+	Decode(r io.Reader) (*ProverInput, error)
+}
+
+const (
+	// CodecJSON is the name of the JSON codec
+	CodecJSON = "json"
+	// CodecRLP is the name of the RLP codec
+	CodecRLP = "rlp"
+	// CodecGzip is the name of the JSON codec with gzip compressed witness blobs
+	CodecGzip = "gzip"
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]ProverInputCodec{
+		CodecJSON: &jsonCodec{},
+		CodecRLP:  &rlpCodec{},
+		CodecGzip: &compressedCodec{compression: CompressionGzip},
+	}
+)
+
+// RegisterCodec registers a codec under the given name, replacing any codec previously registered with this name
+func RegisterCodec(name string, codec ProverInputCodec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[name] = codec
+}
+
+// UnregisterCodec removes the codec registered under the given name, if any
+func UnregisterCodec(name string) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	delete(codecs, name)
+}
+
+// GetCodec returns the codec registered under the given name
+func GetCodec(name string) (ProverInputCodec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	return codec, nil
+}
+
+// Codecs returns the names of the registered codecs in alphabetical order
+func Codecs() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonCodec is the canonical JSON codec
+type jsonCodec struct{}
+
+func (c *jsonCodec) Encode(w io.Writer, in *ProverInput) error {
+	return writeJSON(w, in)
+}
+
+func (c *jsonCodec) Decode(r io.Reader) (*ProverInput, error) {
+	return Decode(r)
+}
+
+// rlpCodec is a compact binary codec
+// The chain configuration, the state proofs and the receipts are not RLP serializable (receipts only partially) so they are embedded JSON encoded
+type rlpCodec struct{}
+
+type rlpProverInput struct {
+	Version     string
+	ChainConfig []byte
+	Blocks      []*rlpBlock
+	Witness     *rlpWitness
+	Proofs      []byte
+	TxScope     *TransactionScope `rlp:"nil,optional"`
+	Metadata    *Metadata         `rlp:"nil,optional"`
+	Checksum    gethcommon.Hash   `rlp:"optional"` // See Checksum, zero for inputs encoded without checksum
+}
+
+type rlpBlock struct {
+	Header       *gethtypes.Header
+	Transactions []*gethtypes.Transaction
+	Uncles       []*gethtypes.Header
+	Withdrawals  []*gethtypes.Withdrawal
+	Senders      []gethcommon.Address
+	Logs         [][]*Log `rlp:"optional"`
+	Receipts     []byte   `rlp:"optional"`
+}
+
+type rlpWitness struct {
+	State        [][]byte
+	Ancestors    []*gethtypes.Header
+	Codes        [][]byte
+	StateByOwner []*rlpOwnerNodes
+
+	CompactBranches [][]byte         `rlp:"optional"`
+	StateByPath     []*trie.PathNode `rlp:"optional"`
+	StateDiff       [][]byte         `rlp:"optional"`
+}
+
+type rlpOwnerNodes struct {
+	Owner gethcommon.Hash
+	Nodes [][]byte
+}
+
+type stateProofs struct {
+	PreStateProofs  []*trie.AccountProof `json:"preStateProofs,omitempty"`
+	PostStateProofs []*trie.AccountProof `json:"postStateProofs,omitempty"`
+}
+
+func (c *rlpCodec) Encode(w io.Writer, in *ProverInput) error {
+	enc := &rlpProverInput{
+		Version:  in.Version,
+		Blocks:   make([]*rlpBlock, 0, len(in.Blocks)),
+		TxScope:  in.TxScope,
+		Metadata: in.Metadata,
+	}
+
+	var err error
+	if enc.ChainConfig, err = json.Marshal(in.ChainConfig); err != nil {
+		return fmt.Errorf("failed to encode chain config: %v", err)
+	}
+	if in.PreStateProofs != nil || in.PostStateProofs != nil {
+		if enc.Proofs, err = json.Marshal(&stateProofs{in.PreStateProofs, in.PostStateProofs}); err != nil {
+			return fmt.Errorf("failed to encode state proofs: %v", err)
+		}
+	}
+
+	for _, block := range in.Blocks {
+		b, err := toRLPBlock(block)
+		if err != nil {
+			return err
+		}
+		enc.Blocks = append(enc.Blocks, b)
+	}
+
+	enc.Witness = toRLPWitness(in.Witness)
+
+	if enc.Checksum, err = Checksum(in); err != nil {
+		return err
+	}
+
+	return rlp.Encode(w, enc)
+}
+
+func (c *rlpCodec) Decode(r io.Reader) (*ProverInput, error) {
+	var dec rlpProverInput
+	if err := rlp.Decode(r, &dec); err != nil {
+		return nil, err
+	}
+
+	in := &ProverInput{
+		Version:  dec.Version,
+		Blocks:   make([]*Block, 0, len(dec.Blocks)),
+		TxScope:  dec.TxScope,
+		Metadata: dec.Metadata,
+	}
+
+	in.ChainConfig = new(params.ChainConfig)
+	if err := json.Unmarshal(dec.ChainConfig, &in.ChainConfig); err != nil {
+		return nil, fmt.Errorf("failed to decode chain config: %v", err)
+	}
+	if len(dec.Proofs) > 0 {
+		var proofs stateProofs
+		if err := json.Unmarshal(dec.Proofs, &proofs); err != nil {
+			return nil, fmt.Errorf("failed to decode state proofs: %v", err)
+		}
+		in.PreStateProofs, in.PostStateProofs = proofs.PreStateProofs, proofs.PostStateProofs
+	}
+
+	for _, block := range dec.Blocks {
+		b, err := fromRLPBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		in.Blocks = append(in.Blocks, b)
+	}
+
+	if dec.Witness != nil {
+		in.Witness = &Witness{
+			State:     fromBytesList(dec.Witness.State),
+			Ancestors: dec.Witness.Ancestors,
+			Codes:     fromBytesList(dec.Witness.Codes),
+		}
+		if len(dec.Witness.CompactBranches) > 0 {
+			in.Witness.CompactBranches = fromBytesList(dec.Witness.CompactBranches)
+		}
+		if len(dec.Witness.StateByPath) > 0 {
+			in.Witness.StateByPath = dec.Witness.StateByPath
+		}
+		if len(dec.Witness.StateDiff) > 0 {
+			in.Witness.StateDiff = fromBytesList(dec.Witness.StateDiff)
+		}
+		for _, group := range dec.Witness.StateByOwner {
+			if in.Witness.StateByOwner == nil {
+				in.Witness.StateByOwner = make(map[gethcommon.Hash][]hexutil.Bytes)
+			}
+			in.Witness.StateByOwner[group.Owner] = fromBytesList(group.Nodes)
+		}
+	}
+
+	if err := VerifyChecksum(in, dec.Checksum); err != nil {
+		return nil, err
+	}
+
+	return in, nil
+}
+
+func toRLPWitness(witness *Witness) *rlpWitness {
+	if witness == nil {
+		return nil
+	}
+
+	enc := &rlpWitness{
+		State:     toBytesList(witness.State),
+		Ancestors: witness.Ancestors,
+		Codes:     toBytesList(witness.Codes),
+	}
+	if len(witness.CompactBranches) > 0 {
+		enc.CompactBranches = toBytesList(witness.CompactBranches)
+	}
+	enc.StateByPath = witness.StateByPath
+	if len(witness.StateDiff) > 0 {
+		enc.StateDiff = toBytesList(witness.StateDiff)
+	}
+	for owner, nodes := range witness.StateByOwner {
+		enc.StateByOwner = append(enc.StateByOwner, &rlpOwnerNodes{Owner: owner, Nodes: toBytesList(nodes)})
+	}
+	sort.Slice(enc.StateByOwner, func(i, j int) bool {
+		return enc.StateByOwner[i].Owner.Cmp(enc.StateByOwner[j].Owner) < 0
+	})
+	return enc
+}
+
+func toRLPBlock(block *Block) (*rlpBlock, error) {
+	b := &rlpBlock{
+		Header:       block.Header,
+		Transactions: block.Transactions,
+		Uncles:       block.Uncles,
+		Withdrawals:  block.Withdrawals,
+		Senders:      block.Senders,
+		Logs:         block.Logs,
+	}
+	if block.Receipts != nil {
+		var err error
+		if b.Receipts, err = json.Marshal(block.Receipts); err != nil {
+			return nil, fmt.Errorf("failed to encode receipts: %v", err)
+		}
+	}
+	return b, nil
+}
+
+// fromRLPBlock converts a decoded block, RLP not distinguishing nil and empty lists
+func fromRLPBlock(block *rlpBlock) (*Block, error) {
+	b := &Block{
+		Header:       block.Header,
+		Transactions: block.Transactions,
+	}
+	if len(block.Uncles) > 0 {
+		b.Uncles = block.Uncles
+	}
+	if len(block.Senders) > 0 {
+		b.Senders = block.Senders
+	}
+	if len(block.Logs) > 0 {
+		b.Logs = block.Logs
+	}
+	if len(block.Receipts) > 0 {
+		if err := json.Unmarshal(block.Receipts, &b.Receipts); err != nil {
+			return nil, fmt.Errorf("failed to decode receipts: %v", err)
+		}
+	}
+	if block.Header != nil && block.Header.WithdrawalsHash != nil {
+		b.Withdrawals = block.Withdrawals
+	}
+	return b, nil
+}
+
+func toBytesList(blobs []hexutil.Bytes) [][]byte {
+	res := make([][]byte, 0, len(blobs))
+	for _, blob := range blobs {
+		res = append(res, blob)
+	}
+	return res
+}
+
+func fromBytesList(blobs [][]byte) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, 0, len(blobs))
+	for _, blob := range blobs {
+		res = append(res, blob)
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	in := testProverInput()
+	in.Blocks[0].Senders = []gethcommon.Address{gethcommon.HexToAddress("0xbeef")}
+	in.Blocks[0].Logs = [][]*Log{{{Address: gethcommon.HexToAddress("0xc0de"), Topics: []gethcommon.Hash{{0x01}}, Data: hexutil.Bytes{0xaa}}}}
+	in.Blocks[0].Receipts = []*gethtypes.Receipt{{Status: gethtypes.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, GasUsed: 21000, Logs: []*gethtypes.Log{}, TxHash: in.Blocks[0].Transactions[0].Hash()}}
+	in.Witness.StateByOwner = map[gethcommon.Hash][]hexutil.Bytes{
+		trie.AccountTrieOwner(): {{0xc2, 0x20, 0x01}},
+		{0x01}:                  {{0xc2, 0x20, 0x02}},
+	}
+	in.PreStateProofs = []*trie.AccountProof{{Address: gethcommon.HexToAddress("0xdead"), Proof: []string{"0xc22001"}}}
+	in.Witness.StateByPath = []*trie.PathNode{{Owner: trie.AccountTrieOwner(), Path: hexutil.Bytes{}, Blob: hexutil.Bytes{0xc2, 0x20, 0x01}}}
+	in.Witness.StateDiff = []hexutil.Bytes{{0xc2, 0x20, 0x02}}
+	in.TxScope = &TransactionScope{Index: 1, PreStateRoot: gethcommon.Hash{0x01}, PostStateRoot: gethcommon.Hash{0x02}, GasUsed: 21000}
+
+	expected, err := json.Marshal(in)
+	require.NoError(t, err)
+
+	for _, name := range []string{CodecJSON, CodecRLP, CodecGzip} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := GetCodec(name)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, codec.Encode(&buf, in))
+			decoded, err := codec.Decode(&buf)
+			require.NoError(t, err)
+
+			actual, err := json.Marshal(decoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+
+	_, err = GetCodec("unknown")
+	assert.Error(t, err)
+}
+
+func TestUnregisterCodec(t *testing.T) {
+	RegisterCodec("custom", &jsonCodec{})
+	assert.Contains(t, Codecs(), "custom")
+
+	UnregisterCodec("custom")
+	assert.Equal(t, []string{CodecGzip, CodecJSON, CodecRLP}, Codecs())
+	_, err := GetCodec("custom")
+	assert.Error(t, err)
+}
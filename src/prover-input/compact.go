@@ -0,0 +1,183 @@
+package input
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// compactBranch is the compact encoding of a branch node whose children are all part of the witness.
+// Children are referenced by their index in the witness state nodes followed by the other compact branches,
+// so the branch can be regenerated from its children without storing their 32 bytes hashes.
+type compactBranch struct {
+	Children uint16   // Bitmap of the non-empty children, bit i is set if child i is present
+	Indices  []uint64 // Index of each non-empty child, in children order
+}
+
+// CompactWitness returns a copy of the witness where the branch nodes that can be regenerated from their children are omitted.
+//
+// A branch node is omitted when all its children are hash references to nodes of the witness (or to other omitted branch nodes).
+// Omitted branch nodes are recorded in CompactBranches, ExpandWitness regenerates them.
+// Nodes grouped by owner are left untouched.
+func CompactWitness(w *Witness) *Witness {
+	c := &Witness{
+		Ancestors:    w.Ancestors,
+		Codes:        w.Codes,
+		StateByOwner: w.StateByOwner,
+		StateByPath:  w.StateByPath,
+		StateDiff:    w.StateDiff,
+	}
+
+	nodes := make(map[gethcommon.Hash][]byte, len(w.State))
+	for _, node := range w.State {
+		nodes[crypto.Keccak256Hash(node)] = node
+	}
+
+	// A branch is compactable if all its children are present, whether they are compactable or not
+	children := make(map[gethcommon.Hash][]*gethcommon.Hash)
+	for hash, node := range nodes {
+		if refs, ok := branchChildren(node); ok && allPresent(refs, nodes) {
+			children[hash] = refs
+		}
+	}
+
+	// Kept nodes come first, in their original order
+	index := make(map[gethcommon.Hash]uint64, len(nodes))
+	for _, node := range w.State {
+		hash := crypto.Keccak256Hash(node)
+		if _, compactable := children[hash]; compactable {
+			continue
+		}
+		if _, ok := index[hash]; !ok {
+			index[hash] = uint64(len(c.State))
+			c.State = append(c.State, node)
+		}
+	}
+
+	// Compact branches are ordered children first so they can be expanded in a single pass
+	var visit func(hash gethcommon.Hash)
+	visit = func(hash gethcommon.Hash) {
+		if _, ok := index[hash]; ok {
+			return
+		}
+		b := &compactBranch{}
+		for i, ref := range children[hash] {
+			if ref != nil {
+				visit(*ref)
+				b.Children |= 1 << i
+				b.Indices = append(b.Indices, index[*ref])
+			}
+		}
+		enc, _ := rlp.EncodeToBytes(b)
+		index[hash] = uint64(len(c.State) + len(c.CompactBranches))
+		c.CompactBranches = append(c.CompactBranches, enc)
+	}
+	for _, node := range w.State {
+		if hash := crypto.Keccak256Hash(node); children[hash] != nil {
+			visit(hash)
+		}
+	}
+
+	return c
+}
+
+// ExpandWitness returns a copy of the witness where the branch nodes omitted by CompactWitness are regenerated.
+// Regenerated nodes are appended to the state nodes. A witness without compact branches is returned as is.
+func ExpandWitness(w *Witness) (*Witness, error) {
+	if len(w.CompactBranches) == 0 {
+		return w, nil
+	}
+
+	expanded := &Witness{
+		State:        make([]hexutil.Bytes, len(w.State), len(w.State)+len(w.CompactBranches)),
+		Ancestors:    w.Ancestors,
+		Codes:        w.Codes,
+		StateByOwner: w.StateByOwner,
+		StateByPath:  w.StateByPath,
+		StateDiff:    w.StateDiff,
+	}
+	copy(expanded.State, w.State)
+
+	for i, enc := range w.CompactBranches {
+		var b compactBranch
+		if err := rlp.DecodeBytes(enc, &b); err != nil {
+			return nil, fmt.Errorf("invalid compact branch %d: %v", i, err)
+		}
+
+		items := make([][]byte, 17)
+		next := 0
+		for j := 0; j < 16; j++ {
+			items[j] = []byte{}
+			if b.Children&(1<<j) == 0 {
+				continue
+			}
+			if next >= len(b.Indices) {
+				return nil, fmt.Errorf("invalid compact branch %d: missing child %d index", i, j)
+			}
+			idx := b.Indices[next]
+			next++
+			if idx >= uint64(len(expanded.State)) {
+				return nil, fmt.Errorf("invalid compact branch %d: child %d references unknown node %d", i, j, idx)
+			}
+			items[j] = crypto.Keccak256(expanded.State[idx])
+		}
+		if next != len(b.Indices) {
+			return nil, fmt.Errorf("invalid compact branch %d: %d indices for %d children", i, len(b.Indices), next)
+		}
+		items[16] = []byte{}
+
+		node, err := rlp.EncodeToBytes(items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode branch %d: %v", i, err)
+		}
+		expanded.State = append(expanded.State, node)
+	}
+
+	return expanded, nil
+}
+
+// branchChildren returns the child references of a branch node whose children are all hash references or empty.
+// It returns false for any other node.
+func branchChildren(node []byte) ([]*gethcommon.Hash, bool) {
+	elems, rest, err := rlp.SplitList(node)
+	if err != nil || len(rest) != 0 {
+		return nil, false
+	}
+	if count, err := rlp.CountValues(elems); err != nil || count != 17 {
+		return nil, false
+	}
+
+	refs := make([]*gethcommon.Hash, 16)
+	for i := 0; i < 17; i++ {
+		kind, val, tail, err := rlp.Split(elems)
+		if err != nil || kind != rlp.String {
+			return nil, false
+		}
+		switch {
+		case len(val) == 0:
+		case i < 16 && len(val) == gethcommon.HashLength:
+			hash := gethcommon.BytesToHash(val)
+			refs[i] = &hash
+		default:
+			return nil, false
+		}
+		elems = tail
+	}
+
+	return refs, true
+}
+
+func allPresent(refs []*gethcommon.Hash, nodes map[gethcommon.Hash][]byte) bool {
+	for _, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		if _, ok := nodes[*ref]; !ok {
+			return false
+		}
+	}
+	return true
+}
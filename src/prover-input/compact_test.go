@@ -0,0 +1,72 @@
+package input
+
+import (
+	"encoding/binary"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func witnessSize(w *Witness) int {
+	size := 0
+	for _, node := range w.State {
+		size += len(node)
+	}
+	for _, branch := range w.CompactBranches {
+		size += len(branch)
+	}
+	return size
+}
+
+func TestCompactWitness(t *testing.T) {
+	db := triedb.NewDatabase(rawdb.NewMemoryDatabase(), &triedb.Config{HashDB: &hashdb.Config{}})
+
+	// Trie deep enough to have branch nodes referencing branch nodes
+	tr := gethtrie.NewEmpty(db)
+	for i := uint64(0); i < 1024; i++ {
+		key := crypto.Keccak256(binary.BigEndian.AppendUint64(nil, i))
+		require.NoError(t, tr.Update(key, crypto.Keccak256(key)))
+	}
+	root, nodes := commitTrie(t, db, tr, gethcommon.Hash{})
+
+	w := &Witness{State: nodes}
+	compacted := CompactWitness(w)
+	assert.NotEmpty(t, compacted.CompactBranches)
+	assert.Less(t, witnessSize(compacted), witnessSize(w))
+	assert.Equal(t, len(w.State), len(compacted.State)+len(compacted.CompactBranches))
+
+	// Compacted witness misses the root node until it is expanded
+	assert.Error(t, VerifyWitnessRoots(compacted, root, root))
+
+	expanded, err := ExpandWitness(compacted)
+	require.NoError(t, err)
+	require.NoError(t, VerifyWitnessRoots(expanded, root, root))
+	assert.ElementsMatch(t, hashesOf(w.State), hashesOf(expanded.State))
+
+	// Witnesses with missing children are not compacted
+	partial := &Witness{State: nodes[:len(nodes)/2]}
+	assert.Empty(t, CompactWitness(&Witness{State: []hexutil.Bytes{nodes[len(nodes)-1]}}).CompactBranches)
+	expanded, err = ExpandWitness(CompactWitness(partial))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, hashesOf(partial.State), hashesOf(expanded.State))
+
+	// Invalid references are rejected
+	_, err = ExpandWitness(&Witness{CompactBranches: []hexutil.Bytes{{0xc4, 0x01, 0xc2, 0x05, 0x06}}})
+	assert.Error(t, err)
+}
+
+func hashesOf(nodes []hexutil.Bytes) []gethcommon.Hash {
+	hashes := make([]gethcommon.Hash, 0, len(nodes))
+	for _, node := range nodes {
+		hashes = append(hashes, crypto.Keccak256Hash(node))
+	}
+	return hashes
+}
@@ -0,0 +1,41 @@
+package input
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// DecodeOption is an option to configure the decoding of a ProverInput from JSON
+type DecodeOption func(*json.Decoder)
+
+// WithDisallowUnknownFields makes decoding fail when the JSON contains fields unknown to the ProverInput structure.
+// It is recommended for production, while the default lenient mode eases loading inputs produced by newer versions.
+//
+// Note that it does not apply to blocks and go-ethereum types (headers, transactions...) that implement their own decoding.
+func WithDisallowUnknownFields() DecodeOption {
+	return func(dec *json.Decoder) {
+		dec.DisallowUnknownFields()
+	}
+}
+
+// Decode decodes a JSON encoded ProverInput from r
+// By default unknown fields are ignored.
+// It fails with a ChecksumError if the input has been encoded with a checksum its content does not match.
+func Decode(r io.Reader, opts ...DecodeOption) (*ProverInput, error) {
+	dec := json.NewDecoder(r)
+	for _, opt := range opts {
+		opt(dec)
+	}
+
+	data := checksummedProverInput{ProverInput: new(ProverInput)}
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Checksum != nil {
+		if err := VerifyChecksum(data.ProverInput, *data.Checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	return data.ProverInput, nil
+}
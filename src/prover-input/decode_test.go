@@ -0,0 +1,25 @@
+package input
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeUnknownFields(t *testing.T) {
+	data := `{"version":"v1","blocks":[],"witness":{"state":[],"ancestors":[],"codes":[],"futureField":"0x01"},"newTopLevelField":1}`
+
+	t.Run("lenient", func(t *testing.T) {
+		proverInput, err := Decode(strings.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "v1", proverInput.Version)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := Decode(strings.NewReader(data), WithDisallowUnknownFields())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown field")
+	})
+}
@@ -0,0 +1,278 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// ProverInputDiff reports how two ProverInputs a and b diverge
+type ProverInputDiff struct {
+	BlockCountA, BlockCountB int
+
+	Blocks []*BlockDiff // Blocks at the same index in a and b that differ
+
+	AddedNodes   []gethcommon.Hash // Hashes of the witness state nodes only in b
+	RemovedNodes []gethcommon.Hash // Hashes of the witness state nodes only in a
+	ChangedNodes []*NodeChange     // Witness state nodes at the same trie path in a and b with different hashes
+
+	AddedCodes   []gethcommon.Hash // Hashes of the witness codes only in b
+	RemovedCodes []gethcommon.Hash // Hashes of the witness codes only in a
+}
+
+// BlockDiff reports the fields differing between the blocks at the same index of two ProverInputs
+type BlockDiff struct {
+	Index  int
+	Fields []string // Header fields, by their JSON name, and "transactions", "uncles" or "withdrawals"
+}
+
+// NodeChange is a witness state node whose hash differs between two ProverInputs
+type NodeChange struct {
+	Owner gethcommon.Hash // Owner of the trie holding the node, see trie.StorageTrieOwner
+	Path  hexutil.Bytes   // Nibble path of the node in its trie
+	A, B  gethcommon.Hash
+}
+
+// Diff compares the ProverInputs a and b, reporting their differing blocks, witness state nodes and codes.
+//
+// Witness state nodes are compared by hash. The nodes found at the same path of the tries in a and b are reported
+// as changed rather than added and removed.
+func Diff(a, b *ProverInput) (*ProverInputDiff, error) {
+	if a == nil || b == nil {
+		return nil, fmt.Errorf("can not diff nil prover inputs")
+	}
+
+	diff := &ProverInputDiff{BlockCountA: len(a.Blocks), BlockCountB: len(b.Blocks)}
+	for i := 0; i < len(a.Blocks) && i < len(b.Blocks); i++ {
+		fields, err := diffBlocks(a.Blocks[i], b.Blocks[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff block %d: %v", i, err)
+		}
+		if len(fields) > 0 {
+			diff.Blocks = append(diff.Blocks, &BlockDiff{Index: i, Fields: fields})
+		}
+	}
+
+	var stateA, stateB, codesA, codesB []hexutil.Bytes
+	if a.Witness != nil {
+		stateA, codesA = a.Witness.State, a.Witness.Codes
+	}
+	if b.Witness != nil {
+		stateB, codesB = b.Witness.State, b.Witness.Codes
+	}
+
+	added, removed := diffByHash(stateA, stateB)
+	diff.ChangedNodes = changedNodes(a.Witness, b.Witness, added, removed)
+	for _, change := range diff.ChangedNodes {
+		delete(removed, change.A)
+		delete(added, change.B)
+	}
+	diff.AddedNodes, diff.RemovedNodes = sortedHashes(added), sortedHashes(removed)
+
+	added, removed = diffByHash(codesA, codesB)
+	diff.AddedCodes, diff.RemovedCodes = sortedHashes(added), sortedHashes(removed)
+
+	return diff, nil
+}
+
+// Empty returns whether the ProverInputs compared hold the same blocks, witness state and codes
+func (d *ProverInputDiff) Empty() bool {
+	return d.BlockCountA == d.BlockCountB &&
+		len(d.Blocks) == 0 &&
+		len(d.AddedNodes)+len(d.RemovedNodes)+len(d.ChangedNodes) == 0 &&
+		len(d.AddedCodes)+len(d.RemovedCodes) == 0
+}
+
+// String returns a human-readable summary of the differences
+func (d *ProverInputDiff) String() string {
+	if d.Empty() {
+		return "prover inputs are identical"
+	}
+
+	var sb strings.Builder
+	if d.BlockCountA != d.BlockCountB {
+		fmt.Fprintf(&sb, "block count: %d != %d\n", d.BlockCountA, d.BlockCountB)
+	}
+	for _, block := range d.Blocks {
+		fmt.Fprintf(&sb, "block %d: %s differ\n", block.Index, strings.Join(block.Fields, ", "))
+	}
+	if n := len(d.AddedNodes) + len(d.RemovedNodes) + len(d.ChangedNodes); n > 0 {
+		fmt.Fprintf(&sb, "witness state: %d added, %d removed, %d changed nodes\n", len(d.AddedNodes), len(d.RemovedNodes), len(d.ChangedNodes))
+	}
+	for _, change := range d.ChangedNodes {
+		fmt.Fprintf(&sb, "  ~ %v at path %x of trie %v: %v\n", change.A.Hex(), []byte(change.Path), change.Owner.Hex(), change.B.Hex())
+	}
+	for _, hash := range d.AddedNodes {
+		fmt.Fprintf(&sb, "  + %v\n", hash.Hex())
+	}
+	for _, hash := range d.RemovedNodes {
+		fmt.Fprintf(&sb, "  - %v\n", hash.Hex())
+	}
+	if len(d.AddedCodes)+len(d.RemovedCodes) > 0 {
+		fmt.Fprintf(&sb, "witness codes: %d added, %d removed\n", len(d.AddedCodes), len(d.RemovedCodes))
+	}
+	for _, hash := range d.AddedCodes {
+		fmt.Fprintf(&sb, "  + %v\n", hash.Hex())
+	}
+	for _, hash := range d.RemovedCodes {
+		fmt.Fprintf(&sb, "  - %v\n", hash.Hex())
+	}
+	return sb.String()
+}
+
+// diffBlocks returns the header fields, by their JSON name, and the block bodies differing between a and b
+func diffBlocks(a, b *Block) ([]string, error) {
+	headerA, err := headerFields(a.Header)
+	if err != nil {
+		return nil, err
+	}
+	headerB, err := headerFields(b.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for name, value := range headerA {
+		if other, ok := headerB[name]; !ok || string(other) != string(value) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range headerB {
+		if _, ok := headerA[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+
+	if deriveSha(gethtypes.Transactions(a.Transactions)) != deriveSha(gethtypes.Transactions(b.Transactions)) {
+		fields = append(fields, "transactions")
+	}
+	if gethtypes.CalcUncleHash(a.Uncles) != gethtypes.CalcUncleHash(b.Uncles) {
+		fields = append(fields, "uncles")
+	}
+	if deriveSha(gethtypes.Withdrawals(a.Withdrawals)) != deriveSha(gethtypes.Withdrawals(b.Withdrawals)) {
+		fields = append(fields, "withdrawals")
+	}
+	return fields, nil
+}
+
+func deriveSha(list gethtypes.DerivableList) gethcommon.Hash {
+	return gethtypes.DeriveSha(list, gethtrie.NewStackTrie(nil))
+}
+
+func headerFields(header *gethtypes.Header) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if header == nil {
+		return fields, nil
+	}
+	b, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// diffByHash indexes a and b by hash and returns the items only in b (added) and only in a (removed)
+func diffByHash(a, b []hexutil.Bytes) (added, removed map[gethcommon.Hash]struct{}) {
+	inA, inB := hashSet(a), hashSet(b)
+	added, removed = make(map[gethcommon.Hash]struct{}), make(map[gethcommon.Hash]struct{})
+	for hash := range inB {
+		if _, ok := inA[hash]; !ok {
+			added[hash] = struct{}{}
+		}
+	}
+	for hash := range inA {
+		if _, ok := inB[hash]; !ok {
+			removed[hash] = struct{}{}
+		}
+	}
+	return added, removed
+}
+
+func hashSet(items []hexutil.Bytes) map[gethcommon.Hash]struct{} {
+	set := make(map[gethcommon.Hash]struct{}, len(items))
+	for _, item := range items {
+		set[crypto.Keccak256Hash(item)] = struct{}{}
+	}
+	return set
+}
+
+// changedNodes returns the added and removed nodes found at the same trie path in the witness state of a and b
+func changedNodes(a, b *Witness, added, removed map[gethcommon.Hash]struct{}) []*NodeChange {
+	if len(added) == 0 || len(removed) == 0 {
+		return nil
+	}
+
+	type nodeKey struct {
+		owner gethcommon.Hash
+		path  string
+	}
+	byPath := make(map[nodeKey]gethcommon.Hash)
+	walkPaths(a, func(owner, hash gethcommon.Hash, path []byte) {
+		if _, ok := removed[hash]; ok {
+			byPath[nodeKey{owner, string(path)}] = hash
+		}
+	})
+
+	var changes []*NodeChange
+	matched := make(map[gethcommon.Hash]struct{})
+	walkPaths(b, func(owner, hash gethcommon.Hash, path []byte) {
+		if _, ok := added[hash]; !ok {
+			return
+		}
+		if _, ok := matched[hash]; ok {
+			return
+		}
+		if prev, ok := byPath[nodeKey{owner, string(path)}]; ok {
+			if _, ok := matched[prev]; !ok {
+				matched[prev], matched[hash] = struct{}{}, struct{}{}
+				changes = append(changes, &NodeChange{Owner: owner, Path: append([]byte{}, path...), A: prev, B: hash})
+			}
+		}
+	})
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Owner != changes[j].Owner {
+			return changes[i].Owner.Cmp(changes[j].Owner) < 0
+		}
+		return string(changes[i].Path) < string(changes[j].Path)
+	})
+	return changes
+}
+
+// walkPaths walks the witness state tries from the pre-state root
+func walkPaths(w *Witness, onNode func(owner, hash gethcommon.Hash, path []byte)) {
+	if w == nil || len(w.Ancestors) == 0 {
+		return
+	}
+	nodes := make([][]byte, 0, len(w.State))
+	for _, node := range w.State {
+		nodes = append(nodes, node)
+	}
+	trie.WalkNodePaths(w.Ancestors[0].Root, nodes, func(owner, hash gethcommon.Hash, path, _ []byte) {
+		onNode(owner, hash, path)
+	})
+}
+
+func sortedHashes(set map[gethcommon.Hash]struct{}) []gethcommon.Hash {
+	if len(set) == 0 {
+		return nil
+	}
+	hashes := make([]gethcommon.Hash, 0, len(set))
+	for hash := range set {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].Cmp(hashes[j]) < 0 })
+	return hashes
+}
@@ -0,0 +1,96 @@
+package input
+
+import (
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDiffInput returns an input whose witness state is a trie of 16 accounts, the first one holding value
+func testDiffInput(t *testing.T, value byte) *ProverInput {
+	db := triedb.NewDatabase(rawdb.NewMemoryDatabase(), &triedb.Config{HashDB: &hashdb.Config{}})
+	tr := gethtrie.NewEmpty(db)
+	for i := byte(0); i < 16; i++ {
+		require.NoError(t, tr.Update(crypto.Keccak256([]byte{i}), []byte{0x01, i}))
+	}
+	require.NoError(t, tr.Update(crypto.Keccak256([]byte{0}), []byte{value}))
+	root, nodes := commitTrie(t, db, tr, gethtypes.EmptyRootHash)
+
+	in := testProverInput()
+	in.Witness.State = nodes
+	in.Witness.Ancestors = []*gethtypes.Header{{Number: big.NewInt(9), Difficulty: new(big.Int), Root: root}}
+	return in
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("identical", func(t *testing.T) {
+		diff, err := Diff(testDiffInput(t, 1), testDiffInput(t, 1))
+		require.NoError(t, err)
+		assert.True(t, diff.Empty())
+		assert.Equal(t, "prover inputs are identical", diff.String())
+	})
+
+	t.Run("changed node", func(t *testing.T) {
+		a, b := testDiffInput(t, 1), testDiffInput(t, 2)
+		diff, err := Diff(a, b)
+		require.NoError(t, err)
+		assert.False(t, diff.Empty())
+
+		// Changing the leaf changes the nodes on its path, which are reported at their path, the root first
+		require.NotEmpty(t, diff.ChangedNodes)
+		assert.Equal(t, hexutil.Bytes{}, diff.ChangedNodes[0].Path)
+		assert.Equal(t, a.Witness.Ancestors[0].Root, diff.ChangedNodes[0].A)
+		assert.Equal(t, b.Witness.Ancestors[0].Root, diff.ChangedNodes[0].B)
+		var nibbles []byte
+		for _, b := range crypto.Keccak256([]byte{0}) {
+			nibbles = append(nibbles, b>>4, b&0x0f)
+		}
+		for _, change := range diff.ChangedNodes {
+			assert.Equal(t, nibbles[:len(change.Path)], []byte(change.Path))
+		}
+		assert.Empty(t, diff.AddedNodes)
+		assert.Empty(t, diff.RemovedNodes)
+
+		// The pre-state root differs in the ancestors, not in the blocks
+		assert.Empty(t, diff.Blocks)
+		assert.Contains(t, diff.String(), "witness state: 0 added, 0 removed")
+	})
+
+	t.Run("unreachable node and codes", func(t *testing.T) {
+		a, b := testDiffInput(t, 1), testDiffInput(t, 1)
+		b.Witness.State = append(b.Witness.State, hexutil.Bytes{0xc2, 0x20, 0x03})
+		b.Witness.Codes = []hexutil.Bytes{{0x60, 0x01}}
+		diff, err := Diff(a, b)
+		require.NoError(t, err)
+		assert.Equal(t, []gethcommon.Hash{crypto.Keccak256Hash([]byte{0xc2, 0x20, 0x03})}, diff.AddedNodes)
+		assert.Empty(t, diff.ChangedNodes)
+		assert.Equal(t, []gethcommon.Hash{crypto.Keccak256Hash([]byte{0x60, 0x01})}, diff.AddedCodes)
+		assert.Equal(t, []gethcommon.Hash{crypto.Keccak256Hash([]byte{0x60, 0x00})}, diff.RemovedCodes)
+	})
+
+	t.Run("different block count", func(t *testing.T) {
+		a, b := testDiffInput(t, 1), testDiffInput(t, 1)
+		next := &Block{Header: &gethtypes.Header{Number: big.NewInt(11), ParentHash: a.Blocks[0].Header.Hash(), Difficulty: new(big.Int)}}
+		b.Blocks = append(b.Blocks, next)
+		b.Blocks[0] = &Block{Header: gethtypes.CopyHeader(a.Blocks[0].Header), Transactions: a.Blocks[0].Transactions}
+		b.Blocks[0].Header.GasUsed = 21000
+
+		diff, err := Diff(a, b)
+		require.NoError(t, err)
+		assert.Equal(t, 1, diff.BlockCountA)
+		assert.Equal(t, 2, diff.BlockCountB)
+		assert.Equal(t, []*BlockDiff{{Index: 0, Fields: []string{"gasUsed", "hash"}}}, diff.Blocks)
+		assert.Contains(t, diff.String(), "block count: 1 != 2")
+		assert.Contains(t, diff.String(), "block 0: gasUsed, hash differ")
+	})
+}
@@ -1,9 +1,14 @@
 package input
 
 import (
+	"encoding/json"
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 )
 
 // ProverInput contains the data expected by an EVM prover engine to execute & prove the block.
@@ -13,12 +18,45 @@ type ProverInput struct {
 	Blocks      []*Block            `json:"blocks"`      // Block to execute
 	Witness     *Witness            `json:"witness"`     // Ancestors of the block that are accessed during the block execution
 	ChainConfig *params.ChainConfig `json:"chainConfig"` // Chain configuration
+
+	// Optional, eth_getProof proofs of the accessed accounts and storage slots at the parent state, and of the deleted ones at the block state
+	PreStateProofs  []*trie.AccountProof `json:"preStateProofs,omitempty"`
+	PostStateProofs []*trie.AccountProof `json:"postStateProofs,omitempty"`
+
+	// Optional, set when the input is scoped to a single transaction of the block
+	TxScope *TransactionScope `json:"txScope,omitempty"`
+
+	// Optional, software versions that produced the input, see Metadata
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// TransactionScope scopes a ProverInput to a single transaction.
+// The input block holds the block header and the scoped transaction only,
+// and the witness state roots to the intermediate state the transaction is executed on.
+type TransactionScope struct {
+	Index         uint64          `json:"index"`         // Index of the transaction in the block
+	PreStateRoot  gethcommon.Hash `json:"preStateRoot"`  // State root before the transaction
+	PostStateRoot gethcommon.Hash `json:"postStateRoot"` // State root after the transaction
+	GasUsed       uint64          `json:"gasUsed"`       // Gas used by the transaction
 }
 
 type Witness struct {
-	State     []hexutil.Bytes     `json:"state"`     // Partial pre-state, consisting in a list of MPT nodes
+	State     []hexutil.Bytes     `json:"state"`     // Partial pre-state, consisting in a list of MPT nodes, including the nodes proving the absence of the accessed missing accounts and slots
 	Ancestors []*gethtypes.Header `json:"ancestors"` // Ancestors of the block that are accessed during the block execution
 	Codes     []hexutil.Bytes     `json:"codes"`     // Contract bytecodes used during the block execution
+
+	// Optional, state nodes grouped by owning trie: zero hash for the account trie, hash of the account address for storage tries
+	StateByOwner map[gethcommon.Hash][]hexutil.Bytes `json:"stateByOwner,omitempty"`
+
+	// Optional, branch nodes omitted from State that are regenerated from their children, see CompactWitness
+	CompactBranches []hexutil.Bytes `json:"compactBranches,omitempty"`
+
+	// Optional, state nodes keyed by owner and path for provers expecting the path scheme, replacing State
+	StateByPath []*trie.PathNode `json:"stateByPath,omitempty"`
+
+	// Optional, trie nodes created by the block, whose addition to the pre-state holds the post-state, replacing State
+	// for provers maintaining their own state
+	StateDiff []hexutil.Bytes `json:"stateDiff,omitempty"`
 }
 
 type Block struct {
@@ -26,6 +64,78 @@ type Block struct {
 	Transactions []*gethtypes.Transaction `json:"transaction"`
 	Uncles       []*gethtypes.Header      `json:"uncles"`
 	Withdrawals  []*gethtypes.Withdrawal  `json:"withdrawals"`
+
+	// Optional, sender addresses of the transactions in the same order, saving provers from ECDSA recovery
+	Senders []gethcommon.Address `json:"senders,omitempty"`
+
+	// Optional, logs emitted by each transaction during the execution, in the same order as the transactions
+	Logs [][]*Log `json:"logs,omitempty"`
+
+	// Optional, receipts of the transactions produced by the execution, with their gas used, cumulative gas used and logs bloom
+	Receipts []*gethtypes.Receipt `json:"receipts,omitempty"`
+}
+
+// Log is a log emitted during the execution of a transaction, in emission order
+type Log struct {
+	Address gethcommon.Address `json:"address"`
+	Topics  []gethcommon.Hash  `json:"topics"`
+	Data    hexutil.Bytes      `json:"data"`
+}
+
+// blockJSON has the fields of Block without its JSON methods, so they are encoded as is
+type blockJSON Block
+
+// MarshalJSON encodes the block as JSON, the transactions being hex encoded in their binary form (RLP, prefixed with the type for typed transactions)
+// so they can be decoded without go-ethereum JSON types.
+func (b *Block) MarshalJSON() ([]byte, error) {
+	enc := struct {
+		*blockJSON
+		Transactions []hexutil.Bytes `json:"transaction"`
+	}{blockJSON: (*blockJSON)(b)}
+	if b.Transactions != nil {
+		enc.Transactions = make([]hexutil.Bytes, 0, len(b.Transactions))
+		for i, tx := range b.Transactions {
+			bin, err := tx.MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode transaction %d: %v", i, err)
+			}
+			enc.Transactions = append(enc.Transactions, bin)
+		}
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a block encoded with MarshalJSON.
+// Transactions encoded as go-ethereum JSON objects, as by previous versions, are also accepted.
+func (b *Block) UnmarshalJSON(data []byte) error {
+	dec := struct {
+		*blockJSON
+		Transactions []json.RawMessage `json:"transaction"`
+	}{blockJSON: (*blockJSON)(b)}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+
+	b.Transactions = nil
+	if dec.Transactions != nil {
+		b.Transactions = make([]*gethtypes.Transaction, 0, len(dec.Transactions))
+	}
+	for i, raw := range dec.Transactions {
+		tx := new(gethtypes.Transaction)
+		if len(raw) > 0 && raw[0] == '"' {
+			var bin hexutil.Bytes
+			if err := json.Unmarshal(raw, &bin); err != nil {
+				return fmt.Errorf("failed to decode transaction %d: %v", i, err)
+			}
+			if err := tx.UnmarshalBinary(bin); err != nil {
+				return fmt.Errorf("failed to decode transaction %d: %v", i, err)
+			}
+		} else if err := tx.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("failed to decode transaction %d: %v", i, err)
+		}
+		b.Transactions = append(b.Transactions, tx)
+	}
+	return nil
 }
 
 func (b *Block) Block() *gethtypes.Block {
@@ -1 +1,73 @@
 package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockJSON(t *testing.T) {
+	to := gethcommon.HexToAddress("0xdead")
+	in := testProverInput()
+	in.Blocks[0].Transactions = append(in.Blocks[0].Transactions, gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     2,
+		To:        &to,
+		Value:     big.NewInt(1),
+		Gas:       21000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+	}))
+	in.Blocks[0].Uncles = []*gethtypes.Header{{Number: big.NewInt(9), Difficulty: big.NewInt(1), Extra: []byte("uncle")}}
+	in.Blocks[0].Withdrawals = gethtypes.Withdrawals{{Index: 1, Validator: 2, Address: to, Amount: 3}}
+
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+
+	// Transactions are hex encoded in their binary form
+	var raw struct {
+		Blocks []struct {
+			Transactions []string `json:"transaction"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(b, &raw))
+	require.Len(t, raw.Blocks[0].Transactions, 2)
+	for i, tx := range in.Blocks[0].Transactions {
+		bin, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		assert.Equal(t, gethcommon.Bytes2Hex(bin), raw.Blocks[0].Transactions[i][2:])
+	}
+
+	decoded, err := Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	reencoded, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(b), string(reencoded))
+	assert.Equal(t, in.Blocks[0].Block().Hash(), decoded.Blocks[0].Block().Hash())
+	assert.Equal(t, in.Blocks[0].Transactions[1].Hash(), decoded.Blocks[0].Transactions[1].Hash())
+	assert.Equal(t, in.Blocks[0].Uncles[0].Hash(), decoded.Blocks[0].Uncles[0].Hash())
+	assert.Equal(t, in.Blocks[0].Withdrawals, decoded.Blocks[0].Withdrawals)
+	assert.Equal(t, in.ChainConfig, decoded.ChainConfig)
+
+	t.Run("go-ethereum JSON transactions", func(t *testing.T) {
+		txJSON, err := json.Marshal(in.Blocks[0].Transactions)
+		require.NoError(t, err)
+		var block Block
+		require.NoError(t, json.Unmarshal([]byte(`{"header":null,"transaction":`+string(txJSON)+`}`), &block))
+		require.Len(t, block.Transactions, 2)
+		assert.Equal(t, in.Blocks[0].Transactions[0].Hash(), block.Transactions[0].Hash())
+		assert.Equal(t, in.Blocks[0].Transactions[1].Hash(), block.Transactions[1].Hash())
+	})
+
+	t.Run("no transactions", func(t *testing.T) {
+		var block Block
+		require.NoError(t, json.Unmarshal([]byte(`{"header":null,"transaction":null}`), &block))
+		assert.Nil(t, block.Transactions)
+	})
+}
@@ -0,0 +1,133 @@
+package input
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sort"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ContentHash returns the hash of the content of a ProverInput.
+//
+// It is the Keccak256 hash of the RLP encoding of the input, so it does not depend on the serialization format
+// or compression the input is stored with. Metadata is not part of the content so it is excluded.
+func ContentHash(in *ProverInput) (gethcommon.Hash, error) {
+	content := *in
+	content.Metadata = nil
+
+	var buf bytes.Buffer
+	if err := (&rlpCodec{}).Encode(&buf, &content); err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("failed to encode prover input: %v", err)
+	}
+	return crypto.Keccak256Hash(buf.Bytes()), nil
+}
+
+// Manifest lists the prover inputs of a range of blocks by content hash.
+// It can be signed with an Ed25519 key so consumers can verify the inputs come from a trusted preparer.
+type Manifest struct {
+	ChainID   uint64           `json:"chainId"`
+	Entries   []*ManifestEntry `json:"entries"`             // Inputs sorted by block number
+	Signature hexutil.Bytes    `json:"signature,omitempty"` // Ed25519 signature of the chain ID and entries
+}
+
+// ManifestEntry identifies the prover input of a block
+type ManifestEntry struct {
+	BlockNumber uint64          `json:"blockNumber"`
+	BlockHash   gethcommon.Hash `json:"blockHash"`
+	ContentHash gethcommon.Hash `json:"contentHash"` // See ContentHash
+}
+
+// ErrInvalidSignature is returned when the signature of a manifest does not verify
+var ErrInvalidSignature = errors.New("invalid manifest signature")
+
+// NewManifest creates the unsigned manifest of the prover inputs of a chain
+func NewManifest(inputs []*ProverInput) (*Manifest, error) {
+	m := &Manifest{}
+	for i, in := range inputs {
+		if len(in.Blocks) == 0 {
+			return nil, fmt.Errorf("prover input %d has no block", i)
+		}
+
+		chainID := in.ChainConfig.ChainID.Uint64()
+		if i == 0 {
+			m.ChainID = chainID
+		} else if chainID != m.ChainID {
+			return nil, fmt.Errorf("prover input %d is for chain %d, expected chain %d", i, chainID, m.ChainID)
+		}
+
+		contentHash, err := ContentHash(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute content hash of prover input %d: %v", i, err)
+		}
+		m.Entries = append(m.Entries, &ManifestEntry{
+			BlockNumber: in.Blocks[0].Header.Number.Uint64(),
+			BlockHash:   in.Blocks[0].Header.Hash(),
+			ContentHash: contentHash,
+		})
+	}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].BlockNumber < m.Entries[j].BlockNumber })
+
+	return m, nil
+}
+
+// signingPayload is the RLP encoding of the chain ID and entries, so the signature does not depend on the JSON formatting
+func (m *Manifest) signingPayload() ([]byte, error) {
+	return rlp.EncodeToBytes([]interface{}{m.ChainID, m.Entries})
+}
+
+// Sign signs the manifest with an Ed25519 private key
+func (m *Manifest) Sign(key ed25519.PrivateKey) error {
+	payload, err := m.signingPayload()
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	m.Signature = ed25519.Sign(key, payload)
+	return nil
+}
+
+// VerifySignature verifies the manifest has been signed by the private key of pub.
+// It returns ErrInvalidSignature if the manifest is unsigned or has been tampered with.
+func (m *Manifest) VerifySignature(pub ed25519.PublicKey) error {
+	payload, err := m.signingPayload()
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, payload, m.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyInput verifies a prover input is listed in the manifest with the same content hash.
+// The manifest signature should be verified first with VerifySignature.
+func (m *Manifest) VerifyInput(in *ProverInput) error {
+	if len(in.Blocks) == 0 {
+		return fmt.Errorf("prover input has no block")
+	}
+	if chainID := in.ChainConfig.ChainID.Uint64(); chainID != m.ChainID {
+		return fmt.Errorf("prover input is for chain %d, manifest is for chain %d", chainID, m.ChainID)
+	}
+
+	blockHash := in.Blocks[0].Header.Hash()
+	for _, entry := range m.Entries {
+		if entry.BlockHash != blockHash {
+			continue
+		}
+		contentHash, err := ContentHash(in)
+		if err != nil {
+			return fmt.Errorf("failed to compute content hash: %v", err)
+		}
+		if contentHash != entry.ContentHash {
+			return fmt.Errorf("content hash mismatch for block %d: manifest has %v, got %v", entry.BlockNumber, entry.ContentHash.Hex(), contentHash.Hex())
+		}
+		return nil
+	}
+
+	return fmt.Errorf("block %v is not listed in the manifest", blockHash.Hex())
+}
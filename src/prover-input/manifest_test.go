@@ -0,0 +1,65 @@
+package input
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	first, second := testProverInput(), testProverInput()
+	second.Blocks[0].Header = &gethtypes.Header{Number: big.NewInt(11), ParentHash: first.Blocks[0].Header.Hash(), Difficulty: new(big.Int)}
+
+	// Metadata is not part of the content hash
+	hash, err := ContentHash(first)
+	require.NoError(t, err)
+	stamped := testProverInput()
+	stamped.Metadata = NewMetadata("v1.2.3")
+	stampedHash, err := ContentHash(stamped)
+	require.NoError(t, err)
+	assert.Equal(t, hash, stampedHash)
+
+	m, err := NewManifest([]*ProverInput{second, first})
+	require.NoError(t, err)
+	require.Len(t, m.Entries, 2)
+	assert.Equal(t, uint64(10), m.Entries[0].BlockNumber)
+	assert.Equal(t, hash, m.Entries[0].ContentHash)
+
+	pub, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.VerifySignature(pub), ErrInvalidSignature)
+	require.NoError(t, m.Sign(key))
+
+	// The signature survives a JSON round trip
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	var loaded Manifest
+	require.NoError(t, json.Unmarshal(b, &loaded))
+	require.NoError(t, loaded.VerifySignature(pub))
+	require.NoError(t, loaded.VerifyInput(first))
+	require.NoError(t, loaded.VerifyInput(second))
+
+	// Signature from another key
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, loaded.VerifySignature(otherPub), ErrInvalidSignature)
+
+	// Tampered manifest
+	loaded.Entries[1].ContentHash = gethcommon.Hash{0x01}
+	assert.ErrorIs(t, loaded.VerifySignature(pub), ErrInvalidSignature)
+
+	// Tampered input
+	tampered := testProverInput()
+	tampered.Witness.State = append(tampered.Witness.State, hexutil.Bytes{0xc2, 0x20, 0x03})
+	assert.ErrorContains(t, m.VerifyInput(tampered), "content hash mismatch")
+
+	// Input not listed
+	assert.ErrorContains(t, m.VerifyInput(&ProverInput{ChainConfig: first.ChainConfig, Blocks: []*Block{{Header: &gethtypes.Header{Number: big.NewInt(12)}}}}), "not listed")
+}
@@ -0,0 +1,45 @@
+package input
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/version"
+	"github.com/kkrt-labs/go-utils/log"
+	"go.uber.org/zap"
+)
+
+// GethVersion is the version of the go-ethereum library zk-pig is built with
+var GethVersion = fmt.Sprintf("%d.%d.%d-%s", version.Major, version.Minor, version.Patch, version.Meta)
+
+// Metadata records the software that produced a ProverInput, for reproducibility and bug triage.
+// It is not part of the input content, so it is ignored when comparing inputs.
+type Metadata struct {
+	ZkPigVersion string `json:"zkPigVersion"` // Version of zk-pig that produced the input
+	GethVersion  string `json:"gethVersion"`  // Version of go-ethereum that produced the input
+}
+
+// NewMetadata returns the metadata of an input produced by the given zk-pig version with the current go-ethereum library
+func NewMetadata(zkPigVersion string) *Metadata {
+	return &Metadata{
+		ZkPigVersion: zkPigVersion,
+		GethVersion:  GethVersion,
+	}
+}
+
+// WarnOnVersionMismatch logs a warning if the input has been produced by a different zk-pig or go-ethereum version
+// than the current binary. It returns true if a warning has been logged. Inputs without metadata are not checked.
+func WarnOnVersionMismatch(ctx context.Context, in *ProverInput, zkPigVersion string) bool {
+	if in.Metadata == nil || (in.Metadata.ZkPigVersion == zkPigVersion && in.Metadata.GethVersion == GethVersion) {
+		return false
+	}
+
+	log.LoggerFromContext(ctx).Warn(
+		"Prover input produced by a different version",
+		zap.String("producer.zkpig", in.Metadata.ZkPigVersion),
+		zap.String("producer.geth", in.Metadata.GethVersion),
+		zap.String("current.zkpig", zkPigVersion),
+		zap.String("current.geth", GethVersion),
+	)
+	return true
+}
@@ -0,0 +1,50 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kkrt-labs/go-utils/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMetadata(t *testing.T) {
+	in := testProverInput()
+	in.Metadata = NewMetadata("v1.2.3")
+	assert.Equal(t, "v1.2.3", in.Metadata.ZkPigVersion)
+	assert.Equal(t, GethVersion, in.Metadata.GethVersion)
+
+	for _, name := range []string{CodecJSON, CodecRLP} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := GetCodec(name)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, codec.Encode(&buf, in))
+			decoded, err := codec.Decode(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, in.Metadata, decoded.Metadata)
+			assert.Nil(t, decoded.TxScope)
+		})
+	}
+
+	// Metadata is not part of the input content
+	assert.True(t, CompareProverInput(in, testProverInput()))
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	ctx := log.WithLogger(context.Background(), zap.New(core))
+	assert.False(t, WarnOnVersionMismatch(ctx, in, "v1.2.3"))
+	assert.False(t, WarnOnVersionMismatch(ctx, testProverInput(), "v1.2.4"))
+	assert.Equal(t, 0, logs.Len())
+
+	assert.True(t, WarnOnVersionMismatch(ctx, in, "v1.2.4"))
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "v1.2.3", entries[0].ContextMap()["producer.zkpig"])
+	assert.Equal(t, "v1.2.4", entries[0].ContextMap()["current.zkpig"])
+}
@@ -0,0 +1,111 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// OutputFormat is a layout of the ProverInput expected by a specific prover
+type OutputFormat int
+
+const (
+	// OutputFormatJSON is the canonical zk-pig JSON layout
+	OutputFormatJSON OutputFormat = iota
+	// OutputFormatGethStateless is the RLP encoded (block, witness) pair expected by go-ethereum stateless execution (core.ExecuteStateless)
+	OutputFormatGethStateless
+)
+
+var outputFormatNames = map[OutputFormat]string{
+	OutputFormatJSON:          "json",
+	OutputFormatGethStateless: "geth-stateless",
+}
+
+func (f OutputFormat) String() string {
+	if name, ok := outputFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("OutputFormat(%d)", int(f))
+}
+
+// ParseOutputFormat parses an output format from its name
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	for f, n := range outputFormatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown output format %q", name)
+}
+
+// outputAdapter writes a ProverInput in the layout of a given format
+type outputAdapter func(w io.Writer, in *ProverInput) error
+
+var outputAdapters = map[OutputFormat]outputAdapter{
+	OutputFormatJSON:          writeJSON,
+	OutputFormatGethStateless: writeGethStateless,
+}
+
+// Encode writes the ProverInput to w in the given output format
+func Encode(w io.Writer, in *ProverInput, format OutputFormat) error {
+	adapter, ok := outputAdapters[format]
+	if !ok {
+		return fmt.Errorf("unsupported output format %v", format)
+	}
+	return adapter(w, in)
+}
+
+// checksummedProverInput is the JSON encoding of a ProverInput, along with its checksum
+type checksummedProverInput struct {
+	*ProverInput
+	Checksum *gethcommon.Hash `json:"checksum,omitempty"` // See Checksum
+}
+
+func writeJSON(w io.Writer, in *ProverInput) error {
+	checksum, err := Checksum(in)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(&checksummedProverInput{ProverInput: in, Checksum: &checksum})
+}
+
+// gethStatelessInput mirrors the arguments of go-ethereum stateless execution
+type gethStatelessInput struct {
+	Block   *gethtypes.Block
+	Witness *gethExtWitness
+}
+
+// gethExtWitness has the same RLP layout as go-ethereum stateless.Witness
+// It is re-declared so the input order of the codes and state nodes is preserved
+type gethExtWitness struct {
+	Headers []*gethtypes.Header
+	Codes   [][]byte
+	State   [][]byte
+}
+
+func writeGethStateless(w io.Writer, in *ProverInput) error {
+	if len(in.Blocks) != 1 {
+		return fmt.Errorf("geth stateless format supports a single block, got %d", len(in.Blocks))
+	}
+
+	witness := &gethExtWitness{
+		Headers: in.Witness.Ancestors,
+		Codes:   make([][]byte, 0, len(in.Witness.Codes)),
+		State:   make([][]byte, 0, len(in.Witness.State)),
+	}
+	for _, code := range in.Witness.Codes {
+		witness.Codes = append(witness.Codes, code)
+	}
+	for _, node := range in.Witness.State {
+		witness.State = append(witness.State, node)
+	}
+
+	return rlp.Encode(w, &gethStatelessInput{
+		Block:   in.Blocks[0].Block(),
+		Witness: witness,
+	})
+}
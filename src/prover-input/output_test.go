@@ -0,0 +1,47 @@
+package input
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeGethStateless(t *testing.T) {
+	in := testProverInput()
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, in, OutputFormatGethStateless))
+
+	golden, err := os.ReadFile("testdata/geth_stateless.golden")
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(golden)), hexutil.Encode(buf.Bytes()))
+
+	// Output decodes into go-ethereum stateless types
+	var decoded struct {
+		Block   *gethtypes.Block
+		Witness *stateless.Witness
+	}
+	require.NoError(t, rlp.DecodeBytes(buf.Bytes(), &decoded))
+	assert.Equal(t, in.Blocks[0].Header.Hash(), decoded.Block.Hash())
+	assert.Len(t, decoded.Witness.State, len(in.Witness.State))
+	assert.Len(t, decoded.Witness.Codes, len(in.Witness.Codes))
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, format := range []OutputFormat{OutputFormatJSON, OutputFormatGethStateless} {
+		parsed, err := ParseOutputFormat(format.String())
+		require.NoError(t, err)
+		assert.Equal(t, format, parsed)
+	}
+
+	_, err := ParseOutputFormat("unknown")
+	assert.Error(t, err)
+}
@@ -1,49 +1,192 @@
 package proto
 
 import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
 )
 
-// ToProto converts Go input.ProverInput to protobuf format
-func ToProto(pi *input.ProverInput) *ProverInput {
+// SchemaVersion is the version of the protobuf schema inputs are encoded with by ToProto.
+//
+// Version 1, the default for inputs encoded before versioning, holds structured headers and transactions.
+// Version 2 holds RLP encoded headers and transactions in their binary form.
+const SchemaVersion = 2
+
+// ToProto converts Go input.ProverInput to protobuf format, encoded with the SchemaVersion schema
+func ToProto(pi *input.ProverInput) (*ProverInput, error) {
 	if pi == nil {
-		return nil
+		return nil, nil
 	}
 
-	return &ProverInput{
-		Version:     pi.Version,
-		Blocks:      BlocksToProto(pi.Blocks),
-		Witness:     WitnessToProto(pi.Witness),
-		ChainConfig: ChainConfigToProto(pi.ChainConfig),
+	var blocks []*Block
+	if pi.Blocks != nil {
+		blocks = make([]*Block, len(pi.Blocks))
+		for i, b := range pi.Blocks {
+			block, err := blockToProtoRLP(b)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert block %d: %v", i, err)
+			}
+			blocks[i] = block
+		}
+	}
+
+	witness, err := WitnessToProto(pi.Witness)
+	if err != nil {
+		return nil, err
+	}
+
+	preStateProofs, err := accountProofsToProto(pi.PreStateProofs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert pre-state proofs: %v", err)
+	}
+	postStateProofs, err := accountProofsToProto(pi.PostStateProofs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert post-state proofs: %v", err)
+	}
+
+	checksum, err := input.Checksum(pi)
+	if err != nil {
+		return nil, err
 	}
+
+	return &ProverInput{
+		SchemaVersion:   SchemaVersion,
+		Version:         pi.Version,
+		Blocks:          blocks,
+		Witness:         witness,
+		ChainConfig:     ChainConfigToProto(pi.ChainConfig),
+		Checksum:        checksum.Bytes(),
+		PreStateProofs:  preStateProofs,
+		PostStateProofs: postStateProofs,
+		TxScope:         txScopeToProto(pi.TxScope),
+		Metadata:        metadataToProto(pi.Metadata),
+	}, nil
 }
 
-func FromProto(pi *ProverInput) *input.ProverInput {
+// FromProto converts a protobuf ProverInput to Go input.ProverInput, encoded with any schema up to SchemaVersion
+func FromProto(pi *ProverInput) (*input.ProverInput, error) {
 	if pi == nil {
-		return nil
+		return nil, nil
+	}
+
+	if pi.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported prover input schema version %d, latest supported is %d", pi.SchemaVersion, SchemaVersion)
+	}
+
+	if pi.SchemaVersion < 2 {
+		return &input.ProverInput{
+			Version:     pi.Version,
+			Blocks:      BlocksFromProto(pi.Blocks),
+			Witness:     witnessFromProtoV1(pi.Witness),
+			ChainConfig: ChainConfigFromProto(pi.ChainConfig),
+		}, nil
+	}
+
+	var blocks []*input.Block
+	if pi.Blocks != nil {
+		blocks = make([]*input.Block, len(pi.Blocks))
+		for i, b := range pi.Blocks {
+			block, err := blockFromProtoRLP(b)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert block %d: %v", i, err)
+			}
+			blocks[i] = block
+		}
+	}
+
+	witness, err := WitnessFromProto(pi.Witness)
+	if err != nil {
+		return nil, err
 	}
 
-	return &input.ProverInput{
-		Version:     pi.Version,
-		Blocks:      BlocksFromProto(pi.Blocks),
-		Witness:     WitnessFromProto(pi.Witness),
-		ChainConfig: ChainConfigFromProto(pi.ChainConfig),
+	in := &input.ProverInput{
+		Version:         pi.Version,
+		Blocks:          blocks,
+		Witness:         witness,
+		ChainConfig:     ChainConfigFromProto(pi.ChainConfig),
+		PreStateProofs:  accountProofsFromProto(pi.PreStateProofs),
+		PostStateProofs: accountProofsFromProto(pi.PostStateProofs),
+		TxScope:         txScopeFromProto(pi.TxScope),
+		Metadata:        metadataFromProto(pi.Metadata),
 	}
+	if err := input.VerifyChecksum(in, gethcommon.BytesToHash(pi.Checksum)); err != nil {
+		return nil, err
+	}
+
+	return in, nil
 }
 
-func WitnessToProto(w *input.Witness) *Witness {
+// WitnessToProto converts Go input.Witness to protobuf format, with RLP encoded ancestors
+func WitnessToProto(w *input.Witness) (*Witness, error) {
 	if w == nil {
-		return nil
+		return nil, nil
 	}
 
-	return &Witness{
-		Ancestors: HeadersToProto(w.Ancestors),
-		State:     hexBytesToBytes(w.State),
-		Codes:     hexBytesToBytes(w.Codes),
+	ancestors, err := headersToRLP(w.Ancestors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert ancestors: %v", err)
+	}
+
+	witness := &Witness{
+		AncestorsRlp:    ancestors,
+		State:           hexBytesToBytes(w.State),
+		Codes:           hexBytesToBytes(w.Codes),
+		CompactBranches: hexBytesToBytes(w.CompactBranches),
+		StateDiff:       hexBytesToBytes(w.StateDiff),
+	}
+	for owner, nodes := range w.StateByOwner {
+		witness.StateByOwner = append(witness.StateByOwner, &OwnerNodes{Owner: owner.Bytes(), Nodes: hexBytesToBytes(nodes)})
 	}
+	// Map iteration order is random, owners are sorted so the encoding is deterministic
+	sort.Slice(witness.StateByOwner, func(i, j int) bool {
+		return bytes.Compare(witness.StateByOwner[i].Owner, witness.StateByOwner[j].Owner) < 0
+	})
+	for _, node := range w.StateByPath {
+		witness.StateByPath = append(witness.StateByPath, &PathNode{Owner: node.Owner.Bytes(), Path: node.Path, Blob: node.Blob})
+	}
+
+	return witness, nil
 }
 
-func WitnessFromProto(w *Witness) *input.Witness {
+// WitnessFromProto converts a protobuf Witness encoded with WitnessToProto to Go input.Witness
+func WitnessFromProto(w *Witness) (*input.Witness, error) {
+	if w == nil {
+		return nil, nil
+	}
+
+	ancestors, err := headersFromRLP(w.AncestorsRlp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert ancestors: %v", err)
+	}
+
+	witness := &input.Witness{
+		Ancestors:       ancestors,
+		State:           bytesToHexutil(w.State),
+		Codes:           bytesToHexutil(w.Codes),
+		CompactBranches: bytesToHexutil(w.CompactBranches),
+		StateDiff:       bytesToHexutil(w.StateDiff),
+	}
+	for _, group := range w.StateByOwner {
+		if witness.StateByOwner == nil {
+			witness.StateByOwner = make(map[gethcommon.Hash][]hexutil.Bytes)
+		}
+		witness.StateByOwner[gethcommon.BytesToHash(group.Owner)] = bytesToHexutil(group.Nodes)
+	}
+	for _, node := range w.StateByPath {
+		witness.StateByPath = append(witness.StateByPath, &trie.PathNode{Owner: gethcommon.BytesToHash(node.Owner), Path: node.Path, Blob: node.Blob})
+	}
+
+	return witness, nil
+}
+
+// witnessFromProtoV1 converts a protobuf Witness with structured ancestors (schema version 1)
+func witnessFromProtoV1(w *Witness) *input.Witness {
 	if w == nil {
 		return nil
 	}
@@ -54,3 +197,134 @@ func WitnessFromProto(w *Witness) *input.Witness {
 		Codes:     bytesToHexutil(w.Codes),
 	}
 }
+
+func accountProofsToProto(proofs []*trie.AccountProof) ([]*AccountProof, error) {
+	if proofs == nil {
+		return nil, nil
+	}
+
+	result := make([]*AccountProof, len(proofs))
+	for i, p := range proofs {
+		proof, err := proofNodesToBytes(p.Proof)
+		if err != nil {
+			return nil, fmt.Errorf("account %v: %v", p.Address.Hex(), err)
+		}
+		result[i] = &AccountProof{
+			Address:     p.Address.Bytes(),
+			Proof:       proof,
+			Balance:     p.Balance.ToInt().Bytes(),
+			CodeHash:    p.CodeHash.Bytes(),
+			Nonce:       p.Nonce,
+			StorageHash: p.StorageHash.Bytes(),
+		}
+		for _, st := range p.Storage {
+			proof, err := proofNodesToBytes(st.Proof)
+			if err != nil {
+				return nil, fmt.Errorf("slot %v of account %v: %v", st.Key, p.Address.Hex(), err)
+			}
+			result[i].Storage = append(result[i].Storage, &StorageProof{Key: st.Key, Value: st.Value.ToInt().Bytes(), Proof: proof})
+		}
+	}
+	return result, nil
+}
+
+func accountProofsFromProto(proofs []*AccountProof) []*trie.AccountProof {
+	if proofs == nil {
+		return nil
+	}
+
+	result := make([]*trie.AccountProof, len(proofs))
+	for i, p := range proofs {
+		result[i] = &trie.AccountProof{
+			Address:     gethcommon.BytesToAddress(p.Address),
+			Proof:       proofNodesFromBytes(p.Proof),
+			Balance:     hexutil.Big(*new(big.Int).SetBytes(p.Balance)),
+			CodeHash:    gethcommon.BytesToHash(p.CodeHash),
+			Nonce:       p.Nonce,
+			StorageHash: gethcommon.BytesToHash(p.StorageHash),
+		}
+		for _, st := range p.Storage {
+			result[i].Storage = append(result[i].Storage, &trie.StorageProof{
+				Key:   st.Key,
+				Value: hexutil.Big(*new(big.Int).SetBytes(st.Value)),
+				Proof: proofNodesFromBytes(st.Proof),
+			})
+		}
+	}
+	return result
+}
+
+// proofNodesToBytes decodes the hex encoded nodes of an eth_getProof proof
+func proofNodesToBytes(proof []string) ([][]byte, error) {
+	if proof == nil {
+		return nil, nil
+	}
+	result := make([][]byte, len(proof))
+	for i, node := range proof {
+		b, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node %d: %v", i, err)
+		}
+		result[i] = b
+	}
+	return result, nil
+}
+
+func proofNodesFromBytes(proof [][]byte) []string {
+	if proof == nil {
+		return nil
+	}
+	result := make([]string, len(proof))
+	for i, node := range proof {
+		result[i] = hexutil.Encode(node)
+	}
+	return result
+}
+
+func txScopeToProto(scope *input.TransactionScope) *TransactionScope {
+	if scope == nil {
+		return nil
+	}
+
+	return &TransactionScope{
+		Index:         scope.Index,
+		PreStateRoot:  scope.PreStateRoot.Bytes(),
+		PostStateRoot: scope.PostStateRoot.Bytes(),
+		GasUsed:       scope.GasUsed,
+	}
+}
+
+func txScopeFromProto(scope *TransactionScope) *input.TransactionScope {
+	if scope == nil {
+		return nil
+	}
+
+	return &input.TransactionScope{
+		Index:         scope.Index,
+		PreStateRoot:  gethcommon.BytesToHash(scope.PreStateRoot),
+		PostStateRoot: gethcommon.BytesToHash(scope.PostStateRoot),
+		GasUsed:       scope.GasUsed,
+	}
+}
+
+func metadataToProto(m *input.Metadata) *Metadata {
+	if m == nil {
+		return nil
+	}
+
+	return &Metadata{
+		ZkPigVersion: m.ZkPigVersion,
+		GethVersion:  m.GethVersion,
+	}
+}
+
+func metadataFromProto(m *Metadata) *input.Metadata {
+	if m == nil {
+		return nil
+	}
+
+	return &input.Metadata{
+		ZkPigVersion: m.ZkPigVersion,
+		GethVersion:  m.GethVersion,
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.3
+// 	protoc-gen-go v1.36.5
 // 	protoc        v5.29.3
 // source: src/prover-input/proto/input.proto
 
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -22,11 +23,22 @@ const (
 
 // ProverInput contains the minimal data needed for block execution and proof validation
 type ProverInput struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
-	Blocks        []*Block               `protobuf:"bytes,2,rep,name=blocks,proto3" json:"blocks,omitempty"`
-	Witness       *Witness               `protobuf:"bytes,3,opt,name=witness,proto3" json:"witness,omitempty"`
-	ChainConfig   *ChainConfig           `protobuf:"bytes,4,opt,name=chain_config,json=chainConfig,proto3" json:"chain_config,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Version     string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Blocks      []*Block               `protobuf:"bytes,2,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	Witness     *Witness               `protobuf:"bytes,3,opt,name=witness,proto3" json:"witness,omitempty"`
+	ChainConfig *ChainConfig           `protobuf:"bytes,4,opt,name=chain_config,json=chainConfig,proto3" json:"chain_config,omitempty"`
+	// Version of the protobuf schema the input is encoded with, 0 for inputs encoded before versioning (schema version 1)
+	SchemaVersion uint32 `protobuf:"varint,5,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	// Integrity checksum of the blocks and witness, see input.Checksum, empty for inputs encoded without checksum
+	Checksum []byte `protobuf:"bytes,6,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// Optional, eth_getProof proofs of the accessed accounts and storage slots at the parent state, and of the deleted ones at the block state
+	PreStateProofs  []*AccountProof `protobuf:"bytes,7,rep,name=pre_state_proofs,json=preStateProofs,proto3" json:"pre_state_proofs,omitempty"`
+	PostStateProofs []*AccountProof `protobuf:"bytes,8,rep,name=post_state_proofs,json=postStateProofs,proto3" json:"post_state_proofs,omitempty"`
+	// Optional, set when the input is scoped to a single transaction of the block
+	TxScope *TransactionScope `protobuf:"bytes,9,opt,name=tx_scope,json=txScope,proto3" json:"tx_scope,omitempty"`
+	// Optional, software versions that produced the input
+	Metadata      *Metadata `protobuf:"bytes,10,opt,name=metadata,proto3" json:"metadata,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,13 +101,61 @@ func (x *ProverInput) GetChainConfig() *ChainConfig {
 	return nil
 }
 
+func (x *ProverInput) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *ProverInput) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+func (x *ProverInput) GetPreStateProofs() []*AccountProof {
+	if x != nil {
+		return x.PreStateProofs
+	}
+	return nil
+}
+
+func (x *ProverInput) GetPostStateProofs() []*AccountProof {
+	if x != nil {
+		return x.PostStateProofs
+	}
+	return nil
+}
+
+func (x *ProverInput) GetTxScope() *TransactionScope {
+	if x != nil {
+		return x.TxScope
+	}
+	return nil
+}
+
+func (x *ProverInput) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 type Witness struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	State         [][]byte               `protobuf:"bytes,1,rep,name=state,proto3" json:"state,omitempty"`
-	Ancestors     []*Header              `protobuf:"bytes,2,rep,name=ancestors,proto3" json:"ancestors,omitempty"`
-	Codes         [][]byte               `protobuf:"bytes,3,rep,name=codes,proto3" json:"codes,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	State        [][]byte               `protobuf:"bytes,1,rep,name=state,proto3" json:"state,omitempty"`
+	Ancestors    []*Header              `protobuf:"bytes,2,rep,name=ancestors,proto3" json:"ancestors,omitempty"` // Set up to schema version 1
+	Codes        [][]byte               `protobuf:"bytes,3,rep,name=codes,proto3" json:"codes,omitempty"`
+	AncestorsRlp [][]byte               `protobuf:"bytes,4,rep,name=ancestors_rlp,json=ancestorsRlp,proto3" json:"ancestors_rlp,omitempty"` // RLP encoded ancestors, set from schema version 2
+	// Optional, see input.Witness
+	StateByOwner    []*OwnerNodes `protobuf:"bytes,5,rep,name=state_by_owner,json=stateByOwner,proto3" json:"state_by_owner,omitempty"`
+	CompactBranches [][]byte      `protobuf:"bytes,6,rep,name=compact_branches,json=compactBranches,proto3" json:"compact_branches,omitempty"`
+	StateByPath     []*PathNode   `protobuf:"bytes,7,rep,name=state_by_path,json=stateByPath,proto3" json:"state_by_path,omitempty"`
+	StateDiff       [][]byte      `protobuf:"bytes,8,rep,name=state_diff,json=stateDiff,proto3" json:"state_diff,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *Witness) Reset() {
@@ -149,9 +209,429 @@ func (x *Witness) GetCodes() [][]byte {
 	return nil
 }
 
+func (x *Witness) GetAncestorsRlp() [][]byte {
+	if x != nil {
+		return x.AncestorsRlp
+	}
+	return nil
+}
+
+func (x *Witness) GetStateByOwner() []*OwnerNodes {
+	if x != nil {
+		return x.StateByOwner
+	}
+	return nil
+}
+
+func (x *Witness) GetCompactBranches() [][]byte {
+	if x != nil {
+		return x.CompactBranches
+	}
+	return nil
+}
+
+func (x *Witness) GetStateByPath() []*PathNode {
+	if x != nil {
+		return x.StateByPath
+	}
+	return nil
+}
+
+func (x *Witness) GetStateDiff() [][]byte {
+	if x != nil {
+		return x.StateDiff
+	}
+	return nil
+}
+
+// State nodes of the trie of an owner, zero hash for the account trie, hash of the account address for storage tries
+type OwnerNodes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         []byte                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Nodes         [][]byte               `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OwnerNodes) Reset() {
+	*x = OwnerNodes{}
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OwnerNodes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OwnerNodes) ProtoMessage() {}
+
+func (x *OwnerNodes) ProtoReflect() protoreflect.Message {
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OwnerNodes.ProtoReflect.Descriptor instead.
+func (*OwnerNodes) Descriptor() ([]byte, []int) {
+	return file_src_prover_input_proto_input_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OwnerNodes) GetOwner() []byte {
+	if x != nil {
+		return x.Owner
+	}
+	return nil
+}
+
+func (x *OwnerNodes) GetNodes() [][]byte {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type PathNode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         []byte                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Path          []byte                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Blob          []byte                 `protobuf:"bytes,3,opt,name=blob,proto3" json:"blob,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PathNode) Reset() {
+	*x = PathNode{}
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PathNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PathNode) ProtoMessage() {}
+
+func (x *PathNode) ProtoReflect() protoreflect.Message {
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PathNode.ProtoReflect.Descriptor instead.
+func (*PathNode) Descriptor() ([]byte, []int) {
+	return file_src_prover_input_proto_input_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PathNode) GetOwner() []byte {
+	if x != nil {
+		return x.Owner
+	}
+	return nil
+}
+
+func (x *PathNode) GetPath() []byte {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+func (x *PathNode) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+type AccountProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       []byte                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Proof         [][]byte               `protobuf:"bytes,2,rep,name=proof,proto3" json:"proof,omitempty"`
+	Balance       []byte                 `protobuf:"bytes,3,opt,name=balance,proto3" json:"balance,omitempty"`
+	CodeHash      []byte                 `protobuf:"bytes,4,opt,name=code_hash,json=codeHash,proto3" json:"code_hash,omitempty"`
+	Nonce         uint64                 `protobuf:"varint,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	StorageHash   []byte                 `protobuf:"bytes,6,opt,name=storage_hash,json=storageHash,proto3" json:"storage_hash,omitempty"`
+	Storage       []*StorageProof        `protobuf:"bytes,7,rep,name=storage,proto3" json:"storage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccountProof) Reset() {
+	*x = AccountProof{}
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccountProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountProof) ProtoMessage() {}
+
+func (x *AccountProof) ProtoReflect() protoreflect.Message {
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountProof.ProtoReflect.Descriptor instead.
+func (*AccountProof) Descriptor() ([]byte, []int) {
+	return file_src_prover_input_proto_input_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AccountProof) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *AccountProof) GetProof() [][]byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *AccountProof) GetBalance() []byte {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *AccountProof) GetCodeHash() []byte {
+	if x != nil {
+		return x.CodeHash
+	}
+	return nil
+}
+
+func (x *AccountProof) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *AccountProof) GetStorageHash() []byte {
+	if x != nil {
+		return x.StorageHash
+	}
+	return nil
+}
+
+func (x *AccountProof) GetStorage() []*StorageProof {
+	if x != nil {
+		return x.Storage
+	}
+	return nil
+}
+
+type StorageProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"` // Key as requested to eth_getProof
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Proof         [][]byte               `protobuf:"bytes,3,rep,name=proof,proto3" json:"proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StorageProof) Reset() {
+	*x = StorageProof{}
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StorageProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StorageProof) ProtoMessage() {}
+
+func (x *StorageProof) ProtoReflect() protoreflect.Message {
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StorageProof.ProtoReflect.Descriptor instead.
+func (*StorageProof) Descriptor() ([]byte, []int) {
+	return file_src_prover_input_proto_input_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StorageProof) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *StorageProof) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *StorageProof) GetProof() [][]byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type TransactionScope struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	PreStateRoot  []byte                 `protobuf:"bytes,2,opt,name=pre_state_root,json=preStateRoot,proto3" json:"pre_state_root,omitempty"`
+	PostStateRoot []byte                 `protobuf:"bytes,3,opt,name=post_state_root,json=postStateRoot,proto3" json:"post_state_root,omitempty"`
+	GasUsed       uint64                 `protobuf:"varint,4,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionScope) Reset() {
+	*x = TransactionScope{}
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionScope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionScope) ProtoMessage() {}
+
+func (x *TransactionScope) ProtoReflect() protoreflect.Message {
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionScope.ProtoReflect.Descriptor instead.
+func (*TransactionScope) Descriptor() ([]byte, []int) {
+	return file_src_prover_input_proto_input_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TransactionScope) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *TransactionScope) GetPreStateRoot() []byte {
+	if x != nil {
+		return x.PreStateRoot
+	}
+	return nil
+}
+
+func (x *TransactionScope) GetPostStateRoot() []byte {
+	if x != nil {
+		return x.PostStateRoot
+	}
+	return nil
+}
+
+func (x *TransactionScope) GetGasUsed() uint64 {
+	if x != nil {
+		return x.GasUsed
+	}
+	return 0
+}
+
+type Metadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ZkPigVersion  string                 `protobuf:"bytes,1,opt,name=zk_pig_version,json=zkPigVersion,proto3" json:"zk_pig_version,omitempty"`
+	GethVersion   string                 `protobuf:"bytes,2,opt,name=geth_version,json=gethVersion,proto3" json:"geth_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_src_prover_input_proto_input_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_src_prover_input_proto_input_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Metadata) GetZkPigVersion() string {
+	if x != nil {
+		return x.ZkPigVersion
+	}
+	return ""
+}
+
+func (x *Metadata) GetGethVersion() string {
+	if x != nil {
+		return x.GethVersion
+	}
+	return ""
+}
+
 var File_src_prover_input_proto_input_proto protoreflect.FileDescriptor
 
-var file_src_prover_input_proto_input_proto_rawDesc = []byte{
+var file_src_prover_input_proto_input_proto_rawDesc = string([]byte{
 	0x0a, 0x22, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x2d, 0x69, 0x6e, 0x70,
 	0x75, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x1a, 0x22, 0x73, 0x72, 0x63,
@@ -159,7 +639,7 @@ var file_src_prover_input_proto_input_proto_rawDesc = []byte{
 	0x6f, 0x74, 0x6f, 0x2f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
 	0x29, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x2d, 0x69, 0x6e, 0x70, 0x75,
 	0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x63, 0x6f,
-	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xae, 0x01, 0x0a, 0x0b, 0x50,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xd2, 0x03, 0x0a, 0x0b, 0x50,
 	0x72, 0x6f, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
 	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72,
 	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x02,
@@ -170,49 +650,136 @@ var file_src_prover_input_proto_input_proto_rawDesc = []byte{
 	0x6e, 0x65, 0x73, 0x73, 0x12, 0x35, 0x0a, 0x0c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x63, 0x6f,
 	0x6e, 0x66, 0x69, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x69, 0x6e, 0x70,
 	0x75, 0x74, 0x2e, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x0b,
-	0x63, 0x68, 0x61, 0x69, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0x62, 0x0a, 0x07, 0x57,
-	0x69, 0x74, 0x6e, 0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x2b, 0x0a, 0x09,
-	0x61, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x0d, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x09,
-	0x61, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64,
-	0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x42,
-	0x34, 0x5a, 0x32, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6b, 0x6b,
-	0x72, 0x74, 0x2d, 0x6c, 0x61, 0x62, 0x73, 0x2f, 0x7a, 0x6b, 0x2d, 0x70, 0x69, 0x67, 0x2f, 0x73,
-	0x72, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x2d, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2f,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-}
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x25, 0x0a, 0x0e, 0x73,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x0d, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x3d,
+	0x0a, 0x10, 0x70, 0x72, 0x65, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x70, 0x72, 0x6f, 0x6f,
+	0x66, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74,
+	0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x0e, 0x70,
+	0x72, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x73, 0x12, 0x3f, 0x0a,
+	0x11, 0x70, 0x6f, 0x73, 0x74, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x70, 0x72, 0x6f, 0x6f,
+	0x66, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74,
+	0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x0f, 0x70,
+	0x6f, 0x73, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x73, 0x12, 0x32,
+	0x0a, 0x08, 0x74, 0x78, 0x5f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x17, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x52, 0x07, 0x74, 0x78, 0x53, 0x63, 0x6f,
+	0x70, 0x65, 0x12, 0x2b, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x22,
+	0xbf, 0x02, 0x0a, 0x07, 0x57, 0x69, 0x74, 0x6e, 0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x2b, 0x0a, 0x09, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x48, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x52, 0x09, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x14,
+	0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x63,
+	0x6f, 0x64, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f, 0x72,
+	0x73, 0x5f, 0x72, 0x6c, 0x70, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0c, 0x61, 0x6e, 0x63,
+	0x65, 0x73, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x6c, 0x70, 0x12, 0x37, 0x0a, 0x0e, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x5f, 0x62, 0x79, 0x5f, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x4e,
+	0x6f, 0x64, 0x65, 0x73, 0x52, 0x0c, 0x73, 0x74, 0x61, 0x74, 0x65, 0x42, 0x79, 0x4f, 0x77, 0x6e,
+	0x65, 0x72, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6d, 0x70, 0x61, 0x63, 0x74, 0x5f, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0f, 0x63, 0x6f,
+	0x6d, 0x70, 0x61, 0x63, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x65, 0x73, 0x12, 0x33, 0x0a,
+	0x0d, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x62, 0x79, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x07,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e, 0x50, 0x61, 0x74,
+	0x68, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x65, 0x42, 0x79, 0x50, 0x61,
+	0x74, 0x68, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x64, 0x69, 0x66, 0x66,
+	0x18, 0x08, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x74, 0x61, 0x74, 0x65, 0x44, 0x69, 0x66,
+	0x66, 0x22, 0x38, 0x0a, 0x0a, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12,
+	0x14, 0x0a, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
+	0x6f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x64, 0x65, 0x73, 0x22, 0x48, 0x0a, 0x08, 0x50,
+	0x61, 0x74, 0x68, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x12, 0x0a,
+	0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x70, 0x61, 0x74,
+	0x68, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x04, 0x62, 0x6c, 0x6f, 0x62, 0x22, 0xdd, 0x01, 0x0a, 0x0c, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0c, 0x52,
+	0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65,
+	0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6f, 0x64, 0x65, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x6f, 0x64, 0x65, 0x48, 0x61, 0x73, 0x68, 0x12, 0x14, 0x0a,
+	0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x6e, 0x6f,
+	0x6e, 0x63, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x68,
+	0x61, 0x73, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x73, 0x74, 0x6f, 0x72, 0x61,
+	0x67, 0x65, 0x48, 0x61, 0x73, 0x68, 0x12, 0x2d, 0x0a, 0x07, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67,
+	0x65, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2e,
+	0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x07, 0x73, 0x74,
+	0x6f, 0x72, 0x61, 0x67, 0x65, 0x22, 0x4c, 0x0a, 0x0c, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65,
+	0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x70, 0x72,
+	0x6f, 0x6f, 0x66, 0x22, 0x91, 0x01, 0x0a, 0x10, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x24,
+	0x0a, 0x0e, 0x70, 0x72, 0x65, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x72, 0x6f, 0x6f, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x70, 0x72, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x52, 0x6f, 0x6f, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x70, 0x6f, 0x73, 0x74, 0x5f, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x5f, 0x72, 0x6f, 0x6f, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x70,
+	0x6f, 0x73, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x6f, 0x6f, 0x74, 0x12, 0x19, 0x0a, 0x08,
+	0x67, 0x61, 0x73, 0x5f, 0x75, 0x73, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07,
+	0x67, 0x61, 0x73, 0x55, 0x73, 0x65, 0x64, 0x22, 0x53, 0x0a, 0x08, 0x4d, 0x65, 0x74, 0x61, 0x64,
+	0x61, 0x74, 0x61, 0x12, 0x24, 0x0a, 0x0e, 0x7a, 0x6b, 0x5f, 0x70, 0x69, 0x67, 0x5f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x7a, 0x6b, 0x50,
+	0x69, 0x67, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x67, 0x65, 0x74,
+	0x68, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x67, 0x65, 0x74, 0x68, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x34, 0x5a, 0x32,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6b, 0x6b, 0x72, 0x74, 0x2d,
+	0x6c, 0x61, 0x62, 0x73, 0x2f, 0x7a, 0x6b, 0x2d, 0x70, 0x69, 0x67, 0x2f, 0x73, 0x72, 0x63, 0x2f,
+	0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x2d, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+})
 
 var (
 	file_src_prover_input_proto_input_proto_rawDescOnce sync.Once
-	file_src_prover_input_proto_input_proto_rawDescData = file_src_prover_input_proto_input_proto_rawDesc
+	file_src_prover_input_proto_input_proto_rawDescData []byte
 )
 
 func file_src_prover_input_proto_input_proto_rawDescGZIP() []byte {
 	file_src_prover_input_proto_input_proto_rawDescOnce.Do(func() {
-		file_src_prover_input_proto_input_proto_rawDescData = protoimpl.X.CompressGZIP(file_src_prover_input_proto_input_proto_rawDescData)
+		file_src_prover_input_proto_input_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_src_prover_input_proto_input_proto_rawDesc), len(file_src_prover_input_proto_input_proto_rawDesc)))
 	})
 	return file_src_prover_input_proto_input_proto_rawDescData
 }
 
-var file_src_prover_input_proto_input_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_src_prover_input_proto_input_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_src_prover_input_proto_input_proto_goTypes = []any{
-	(*ProverInput)(nil), // 0: input.ProverInput
-	(*Witness)(nil),     // 1: input.Witness
-	(*Block)(nil),       // 2: input.Block
-	(*ChainConfig)(nil), // 3: input.ChainConfig
-	(*Header)(nil),      // 4: input.Header
+	(*ProverInput)(nil),      // 0: input.ProverInput
+	(*Witness)(nil),          // 1: input.Witness
+	(*OwnerNodes)(nil),       // 2: input.OwnerNodes
+	(*PathNode)(nil),         // 3: input.PathNode
+	(*AccountProof)(nil),     // 4: input.AccountProof
+	(*StorageProof)(nil),     // 5: input.StorageProof
+	(*TransactionScope)(nil), // 6: input.TransactionScope
+	(*Metadata)(nil),         // 7: input.Metadata
+	(*Block)(nil),            // 8: input.Block
+	(*ChainConfig)(nil),      // 9: input.ChainConfig
+	(*Header)(nil),           // 10: input.Header
 }
 var file_src_prover_input_proto_input_proto_depIdxs = []int32{
-	2, // 0: input.ProverInput.blocks:type_name -> input.Block
-	1, // 1: input.ProverInput.witness:type_name -> input.Witness
-	3, // 2: input.ProverInput.chain_config:type_name -> input.ChainConfig
-	4, // 3: input.Witness.ancestors:type_name -> input.Header
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	8,  // 0: input.ProverInput.blocks:type_name -> input.Block
+	1,  // 1: input.ProverInput.witness:type_name -> input.Witness
+	9,  // 2: input.ProverInput.chain_config:type_name -> input.ChainConfig
+	4,  // 3: input.ProverInput.pre_state_proofs:type_name -> input.AccountProof
+	4,  // 4: input.ProverInput.post_state_proofs:type_name -> input.AccountProof
+	6,  // 5: input.ProverInput.tx_scope:type_name -> input.TransactionScope
+	7,  // 6: input.ProverInput.metadata:type_name -> input.Metadata
+	10, // 7: input.Witness.ancestors:type_name -> input.Header
+	2,  // 8: input.Witness.state_by_owner:type_name -> input.OwnerNodes
+	3,  // 9: input.Witness.state_by_path:type_name -> input.PathNode
+	5,  // 10: input.AccountProof.storage:type_name -> input.StorageProof
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_src_prover_input_proto_input_proto_init() }
@@ -226,9 +793,9 @@ func file_src_prover_input_proto_input_proto_init() {
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_src_prover_input_proto_input_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_src_prover_input_proto_input_proto_rawDesc), len(file_src_prover_input_proto_input_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -237,7 +804,6 @@ func file_src_prover_input_proto_input_proto_init() {
 		MessageInfos:      file_src_prover_input_proto_input_proto_msgTypes,
 	}.Build()
 	File_src_prover_input_proto_input_proto = out.File
-	file_src_prover_input_proto_input_proto_rawDesc = nil
 	file_src_prover_input_proto_input_proto_goTypes = nil
 	file_src_prover_input_proto_input_proto_depIdxs = nil
 }
@@ -1,13 +1,19 @@
 package proto
 
 import (
+	"encoding/json"
+	"math/big"
 	"testing"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestInput(t *testing.T) {
@@ -36,8 +42,10 @@ func TestInput(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.desc, func(t *testing.T) {
-			protoInput := ToProto(tc.input)
-			inputFromProto := FromProto(protoInput)
+			protoInput, err := ToProto(tc.input)
+			require.NoError(t, err)
+			inputFromProto, err := FromProto(protoInput)
+			require.NoError(t, err)
 			assert.Equal(t, tc.input, inputFromProto)
 		})
 	}
@@ -76,9 +84,138 @@ func TestWitness(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.desc, func(t *testing.T) {
-			protoWitness := WitnessToProto(tc.input)
-			witnessFromProto := WitnessFromProto(protoWitness)
+			protoWitness, err := WitnessToProto(tc.input)
+			require.NoError(t, err)
+			witnessFromProto, err := WitnessFromProto(protoWitness)
+			require.NoError(t, err)
 			assert.Equal(t, tc.input, witnessFromProto)
 		})
 	}
 }
+
+func testRoundTripInput() *input.ProverInput {
+	to := gethcommon.HexToAddress("0xdead")
+	parent := &gethtypes.Header{Number: big.NewInt(9), Difficulty: new(big.Int), BaseFee: big.NewInt(7)}
+	return &input.ProverInput{
+		Version:     "v1",
+		ChainConfig: params.MainnetChainConfig,
+		Blocks: []*input.Block{{
+			Header: &gethtypes.Header{Number: big.NewInt(10), ParentHash: parent.Hash(), Difficulty: new(big.Int), BaseFee: big.NewInt(7), Extra: []byte("extra")},
+			Transactions: []*gethtypes.Transaction{
+				gethtypes.NewTx(&gethtypes.LegacyTx{Nonce: 1, To: &to, Value: big.NewInt(1), Gas: 21000, GasPrice: big.NewInt(1), V: big.NewInt(27), R: big.NewInt(1), S: big.NewInt(2)}),
+				gethtypes.NewTx(&gethtypes.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 2, To: &to, Value: big.NewInt(1), Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), V: big.NewInt(1), R: big.NewInt(3), S: big.NewInt(4)}),
+			},
+			Uncles:      []*gethtypes.Header{{Number: big.NewInt(9), Difficulty: big.NewInt(1), Extra: []byte("uncle")}},
+			Withdrawals: []*gethtypes.Withdrawal{{Index: 1, Validator: 2, Address: to, Amount: 3}},
+			Senders:     []gethcommon.Address{gethcommon.HexToAddress("0xa1"), gethcommon.HexToAddress("0xa2")},
+			Logs: [][]*input.Log{
+				{},
+				{{Address: to, Topics: []gethcommon.Hash{gethcommon.HexToHash("0x01")}, Data: []byte{0x02}}},
+			},
+			Receipts: []*gethtypes.Receipt{
+				{Type: gethtypes.LegacyTxType, Status: 1, CumulativeGasUsed: 21000, GasUsed: 21000, Logs: []*gethtypes.Log{}, TxHash: gethcommon.HexToHash("0x10")},
+				{Type: gethtypes.DynamicFeeTxType, Status: 1, CumulativeGasUsed: 42000, GasUsed: 21000, Logs: []*gethtypes.Log{}, TxHash: gethcommon.HexToHash("0x11")},
+			},
+		}},
+		Witness: &input.Witness{
+			State:     []hexutil.Bytes{{0xc2, 0x20, 0x01}, {0xc2, 0x20, 0x02}},
+			Ancestors: []*gethtypes.Header{parent},
+			Codes:     []hexutil.Bytes{{0x60, 0x00}},
+			StateByOwner: map[gethcommon.Hash][]hexutil.Bytes{
+				{}:                          {{0xc2, 0x20, 0x01}},
+				gethcommon.HexToHash("0x1"): {{0xc2, 0x20, 0x02}},
+			},
+			CompactBranches: []hexutil.Bytes{{0xc2, 0x20, 0x03}},
+			StateByPath:     []*trie.PathNode{{Owner: gethcommon.HexToHash("0x1"), Path: hexutil.Bytes{0x01, 0x02}, Blob: hexutil.Bytes{0xc2, 0x20, 0x02}}},
+			StateDiff:       []hexutil.Bytes{{0xc2, 0x20, 0x04}},
+		},
+		PreStateProofs: []*trie.AccountProof{{
+			Address:     to,
+			Proof:       []string{"0xc22001"},
+			Balance:     hexutil.Big(*big.NewInt(5)),
+			CodeHash:    gethtypes.EmptyCodeHash,
+			Nonce:       1,
+			StorageHash: gethtypes.EmptyRootHash,
+			Storage:     []*trie.StorageProof{{Key: "0x05", Value: hexutil.Big(*big.NewInt(6)), Proof: []string{"0xc22002"}}},
+		}},
+		PostStateProofs: []*trie.AccountProof{{Address: to, Proof: []string{"0xc22003"}, CodeHash: gethtypes.EmptyCodeHash, StorageHash: gethtypes.EmptyRootHash}},
+		TxScope:         &input.TransactionScope{Index: 1, PreStateRoot: gethcommon.HexToHash("0x20"), PostStateRoot: gethcommon.HexToHash("0x21"), GasUsed: 21000},
+		Metadata:        &input.Metadata{ZkPigVersion: "v0.1.0", GethVersion: "1.14.12-stable"},
+	}
+}
+
+func TestProverInputRoundTrip(t *testing.T) {
+	in := testRoundTripInput()
+
+	msg, err := ToProto(in)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(SchemaVersion), msg.SchemaVersion)
+	b, err := proto.Marshal(msg)
+	require.NoError(t, err)
+
+	decodedMsg := &ProverInput{}
+	require.NoError(t, proto.Unmarshal(b, decodedMsg))
+	decoded, err := FromProto(decodedMsg)
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(in)
+	require.NoError(t, err)
+	actual, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+	assert.Equal(t, in.Blocks[0].Block().Hash(), decoded.Blocks[0].Block().Hash())
+
+	// The binary encoding is more compact than the JSON one
+	t.Logf("protobuf %d bytes, JSON %d bytes", len(b), len(expected))
+	assert.Less(t, len(b), len(expected))
+}
+
+func TestProverInputSchemaVersions(t *testing.T) {
+	in := testRoundTripInput()
+
+	t.Run("version 1", func(t *testing.T) {
+		// Inputs encoded before versioning hold structured headers and transactions
+		msg := &ProverInput{
+			Version: in.Version,
+			Blocks:  BlocksToProto(in.Blocks),
+			Witness: &Witness{
+				State:     hexBytesToBytes(in.Witness.State),
+				Ancestors: HeadersToProto(in.Witness.Ancestors),
+				Codes:     hexBytesToBytes(in.Witness.Codes),
+			},
+			ChainConfig: ChainConfigToProto(in.ChainConfig),
+		}
+		decoded, err := FromProto(msg)
+		require.NoError(t, err)
+		assert.Equal(t, in.Blocks[0].Header.Hash(), decoded.Blocks[0].Header.Hash())
+		assert.Equal(t, in.Blocks[0].Transactions[1].Hash(), decoded.Blocks[0].Transactions[1].Hash())
+		assert.Equal(t, in.Witness.Ancestors[0].Hash(), decoded.Witness.Ancestors[0].Hash())
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		msg, err := ToProto(in)
+		require.NoError(t, err)
+		msg.SchemaVersion = SchemaVersion + 1
+		_, err = FromProto(msg)
+		assert.ErrorContains(t, err, "unsupported prover input schema version")
+	})
+}
+
+func TestProverInputChecksum(t *testing.T) {
+	msg, err := ToProto(testRoundTripInput())
+	require.NoError(t, err)
+	assert.NotEmpty(t, msg.Checksum)
+	_, err = FromProto(msg)
+	require.NoError(t, err)
+
+	corrupted := proto.Clone(msg).(*ProverInput)
+	corrupted.Witness.Codes[0][1] ^= 1
+	var checksumErr *input.ChecksumError
+	_, err = FromProto(corrupted)
+	require.ErrorAs(t, err, &checksumErr)
+
+	// Inputs encoded before checksums were introduced are not verified
+	corrupted.Checksum = nil
+	_, err = FromProto(corrupted)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,194 @@
+package proto
+
+import (
+	"encoding/json"
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// blockToProtoRLP converts a block to protobuf format, with its header, transactions and uncles RLP encoded (schema version 2)
+func blockToProtoRLP(b *input.Block) (*Block, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	header, err := headerToRLP(b.Header)
+	if err != nil {
+		return nil, err
+	}
+	uncles, err := headersToRLP(b.Uncles)
+	if err != nil {
+		return nil, fmt.Errorf("uncles: %v", err)
+	}
+	var txs [][]byte
+	if b.Transactions != nil {
+		txs = make([][]byte, len(b.Transactions))
+		for i, tx := range b.Transactions {
+			if txs[i], err = tx.MarshalBinary(); err != nil {
+				return nil, fmt.Errorf("failed to encode transaction %d: %v", i, err)
+			}
+		}
+	}
+
+	block := &Block{
+		HeaderRlp:       header,
+		TransactionsRlp: txs,
+		UnclesRlp:       uncles,
+		Withdrawals:     WithdrawalsToProto(b.Withdrawals),
+		Logs:            logsToProto(b.Logs),
+	}
+	if b.Senders != nil {
+		block.Senders = make([][]byte, len(b.Senders))
+		for i, sender := range b.Senders {
+			block.Senders[i] = sender.Bytes()
+		}
+	}
+	if b.Receipts != nil {
+		if block.ReceiptsJson, err = json.Marshal(b.Receipts); err != nil {
+			return nil, fmt.Errorf("failed to encode receipts: %v", err)
+		}
+	}
+
+	return block, nil
+}
+
+// blockFromProtoRLP converts a block encoded with blockToProtoRLP
+func blockFromProtoRLP(b *Block) (*input.Block, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	header, err := headerFromRLP(b.HeaderRlp)
+	if err != nil {
+		return nil, err
+	}
+	uncles, err := headersFromRLP(b.UnclesRlp)
+	if err != nil {
+		return nil, fmt.Errorf("uncles: %v", err)
+	}
+	var txs []*gethtypes.Transaction
+	if b.TransactionsRlp != nil {
+		txs = make([]*gethtypes.Transaction, len(b.TransactionsRlp))
+		for i, bin := range b.TransactionsRlp {
+			txs[i] = new(gethtypes.Transaction)
+			if err := txs[i].UnmarshalBinary(bin); err != nil {
+				return nil, fmt.Errorf("failed to decode transaction %d: %v", i, err)
+			}
+		}
+	}
+
+	block := &input.Block{
+		Header:       header,
+		Transactions: txs,
+		Uncles:       uncles,
+		Withdrawals:  WithdrawalsFromProto(b.Withdrawals),
+		Logs:         logsFromProto(b.Logs),
+	}
+	if b.Senders != nil {
+		block.Senders = make([]gethcommon.Address, len(b.Senders))
+		for i, sender := range b.Senders {
+			block.Senders[i] = gethcommon.BytesToAddress(sender)
+		}
+	}
+	if len(b.ReceiptsJson) > 0 {
+		if err := json.Unmarshal(b.ReceiptsJson, &block.Receipts); err != nil {
+			return nil, fmt.Errorf("failed to decode receipts: %v", err)
+		}
+	}
+
+	return block, nil
+}
+
+func logsToProto(logs [][]*input.Log) []*TransactionLogs {
+	if logs == nil {
+		return nil
+	}
+
+	result := make([]*TransactionLogs, len(logs))
+	for i, txLogs := range logs {
+		result[i] = &TransactionLogs{}
+		for _, l := range txLogs {
+			topics := make([][]byte, len(l.Topics))
+			for j, topic := range l.Topics {
+				topics[j] = topic.Bytes()
+			}
+			result[i].Logs = append(result[i].Logs, &Log{Address: l.Address.Bytes(), Topics: topics, Data: l.Data})
+		}
+	}
+	return result
+}
+
+func logsFromProto(logs []*TransactionLogs) [][]*input.Log {
+	if logs == nil {
+		return nil
+	}
+
+	result := make([][]*input.Log, len(logs))
+	for i, txLogs := range logs {
+		result[i] = make([]*input.Log, 0, len(txLogs.Logs))
+		for _, l := range txLogs.Logs {
+			topics := make([]gethcommon.Hash, len(l.Topics))
+			for j, topic := range l.Topics {
+				topics[j] = gethcommon.BytesToHash(topic)
+			}
+			result[i] = append(result[i], &input.Log{Address: gethcommon.BytesToAddress(l.Address), Topics: topics, Data: l.Data})
+		}
+	}
+	return result
+}
+
+func headerToRLP(h *gethtypes.Header) ([]byte, error) {
+	if h == nil {
+		return nil, nil
+	}
+	b, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode header %v: %v", h.Number, err)
+	}
+	return b, nil
+}
+
+func headerFromRLP(b []byte) (*gethtypes.Header, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	h := new(gethtypes.Header)
+	if err := rlp.DecodeBytes(b, h); err != nil {
+		return nil, fmt.Errorf("failed to decode header: %v", err)
+	}
+	return h, nil
+}
+
+func headersToRLP(headers []*gethtypes.Header) ([][]byte, error) {
+	if headers == nil {
+		return nil, nil
+	}
+	result := make([][]byte, len(headers))
+	for i, h := range headers {
+		b, err := headerToRLP(h)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = b
+	}
+	return result, nil
+}
+
+func headersFromRLP(headers [][]byte) ([]*gethtypes.Header, error) {
+	if headers == nil {
+		return nil, nil
+	}
+	result := make([]*gethtypes.Header, len(headers))
+	for i, b := range headers {
+		h, err := headerFromRLP(b)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = h
+	}
+	return result, nil
+}
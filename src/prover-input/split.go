@@ -0,0 +1,152 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	splitIndexFile = "index.json"
+	splitBlockFile = "block.json"
+	splitStateFile = "state.bin"
+	splitCodesFile = "codes.bin"
+)
+
+// SplitIndex links the files of a ProverInput saved with SaveSplit.
+// Paths are relative to the directory of the index, so several indexes can point to a shared codes file.
+type SplitIndex struct {
+	Block string `json:"block"` // JSON file holding the ProverInput without witness state and codes
+	State string `json:"state"` // RLP file holding the witness state nodes
+	Codes string `json:"codes"` // RLP file holding the witness codes
+
+	Checksum *gethcommon.Hash `json:"checksum,omitempty"` // Checksum of the reassembled ProverInput, see Checksum
+}
+
+// SaveSplit saves a ProverInput into dir as separate files for the block, the witness state and the witness codes,
+// along with an index.json file linking them.
+func SaveSplit(dir string, in *ProverInput) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Block file holds everything but the witness state and codes
+	block := *in
+	if in.Witness != nil {
+		block.Witness = &Witness{Ancestors: in.Witness.Ancestors, StateByOwner: in.Witness.StateByOwner}
+	}
+	if err := writeJSONFile(filepath.Join(dir, splitBlockFile), &block); err != nil {
+		return fmt.Errorf("failed to write block file: %v", err)
+	}
+
+	var state, codes []hexutil.Bytes
+	if in.Witness != nil {
+		state, codes = in.Witness.State, in.Witness.Codes
+	}
+	if err := writeRLPFile(filepath.Join(dir, splitStateFile), state); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := writeRLPFile(filepath.Join(dir, splitCodesFile), codes); err != nil {
+		return fmt.Errorf("failed to write codes file: %v", err)
+	}
+
+	checksum, err := Checksum(in)
+	if err != nil {
+		return err
+	}
+
+	index := &SplitIndex{
+		Block:    splitBlockFile,
+		State:    splitStateFile,
+		Codes:    splitCodesFile,
+		Checksum: &checksum,
+	}
+	if err := writeJSONFile(filepath.Join(dir, splitIndexFile), index); err != nil {
+		return fmt.Errorf("failed to write index file: %v", err)
+	}
+
+	return nil
+}
+
+// LoadSplit loads a ProverInput saved with SaveSplit by reassembling the files linked by the index in dir.
+func LoadSplit(dir string) (*ProverInput, error) {
+	var index SplitIndex
+	if err := readJSONFile(filepath.Join(dir, splitIndexFile), &index); err != nil {
+		return nil, fmt.Errorf("failed to read index file: %v", err)
+	}
+
+	var in ProverInput
+	if err := readJSONFile(filepath.Join(dir, index.Block), &in); err != nil {
+		return nil, fmt.Errorf("failed to read block file: %v", err)
+	}
+	if in.Witness == nil {
+		in.Witness = &Witness{}
+	}
+
+	var err error
+	if in.Witness.State, err = readRLPFile(filepath.Join(dir, index.State)); err != nil {
+		return nil, fmt.Errorf("failed to read state file: %v", err)
+	}
+	if in.Witness.Codes, err = readRLPFile(filepath.Join(dir, index.Codes)); err != nil {
+		return nil, fmt.Errorf("failed to read codes file: %v", err)
+	}
+
+	if index.Checksum != nil {
+		if err := VerifyChecksum(&in, *index.Checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	return &in, nil
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func writeRLPFile(path string, blobs []hexutil.Bytes) error {
+	list := make([][]byte, 0, len(blobs))
+	for _, blob := range blobs {
+		list = append(list, blob)
+	}
+	b, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+func readRLPFile(path string) ([]hexutil.Bytes, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var list [][]byte
+	if err := rlp.DecodeBytes(b, &list); err != nil {
+		return nil, err
+	}
+
+	blobs := make([]hexutil.Bytes, 0, len(list))
+	for _, blob := range list {
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
@@ -0,0 +1,54 @@
+package input
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testProverInput() *ProverInput {
+	to := gethcommon.HexToAddress("0xdead")
+	parent := &gethtypes.Header{Number: big.NewInt(9), Difficulty: new(big.Int)}
+	return &ProverInput{
+		Version:     "v1",
+		ChainConfig: params.MainnetChainConfig,
+		Blocks: []*Block{
+			{
+				Header:       &gethtypes.Header{Number: big.NewInt(10), ParentHash: parent.Hash(), Difficulty: new(big.Int)},
+				Transactions: []*gethtypes.Transaction{gethtypes.NewTx(&gethtypes.LegacyTx{Nonce: 1, To: &to, Value: big.NewInt(1), Gas: 21000, GasPrice: big.NewInt(1)})},
+			},
+		},
+		Witness: &Witness{
+			State:     []hexutil.Bytes{{0xc2, 0x20, 0x01}, {0xc2, 0x20, 0x02}},
+			Ancestors: []*gethtypes.Header{parent},
+			Codes:     []hexutil.Bytes{{0x60, 0x00}},
+		},
+	}
+}
+
+func TestSaveLoadSplit(t *testing.T) {
+	dir := t.TempDir()
+	in := testProverInput()
+
+	require.NoError(t, SaveSplit(dir, in))
+	assert.FileExists(t, dir+"/index.json")
+	assert.FileExists(t, dir+"/block.json")
+	assert.FileExists(t, dir+"/state.bin")
+	assert.FileExists(t, dir+"/codes.bin")
+
+	loaded, err := LoadSplit(dir)
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(in)
+	require.NoError(t, err)
+	actual, err := json.Marshal(loaded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
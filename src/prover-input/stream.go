@@ -0,0 +1,343 @@
+package input
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// The stream format is a magic prefix followed by records, each record being a kind byte,
+// the uvarint length of its payload and the payload, and the stream ending with an end record.
+// The first record is the JSON encoded header, then come the blocks and the witness items one per record,
+// so neither writing nor reading needs the whole serialized input in memory.
+var streamMagic = []byte("ZKPIG\x00\x01")
+
+// maxStreamRecordSize and maxStreamStateNodes bound the allocations, so a corrupted stream does not trigger a huge allocation
+const (
+	maxStreamRecordSize = 1 << 30
+	maxStreamStateNodes = 1 << 24
+)
+
+const (
+	recordEnd byte = iota
+	recordHeader
+	recordBlock          // RLP encoded, as in the RLP codec
+	recordStateNode      // Raw node
+	recordAncestor       // RLP encoded header
+	recordCode           // Raw bytecode
+	recordCompactBranch  // Raw node
+	recordPathNode       // RLP encoded trie.PathNode
+	recordOwnerStateNode // Owner hash followed by the raw node
+	recordStateDiffNode  // Raw node
+)
+
+// streamHeader holds the fields of a ProverInput that are not streamed item by item
+type streamHeader struct {
+	Version         string               `json:"version"`
+	ChainConfig     json.RawMessage      `json:"chainConfig"`
+	PreStateProofs  []*trie.AccountProof `json:"preStateProofs,omitempty"`
+	PostStateProofs []*trie.AccountProof `json:"postStateProofs,omitempty"`
+	TxScope         *TransactionScope    `json:"txScope,omitempty"`
+	Metadata        *Metadata            `json:"metadata,omitempty"`
+	Witness         bool                 `json:"witness"`              // Whether the input has a witness
+	StateNodes      int                  `json:"stateNodes,omitempty"` // Number of witness state nodes, so the reader allocates them at once
+}
+
+// WriteTo writes the ProverInput to w in the stream format, each block and witness item being written as a separate record.
+// It implements io.WriterTo, returning the number of bytes written.
+func (in *ProverInput) WriteTo(w io.Writer) (int64, error) {
+	sw := &streamWriter{w: w}
+	if err := sw.write(streamMagic); err != nil {
+		return sw.n, err
+	}
+
+	header := &streamHeader{
+		Version:         in.Version,
+		PreStateProofs:  in.PreStateProofs,
+		PostStateProofs: in.PostStateProofs,
+		TxScope:         in.TxScope,
+		Metadata:        in.Metadata,
+		Witness:         in.Witness != nil,
+	}
+	if in.Witness != nil {
+		header.StateNodes = len(in.Witness.State)
+	}
+	var err error
+	if header.ChainConfig, err = json.Marshal(in.ChainConfig); err != nil {
+		return sw.n, fmt.Errorf("failed to encode chain config: %v", err)
+	}
+	b, err := json.Marshal(header)
+	if err != nil {
+		return sw.n, fmt.Errorf("failed to encode header: %v", err)
+	}
+	if err := sw.record(recordHeader, b); err != nil {
+		return sw.n, err
+	}
+
+	for _, block := range in.Blocks {
+		enc, err := toRLPBlock(block)
+		if err != nil {
+			return sw.n, err
+		}
+		if err := sw.rlpRecord(recordBlock, enc); err != nil {
+			return sw.n, err
+		}
+	}
+
+	if in.Witness != nil {
+		if err := sw.writeWitness(in.Witness); err != nil {
+			return sw.n, err
+		}
+	}
+
+	return sw.n, sw.record(recordEnd, nil)
+}
+
+func (sw *streamWriter) writeWitness(w *Witness) error {
+	for _, node := range w.State {
+		if err := sw.record(recordStateNode, node); err != nil {
+			return err
+		}
+	}
+	for _, header := range w.Ancestors {
+		if err := sw.rlpRecord(recordAncestor, header); err != nil {
+			return err
+		}
+	}
+	for _, code := range w.Codes {
+		if err := sw.record(recordCode, code); err != nil {
+			return err
+		}
+	}
+	for _, node := range w.CompactBranches {
+		if err := sw.record(recordCompactBranch, node); err != nil {
+			return err
+		}
+	}
+	for _, node := range w.StateByPath {
+		if err := sw.rlpRecord(recordPathNode, node); err != nil {
+			return err
+		}
+	}
+	for _, node := range w.StateDiff {
+		if err := sw.record(recordStateDiffNode, node); err != nil {
+			return err
+		}
+	}
+	owners := make([]gethcommon.Hash, 0, len(w.StateByOwner))
+	for owner := range w.StateByOwner {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i].Cmp(owners[j]) < 0 })
+	for _, owner := range owners {
+		for _, node := range w.StateByOwner[owner] {
+			if err := sw.record(recordOwnerStateNode, owner.Bytes(), node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadFrom reads a ProverInput in the stream format from r, replacing the content of in.
+// It implements io.ReaderFrom, returning the number of bytes read. It may read past the end record.
+func (in *ProverInput) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	sr := &streamReader{r: bufio.NewReader(cr)}
+
+	magic := make([]byte, len(streamMagic))
+	if _, err := io.ReadFull(sr.r, magic); err != nil {
+		return cr.n, fmt.Errorf("failed to read stream magic: %v", err)
+	}
+	if string(magic) != string(streamMagic) {
+		return cr.n, fmt.Errorf("invalid stream magic %x", magic)
+	}
+
+	kind, payload, err := sr.record()
+	if err != nil {
+		return cr.n, err
+	}
+	if kind != recordHeader {
+		return cr.n, fmt.Errorf("expected header record, got record of kind %d", kind)
+	}
+	var header streamHeader
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return cr.n, fmt.Errorf("failed to decode header: %v", err)
+	}
+
+	*in = ProverInput{
+		Version:         header.Version,
+		PreStateProofs:  header.PreStateProofs,
+		PostStateProofs: header.PostStateProofs,
+		TxScope:         header.TxScope,
+		Metadata:        header.Metadata,
+		Blocks:          make([]*Block, 0),
+	}
+	if err := json.Unmarshal(header.ChainConfig, &in.ChainConfig); err != nil {
+		return cr.n, fmt.Errorf("failed to decode chain config: %v", err)
+	}
+	if header.Witness {
+		in.Witness = &Witness{
+			State:     make([]hexutil.Bytes, 0, min(max(header.StateNodes, 0), maxStreamStateNodes)),
+			Ancestors: make([]*gethtypes.Header, 0),
+			Codes:     make([]hexutil.Bytes, 0),
+		}
+	}
+
+	for {
+		kind, payload, err := sr.record()
+		if err != nil {
+			return cr.n, err
+		}
+		if kind == recordEnd {
+			return cr.n, nil
+		}
+		if kind != recordBlock && in.Witness == nil {
+			return cr.n, fmt.Errorf("unexpected witness record of kind %d in input without witness", kind)
+		}
+		if err := in.readRecord(kind, payload); err != nil {
+			return cr.n, err
+		}
+	}
+}
+
+func (in *ProverInput) readRecord(kind byte, payload []byte) error {
+	switch kind {
+	case recordBlock:
+		var dec rlpBlock
+		if err := rlp.DecodeBytes(payload, &dec); err != nil {
+			return fmt.Errorf("failed to decode block: %v", err)
+		}
+		block, err := fromRLPBlock(&dec)
+		if err != nil {
+			return err
+		}
+		in.Blocks = append(in.Blocks, block)
+	case recordStateNode:
+		in.Witness.State = append(in.Witness.State, payload)
+	case recordAncestor:
+		header := new(gethtypes.Header)
+		if err := rlp.DecodeBytes(payload, header); err != nil {
+			return fmt.Errorf("failed to decode ancestor: %v", err)
+		}
+		in.Witness.Ancestors = append(in.Witness.Ancestors, header)
+	case recordCode:
+		in.Witness.Codes = append(in.Witness.Codes, payload)
+	case recordCompactBranch:
+		in.Witness.CompactBranches = append(in.Witness.CompactBranches, payload)
+	case recordPathNode:
+		node := new(trie.PathNode)
+		if err := rlp.DecodeBytes(payload, node); err != nil {
+			return fmt.Errorf("failed to decode path node: %v", err)
+		}
+		in.Witness.StateByPath = append(in.Witness.StateByPath, node)
+	case recordOwnerStateNode:
+		if len(payload) < gethcommon.HashLength {
+			return fmt.Errorf("owner state node record too short: %d bytes", len(payload))
+		}
+		if in.Witness.StateByOwner == nil {
+			in.Witness.StateByOwner = make(map[gethcommon.Hash][]hexutil.Bytes)
+		}
+		owner := gethcommon.BytesToHash(payload[:gethcommon.HashLength])
+		in.Witness.StateByOwner[owner] = append(in.Witness.StateByOwner[owner], payload[gethcommon.HashLength:])
+	case recordStateDiffNode:
+		in.Witness.StateDiff = append(in.Witness.StateDiff, payload)
+	default:
+		return fmt.Errorf("unknown record kind %d", kind)
+	}
+	return nil
+}
+
+type streamWriter struct {
+	w       io.Writer
+	n       int64
+	scratch [1 + binary.MaxVarintLen64]byte
+}
+
+func (sw *streamWriter) write(b []byte) error {
+	n, err := sw.w.Write(b)
+	sw.n += int64(n)
+	return err
+}
+
+// record writes a record whose payload is the concatenation of parts
+func (sw *streamWriter) record(kind byte, parts ...[]byte) error {
+	size := 0
+	for _, part := range parts {
+		size += len(part)
+	}
+	sw.scratch[0] = kind
+	n := binary.PutUvarint(sw.scratch[1:], uint64(size))
+	if err := sw.write(sw.scratch[:1+n]); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if err := sw.write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sw *streamWriter) rlpRecord(kind byte, v interface{}) error {
+	b, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode record of kind %d: %v", kind, err)
+	}
+	return sw.record(kind, b)
+}
+
+type streamReader struct {
+	r *bufio.Reader
+}
+
+func (sr *streamReader) record() (byte, []byte, error) {
+	kind, err := sr.r.ReadByte()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read record: %w", unexpectedEOF(err))
+	}
+	size, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read record size: %w", unexpectedEOF(err))
+	}
+	if size > maxStreamRecordSize {
+		return 0, nil, fmt.Errorf("record of kind %d too large: %d bytes", kind, size)
+	}
+	if size == 0 {
+		return kind, []byte{}, nil
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(sr.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("failed to read record payload: %w", unexpectedEOF(err))
+	}
+	return kind, payload, nil
+}
+
+// unexpectedEOF reports the stream ending before its end record
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,104 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"runtime"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProverInputStreamRoundTrip(t *testing.T) {
+	in := testProverInput()
+	in.Blocks[0].Senders = []gethcommon.Address{gethcommon.HexToAddress("0xbeef")}
+	in.Witness.StateByOwner = map[gethcommon.Hash][]hexutil.Bytes{
+		trie.AccountTrieOwner(): {{0xc2, 0x20, 0x01}},
+		{0x01}:                  {{0xc2, 0x20, 0x02}, {0xc2, 0x20, 0x03}},
+	}
+	in.Witness.StateByPath = []*trie.PathNode{{Owner: trie.AccountTrieOwner(), Path: hexutil.Bytes{}, Blob: hexutil.Bytes{0xc2, 0x20, 0x01}}}
+	in.Witness.StateDiff = []hexutil.Bytes{{0xc2, 0x20, 0x02}}
+	in.PreStateProofs = []*trie.AccountProof{{Address: gethcommon.HexToAddress("0xdead"), Proof: []string{"0xc22001"}}}
+
+	var buf bytes.Buffer
+	n, err := in.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	size := int64(buf.Len())
+	decoded := new(ProverInput)
+	n, err = decoded.ReadFrom(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, size, n)
+
+	expected, err := json.Marshal(in)
+	require.NoError(t, err)
+	actual, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestProverInputStreamLargeWitness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large witness stream test in short mode")
+	}
+
+	const nodes, nodeSize = 300_000, 128
+	in := testProverInput()
+	in.Witness.State = make([]hexutil.Bytes, nodes)
+	for i := range in.Witness.State {
+		node := make([]byte, nodeSize)
+		binary.BigEndian.PutUint64(node, uint64(i))
+		in.Witness.State[i] = node
+	}
+
+	// Writing allocates a bounded amount of memory, independent of the witness size
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	before := stats.TotalAlloc
+	n, err := in.WriteTo(io.Discard)
+	require.NoError(t, err)
+	runtime.ReadMemStats(&stats)
+	assert.Greater(t, n, int64(nodes*nodeSize))
+	assert.Less(t, stats.TotalAlloc-before, uint64(1<<20), "writing should not buffer the serialized input")
+
+	var buf bytes.Buffer
+	_, err = in.WriteTo(&buf)
+	require.NoError(t, err)
+
+	// Reading allocates the decoded nodes, but no copy of the serialized input
+	runtime.ReadMemStats(&stats)
+	before = stats.TotalAlloc
+	decoded := new(ProverInput)
+	_, err = decoded.ReadFrom(&buf)
+	require.NoError(t, err)
+	runtime.ReadMemStats(&stats)
+	assert.Less(t, stats.TotalAlloc-before, uint64(nodes*(nodeSize+24)+1<<20), "reading should not buffer the serialized input")
+
+	require.Len(t, decoded.Witness.State, nodes)
+	for i, node := range decoded.Witness.State {
+		if !bytes.Equal(node, in.Witness.State[i]) {
+			t.Fatalf("node %d differs", i)
+		}
+	}
+}
+
+func TestProverInputStreamTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := testProverInput().WriteTo(&buf)
+	require.NoError(t, err)
+
+	// The end record is missing
+	b := buf.Bytes()
+	_, err = new(ProverInput).ReadFrom(bytes.NewReader(b[:len(b)-2]))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = new(ProverInput).ReadFrom(bytes.NewReader([]byte("not a stream")))
+	assert.ErrorContains(t, err, "invalid stream magic")
+}
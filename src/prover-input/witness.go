@@ -0,0 +1,129 @@
+package input
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kkrt-labs/zk-pig/src/ethereum/trie"
+)
+
+// VerifyWitnessRoots verifies the witness state nodes root to both ends of a state transition.
+//
+// Both the pre-state and post-state root nodes must be present in the witness,
+// and every state node must be reachable from at least one of the roots.
+func VerifyWitnessRoots(w *Witness, preRoot, postRoot gethcommon.Hash) error {
+	nodes := make([][]byte, 0, len(w.State))
+	for _, node := range w.State {
+		nodes = append(nodes, node)
+	}
+
+	reachable := make(map[gethcommon.Hash]struct{})
+	onNode := func(_, hash gethcommon.Hash, _ []byte) {
+		reachable[hash] = struct{}{}
+	}
+
+	trie.WalkNodes(preRoot, nodes, onNode)
+	if !trie.HasKey(reachable, preRoot) {
+		return fmt.Errorf("missing pre-state root node %v", preRoot)
+	}
+
+	trie.WalkNodes(postRoot, nodes, onNode)
+	if !trie.HasKey(reachable, postRoot) {
+		return fmt.Errorf("missing post-state root node %v", postRoot)
+	}
+
+	var unreachable []gethcommon.Hash
+	for _, node := range nodes {
+		if hash := crypto.Keccak256Hash(node); !trie.HasKey(reachable, hash) {
+			unreachable = append(unreachable, hash)
+		}
+	}
+	if len(unreachable) > 0 {
+		return fmt.Errorf("%d state nodes are not reachable from pre-state root %v nor post-state root %v: %v", len(unreachable), preRoot, postRoot, unreachable)
+	}
+
+	return nil
+}
+
+// MissingCodeError is returned when the witness misses the bytecode of an account
+type MissingCodeError struct {
+	CodeHash gethcommon.Hash
+}
+
+func (e *MissingCodeError) Error() string {
+	return fmt.Sprintf("missing code with hash %v in witness", e.CodeHash)
+}
+
+// VerifyWitnessCodes verifies the witness holds the bytecode of each of the given code hashes.
+// It returns a MissingCodeError for the first missing code.
+func VerifyWitnessCodes(w *Witness, codeHashes []gethcommon.Hash) error {
+	codes := make(map[gethcommon.Hash]struct{}, len(w.Codes))
+	for _, code := range w.Codes {
+		codes[crypto.Keccak256Hash(code)] = struct{}{}
+	}
+
+	for _, codeHash := range codeHashes {
+		if !trie.HasKey(codes, codeHash) {
+			return &MissingCodeError{CodeHash: codeHash}
+		}
+	}
+
+	return nil
+}
+
+// MergeWitnesses combines several witnesses into a single witness shared by their blocks.
+//
+// State nodes, codes and ancestors are deduplicated by hash and kept in the order they are first seen.
+// Nil witnesses are ignored.
+func MergeWitnesses(ws ...*Witness) *Witness {
+	merged := &Witness{
+		State:     make([]hexutil.Bytes, 0),
+		Ancestors: make([]*gethtypes.Header, 0),
+		Codes:     make([]hexutil.Bytes, 0),
+	}
+
+	seenState := make(map[gethcommon.Hash]struct{})
+	seenCodes := make(map[gethcommon.Hash]struct{})
+	seenAncestors := make(map[gethcommon.Hash]struct{})
+	seenByOwner := make(map[gethcommon.Hash]map[gethcommon.Hash]struct{})
+	for _, w := range ws {
+		if w == nil {
+			continue
+		}
+
+		merged.State = appendUnique(merged.State, seenState, w.State...)
+		merged.Codes = appendUnique(merged.Codes, seenCodes, w.Codes...)
+		for _, header := range w.Ancestors {
+			if hash := header.Hash(); !trie.HasKey(seenAncestors, hash) {
+				seenAncestors[hash] = struct{}{}
+				merged.Ancestors = append(merged.Ancestors, header)
+			}
+		}
+
+		for owner, nodes := range w.StateByOwner {
+			if merged.StateByOwner == nil {
+				merged.StateByOwner = make(map[gethcommon.Hash][]hexutil.Bytes)
+			}
+			if seenByOwner[owner] == nil {
+				seenByOwner[owner] = make(map[gethcommon.Hash]struct{})
+			}
+			merged.StateByOwner[owner] = appendUnique(merged.StateByOwner[owner], seenByOwner[owner], nodes...)
+		}
+	}
+
+	return merged
+}
+
+// appendUnique appends the items whose hash has not been seen yet
+func appendUnique(items []hexutil.Bytes, seen map[gethcommon.Hash]struct{}, newItems ...hexutil.Bytes) []hexutil.Bytes {
+	for _, item := range newItems {
+		if hash := crypto.Keccak256Hash(item); !trie.HasKey(seen, hash) {
+			seen[hash] = struct{}{}
+			items = append(items, item)
+		}
+	}
+	return items
+}
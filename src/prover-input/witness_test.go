@@ -0,0 +1,103 @@
+package input
+
+import (
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitTrie commits the trie and returns the new root and the blobs of the updated nodes
+func commitTrie(t *testing.T, db *triedb.Database, tr *gethtrie.Trie, parent gethcommon.Hash) (gethcommon.Hash, []hexutil.Bytes) {
+	root, set := tr.Commit(false)
+	var blobs []hexutil.Bytes
+	for _, node := range set.Nodes {
+		if !node.IsDeleted() {
+			blobs = append(blobs, node.Blob)
+		}
+	}
+	require.NoError(t, db.Update(root, parent, 0, trienode.NewWithNodeSet(set), triedb.NewStateSet()))
+	return root, blobs
+}
+
+func TestVerifyWitnessRoots(t *testing.T) {
+	db := triedb.NewDatabase(rawdb.NewMemoryDatabase(), &triedb.Config{HashDB: &hashdb.Config{}})
+
+	// Pre-state trie
+	tr := gethtrie.NewEmpty(db)
+	for i := byte(0); i < 16; i++ {
+		require.NoError(t, tr.Update(crypto.Keccak256([]byte{i}), []byte{0x01, i}))
+	}
+	preRoot, preNodes := commitTrie(t, db, tr, gethtypes.EmptyRootHash)
+
+	// Post-state trie, updating a single key
+	tr, err := gethtrie.New(gethtrie.TrieID(preRoot), db)
+	require.NoError(t, err)
+	require.NoError(t, tr.Update(crypto.Keccak256([]byte{0}), []byte{0x02}))
+	postRoot, postNodes := commitTrie(t, db, tr, preRoot)
+
+	t.Run("complete", func(t *testing.T) {
+		w := &Witness{State: append(append([]hexutil.Bytes{}, preNodes...), postNodes...)}
+		assert.NoError(t, VerifyWitnessRoots(w, preRoot, postRoot))
+	})
+
+	t.Run("incomplete post-state", func(t *testing.T) {
+		var state []hexutil.Bytes
+		state = append(state, preNodes...)
+		for _, node := range postNodes {
+			if crypto.Keccak256Hash(node) != postRoot {
+				state = append(state, node)
+			}
+		}
+		err := VerifyWitnessRoots(&Witness{State: state}, preRoot, postRoot)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "post-state root")
+	})
+
+	t.Run("unreachable node", func(t *testing.T) {
+		w := &Witness{State: append(append([]hexutil.Bytes{}, preNodes...), postNodes...)}
+		w.State = append(w.State, hexutil.Bytes{0xc2, 0x20, 0x01})
+		assert.Error(t, VerifyWitnessRoots(w, preRoot, postRoot))
+	})
+}
+
+func TestMergeWitnesses(t *testing.T) {
+	parent := &gethtypes.Header{Number: big.NewInt(10), Difficulty: new(big.Int)}
+	grandParent := &gethtypes.Header{Number: big.NewInt(9), Difficulty: new(big.Int)}
+	other := &gethtypes.Header{Number: big.NewInt(20), Difficulty: new(big.Int)}
+
+	w1 := &Witness{
+		State:     []hexutil.Bytes{{0x01}, {0x02}},
+		Ancestors: []*gethtypes.Header{parent, grandParent},
+		Codes:     []hexutil.Bytes{{0x60}},
+	}
+	w2 := &Witness{
+		State:     []hexutil.Bytes{{0x02}, {0x03}},
+		Ancestors: []*gethtypes.Header{other, gethtypes.CopyHeader(parent)},
+		Codes:     []hexutil.Bytes{{0x60}, {0x61}},
+	}
+
+	merged := MergeWitnesses(w1, nil, w2)
+
+	assert.Equal(t, []hexutil.Bytes{{0x01}, {0x02}, {0x03}}, merged.State)
+	assert.Equal(t, []hexutil.Bytes{{0x60}, {0x61}}, merged.Codes)
+	require.Len(t, merged.Ancestors, 3)
+	assert.Equal(t, parent.Hash(), merged.Ancestors[0].Hash())
+	assert.Equal(t, grandParent.Hash(), merged.Ancestors[1].Hash())
+	assert.Equal(t, other.Hash(), merged.Ancestors[2].Hash())
+	assert.Nil(t, merged.StateByOwner)
+
+	// Inputs are left untouched
+	assert.Len(t, w1.State, 2)
+	assert.Len(t, w2.Ancestors, 2)
+}
@@ -0,0 +1,79 @@
+// Package server serves the stored prover inputs over HTTP.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/kkrt-labs/go-utils/log"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	inputstore "github.com/kkrt-labs/zk-pig/src/store"
+	"go.uber.org/zap"
+)
+
+// NewHandler creates an HTTP handler serving the prover inputs of the chain stored in inputs.
+//
+// It serves the route `/witness`, returning the JSON encoded prover input of the block
+// identified either by its hash with `/witness?hash=0x...` or by its number with `/witness?number=...`.
+// It responds 404 if no input is stored for the block.
+func NewHandler(inputs inputstore.ProverInputStore, chainID uint64) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/witness", &witnessHandler{inputs: inputs, chainID: chainID})
+	return mux
+}
+
+type witnessHandler struct {
+	inputs  inputstore.ProverInputStore
+	chainID uint64
+}
+
+func (h *witnessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		in    *input.ProverInput
+		err   error
+		query = r.URL.Query()
+	)
+	switch {
+	case query.Has("hash"):
+		var hash []byte
+		if hash, err = hexutil.Decode(query.Get("hash")); err != nil || len(hash) != gethcommon.HashLength {
+			http.Error(w, fmt.Sprintf("invalid block hash %q", query.Get("hash")), http.StatusBadRequest)
+			return
+		}
+		in, err = h.inputs.LoadProverInputByHash(r.Context(), h.chainID, gethcommon.BytesToHash(hash))
+	case query.Has("number"):
+		var number uint64
+		if number, err = strconv.ParseUint(query.Get("number"), 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid block number %q", query.Get("number")), http.StatusBadRequest)
+			return
+		}
+		in, err = h.inputs.LoadProverInput(r.Context(), h.chainID, number)
+	default:
+		http.Error(w, "missing block hash or number", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case errors.Is(err, inputstore.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		log.LoggerFromContext(r.Context()).Error("Failed to load prover input", zap.Error(err))
+		http.Error(w, "failed to load prover input", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := input.Encode(w, in, input.OutputFormatJSON); err != nil {
+		log.LoggerFromContext(r.Context()).Error("Failed to write prover input", zap.Error(err))
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	storeinputs "github.com/kkrt-labs/go-utils/store"
+	filestore "github.com/kkrt-labs/go-utils/store/file"
+	multistore "github.com/kkrt-labs/go-utils/store/multi"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	inputstore "github.com/kkrt-labs/zk-pig/src/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWitnessHandler(t *testing.T) {
+	inputs, err := inputstore.New(&inputstore.ProverInputStoreConfig{
+		StoreConfig: multistore.Config{FileConfig: &filestore.Config{DataDir: t.TempDir()}},
+		ContentType: storeinputs.ContentTypeJSON,
+	})
+	require.NoError(t, err)
+
+	in := &input.ProverInput{
+		ChainConfig: &params.ChainConfig{ChainID: big.NewInt(2)},
+		Blocks:      []*input.Block{{Header: &gethtypes.Header{Number: big.NewInt(15), Difficulty: big.NewInt(15)}}},
+		Witness:     &input.Witness{},
+	}
+	require.NoError(t, inputs.StoreProverInput(context.Background(), in))
+	hash := in.Blocks[0].Header.Hash()
+
+	srv := httptest.NewServer(NewHandler(inputs, 2))
+	defer srv.Close()
+
+	get := func(query string) (int, string) {
+		resp, err := http.Get(srv.URL + "/witness?" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	for _, query := range []string{"hash=" + hash.Hex(), "number=15"} {
+		status, body := get(query)
+		require.Equal(t, http.StatusOK, status, body)
+		var served input.ProverInput
+		require.NoError(t, json.Unmarshal([]byte(body), &served))
+		assert.Equal(t, hash, served.Blocks[0].Header.Hash())
+	}
+
+	unknown := gethcommon.Hash{0x01}
+	status, body := get("hash=" + unknown.Hex())
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, body, unknown.Hex())
+
+	status, _ = get("number=16")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = get("hash=0x01")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = get("")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
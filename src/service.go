@@ -2,11 +2,15 @@ package src
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
 	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
 	ethjsonrpc "github.com/kkrt-labs/go-utils/ethereum/rpc/jsonrpc"
 	"github.com/kkrt-labs/go-utils/jsonrpc"
@@ -14,6 +18,7 @@ import (
 	compressstore "github.com/kkrt-labs/go-utils/store/compress"
 	"github.com/kkrt-labs/go-utils/svc"
 	"github.com/kkrt-labs/zk-pig/src/generator"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
 	inputstore "github.com/kkrt-labs/zk-pig/src/store"
 )
 
@@ -37,6 +42,19 @@ func New(cfg *Config) (*Service, error) {
 		cfg: cfg,
 	}
 
+	if cfg.Chain.Genesis != "" {
+		genesis, err := generator.LoadGenesis(cfg.Chain.Genesis)
+		if err != nil {
+			return nil, err
+		}
+		if err := generator.RegisterGenesis(genesis); err != nil {
+			return nil, fmt.Errorf("failed to register genesis: %v", err)
+		}
+		if cfg.Chain.ID == nil {
+			cfg.Chain.ID = genesis.Config.ChainID
+		}
+	}
+
 	if cfg.Chain.RPC != nil {
 		remote, err := jsonrpcmrgd.New(cfg.Chain.RPC)
 		if err != nil {
@@ -65,11 +83,19 @@ func New(cfg *Config) (*Service, error) {
 		ContentEncoding:  cfg.ProverInputStore.ContentEncoding,
 	})
 
-	ProverInputStore := inputstore.NewFromStore(compressStore, cfg.ProverInputStore.ContentType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create prover inputs store: %v", err)
 	}
 
+	ProverInputStore := inputstore.NewFromStore(compressStore, cfg.ProverInputStore.ContentType)
+	if cfg.ProverInputStore.Codec != "" {
+		codec, err := input.GetCodec(cfg.ProverInputStore.Codec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prover inputs store: %v", err)
+		}
+		ProverInputStore = inputstore.NewFromStoreWithCodec(compressStore, codec)
+	}
+
 	s.preflightDataStore = preflightDataStore
 	s.ProverInputStore = ProverInputStore
 
@@ -105,29 +131,51 @@ func (s *Service) Start(ctx context.Context) error {
 	return s.err
 }
 
+// Generate runs preflight, prepare and execute for the given block number.
+// It also writes a report of the time and resources used to <data-dir>/reports/<chain ID>/<block number>.json
 func (s *Service) Generate(ctx context.Context, blockNumber *big.Int) error {
-	data, err := s.preflight(ctx, blockNumber)
-	if err != nil {
-		return err
-	}
-
 	if s.chainID == nil {
 		return fmt.Errorf("chain ID missing")
 	}
-	_, err = s.preflight(ctx, data.Block.Number.ToInt())
+
+	profile, err := generator.ChainProfile(s.chainID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to resolve preparer profile: %v", err)
+	}
+
+	preparer := generator.NewPreparerFromProfile(profile, generator.WithVersionMetadata(Version))
+	data, inputs, report, err := generator.PrepareWithReport(ctx, s.ethrpc, preparer, blockNumber, s.preflightOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to generate provable inputs: %v", err)
+	}
+
+	if err := s.preflightDataStore.StorePreflightData(ctx, data); err != nil {
+		return fmt.Errorf("failed to store preflight data: %v", err)
+	}
+
+	if err := s.ProverInputStore.StoreProverInput(ctx, inputs); err != nil {
+		return fmt.Errorf("failed to store provable inputs: %v", err)
+	}
+
+	if err := s.writeReport(report); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
 	}
 
-	if err := s.prepare(ctx, data.Block.Number.ToInt()); err != nil {
+	return s.execute(ctx, data.Block.Number.ToInt())
+}
+
+func (s *Service) writeReport(report *generator.Report) error {
+	dir := filepath.Join(s.cfg.DataDir, "reports", report.ChainID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
 
-	if err := s.execute(ctx, data.Block.Number.ToInt()); err != nil {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", report.BlockNumber)), b, 0o600)
 }
 
 // Preflight executes the preflight checks for the given block number.
@@ -139,7 +187,7 @@ func (s *Service) Preflight(ctx context.Context, blockNumber *big.Int) error {
 }
 
 func (s *Service) preflight(ctx context.Context, blockNumber *big.Int) (*generator.PreflightData, error) {
-	data, err := generator.NewPreflight(s.ethrpc).Preflight(ctx, blockNumber)
+	data, err := generator.NewPreflight(s.ethrpc, s.preflightOptions()...).Preflight(ctx, blockNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute preflight: %v", err)
 	}
@@ -158,13 +206,52 @@ func (s *Service) Prepare(ctx context.Context, blockNumber *big.Int) error {
 	return s.prepare(ctx, blockNumber)
 }
 
+// PrepareFromFile prepares prover inputs from preflight data saved to a file, without any network access.
+// The preflight data must be of the chain of the service, if it has a chain ID.
+func (s *Service) PrepareFromFile(ctx context.Context, path string) error {
+	data, err := generator.LoadPreflightData(path)
+	if err != nil {
+		return fmt.Errorf("failed to load preflight data: %v", err)
+	}
+	return s.prepareData(ctx, data)
+}
+
+// preflightOptions returns the preflight options of the service, timing out and retrying the RPC calls as configured
+func (s *Service) preflightOptions() []generator.PreflightOption {
+	opts := []generator.PreflightOption{generator.WithPerCallTimeout(s.cfg.Chain.CallTimeout)}
+	if s.cfg.Chain.RetryPolicy != nil {
+		opts = append(opts, generator.WithRetry(*s.cfg.Chain.RetryPolicy))
+	}
+	return opts
+}
+
 func (s *Service) prepare(ctx context.Context, blockNumber *big.Int) error {
+	blockNumber, err := s.resolveBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return err
+	}
+
 	data, err := s.preflightDataStore.LoadPreflightData(ctx, s.chainID.Uint64(), blockNumber.Uint64())
 	if err != nil {
 		return fmt.Errorf("failed to load preflight data: %v", err)
 	}
+	return s.prepareData(ctx, data)
+}
+
+func (s *Service) prepareData(ctx context.Context, data *generator.PreflightData) error {
+	if data.ChainConfig == nil || data.ChainConfig.ChainID == nil {
+		return fmt.Errorf("preflight data misses the chain ID")
+	}
+	if s.chainID != nil && data.ChainConfig.ChainID.Cmp(s.chainID) != 0 {
+		return fmt.Errorf("preflight data of chain %v does not match chain %v", data.ChainConfig.ChainID, s.chainID)
+	}
 
-	inputs, err := generator.NewPreparer().Prepare(ctx, data)
+	profile, err := generator.ChainProfile(data.ChainConfig.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve preparer profile: %v", err)
+	}
+
+	inputs, err := generator.NewPreparerFromProfile(profile, generator.WithVersionMetadata(Version)).Prepare(ctx, data)
 	if err != nil {
 		return fmt.Errorf("failed to prepare provable inputs: %v", err)
 	}
@@ -186,10 +273,17 @@ func (s *Service) Execute(ctx context.Context, blockNumber *big.Int) error {
 }
 
 func (s *Service) execute(ctx context.Context, blockNumber *big.Int) error {
+	blockNumber, err := s.resolveBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return err
+	}
+
 	inputs, err := s.ProverInputStore.LoadProverInput(ctx, s.chainID.Uint64(), blockNumber.Uint64())
 	if err != nil {
 		return fmt.Errorf("failed to load provable inputs: %v", err)
 	}
+	input.WarnOnVersionMismatch(ctx, inputs, Version)
+
 	_, err = generator.NewExecutor().Execute(ctx, inputs)
 	if err != nil {
 		return fmt.Errorf("failed to execute block on provable inputs: %v", err)
@@ -198,6 +292,28 @@ func (s *Service) execute(ctx context.Context, blockNumber *big.Int) error {
 	return err
 }
 
+// resolveBlockNumber returns the number of the block, resolving a block tag with the remote RPC.
+// Block tags can not be resolved off-line, when no remote RPC is configured.
+func (s *Service) resolveBlockNumber(ctx context.Context, blockNumber *big.Int) (*big.Int, error) {
+	if blockNumber != nil && blockNumber.Sign() >= 0 {
+		return blockNumber, nil
+	}
+
+	tag := gethrpc.LatestBlockNumber
+	if blockNumber != nil {
+		tag = gethrpc.BlockNumber(blockNumber.Int64())
+	}
+	if s.ethrpc == nil {
+		return nil, fmt.Errorf("block tag %q can not be resolved without a remote RPC, a block number must be provided", tag)
+	}
+
+	header, err := s.ethrpc.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve block tag %q: %v", tag, err)
+	}
+	return header.Number, nil
+}
+
 // Errors returns the error channel for possible internal errors of the service.
 func (s *Service) Errors() <-chan error {
 	if errorable, ok := s.remote.(svc.ErrorReporter); ok {
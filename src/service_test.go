@@ -0,0 +1,75 @@
+package src
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	ethrpc "github.com/kkrt-labs/go-utils/ethereum/rpc"
+	"github.com/kkrt-labs/zk-pig/src/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headClient is an ethrpc.Client serving the headers of a chain whose finalized block lags its head
+type headClient struct {
+	ethrpc.Client
+	head uint64
+}
+
+func (c *headClient) HeaderByNumber(_ context.Context, number *big.Int) (*gethtypes.Header, error) {
+	switch {
+	case number == nil || number.Int64() == int64(gethrpc.LatestBlockNumber):
+		return &gethtypes.Header{Number: new(big.Int).SetUint64(c.head)}, nil
+	case number.Int64() == int64(gethrpc.FinalizedBlockNumber):
+		return &gethtypes.Header{Number: new(big.Int).SetUint64(c.head - 64)}, nil
+	}
+	return &gethtypes.Header{Number: number}, nil
+}
+
+func TestServiceResolveBlockNumber(t *testing.T) {
+	s := &Service{ethrpc: &headClient{head: 100}}
+
+	number, err := s.resolveBlockNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(10), number)
+
+	number, err = s.resolveBlockNumber(context.Background(), big.NewInt(int64(gethrpc.LatestBlockNumber)))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), number)
+
+	number, err = s.resolveBlockNumber(context.Background(), big.NewInt(int64(gethrpc.FinalizedBlockNumber)))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(36), number)
+
+	// Off-line, only block numbers are accepted
+	offline := &Service{}
+	number, err = offline.resolveBlockNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(10), number)
+
+	_, err = offline.resolveBlockNumber(context.Background(), big.NewInt(int64(gethrpc.FinalizedBlockNumber)))
+	assert.ErrorContains(t, err, `block tag "finalized"`)
+}
+
+func TestServicePrepareChainMismatch(t *testing.T) {
+	data := &generator.PreflightData{
+		ChainConfig: &params.ChainConfig{ChainID: big.NewInt(1)},
+		Block:       &ethrpc.Block{Header: ethrpc.Header{Number: (*hexutil.Big)(big.NewInt(10))}},
+		Ancestors:   []*gethtypes.Header{{Number: big.NewInt(9), Difficulty: new(big.Int)}},
+	}
+	path := filepath.Join(t.TempDir(), "preflight.json")
+	require.NoError(t, generator.SavePreflightData(path, data))
+
+	s := &Service{chainID: big.NewInt(10)}
+	err := s.PrepareFromFile(context.Background(), path)
+	assert.EqualError(t, err, "preflight data of chain 1 does not match chain 10")
+
+	err = s.prepareData(context.Background(), &generator.PreflightData{})
+	assert.EqualError(t, err, "preflight data misses the chain ID")
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/kkrt-labs/go-utils/log"
+	"github.com/kkrt-labs/go-utils/tag"
+	"github.com/kkrt-labs/zk-pig/src/generator"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"go.uber.org/zap"
+)
+
+// HeaderReader resolves the header of a block by number, it is implemented by ethrpc.Client
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*gethtypes.Header, error)
+}
+
+type cachedGenerator struct {
+	generator   generator.Generator
+	headers     HeaderReader
+	store       Store
+	chainID     uint64
+	fingerprint string
+}
+
+// NewCachedGenerator creates a Generator returning the prover input previously stored in store for a block,
+// and otherwise generating it with gen and storing it.
+//
+// Inputs are keyed by chain ID and block hash, along with a fingerprint of the chain config and of the generator version,
+// so inputs generated under other fork rules or by another version are never served.
+func NewCachedGenerator(gen generator.Generator, headers HeaderReader, store Store, config *params.ChainConfig, version string) (generator.Generator, error) {
+	if config == nil || config.ChainID == nil {
+		return nil, fmt.Errorf("chain config misses the chain ID")
+	}
+	fingerprint, err := Fingerprint(config, version)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedGenerator{
+		generator:   gen,
+		headers:     headers,
+		store:       store,
+		chainID:     config.ChainID.Uint64(),
+		fingerprint: fingerprint,
+	}, nil
+}
+
+// Fingerprint returns an identifier of a chain config and generator version, changing whenever either changes
+func Fingerprint(config *params.ChainConfig, version string) (string, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chain config: %w", err)
+	}
+	return crypto.Keccak256Hash(b, []byte(version)).Hex()[2:18], nil
+}
+
+// CacheKey returns the key addressing the prover input of a block generated under the given fingerprint
+func CacheKey(chainID uint64, blockHash gethcommon.Hash, fingerprint string) string {
+	return fmt.Sprintf("%d/cache/%v/%v", chainID, blockHash.Hex(), fingerprint)
+}
+
+func (g *cachedGenerator) Generate(ctx context.Context, blockNumber *big.Int) (*input.ProverInput, error) {
+	ctx = tag.WithComponent(ctx, "cache")
+	header, err := g.headers.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch header of block %v: %v", blockNumber, err)
+	}
+
+	key := CacheKey(g.chainID, header.Hash(), g.fingerprint)
+	proverInput, err := g.store.Get(ctx, key)
+	switch {
+	case err == nil:
+		log.LoggerFromContext(ctx).Info("Prover input found in cache", zap.String("key", key))
+		return proverInput, nil
+	case !errors.Is(err, ErrNotFound):
+		// A broken entry must not prevent the block from being generated, it is overwritten below
+		log.LoggerFromContext(ctx).Warn("Failed to load prover input from cache", zap.String("key", key), zap.Error(err))
+	}
+
+	// Generate the resolved block, so a new latest block can not be stored under the key of the previous one
+	proverInput, err = g.generator.Generate(ctx, header.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	// The block may have been reorged in between, key the input by the block it has been generated for
+	key = CacheKey(g.chainID, proverInput.Blocks[0].Header.Hash(), g.fingerprint)
+	if err := g.store.Put(ctx, key, proverInput); err != nil {
+		return nil, fmt.Errorf("failed to store prover input in cache: %v", err)
+	}
+
+	return proverInput, nil
+}
+
+// GenerateBlocks generates the ProverInputs of a set of blocks concurrently, each block being served from the cache when stored
+func (g *cachedGenerator) GenerateBlocks(ctx context.Context, numbers []uint64) map[uint64]*generator.GenerateResult {
+	return generator.GenerateEach(ctx, g, numbers)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/kkrt-labs/zk-pig/src/generator"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingGenerator struct {
+	in    *input.ProverInput
+	calls int
+}
+
+func (g *countingGenerator) Generate(_ context.Context, _ *big.Int) (*input.ProverInput, error) {
+	g.calls++
+	return g.in, nil
+}
+
+func (g *countingGenerator) GenerateBlocks(ctx context.Context, numbers []uint64) map[uint64]*generator.GenerateResult {
+	return generator.GenerateEach(ctx, g, numbers)
+}
+
+type headerReader struct {
+	header *gethtypes.Header
+}
+
+func (r *headerReader) HeaderByNumber(_ context.Context, _ *big.Int) (*gethtypes.Header, error) {
+	return r.header, nil
+}
+
+func TestCachedGenerator(t *testing.T) {
+	in := testWitnessInput(4)
+	headers := &headerReader{header: in.Blocks[0].Header}
+	store := NewFileStore(t.TempDir())
+
+	gen := &countingGenerator{in: in}
+	cached, err := NewCachedGenerator(gen, headers, store, params.MainnetChainConfig, "v1.0.0")
+	require.NoError(t, err)
+
+	// Miss: the input is generated and stored
+	first, err := cached.Generate(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, gen.calls)
+	assertSameInput(t, in, first)
+
+	// Hit: the stored input is returned
+	second, err := cached.Generate(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, gen.calls)
+	assertSameInput(t, in, second)
+
+	t.Run("config change invalidates", func(t *testing.T) {
+		config := *params.MainnetChainConfig
+		config.PragueTime = new(uint64)
+		cached, err := NewCachedGenerator(gen, headers, store, &config, "v1.0.0")
+		require.NoError(t, err)
+
+		_, err = cached.Generate(context.Background(), big.NewInt(10))
+		require.NoError(t, err)
+		assert.Equal(t, 2, gen.calls)
+	})
+
+	t.Run("version change invalidates", func(t *testing.T) {
+		cached, err := NewCachedGenerator(gen, headers, store, params.MainnetChainConfig, "v1.1.0")
+		require.NoError(t, err)
+
+		_, err = cached.Generate(context.Background(), big.NewInt(10))
+		require.NoError(t, err)
+		assert.Equal(t, 3, gen.calls)
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	a, err := Fingerprint(params.MainnetChainConfig, "v1")
+	require.NoError(t, err)
+	b, err := Fingerprint(params.MainnetChainConfig, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 16)
+
+	c, err := Fingerprint(params.SepoliaChainConfig, "v1")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, c)
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStore(dir)
+	in := testWitnessInput(16)
+
+	key := BlockNumberKey(1, 10)
+	require.NoError(t, s.Put(context.Background(), key, in))
+	assert.FileExists(t, filepath.Join(dir, "1", "10"))
+
+	loaded, err := s.Get(context.Background(), key)
+	require.NoError(t, err)
+	assertSameInput(t, in, loaded)
+
+	_, err = s.Get(context.Background(), BlockNumberKey(1, 11))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileStoreInvalidKey(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	for _, key := range []string{"", "/1/10", "../10", "1/../../10"} {
+		t.Run(key, func(t *testing.T) {
+			err := s.Put(context.Background(), key, testWitnessInput(1))
+			assert.ErrorContains(t, err, "invalid key")
+			_, err = s.Get(context.Background(), key)
+			assert.ErrorContains(t, err, "invalid key")
+		})
+	}
+}
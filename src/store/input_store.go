@@ -3,9 +3,13 @@ package store
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"strconv"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
 
 	store "github.com/kkrt-labs/go-utils/store"
 	multistore "github.com/kkrt-labs/go-utils/store/multi"
@@ -20,18 +24,28 @@ type ProverInputStore interface {
 
 	// LoadProverInput loads the prover inputs for a block.
 	// format can be "protobuf" or "json"
+	// It returns an error wrapping ErrNotFound if no input is stored for the block.
 	LoadProverInput(ctx context.Context, chainID, blockNumber uint64) (*input.ProverInput, error)
+
+	// LoadProverInputByHash loads the prover inputs for a block identified by its hash.
+	// It returns an error wrapping ErrNotFound if no input is stored for the block.
+	LoadProverInputByHash(ctx context.Context, chainID uint64, blockHash gethcommon.Hash) (*input.ProverInput, error)
 }
 
+// ErrNotFound is returned when no prover input is stored for a block
+var ErrNotFound = errors.New("prover input not found")
+
 type ProverInputStoreConfig struct {
 	StoreConfig     multistore.Config
 	ContentType     store.ContentType
 	ContentEncoding store.ContentEncoding
+	Codec           string // Optional, name of a registered input.ProverInputCodec used instead of the content type serialization
 }
 
 type proverInputStore struct {
 	store       store.Store
 	contentType store.ContentType
+	codec       input.ProverInputCodec
 }
 
 func New(cfg *ProverInputStoreConfig) (ProverInputStore, error) {
@@ -39,6 +53,15 @@ func New(cfg *ProverInputStoreConfig) (ProverInputStore, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if cfg.Codec != "" {
+		codec, err := input.GetCodec(cfg.Codec)
+		if err != nil {
+			return nil, err
+		}
+		return NewFromStoreWithCodec(inputstore, codec), nil
+	}
+
 	return NewFromStore(inputstore, cfg.ContentType), nil
 }
 
@@ -46,18 +69,30 @@ func NewFromStore(inputstore store.Store, contentType store.ContentType) ProverI
 	return &proverInputStore{store: inputstore, contentType: contentType}
 }
 
+// NewFromStoreWithCodec creates a ProverInputStore serializing inputs with the given codec
+func NewFromStoreWithCodec(inputstore store.Store, codec input.ProverInputCodec) ProverInputStore {
+	return &proverInputStore{store: inputstore, codec: codec}
+}
+
 func (s *proverInputStore) StoreProverInput(ctx context.Context, data *input.ProverInput) error {
 	var buf bytes.Buffer
-	switch s.contentType {
-	case store.ContentTypeProtobuf:
-		protoMsg := protoinput.ToProto(data)
+	switch {
+	case s.codec != nil:
+		if err := s.codec.Encode(&buf, data); err != nil {
+			return fmt.Errorf("failed to encode prover input: %w", err)
+		}
+	case s.contentType == store.ContentTypeProtobuf:
+		protoMsg, err := protoinput.ToProto(data)
+		if err != nil {
+			return fmt.Errorf("failed to convert prover input to protobuf: %w", err)
+		}
 		protoBytes, err := proto.Marshal(protoMsg)
 		if err != nil {
 			return fmt.Errorf("failed to marshal protobuf: %w", err)
 		}
 		buf.Write(protoBytes)
-	case store.ContentTypeJSON:
-		if err := json.NewEncoder(&buf).Encode(data); err != nil {
+	case s.contentType == store.ContentTypeJSON:
+		if err := input.Encode(&buf, data, input.OutputFormatJSON); err != nil {
 			return fmt.Errorf("failed to encode JSON: %w", err)
 		}
 	default:
@@ -68,12 +103,21 @@ func (s *proverInputStore) StoreProverInput(ctx context.Context, data *input.Pro
 		return fmt.Errorf("unsupported content type: %s", contentType)
 	}
 
-	path := s.proverPath(data.Blocks[0].Header.Number.Uint64())
+	header := data.Blocks[0].Header
 	headers := store.Headers{
 		ContentType: s.contentType,
 		KeyValue:    map[string]string{"chainID": fmt.Sprintf("%d", data.ChainConfig.ChainID.Uint64())},
 	}
-	return s.store.Store(ctx, path, bytes.NewReader(buf.Bytes()), &headers)
+	if err := s.store.Store(ctx, s.proverPath(header.Number.Uint64()), bytes.NewReader(buf.Bytes()), &headers); err != nil {
+		return err
+	}
+
+	// Index the block number by block hash
+	index := strconv.FormatUint(header.Number.Uint64(), 10)
+	if err := s.store.Store(ctx, s.hashPath(header.Hash()), bytes.NewReader([]byte(index)), &headers); err != nil {
+		return fmt.Errorf("failed to store block hash index: %w", err)
+	}
+	return nil
 }
 
 func (s *proverInputStore) LoadProverInput(ctx context.Context, chainID, blockNumber uint64) (*input.ProverInput, error) {
@@ -83,18 +127,24 @@ func (s *proverInputStore) LoadProverInput(ctx context.Context, chainID, blockNu
 		KeyValue:    map[string]string{"chainID": fmt.Sprintf("%d", chainID)},
 	}
 	reader, err := s.store.Load(ctx, path, &headers)
-	if err != nil {
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no input for block %d", ErrNotFound, blockNumber)
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to load data from store: %w", err)
 	}
 
-	data := &input.ProverInput{}
+	var data *input.ProverInput
 
-	switch s.contentType {
-	case store.ContentTypeJSON:
-		if err := json.NewDecoder(reader).Decode(data); err != nil {
+	switch {
+	case s.codec != nil:
+		if data, err = s.codec.Decode(reader); err != nil {
+			return nil, fmt.Errorf("failed to decode prover input: %w", err)
+		}
+	case s.contentType == store.ContentTypeJSON:
+		if data, err = input.Decode(reader); err != nil {
 			return nil, fmt.Errorf("failed to decode JSON: %w", err)
 		}
-	case store.ContentTypeProtobuf:
+	case s.contentType == store.ContentTypeProtobuf:
 		protoBytes, err := io.ReadAll(reader)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read protobuf data: %w", err)
@@ -103,7 +153,9 @@ func (s *proverInputStore) LoadProverInput(ctx context.Context, chainID, blockNu
 		if err := proto.Unmarshal(protoBytes, protoMsg); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal protobuf: %w", err)
 		}
-		data = protoinput.FromProto(protoMsg)
+		if data, err = protoinput.FromProto(protoMsg); err != nil {
+			return nil, fmt.Errorf("failed to convert prover input from protobuf: %w", err)
+		}
 	default:
 		contentType, err := s.contentType.String()
 		if err != nil {
@@ -115,6 +167,47 @@ func (s *proverInputStore) LoadProverInput(ctx context.Context, chainID, blockNu
 	return data, nil
 }
 
+func (s *proverInputStore) LoadProverInputByHash(ctx context.Context, chainID uint64, blockHash gethcommon.Hash) (*input.ProverInput, error) {
+	headers := store.Headers{
+		ContentType: s.contentType,
+		KeyValue:    map[string]string{"chainID": fmt.Sprintf("%d", chainID)},
+	}
+	reader, err := s.store.Load(ctx, s.hashPath(blockHash), &headers)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no input for block %v", ErrNotFound, blockHash.Hex())
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load block hash index: %w", err)
+	}
+
+	index, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block hash index: %w", err)
+	}
+	blockNumber, err := strconv.ParseUint(string(index), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block hash index %q: %w", index, err)
+	}
+
+	return s.loadProverInputByHash(ctx, chainID, blockNumber, blockHash)
+}
+
+// loadProverInputByHash loads the input stored for the block number and checks it is for the block hash,
+// the input of another block with the same number may have been stored since
+func (s *proverInputStore) loadProverInputByHash(ctx context.Context, chainID, blockNumber uint64, blockHash gethcommon.Hash) (*input.ProverInput, error) {
+	data, err := s.LoadProverInput(ctx, chainID, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Blocks) == 0 || data.Blocks[0].Header.Hash() != blockHash {
+		return nil, fmt.Errorf("%w: input for block %d is not for block %v", ErrNotFound, blockNumber, blockHash.Hex())
+	}
+	return data, nil
+}
+
 func (s *proverInputStore) proverPath(blockNumber uint64) string {
 	return fmt.Sprintf("%d", blockNumber)
 }
+
+func (s *proverInputStore) hashPath(blockHash gethcommon.Hash) string {
+	return fmt.Sprintf("hashes/%v", blockHash.Hex())
+}
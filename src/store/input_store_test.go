@@ -2,7 +2,13 @@ package store
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	gethcommon "github.com/ethereum/go-ethereum/common"
@@ -15,6 +21,7 @@ import (
 	s3store "github.com/kkrt-labs/go-utils/store/s3"
 	input "github.com/kkrt-labs/zk-pig/src/prover-input"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Common test structures and helpers
@@ -118,9 +125,69 @@ func TestProverInputStore(t *testing.T) {
 			assert.NoError(t, err)
 			assert.Equal(t, ProverInput.ChainConfig.ChainID, loadedProverInput.ChainConfig.ChainID)
 
+			// Test loading ProverInput by block hash
+			loadedProverInput, err = ProverInputStore.LoadProverInputByHash(context.Background(), 2, ProverInput.Blocks[0].Header.Hash())
+			assert.NoError(t, err)
+			assert.Equal(t, ProverInput.Blocks[0].Header.Hash(), loadedProverInput.Blocks[0].Header.Hash())
+
 			// Test non-existent ProverInput
 			_, err = ProverInputStore.LoadProverInput(context.Background(), 2, 25)
-			assert.Error(t, err)
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = ProverInputStore.LoadProverInputByHash(context.Background(), 2, gethcommon.Hash{0x2})
+			assert.ErrorIs(t, err, ErrNotFound)
 		})
 	}
 }
+
+// magicCodec is a trivial codec prefixing the JSON encoding with a magic string
+type magicCodec struct{}
+
+const magic = "zk-pig:"
+
+func (c *magicCodec) Encode(w io.Writer, in *input.ProverInput) error {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(in)
+}
+
+func (c *magicCodec) Decode(r io.Reader) (*input.ProverInput, error) {
+	prefix := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if string(prefix) != magic {
+		return nil, fmt.Errorf("invalid magic %q", prefix)
+	}
+	return input.Decode(r)
+}
+
+func TestProverInputStoreCustomCodec(t *testing.T) {
+	input.RegisterCodec("magic", &magicCodec{})
+	t.Cleanup(func() { input.UnregisterCodec("magic") })
+
+	baseDir := t.TempDir()
+	store, err := New(&ProverInputStoreConfig{
+		StoreConfig: multistore.Config{FileConfig: &filestore.Config{DataDir: baseDir}},
+		Codec:       "magic",
+	})
+	require.NoError(t, err)
+
+	in := &input.ProverInput{
+		ChainConfig: &params.ChainConfig{ChainID: big.NewInt(2)},
+		Blocks:      []*input.Block{{Header: &gethtypes.Header{Number: big.NewInt(15), Difficulty: big.NewInt(15)}}},
+	}
+	require.NoError(t, store.StoreProverInput(context.Background(), in))
+
+	raw, err := os.ReadFile(filepath.Join(baseDir, "15"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), magic))
+
+	loaded, err := store.LoadProverInput(context.Background(), 2, 15)
+	require.NoError(t, err)
+	assert.Equal(t, in.ChainConfig.ChainID, loaded.ChainConfig.ChainID)
+	assert.Equal(t, in.Blocks[0].Header.Hash(), loaded.Blocks[0].Header.Hash())
+
+	_, err = New(&ProverInputStoreConfig{Codec: "unknown"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	store "github.com/kkrt-labs/go-utils/store"
+	filestore "github.com/kkrt-labs/go-utils/store/file"
+	s3store "github.com/kkrt-labs/go-utils/store/s3"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+)
+
+// Store persists ProverInputs under keys, serialized in the streaming format of input.ProverInput.WriteTo
+// so inputs are never buffered whole by the Store.
+// Keys are slash separated paths, see BlockNumberKey and BlockHashKey to address the input of a block.
+type Store interface {
+	// Put stores the prover input under the given key, replacing any input previously stored with this key.
+	Put(ctx context.Context, key string, pi *input.ProverInput) error
+
+	// Get loads the prover input stored under the given key.
+	// It returns an error wrapping ErrNotFound if no input is stored with this key.
+	Get(ctx context.Context, key string) (*input.ProverInput, error)
+}
+
+// BlockNumberKey returns the key addressing the prover input of a block by number
+func BlockNumberKey(chainID, blockNumber uint64) string {
+	return fmt.Sprintf("%d/%d", chainID, blockNumber)
+}
+
+// BlockHashKey returns the key addressing the prover input of a block by hash
+func BlockHashKey(chainID uint64, blockHash gethcommon.Hash) string {
+	return fmt.Sprintf("%d/hashes/%v", chainID, blockHash.Hex())
+}
+
+type streamStore struct {
+	store store.Store
+}
+
+// NewStore creates a Store on top of a go-utils store, which reads the inputs as they are serialized.
+//
+// The first segment of the keys is passed to the underlying store as the chainID header and the rest as its key,
+// as go-utils stores namespace their keys by chain ID.
+func NewStore(s store.Store) Store {
+	return &streamStore{store: s}
+}
+
+// NewFileStore creates a Store keeping each prover input in a file of dir, whose path within dir is the key.
+// The go-utils file store replaces the first "default" of its data directory with the chain ID, so dir must not contain "default".
+func NewFileStore(dir string) Store {
+	return NewStore(filestore.New(filestore.Config{DataDir: filepath.Join(dir, "default")}))
+}
+
+// NewS3StoreFromConfig creates a Store keeping each prover input in an object of the bucket of the go-utils S3 configuration,
+// whose key is the store key under the key prefix.
+// The go-utils S3 store reads an input whole before uploading it.
+func NewS3StoreFromConfig(cfg *s3store.Config) (Store, error) {
+	s, err := s3store.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(s), nil
+}
+
+func (s *streamStore) Put(ctx context.Context, key string, pi *input.ProverInput) error {
+	headers, key, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Serialize in a goroutine while the underlying store reads the input
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		_, err := pi.WriteTo(w)
+		if err == nil {
+			err = w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	if err := s.store.Store(ctx, key, pr, headers); err != nil {
+		return fmt.Errorf("failed to store prover input: %w", err)
+	}
+	return nil
+}
+
+func (s *streamStore) Get(ctx context.Context, key string) (*input.ProverInput, error) {
+	headers, storeKey, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := s.store.Load(ctx, storeKey, headers)
+	var noSuchKey *s3types.NoSuchKey
+	if errors.Is(err, fs.ErrNotExist) || errors.As(err, &noSuchKey) {
+		return nil, fmt.Errorf("%w: no input for key %q", ErrNotFound, key)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load prover input: %w", err)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	pi := new(input.ProverInput)
+	if _, err := pi.ReadFrom(bufio.NewReader(reader)); err != nil {
+		return nil, fmt.Errorf("failed to read prover input: %w", err)
+	}
+	return pi, nil
+}
+
+// splitKey splits a key into the headers holding its chain ID and the key within the chain, rejecting the keys escaping the store
+func splitKey(key string) (*store.Headers, string, error) {
+	chainID, rest, ok := strings.Cut(key, "/")
+	if !ok || chainID == "" || !fs.ValidPath(key) {
+		return nil, "", fmt.Errorf("invalid key %q", key)
+	}
+	return &store.Headers{KeyValue: map[string]string{"chainID": chainID}}, rest, nil
+}
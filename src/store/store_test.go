@@ -0,0 +1,126 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"math/big"
+	"sync"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	store "github.com/kkrt-labs/go-utils/store"
+	input "github.com/kkrt-labs/zk-pig/src/prover-input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWitnessInput returns a prover input with a witness of the given number of state nodes
+func testWitnessInput(stateNodes int) *input.ProverInput {
+	to := gethcommon.HexToAddress("0xdead")
+	parent := &gethtypes.Header{Number: big.NewInt(9), Difficulty: new(big.Int)}
+	state := make([]hexutil.Bytes, stateNodes)
+	for i := range state {
+		state[i] = hexutil.Bytes{0xc3, 0x20, byte(i >> 8), byte(i)}
+	}
+	return &input.ProverInput{
+		Version:     "v1",
+		ChainConfig: params.MainnetChainConfig,
+		Blocks: []*input.Block{
+			{
+				Header:       &gethtypes.Header{Number: big.NewInt(10), ParentHash: parent.Hash(), Difficulty: new(big.Int)},
+				Transactions: []*gethtypes.Transaction{gethtypes.NewTx(&gethtypes.LegacyTx{Nonce: 1, To: &to, Value: big.NewInt(1), Gas: 21000, GasPrice: big.NewInt(1)})},
+			},
+		},
+		Witness: &input.Witness{
+			State:     state,
+			Ancestors: []*gethtypes.Header{parent},
+			Codes:     []hexutil.Bytes{{0x60, 0x00}},
+		},
+	}
+}
+
+func assertSameInput(t *testing.T, expected, actual *input.ProverInput) {
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
+
+func TestKeys(t *testing.T) {
+	assert.Equal(t, "1/1234", BlockNumberKey(1, 1234))
+	assert.Equal(t, "1/hashes/0x0000000000000000000000000000000000000000000000000000000000000abc", BlockHashKey(1, gethcommon.HexToHash("0xabc")))
+}
+
+// memStore is an in-memory go-utils store, keeping objects by chain ID and key
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memStore) Store(_ context.Context, key string, reader io.Reader, headers *store.Headers) error {
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects == nil {
+		m.objects = make(map[string][]byte)
+	}
+	m.objects[headers.KeyValue["chainID"]+":"+key] = b
+	return nil
+}
+
+func (m *memStore) Load(_ context.Context, key string, headers *store.Headers) (io.Reader, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[headers.KeyValue["chainID"]+":"+key]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return bytes.NewReader(b), nil
+}
+
+func TestStore(t *testing.T) {
+	mem := &memStore{}
+	s := NewStore(mem)
+	in := testWitnessInput(16)
+
+	key := BlockHashKey(1, gethcommon.HexToHash("0xabc"))
+	require.NoError(t, s.Put(context.Background(), key, in))
+	// The chain ID is passed in the headers, the underlying store namespacing its keys by chain
+	assert.Contains(t, mem.objects, "1:hashes/0x0000000000000000000000000000000000000000000000000000000000000abc")
+
+	loaded, err := s.Get(context.Background(), key)
+	require.NoError(t, err)
+	assertSameInput(t, in, loaded)
+
+	_, err = s.Get(context.Background(), BlockHashKey(2, gethcommon.HexToHash("0xabc")))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// failingStore is a go-utils store failing after reading the beginning of the stored content
+type failingStore struct {
+	memStore
+}
+
+func (f *failingStore) Store(_ context.Context, _ string, reader io.Reader, _ *store.Headers) error {
+	if _, err := io.ReadFull(reader, make([]byte, 16)); err != nil {
+		return err
+	}
+	return errors.New("store failed")
+}
+
+func TestStoreFailure(t *testing.T) {
+	// The serialization is stopped once the underlying store fails, so Put returns instead of blocking on the pipe
+	err := NewStore(&failingStore{}).Put(context.Background(), BlockNumberKey(1, 10), testWitnessInput(1024))
+	assert.ErrorContains(t, err, "store failed")
+}